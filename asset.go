@@ -0,0 +1,99 @@
+package wetwire_aws
+
+import "encoding/json"
+
+// AssetMarker is the key of the object an Asset serializes to. The build
+// command replaces the object with the S3 location of the packaged asset.
+const AssetMarker = "Wetwire::Asset"
+
+// Asset is a local file or directory that the build command packages and
+// stores in S3, in place of a literal S3 location. Use it for Lambda code,
+// layer content and SAM code URIs:
+//
+//	var Handler = lambda.Function{
+//	    Code: lambda.Function_Code{S3Key: wetwire.Asset{Path: "./handler"}},
+//	    ...
+//	}
+//
+//	var Api = serverless.Function{
+//	    CodeUri: wetwire.Asset{Path: "./api"},
+//	    ...
+//	}
+//
+// A directory is zipped with stable ordering and timestamps, so unchanged
+// sources produce the same archive and S3 key. A .zip or .jar file is used
+// as-is; any other file is zipped on its own.
+//
+// An Asset in an S3Key field also sets the S3Bucket next to it. Anywhere
+// else it becomes a {"Bucket": ..., "Key": ...} location, the form SAM takes
+// for CodeUri and ContentUri.
+type Asset struct {
+	// Path is the file or directory, relative to the package directory
+	Path string
+}
+
+// MarshalJSON serializes the Asset to its marker object.
+func (a Asset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{AssetMarker: a.Path})
+}
+
+// ImageAssetMarker is the key of the object an ImageAsset serializes to.
+// The build command replaces the object with the image URI.
+const ImageAssetMarker = "Wetwire::ImageAsset"
+
+// ImageAsset is a container image built from a local Docker context, in
+// place of a literal image URI. Use it for ECS container images and Lambda
+// ImageUri:
+//
+//	var Repo = ecr.Repository{}
+//
+//	var Service = ecs.TaskDefinition{
+//	    ContainerDefinitions: []any{ecs.TaskDefinition_ContainerDefinition{
+//	        Name:  "app",
+//	        Image: wetwire.ImageAsset{Context: "./app", Repository: Repo},
+//	    }},
+//	    ...
+//	}
+//
+// The image tag is the SHA-256 of the context's files, as filtered by its
+// .dockerignore, together with the Dockerfile, build arguments and
+// platform, so the template changes exactly when the image does.
+type ImageAsset struct {
+	// Context is the Docker build context, relative to the package directory
+	Context string
+	// Dockerfile is relative to Context; empty means "Dockerfile"
+	Dockerfile string
+	// BuildArgs are passed to the build as --build-arg
+	BuildArgs map[string]string
+	// Platform is the target platform, e.g. "linux/arm64"
+	Platform string
+	// Repository is an ecr.Repository of the template, a repository URI or
+	// name, or an intrinsic that returns a URI. Empty uses the repository
+	// of wetwire.yaml, or else the AssetsRepositoryUri parameter.
+	Repository any
+}
+
+// CFValue returns the marker object of the ImageAsset. The runner
+// serializes it like a property value, so a Repository resource becomes a
+// Ref.
+func (a ImageAsset) CFValue() any {
+	spec := map[string]any{"Context": a.Context}
+	if a.Dockerfile != "" {
+		spec["Dockerfile"] = a.Dockerfile
+	}
+	if len(a.BuildArgs) > 0 {
+		spec["BuildArgs"] = a.BuildArgs
+	}
+	if a.Platform != "" {
+		spec["Platform"] = a.Platform
+	}
+	if a.Repository != nil {
+		spec["Repository"] = a.Repository
+	}
+	return map[string]any{ImageAssetMarker: spec}
+}
+
+// MarshalJSON serializes the ImageAsset to its marker object.
+func (a ImageAsset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.CFValue())
+}
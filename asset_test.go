@@ -0,0 +1,29 @@
+package wetwire_aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsset_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]any{"CodeUri": Asset{Path: "./handler"}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"CodeUri": {"Wetwire::Asset": "./handler"}}`, string(data))
+}
+
+func TestImageAsset_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]any{"Image": ImageAsset{
+		Context:    "./app",
+		BuildArgs:  map[string]string{"VERSION": "2"},
+		Repository: "app",
+	}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Image": {"Wetwire::ImageAsset": {
+		"Context": "./app",
+		"BuildArgs": {"VERSION": "2"},
+		"Repository": "app"
+	}}}`, string(data))
+}
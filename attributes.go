@@ -0,0 +1,51 @@
+package wetwire_aws
+
+// Deletion and replacement policy values for ResourceAttributes.
+const (
+	// Delete removes the resource (the CloudFormation default).
+	Delete = "Delete"
+	// Retain keeps the resource when it is removed from the stack.
+	Retain = "Retain"
+	// RetainExceptOnCreate keeps the resource unless the stack operation that created it rolls back.
+	RetainExceptOnCreate = "RetainExceptOnCreate"
+	// Snapshot takes a final snapshot before deleting (RDS, EBS, ElastiCache, etc.).
+	Snapshot = "Snapshot"
+)
+
+// ResourceAttributes attaches CloudFormation resource attributes to a resource
+// declaration. It is declared as a companion package-level variable next to
+// the resource it applies to:
+//
+//	var ProdDatabase = rds.DBCluster{...}
+//
+//	var ProdDatabaseAttributes = wetwire.ResourceAttributes{
+//	    Resource:       ProdDatabase,
+//	    DeletionPolicy: wetwire.Retain,
+//	    Condition:      "IsProd",
+//	    DependsOn:      []wetwire.Resource{AppSecurityGroup},
+//	}
+//
+// The CLI discovers the declaration via AST parsing and emits the attributes
+// on the target resource in the generated template.
+type ResourceAttributes struct {
+	// Resource is the resource these attributes apply to
+	Resource Resource `json:"-"`
+	// Condition is the logical name of a template condition that gates creation
+	Condition string `json:"Condition,omitempty"`
+	// DependsOn lists resources that must be created before this one.
+	// Only needed when no Ref or GetAtt already implies the ordering.
+	DependsOn []Resource `json:"-"`
+	// DeletionPolicy is one of Delete, Retain, RetainExceptOnCreate or
+	// Snapshot, or an intrinsic function such as If or FindInMap that
+	// returns one; intrinsics add the AWS::LanguageExtensions transform
+	DeletionPolicy any `json:"DeletionPolicy,omitempty"`
+	// UpdateReplacePolicy is one of Delete, Retain or Snapshot, or an
+	// intrinsic function that returns one
+	UpdateReplacePolicy any `json:"UpdateReplacePolicy,omitempty"`
+	// Metadata is arbitrary structured data associated with the resource
+	Metadata map[string]any `json:"Metadata,omitempty"`
+	// CreationPolicy waits for success signals before completing creation
+	CreationPolicy *CreationPolicy `json:"CreationPolicy,omitempty"`
+	// UpdatePolicy controls how updates are rolled out
+	UpdatePolicy *UpdatePolicy `json:"UpdatePolicy,omitempty"`
+}
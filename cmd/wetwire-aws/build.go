@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lex00/wetwire-aws-go/domain"
+)
+
+// addBuildFlags adds --auto-split to the generated build command.
+func addBuildFlags(root *cobra.Command, d *domain.AwsDomain) {
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == "build" {
+			withAutoSplit(cmd, d)
+		}
+	}
+}
+
+// withAutoSplit wraps the RunE of the build command to build with the
+// splitting builder when --auto-split is set.
+func withAutoSplit(cmd *cobra.Command, d *domain.AwsDomain) {
+	generated := cmd.RunE
+	cmd.Flags().Bool("auto-split", false, "Split templates over the CloudFormation limits into nested stacks")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if split, _ := cmd.Flags().GetBool("auto-split"); !split {
+			return generated(cmd, args)
+		}
+
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		format, _ := cmd.Flags().GetString("format")
+		buildType, _ := cmd.Flags().GetString("type")
+		output, _ := cmd.Flags().GetString("output")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		ctx := domain.NewContextWithVerbose(context.Background(), path, verbose)
+		result, err := d.SplittingBuilder().Build(ctx, path, domain.BuildOpts{
+			Format: format,
+			Type:   buildType,
+			Output: output,
+			DryRun: dryRun,
+		})
+		if err != nil {
+			return fmt.Errorf("build failed: %w", err)
+		}
+
+		formatted, err := domain.FormatResult(result, format)
+		if err != nil {
+			return fmt.Errorf("failed to format result: %w", err)
+		}
+		fmt.Fprint(os.Stdout, formatted)
+		if !result.Success {
+			return fmt.Errorf("operation failed")
+		}
+		return nil
+	}
+}
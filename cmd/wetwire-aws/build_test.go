@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/domain"
+)
+
+func TestAddBuildFlags(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+	addBuildFlags(root, d)
+
+	build, _, err := root.Find([]string{"build"})
+	if err != nil {
+		t.Fatalf("expected generated build command: %v", err)
+	}
+	if build.Flags().Lookup("auto-split") == nil {
+		t.Error("expected --auto-split flag on build")
+	}
+}
+
+func TestAutoSplitMultiStack(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+	addBuildFlags(root, d)
+
+	root.SetArgs([]string{"build", "--auto-split", "./..."})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+	if err := root.Execute(); err == nil {
+		t.Error("expected an error splitting a multi-stack build")
+	}
+}
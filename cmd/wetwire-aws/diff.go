@@ -4,49 +4,205 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/build"
+	"github.com/lex00/wetwire-aws-go/internal/config"
 	"github.com/lex00/wetwire-aws-go/internal/differ"
+	"github.com/lex00/wetwire-aws-go/internal/schema"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+	"github.com/lex00/wetwire-aws-go/internal/worktree"
 )
 
 // newDiffCmd creates the "diff" subcommand for comparing templates.
 func newDiffCmd() *cobra.Command {
-	var (
-		outputFormat string
-		ignoreOrder  bool
-	)
+	var opts diffOptions
 
 	cmd := &cobra.Command{
-		Use:   "diff <template1> <template2>",
+		Use:   "diff <old> <new>",
 		Short: "Compare two CloudFormation templates",
 		Long: `Diff performs a semantic comparison of two CloudFormation templates,
-showing added, removed, and modified resources.
+showing added, removed, and modified transforms, parameters, mappings,
+conditions, resources and outputs.
+
+Either side may be a template file (JSON or YAML) or a Go package, which is
+built in-process. With --base-ref, the one package given is built both from
+the working tree and at the git revision, checked out in a temporary
+worktree, to show what a change does to the template.
+
+Removing an exported output, or removing or renaming its export, is marked
+as breaking since other stacks may import the export.
+
+Each modified resource is classified by the update types of its changed
+properties: "will replace" (an Immutable property changed), "may replace"
+(a Conditional property changed), "unknown" (a changed property has no known
+update type) or "in-place". With --fail-on-replace the command exits with
+status 2 when a resource will or may be replaced, unless its logical name or
+type is listed in --allow-replace. Resources classified "unknown" also fail
+the command with --strict.
 
 The comparison is semantic, not textual - it compares the structure and values
 of resources rather than the raw text.
 
 Examples:
     wetwire-aws diff old.json new.json
+    wetwire-aws diff deployed.json ./infra/...
+    wetwire-aws diff --base-ref main ./infra/...
     wetwire-aws diff old.yaml new.yaml -f json
-    wetwire-aws diff template1.json template2.json --ignore-order`,
-		Args: cobra.ExactArgs(2),
+    wetwire-aws diff template1.json template2.json --ignore-order
+    wetwire-aws diff deployed.json template.json --fail-on-replace --allow-replace AWS::Lambda::Function
+    wetwire-aws diff deployed.json template.json --fail-on-replace --strict`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.baseRef != "" {
+				if len(args) != 1 {
+					return fmt.Errorf("--base-ref takes one package, received %d args", len(args))
+				}
+				return nil
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDiff(args[0], args[1], outputFormat, ignoreOrder)
+			if !isValidColorMode(opts.color) {
+				return fmt.Errorf("invalid color mode: %s (valid: auto, always, never)", opts.color)
+			}
+			if opts.baseRef != "" {
+				return runDiffBaseRef(args[0], opts)
+			}
+			return runDiff(args[0], args[1], opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: text or json")
-	cmd.Flags().BoolVar(&ignoreOrder, "ignore-order", false, "Ignore array element order in comparisons")
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "text", "Output format: text or json")
+	cmd.Flags().BoolVar(&opts.ignoreOrder, "ignore-order", false, "Ignore array element order in comparisons")
+	cmd.Flags().BoolVar(&opts.failOnReplace, "fail-on-replace", false, "Exit with status 2 when a resource will or may be replaced")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "With --fail-on-replace, also fail when a resource's update type is unknown")
+	cmd.Flags().StringSliceVar(&opts.allowReplace, "allow-replace", nil, "Logical names or resource types allowed to be replaced with --fail-on-replace")
+	cmd.Flags().StringVar(&opts.color, "color", "auto", "Colorize text output: auto, always or never")
+	cmd.Flags().StringVar(&opts.baseRef, "base-ref", "", "Compare the package against its build at this git revision")
 
 	return cmd
 }
 
-// runDiff compares two templates and outputs differences.
-func runDiff(file1, file2, format string, ignoreOrder bool) error {
-	diffResult, err := differ.CompareFiles(file1, file2, differ.Options{
-		IgnoreOrder: ignoreOrder,
+// diffOptions holds the flags of the diff command.
+type diffOptions struct {
+	format        string
+	ignoreOrder   bool
+	failOnReplace bool
+	strict        bool
+	allowReplace  []string
+	color         string
+	baseRef       string
+}
+
+// isValidColorMode checks a --color value.
+func isValidColorMode(mode string) bool {
+	return mode == "auto" || mode == "always" || mode == "never"
+}
+
+// useColor reports whether text output is colorized: always, never, or in
+// auto mode when stdout is a terminal and NO_COLOR is unset.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runDiff compares two templates or packages and outputs differences.
+func runDiff(old, new string, opts diffOptions) error {
+	oldTemplate, err := loadTemplateOrPackage(old)
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+	newTemplate, err := loadTemplateOrPackage(new)
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+	return compareTemplates(oldTemplate, newTemplate, old, new, opts)
+}
+
+// runDiffBaseRef compares a package with its build at opts.baseRef.
+func runDiffBaseRef(pkgPath string, opts diffOptions) error {
+	root, err := worktree.Root(strings.TrimSuffix(pkgPath, "/..."))
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+	wt, err := worktree.Add(root, opts.baseRef)
+	if err != nil {
+		return fmt.Errorf("diff failed: %w", err)
+	}
+	// compareTemplates may exit, so remove the worktree before comparing
+	oldTemplate, err := buildAtWorktree(wt, pkgPath)
+	if rmErr := wt.Remove(); rmErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: removing worktree: %v\n", rmErr)
+	}
+	if err != nil {
+		return fmt.Errorf("diff failed: building %s at %s: %w", pkgPath, opts.baseRef, err)
+	}
+
+	newTemplate, err := buildPackageTemplate(pkgPath)
+	if err != nil {
+		return fmt.Errorf("diff failed: building %s: %w", pkgPath, err)
+	}
+	return compareTemplates(oldTemplate, newTemplate, pkgPath+"@"+opts.baseRef, pkgPath, opts)
+}
+
+// buildAtWorktree builds the package at pkgPath as checked out in wt.
+func buildAtWorktree(wt *worktree.Worktree, pkgPath string) (*wetwire.Template, error) {
+	path, err := wt.Path(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	return buildPackageTemplate(path)
+}
+
+// loadTemplateOrPackage loads a template file, or builds the template of a
+// Go package when path is a directory or package pattern.
+func loadTemplateOrPackage(path string) (*wetwire.Template, error) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return differ.LoadTemplate(path)
+	}
+	tmpl, err := buildPackageTemplate(path)
+	if err != nil {
+		return nil, fmt.Errorf("building %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// buildPackageTemplate builds a package's template and converts it to the form
+// of a template read from a file, so templates from either source look alike.
+func buildPackageTemplate(pkgPath string) (*wetwire.Template, error) {
+	tmpl, err := build.Package(pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := template.ToJSON(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var loaded wetwire.Template
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, err
+	}
+	return &loaded, nil
+}
+
+// compareTemplates compares two templates and outputs differences, naming
+// the sides old and new.
+func compareTemplates(oldTemplate, newTemplate *wetwire.Template, old, new string, opts diffOptions) error {
+	diffResult, err := differ.Compare(oldTemplate, newTemplate, differ.Options{
+		IgnoreOrder: opts.ignoreOrder,
 	})
 	if err != nil {
 		return fmt.Errorf("diff failed: %w", err)
@@ -58,10 +214,17 @@ func runDiff(file1, file2, format string, ignoreOrder bool) error {
 		Summary: diffResult.Summary,
 	}
 
-	return outputDiffResult(result, format, file1, file2)
+	var blocked []wetwire.DiffEntry
+	if opts.failOnReplace {
+		blocked = differ.Replacements(diffResult, opts.allowReplace, opts.strict)
+	}
+
+	return outputDiffResult(result, opts.format, old, new, blocked, useColor(opts.color))
 }
 
-func outputDiffResult(result wetwire.DiffResult, format, file1, file2 string) error {
+// outputDiffResult prints the result. Blocked lists the replacements that
+// fail the command.
+func outputDiffResult(result wetwire.DiffResult, format, file1, file2 string, blocked []wetwire.DiffEntry, color bool) error {
 	switch format {
 	case "json":
 		data, err := json.MarshalIndent(result, "", "  ")
@@ -78,43 +241,212 @@ func outputDiffResult(result wetwire.DiffResult, format, file1, file2 string) er
 
 		fmt.Printf("Comparing %s vs %s\n\n", file1, file2)
 
-		if len(result.Diff.Added) > 0 {
-			fmt.Println("=== Added Resources ===")
-			for _, entry := range result.Diff.Added {
-				fmt.Printf("  + %s (%s)\n", entry.Resource, entry.Type)
-			}
-			fmt.Println()
+		fmt.Print(formatDiffEntries(result.Diff, color))
+
+		fmt.Printf("Summary: %d added, %d removed, %d modified",
+			result.Summary.Added, result.Summary.Removed, result.Summary.Modified)
+		var notes []string
+		if result.Summary.Breaking > 0 {
+			notes = append(notes, fmt.Sprintf("%d breaking", result.Summary.Breaking))
+		}
+		if result.Summary.Replaced > 0 {
+			notes = append(notes, fmt.Sprintf("%d replaced", result.Summary.Replaced))
+		}
+		if result.Summary.Renamed > 0 {
+			notes = append(notes, fmt.Sprintf("%d renamed", result.Summary.Renamed))
+		}
+		if len(notes) > 0 {
+			fmt.Printf(" (%s)", strings.Join(notes, ", "))
 		}
+		fmt.Println()
 
-		if len(result.Diff.Removed) > 0 {
-			fmt.Println("=== Removed Resources ===")
-			for _, entry := range result.Diff.Removed {
-				fmt.Printf("  - %s (%s)\n", entry.Resource, entry.Type)
-			}
-			fmt.Println()
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	if len(blocked) > 0 {
+		for _, e := range blocked {
+			fmt.Fprintf(os.Stderr, "Error: %s (%s) %s\n", e.Resource, e.Type, replacementLabels[e.Replacement])
 		}
+		os.Exit(2) // Exit code 2 indicates a replacement not allowed by --allow-replace
+	}
+
+	if result.Summary.Total > 0 {
+		os.Exit(1) // Exit code 1 indicates differences found
+	}
+
+	return nil
+}
+
+// sectionHeadings names the template sections in diff headings.
+var sectionHeadings = map[string]string{
+	differ.SectionTransform:  "Transforms",
+	differ.SectionParameters: "Parameters",
+	differ.SectionMappings:   "Mappings",
+	differ.SectionConditions: "Conditions",
+	differ.SectionResources:  "Resources",
+	differ.SectionOutputs:    "Outputs",
+}
 
-		if len(result.Diff.Modified) > 0 {
-			fmt.Println("=== Modified Resources ===")
-			for _, entry := range result.Diff.Modified {
-				fmt.Printf("  ~ %s (%s)\n", entry.Resource, entry.Type)
+// replacementLabels describes the replacement values of diff entries.
+var replacementLabels = map[string]string{
+	differ.ReplacementNone:        "in-place",
+	differ.ReplacementConditional: "may replace",
+	differ.ReplacementAlways:      "will replace",
+	differ.ReplacementUnknown:     "unknown update type, may replace",
+}
+
+// replacementNote describes how a modified resource is updated, naming the
+// properties that replace it, e.g. "will replace: DBInstanceIdentifier".
+func replacementNote(entry wetwire.DiffEntry) string {
+	label := replacementLabels[entry.Replacement]
+	var causes []string
+	for prop, updateType := range entry.UpdateTypes {
+		if updateType != schema.UpdateMutable {
+			causes = append(causes, prop)
+		}
+	}
+	if len(causes) == 0 {
+		return label
+	}
+	sort.Strings(causes)
+	return label + ": " + strings.Join(causes, ", ")
+}
+
+// formatDiffEntries renders the added, removed and modified entries of each
+// template section, e.g. "=== Removed Outputs ===", followed by the probable
+// renames. Modified entries show their property changes as unified diff hunks.
+func formatDiffEntries(diff wetwire.TemplateDiff, color bool) string {
+	var sb strings.Builder
+	groups := []struct {
+		action  string
+		marker  string
+		entries []wetwire.DiffEntry
+	}{
+		{"Added", "+", diff.Added},
+		{"Removed", "-", diff.Removed},
+		{"Modified", "~", diff.Modified},
+	}
+
+	for _, section := range differ.Sections {
+		for _, group := range groups {
+			var entries []wetwire.DiffEntry
+			for _, e := range group.entries {
+				if e.Section == section {
+					entries = append(entries, e)
+				}
+			}
+			if len(entries) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(&sb, "=== %s %s ===\n", group.action, sectionHeadings[section])
+			for _, entry := range entries {
+				fmt.Fprintf(&sb, "  %s %s", group.marker, entry.Resource)
+				if entry.Type != "" {
+					fmt.Fprintf(&sb, " (%s)", entry.Type)
+				}
+				if entry.Breaking {
+					sb.WriteString(" [BREAKING]")
+				}
+				if entry.Replacement != "" {
+					fmt.Fprintf(&sb, " [%s]", replacementNote(entry))
+				}
+				sb.WriteString("\n")
+				if len(entry.PropertyChanges) > 0 {
+					writeHunks(&sb, entry.PropertyChanges, color)
+					continue
+				}
 				for _, change := range entry.Changes {
-					fmt.Printf("      %s\n", change)
+					fmt.Fprintf(&sb, "      %s\n", change)
 				}
 			}
-			fmt.Println()
+			sb.WriteString("\n")
 		}
+	}
 
-		fmt.Printf("Summary: %d added, %d removed, %d modified\n",
-			result.Summary.Added, result.Summary.Removed, result.Summary.Modified)
+	writeRenames(&sb, diff.Renames, color)
 
-	default:
-		return fmt.Errorf("unknown format: %s", format)
+	return sb.String()
+}
+
+// writeRenames lists probable renames. Renaming a stateful resource gets a
+// warning, since CloudFormation deletes the old resource and its data, and a
+// hint to keep the old logical ID with an alias.
+func writeRenames(sb *strings.Builder, renames []wetwire.Rename, color bool) {
+	if len(renames) == 0 {
+		return
 	}
 
-	if result.Summary.Total > 0 {
-		os.Exit(1) // Exit code 1 indicates differences found
+	sb.WriteString("=== Probable Renames ===\n")
+	for _, r := range renames {
+		fmt.Fprintf(sb, "  %s → %s (%s, %d%% similar)\n", r.From, r.To, r.Type, int(r.Similarity*100))
+		if !r.Stateful {
+			continue
+		}
+		warning := fmt.Sprintf("WARNING: CloudFormation will delete %s and its data after creating %s", r.From, r.To)
+		if color {
+			warning = colorRemoved + warning + colorReset
+		}
+		fmt.Fprintf(sb, "      %s\n", warning)
+		fmt.Fprintf(sb, "      Keep the logical ID with build.aliases in %s:  %s: %s\n", config.FileName, r.To, r.From)
 	}
+	sb.WriteString("\n")
+}
 
-	return nil
+// ANSI colors of unified diff output, as used by git diff.
+const (
+	colorHeader  = "\033[36m"
+	colorRemoved = "\033[31m"
+	colorAdded   = "\033[32m"
+	colorReset   = "\033[0m"
+)
+
+// writeHunks renders property changes as unified diff hunks:
+//
+//	@@ /Properties/BucketName @@
+//	- "logs"
+//	+ "logs-v2"
+func writeHunks(sb *strings.Builder, changes []wetwire.PropertyChange, color bool) {
+	paint := func(c, line string) string {
+		if !color {
+			return line
+		}
+		return c + line + colorReset
+	}
+
+	for _, change := range changes {
+		header := "@@ " + change.Path + " @@"
+		if change.Kind == differ.ChangeMoved {
+			header = "@@ " + change.From + " → " + change.Path + " @@ moved"
+		}
+		fmt.Fprintf(sb, "      %s\n", paint(colorHeader, header))
+
+		switch change.Kind {
+		case differ.ChangeMoved:
+			for _, line := range valueLines(change.New) {
+				fmt.Fprintf(sb, "        %s\n", line)
+			}
+		default:
+			if change.Kind != differ.ChangeAdded {
+				for _, line := range valueLines(change.Old) {
+					fmt.Fprintf(sb, "      %s\n", paint(colorRemoved, "- "+line))
+				}
+			}
+			if change.Kind != differ.ChangeRemoved {
+				for _, line := range valueLines(change.New) {
+					fmt.Fprintf(sb, "      %s\n", paint(colorAdded, "+ "+line))
+				}
+			}
+		}
+	}
+}
+
+// valueLines renders a value as indented JSON lines.
+func valueLines(v any) []string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return []string{fmt.Sprintf("%v", v)}
+	}
+	return strings.Split(string(data), "\n")
 }
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/differ"
 )
 
 func TestNewDiffCmd(t *testing.T) {
 	cmd := newDiffCmd()
 
-	if cmd.Use != "diff <template1> <template2>" {
-		t.Errorf("Use = %q, want 'diff <template1> <template2>'", cmd.Use)
+	if cmd.Use != "diff <old> <new>" {
+		t.Errorf("Use = %q, want 'diff <old> <new>'", cmd.Use)
 	}
 
 	if cmd.Short == "" {
@@ -23,4 +29,204 @@ func TestNewDiffCmd(t *testing.T) {
 	if cmd.Flags().Lookup("ignore-order") == nil {
 		t.Error("missing --ignore-order flag")
 	}
+
+	if cmd.Flags().Lookup("fail-on-replace") == nil {
+		t.Error("missing --fail-on-replace flag")
+	}
+
+	if cmd.Flags().Lookup("allow-replace") == nil {
+		t.Error("missing --allow-replace flag")
+	}
+
+	if cmd.Flags().Lookup("strict") == nil {
+		t.Error("missing --strict flag")
+	}
+
+	if cmd.Flags().Lookup("color") == nil {
+		t.Error("missing --color flag")
+	}
+
+	if cmd.Flags().Lookup("base-ref") == nil {
+		t.Error("missing --base-ref flag")
+	}
+}
+
+func TestFormatDiffEntries(t *testing.T) {
+	diff := wetwire.TemplateDiff{
+		Added: []wetwire.DiffEntry{
+			{Section: differ.SectionParameters, Resource: "Environment", Type: "String"},
+			{Section: differ.SectionResources, Resource: "Bucket", Type: "AWS::S3::Bucket"},
+		},
+		Removed: []wetwire.DiffEntry{
+			{Section: differ.SectionOutputs, Resource: "VpcId", Changes: []string{"Export shared-vpc removed"}, Breaking: true},
+		},
+	}
+
+	got := formatDiffEntries(diff, false)
+	want := `=== Added Parameters ===
+  + Environment (String)
+
+=== Added Resources ===
+  + Bucket (AWS::S3::Bucket)
+
+=== Removed Outputs ===
+  - VpcId [BREAKING]
+      Export shared-vpc removed
+
+`
+	if got != want {
+		t.Errorf("formatDiffEntries() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatDiffEntries_Replacement(t *testing.T) {
+	diff := wetwire.TemplateDiff{
+		Modified: []wetwire.DiffEntry{
+			{
+				Section: differ.SectionResources, Resource: "Orders", Type: "AWS::RDS::DBInstance",
+				Changes:     []string{"AllocatedStorage modified", "DBInstanceIdentifier modified", "Engine modified"},
+				Replacement: differ.ReplacementAlways,
+				UpdateTypes: map[string]string{"AllocatedStorage": "Mutable", "DBInstanceIdentifier": "Immutable", "Engine": "Conditional"},
+			},
+			{
+				Section: differ.SectionResources, Resource: "Logs", Type: "AWS::S3::Bucket",
+				Changes:     []string{"Tags modified"},
+				Replacement: differ.ReplacementNone,
+				UpdateTypes: map[string]string{"Tags": "Mutable"},
+			},
+			{
+				Section: differ.SectionResources, Resource: "Jobs", Type: "AWS::SQS::Queue",
+				Changes:     []string{"DelaySeconds modified"},
+				Replacement: differ.ReplacementUnknown,
+				UpdateTypes: map[string]string{"DelaySeconds": differ.UpdateUnknown},
+			},
+		},
+	}
+
+	got := formatDiffEntries(diff, false)
+	for _, want := range []string{
+		"  ~ Orders (AWS::RDS::DBInstance) [will replace: DBInstanceIdentifier, Engine]\n",
+		"  ~ Logs (AWS::S3::Bucket) [in-place]\n",
+		"  ~ Jobs (AWS::SQS::Queue) [unknown update type, may replace: DelaySeconds]\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatDiffEntries_Renames(t *testing.T) {
+	diff := wetwire.TemplateDiff{
+		Renames: []wetwire.Rename{
+			{From: "DataBucket", To: "LogsBucket", Type: "AWS::S3::Bucket", Similarity: 0.9, Stateful: true},
+			{From: "Handler", To: "Worker", Type: "AWS::Lambda::Function", Similarity: 1},
+		},
+	}
+
+	got := formatDiffEntries(diff, false)
+	for _, want := range []string{
+		"=== Probable Renames ===\n",
+		"  DataBucket → LogsBucket (AWS::S3::Bucket, 90% similar)\n",
+		"      WARNING: CloudFormation will delete DataBucket and its data after creating LogsBucket\n",
+		"LogsBucket: DataBucket\n",
+		"  Handler → Worker (AWS::Lambda::Function, 100% similar)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "WARNING") != 1 {
+		t.Errorf("want a warning for the stateful rename only:\n%s", got)
+	}
+}
+
+func TestFormatDiffEntries_Hunks(t *testing.T) {
+	diff := wetwire.TemplateDiff{
+		Modified: []wetwire.DiffEntry{{
+			Section: differ.SectionResources, Resource: "Logs", Type: "AWS::S3::Bucket",
+			Changes:     []string{"BucketName modified", "Tags modified"},
+			Replacement: differ.ReplacementAlways,
+			UpdateTypes: map[string]string{"BucketName": "Immutable", "Tags": "Mutable"},
+			PropertyChanges: []wetwire.PropertyChange{
+				{Path: "/Properties/BucketName", Kind: differ.ChangeModified, Old: "logs", New: "logs-v2"},
+				{Path: "/Properties/Tags/0", Kind: differ.ChangeMoved, From: "/Properties/Tags/1", New: "b"},
+				{Path: "/Properties/Tags/2", Kind: differ.ChangeAdded, New: map[string]any{"Key": "env"}},
+			},
+		}},
+	}
+
+	want := `=== Modified Resources ===
+  ~ Logs (AWS::S3::Bucket) [will replace: BucketName]
+      @@ /Properties/BucketName @@
+      - "logs"
+      + "logs-v2"
+      @@ /Properties/Tags/1 → /Properties/Tags/0 @@ moved
+        "b"
+      @@ /Properties/Tags/2 @@
+      + {
+      +   "Key": "env"
+      + }
+
+`
+	if got := formatDiffEntries(diff, false); got != want {
+		t.Errorf("formatDiffEntries() =\n%s\nwant:\n%s", got, want)
+	}
+
+	colored := formatDiffEntries(diff, true)
+	for _, want := range []string{colorHeader + "@@ /Properties/BucketName @@" + colorReset, colorRemoved + `- "logs"` + colorReset, colorAdded + `+ "logs-v2"` + colorReset} {
+		if !strings.Contains(colored, want) {
+			t.Errorf("colored output missing %q", want)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	if !useColor("always") {
+		t.Error("always should colorize")
+	}
+	if useColor("never") {
+		t.Error("never should not colorize")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor("auto") {
+		t.Error("auto should not colorize with NO_COLOR set")
+	}
+	if isValidColorMode("sometimes") {
+		t.Error("'sometimes' should not be a valid color mode")
+	}
+}
+
+func TestDiffArgs(t *testing.T) {
+	cmd := newDiffCmd()
+	if err := cmd.Args(cmd, []string{"old.json"}); err == nil {
+		t.Error("expected an error for one template without --base-ref")
+	}
+	if err := cmd.Args(cmd, []string{"old.json", "new.json"}); err != nil {
+		t.Errorf("unexpected error for two templates: %v", err)
+	}
+
+	if err := cmd.Flags().Set("base-ref", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Args(cmd, []string{"./infra/..."}); err != nil {
+		t.Errorf("unexpected error for one package with --base-ref: %v", err)
+	}
+	if err := cmd.Args(cmd, []string{"old.json", "new.json"}); err == nil {
+		t.Error("expected an error for two arguments with --base-ref")
+	}
+}
+
+func TestLoadTemplateOrPackage_TemplateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "template.json")
+	if err := os.WriteFile(path, []byte(`{"Resources": {"Bucket": {"Type": "AWS::S3::Bucket"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadTemplateOrPackage(path)
+	if err != nil {
+		t.Fatalf("loadTemplateOrPackage() error = %v", err)
+	}
+	if tmpl.Resources["Bucket"].Type != "AWS::S3::Bucket" {
+		t.Errorf("Resources = %v", tmpl.Resources)
+	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lex00/wetwire-aws-go/domain"
+)
+
+// addGraphFormats teaches the generated graph command to print dot, mermaid
+// and html graphs as-is. The command shares the global --format flag, whose
+// text default means dot here; json and yaml are left to the generated
+// command.
+func addGraphFormats(root *cobra.Command, d *domain.AwsDomain) {
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == "graph" {
+			withGraphFormats(cmd, d)
+		}
+	}
+}
+
+// withGraphFormats wraps the RunE of the graph command.
+func withGraphFormats(cmd *cobra.Command, d *domain.AwsDomain) {
+	generated := cmd.RunE
+	cmd.Long = `Graph builds the CloudFormation template and draws the references between
+its resources, parameters, conditions and outputs, and the values it imports
+from other stacks.
+
+Formats:
+    dot      - Graphviz DOT (default)
+    mermaid  - Mermaid flowchart for GitHub and markdown
+    html     - Self-contained interactive viewer
+    json     - Nodes and edges as JSON
+    yaml     - Nodes and edges as YAML
+
+Examples:
+    wetwire-aws graph ./infra | dot -Tpng -o deps.png
+    wetwire-aws graph ./infra -f mermaid
+    wetwire-aws graph ./infra -f html > graph.html`
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		switch format {
+		case "text":
+			format = "dot"
+		case "dot", "mermaid", "html":
+		default:
+			return generated(cmd, args)
+		}
+
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		ctx := domain.NewContextWithVerbose(context.Background(), path, verbose)
+		result, err := d.Grapher().Graph(ctx, path, domain.GraphOpts{Format: format})
+		if err != nil {
+			return fmt.Errorf("graph failed: %w", err)
+		}
+		if !result.Success {
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", e.Message)
+			}
+			return fmt.Errorf("operation failed")
+		}
+
+		fmt.Println(result.Data)
+		return nil
+	}
+}
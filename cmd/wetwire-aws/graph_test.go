@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/domain"
+)
+
+func TestAddGraphFormats(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+	addGraphFormats(root, d)
+
+	graph, _, err := root.Find([]string{"graph"})
+	if err != nil {
+		t.Fatalf("expected generated graph command: %v", err)
+	}
+	if !strings.Contains(graph.Long, "mermaid") {
+		t.Errorf("graph help = %q, want formats listed", graph.Long)
+	}
+}
+
+func TestGraphFormatError(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+	addGraphFormats(root, d)
+
+	root.SetArgs([]string{"graph", "--format", "mermaid", "./does-not-exist"})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+	if err := root.Execute(); err == nil {
+		t.Error("expected an error graphing a missing package")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/impact"
+)
+
+// newImpactCmd creates the "impact" subcommand for reverse dependency analysis.
+func newImpactCmd() *cobra.Command {
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "impact <ResourceName> [packages...]",
+		Short: "Show what depends on a resource",
+		Long: `Impact lists everything that would be affected by changing a resource or
+parameter: the resources that reference it directly or transitively, the
+fields through which each one references it, and the outputs and exports
+built from any of them.
+
+Examples:
+    wetwire-aws impact DataBucket ./infra/...
+    wetwire-aws impact SharedVpc ./infra/... -f json`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImpact(args[0], args[1:], outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: text or json")
+
+	return cmd
+}
+
+// runImpact discovers the packages and reports the impact of changing target.
+func runImpact(target string, packages []string, format string) error {
+	discoverResult, err := discover.Discover(discover.Options{
+		Packages: packages,
+	})
+	if err != nil {
+		return fmt.Errorf("impact failed: %w", err)
+	}
+	if len(discoverResult.Errors) > 0 {
+		for _, e := range discoverResult.Errors {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+		}
+		return fmt.Errorf("impact failed: discovery errors")
+	}
+
+	result, err := impact.Analyze(discoverResult, target)
+	if err != nil {
+		return fmt.Errorf("impact failed: %w", err)
+	}
+
+	return outputImpactResult(result, format)
+}
+
+func outputImpactResult(result *impact.Result, format string) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+
+	case "text":
+		fmt.Print(formatImpact(result))
+
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+
+	return nil
+}
+
+// formatImpact renders an impact result as text.
+func formatImpact(result *impact.Result) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s (%s)", result.Target, result.TargetType)
+	if result.File != "" {
+		fmt.Fprintf(&sb, " at %s:%d", result.File, result.Line)
+	}
+	sb.WriteString("\n\n")
+
+	if len(result.Dependents) == 0 {
+		sb.WriteString("No resources depend on it.\n")
+	} else {
+		fmt.Fprintf(&sb, "%d dependent resources:\n", len(result.Dependents))
+		depth := 0
+		for _, d := range result.Dependents {
+			if d.Depth != depth {
+				depth = d.Depth
+				if depth == 1 {
+					sb.WriteString("\n=== Direct ===\n")
+				} else {
+					fmt.Fprintf(&sb, "\n=== Depth %d ===\n", depth)
+				}
+			}
+			fmt.Fprintf(&sb, "%s (%s)", d.Name, d.Type)
+			if d.File != "" {
+				fmt.Fprintf(&sb, " %s:%d", d.File, d.Line)
+			}
+			sb.WriteString("\n")
+			writeReferences(&sb, d.References)
+		}
+	}
+
+	if len(result.Outputs) > 0 {
+		fmt.Fprintf(&sb, "\n=== Outputs (%d) ===\n", len(result.Outputs))
+		for _, o := range result.Outputs {
+			sb.WriteString(o.Name)
+			if o.Exported {
+				if o.ExportName != "" {
+					fmt.Fprintf(&sb, " [export: %s]", o.ExportName)
+				} else {
+					sb.WriteString(" [exported]")
+				}
+			}
+			if o.File != "" {
+				fmt.Fprintf(&sb, " %s:%d", o.File, o.Line)
+			}
+			sb.WriteString("\n")
+			writeReferences(&sb, o.References)
+		}
+	}
+
+	return sb.String()
+}
+
+// writeReferences writes one indented line per reference, e.g.
+// "  VpcConfig.SubnetIds -> AppSubnet [via AppVpcConfig]".
+func writeReferences(sb *strings.Builder, refs []impact.Reference) {
+	for _, ref := range refs {
+		path := ref.FieldPath
+		if path == "" {
+			path = "(unknown field)"
+		}
+		fmt.Fprintf(sb, "  %s -> %s", path, ref.To)
+		if ref.Attribute != "" {
+			fmt.Fprintf(sb, ".%s", ref.Attribute)
+		}
+		if len(ref.Via) > 0 {
+			fmt.Fprintf(sb, " [via %s]", strings.Join(ref.Via, ", "))
+		}
+		sb.WriteString("\n")
+	}
+}
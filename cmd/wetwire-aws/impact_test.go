@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/internal/impact"
+)
+
+func TestNewImpactCmd(t *testing.T) {
+	cmd := newImpactCmd()
+
+	if cmd.Use != "impact <ResourceName> [packages...]" {
+		t.Errorf("Use = %q, want 'impact <ResourceName> [packages...]'", cmd.Use)
+	}
+
+	if cmd.Flags().Lookup("format") == nil {
+		t.Error("missing --format flag")
+	}
+
+	if err := cmd.Args(cmd, []string{"DataBucket"}); err == nil {
+		t.Error("expected an error without packages")
+	}
+}
+
+func TestFormatImpact(t *testing.T) {
+	result := &impact.Result{
+		Target:     "SharedVpc",
+		TargetType: "ec2.VPC",
+		File:       "network.go",
+		Line:       5,
+		Dependents: []impact.Dependent{
+			{Name: "AppSubnet", Type: "ec2.Subnet", File: "network.go", Line: 9, Depth: 1,
+				References: []impact.Reference{{To: "SharedVpc", FieldPath: "VpcId"}}},
+			{Name: "AppFunction", Type: "lambda.Function", Depth: 2,
+				References: []impact.Reference{{To: "AppSubnet", FieldPath: "VpcConfig.SubnetIds", Via: []string{"AppVpcConfig"}}}},
+		},
+		Outputs: []impact.AffectedOutput{
+			{Name: "VpcIdOutput", Exported: true, ExportName: "shared-vpc-id",
+				References: []impact.Reference{{To: "SharedVpc", FieldPath: "Value", Attribute: "VpcId"}}},
+		},
+	}
+
+	got := formatImpact(result)
+	for _, want := range []string{
+		"SharedVpc (ec2.VPC) at network.go:5",
+		"2 dependent resources:",
+		"=== Direct ===\nAppSubnet (ec2.Subnet) network.go:9\n  VpcId -> SharedVpc\n",
+		"=== Depth 2 ===\nAppFunction (lambda.Function)\n  VpcConfig.SubnetIds -> AppSubnet [via AppVpcConfig]\n",
+		"VpcIdOutput [export: shared-vpc-id]\n  Value -> SharedVpc.VpcId\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatImpact_NoDependents(t *testing.T) {
+	got := formatImpact(&impact.Result{Target: "Logs", TargetType: "s3.Bucket"})
+	if !strings.Contains(got, "No resources depend on it.") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+}
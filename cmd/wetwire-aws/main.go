@@ -6,7 +6,7 @@
 //	wetwire-aws lint ./infra/...      Check for issues
 //	wetwire-aws validate ./infra/...  Validate resources and references
 //	wetwire-aws list ./infra/...      List discovered resources
-//	wetwire-aws graph ./infra/...     Generate DOT dependency graph
+//	wetwire-aws graph ./infra/...     Generate DOT or Mermaid dependency graph
 //	wetwire-aws init myproject        Create new project
 //	wetwire-aws import template.yaml  Import CloudFormation template to Go
 //	wetwire-aws design "prompt"       AI-assisted infrastructure design
@@ -14,6 +14,8 @@
 //	wetwire-aws diff old.json new.json Compare two templates
 //	wetwire-aws watch ./infra/...     Auto-rebuild on file changes
 //	wetwire-aws optimize ./infra/...  Suggest CloudFormation optimizations
+//	wetwire-aws impact Name ./infra/... Show what depends on a resource
+//	wetwire-aws render ./infra/...    Resolve intrinsics for given parameters
 //	wetwire-aws mcp                   Run MCP server
 //	wetwire-aws version               Show version
 package main
@@ -22,7 +24,10 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/spf13/cobra"
+
 	"github.com/lex00/wetwire-aws-go/domain"
+	"github.com/lex00/wetwire-aws-go/internal/config"
 )
 
 // Version information set via ldflags
@@ -42,14 +47,43 @@ func run() error {
 	// Create the domain instance and get root command with standard tools
 	d := &domain.AwsDomain{}
 	root := domain.CreateRootCommand(d)
+	root.PersistentPreRunE = applyConfigDefaults
+	addReportFormats(root, d)
+	addGraphFormats(root, d)
+	addBuildFlags(root, d)
 
 	// Add AWS-specific commands
 	root.AddCommand(newDesignCmd())
 	root.AddCommand(newTestCmd())
 	root.AddCommand(newOptimizeCmd())
+	root.AddCommand(newImpactCmd())
 	root.AddCommand(newDiffCmd())
+	root.AddCommand(newRenderCmd())
 	root.AddCommand(newWatchCmd())
 	root.AddCommand(newMCPCmd())
 
 	return root.Execute()
 }
+
+// applyConfigDefaults applies the default output format from wetwire.yaml to
+// commands that use the global --format flag, unless it was set explicitly.
+func applyConfigDefaults(cmd *cobra.Command, args []string) error {
+	format := cmd.Flags().Lookup("format")
+	if format == nil || format.Changed || format != cmd.Root().PersistentFlags().Lookup("format") {
+		return nil
+	}
+
+	target := "."
+	if len(args) > 0 {
+		target = args[0]
+	}
+	cfg, err := config.Load(target)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	if cfg.Format != "" {
+		return format.Value.Set(cfg.Format)
+	}
+	return nil
+}
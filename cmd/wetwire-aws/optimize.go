@@ -8,8 +8,11 @@ import (
 	"github.com/spf13/cobra"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/build"
+	"github.com/lex00/wetwire-aws-go/internal/config"
 	"github.com/lex00/wetwire-aws-go/internal/discover"
 	"github.com/lex00/wetwire-aws-go/internal/optimizer"
+	"github.com/lex00/wetwire-aws-go/internal/report"
 )
 
 // validCategories lists all valid optimization categories.
@@ -36,8 +39,9 @@ func newOptimizeCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "optimize [packages...]",
 		Short: "Suggest CloudFormation optimizations",
-		Long: `Optimize analyzes CloudFormation resources and suggests improvements
-for security, cost, performance, and reliability.
+		Long: `Optimize builds the CloudFormation template for the packages and suggests
+improvements for security, cost, performance, and reliability. Rules inspect
+the resources' property values, so only genuine gaps are reported.
 
 Categories:
     security     - Security best practices (encryption, access control, etc.)
@@ -48,48 +52,104 @@ Categories:
 Examples:
     wetwire-aws optimize ./infra/...
     wetwire-aws optimize ./infra/... --category security
-    wetwire-aws optimize ./infra/... -f json`,
+    wetwire-aws optimize ./infra/... -f json
+    wetwire-aws optimize ./infra/... -f sarif > optimize.sarif`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !isValidCategory(category) {
 				return fmt.Errorf("invalid category: %s (valid: all, security, cost, performance, reliability)", category)
 			}
-			return runOptimize(args, outputFormat, category)
+
+			// Without --category, use the categories from wetwire.yaml
+			cfg, err := config.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			var categories []string
+			if !cmd.Flags().Changed("category") {
+				categories = cfg.Optimize.Categories
+			}
+
+			return runOptimize(args, outputFormat, category, categories)
 		},
 	}
 
-	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: text or json")
+	cmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: text, json, sarif or junit")
 	cmd.Flags().StringVarP(&category, "category", "c", "all", "Category: all, security, cost, performance, or reliability")
 
 	return cmd
 }
 
-// runOptimize analyzes packages and suggests optimizations.
-func runOptimize(packages []string, format, category string) error {
+// runOptimize analyzes packages and suggests optimizations. Each package
+// is built into its own template; the results are merged.
+func runOptimize(packages []string, format, category string, categories []string) error {
+	results := make([]wetwire.OptimizeResult, 0, len(packages))
+	for _, pkg := range packages {
+		result, err := optimizePackage(pkg, category, categories)
+		if err != nil {
+			return fmt.Errorf("optimize failed: %s: %w", pkg, err)
+		}
+		results = append(results, result)
+	}
+	return outputOptimizeResult(mergeOptimizeResults(results), format)
+}
+
+// optimizePackage builds the template of a package and runs the optimizer
+// on it.
+func optimizePackage(pkg, category string, categories []string) (wetwire.OptimizeResult, error) {
 	// Discover resources
 	discoverResult, err := discover.Discover(discover.Options{
-		Packages: packages,
+		Packages: []string{pkg},
 	})
 	if err != nil {
-		return fmt.Errorf("optimize failed: %w", err)
+		return wetwire.OptimizeResult{}, err
+	}
+	if len(discoverResult.Errors) > 0 {
+		for _, e := range discoverResult.Errors {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+		}
+		return wetwire.OptimizeResult{}, fmt.Errorf("discovery errors")
+	}
+
+	// Build the template so rules see the extracted property values
+	tmpl, err := build.Template(pkg, discoverResult)
+	if err != nil {
+		return wetwire.OptimizeResult{}, err
 	}
 
 	// Run optimizer
-	optResult, err := optimizer.Optimize(discoverResult, optimizer.Options{
-		Category: category,
+	optResult, err := optimizer.Optimize(tmpl, optimizer.Options{
+		Category:   category,
+		Categories: categories,
+		Resources:  discoverResult.Resources,
 	})
 	if err != nil {
-		return fmt.Errorf("optimize failed: %w", err)
+		return wetwire.OptimizeResult{}, err
 	}
 
-	result := wetwire.OptimizeResult{
-		Success:       true,
-		Suggestions:   optResult.Suggestions,
-		ResourceCount: len(discoverResult.Resources),
-		Summary:       optResult.Summary,
-	}
+	return wetwire.OptimizeResult{
+		Success:           true,
+		Suggestions:       optResult.Suggestions,
+		ResourceCount:     len(tmpl.Resources),
+		Summary:           optResult.Summary,
+		StaleSuppressions: optResult.StaleSuppressions,
+	}, nil
+}
 
-	return outputOptimizeResult(result, format)
+// mergeOptimizeResults combines the results of several packages.
+func mergeOptimizeResults(results []wetwire.OptimizeResult) wetwire.OptimizeResult {
+	merged := wetwire.OptimizeResult{Success: true}
+	for _, r := range results {
+		merged.Suggestions = append(merged.Suggestions, r.Suggestions...)
+		merged.StaleSuppressions = append(merged.StaleSuppressions, r.StaleSuppressions...)
+		merged.ResourceCount += r.ResourceCount
+		merged.Summary.Security += r.Summary.Security
+		merged.Summary.Cost += r.Summary.Cost
+		merged.Summary.Performance += r.Summary.Performance
+		merged.Summary.Reliability += r.Summary.Reliability
+		merged.Summary.Total += r.Summary.Total
+	}
+	return merged
 }
 
 func outputOptimizeResult(result wetwire.OptimizeResult, format string) error {
@@ -102,6 +162,10 @@ func outputOptimizeResult(result wetwire.OptimizeResult, format string) error {
 		fmt.Println(string(data))
 
 	case "text":
+		for _, stale := range result.StaleSuppressions {
+			fmt.Printf("%s:%d: %s\n", stale.File, stale.Line, stale.Message)
+		}
+
 		if len(result.Suggestions) == 0 {
 			fmt.Printf("Analyzed %d resources. No optimization suggestions.\n", result.ResourceCount)
 			return nil
@@ -124,7 +188,7 @@ func outputOptimizeResult(result wetwire.OptimizeResult, format string) error {
 
 			fmt.Printf("=== %s (%d) ===\n", capitalize(cat), len(suggestions))
 			for _, s := range suggestions {
-				fmt.Printf("\n[%s] %s\n", s.Severity, s.Title)
+				fmt.Printf("\n[%s] %s (%s)\n", s.Severity, s.Title, s.Rule)
 				fmt.Printf("  Resource: %s\n", s.Resource)
 				if s.File != "" {
 					fmt.Printf("  Location: %s:%d\n", s.File, s.Line)
@@ -139,6 +203,11 @@ func outputOptimizeResult(result wetwire.OptimizeResult, format string) error {
 			result.Summary.Security, result.Summary.Cost,
 			result.Summary.Performance, result.Summary.Reliability)
 
+	case report.FormatSARIF, report.FormatJUnit:
+		if err := report.Optimize(reportTool(), result).Write(os.Stdout, format); err != nil {
+			return err
+		}
+
 	default:
 		return fmt.Errorf("unknown format: %s", format)
 	}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"reflect"
 	"testing"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
 )
 
 func TestNewOptimizeCmd(t *testing.T) {
@@ -40,3 +43,41 @@ func TestOptimizeCategories(t *testing.T) {
 		t.Error("'invalid' should not be a valid category")
 	}
 }
+
+func TestMergeOptimizeResults(t *testing.T) {
+	network := wetwire.OptimizeResult{
+		Success:       true,
+		Suggestions:   []wetwire.OptimizeSuggestion{{Rule: "OPT-VPC-001", Category: "security", Resource: "Vpc"}},
+		ResourceCount: 3,
+		Summary:       wetwire.OptimizeSummary{Security: 1, Total: 1},
+	}
+	app := wetwire.OptimizeResult{
+		Success: true,
+		Suggestions: []wetwire.OptimizeSuggestion{
+			{Rule: "OPT-S3-002", Category: "cost", Resource: "Bucket"},
+			{Rule: "OPT-DDB-001", Category: "reliability", Resource: "Table"},
+		},
+		ResourceCount: 5,
+		Summary:       wetwire.OptimizeSummary{Cost: 1, Reliability: 1, Total: 2},
+	}
+
+	merged := mergeOptimizeResults([]wetwire.OptimizeResult{network, app})
+
+	if merged.ResourceCount != 8 {
+		t.Errorf("ResourceCount = %d, want 8", merged.ResourceCount)
+	}
+	want := wetwire.OptimizeSummary{Security: 1, Cost: 1, Reliability: 1, Total: 3}
+	if merged.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", merged.Summary, want)
+	}
+	var resources []string
+	for _, s := range merged.Suggestions {
+		resources = append(resources, s.Resource)
+	}
+	if !reflect.DeepEqual(resources, []string{"Vpc", "Bucket", "Table"}) {
+		t.Errorf("Suggestions for %v, want Vpc, Bucket, Table", resources)
+	}
+	if !merged.Success {
+		t.Error("Success = false, want true")
+	}
+}
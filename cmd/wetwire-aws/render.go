@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lex00/wetwire-aws-go/internal/evaluator"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// renderOptions holds the flags of the render command.
+type renderOptions struct {
+	format    string
+	params    []string
+	region    string
+	accountID string
+	stackName string
+	azs       []string
+}
+
+// newRenderCmd creates the "render" subcommand, which resolves a template's
+// intrinsic functions offline.
+func newRenderCmd() *cobra.Command {
+	var opts renderOptions
+
+	cmd := &cobra.Command{
+		Use:   "render <template|package>",
+		Short: "Show the effective template for a set of parameter values",
+		Long: `Render resolves the intrinsic functions of a template offline, for the given
+parameter values, region and account, and prints the effective template:
+conditions are evaluated, resources and outputs whose condition is false are
+dropped, and Ref, Fn::Sub, Fn::Join, Fn::FindInMap and the other functions
+that need no deployed resources are replaced by their values. Fn::GetAtt,
+Ref to a resource and Fn::ImportValue are kept as placeholders.
+
+The argument is a template file or a Go package, which is built first.
+
+Examples:
+    wetwire-aws render ./infra/... --param Env=prod
+    wetwire-aws render template.json --param Env=dev --region eu-west-1 -f yaml
+    wetwire-aws render ./infra/... --azs us-east-1=us-east-1a,us-east-1b`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRender(args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.format, "format", "f", "json", "Output format: json or yaml")
+	cmd.Flags().StringArrayVar(&opts.params, "param", nil, "Parameter value as NAME=VALUE (repeatable)")
+	cmd.Flags().StringVar(&opts.region, "region", evaluator.DefaultRegion, "Value of AWS::Region")
+	cmd.Flags().StringVar(&opts.accountID, "account", evaluator.DefaultAccountID, "Value of AWS::AccountId")
+	cmd.Flags().StringVar(&opts.stackName, "stack-name", evaluator.DefaultStackName, "Value of AWS::StackName")
+	cmd.Flags().StringArrayVar(&opts.azs, "azs", nil, "Availability zones of a region for Fn::GetAZs, as REGION=AZ,AZ (repeatable)")
+
+	return cmd
+}
+
+// runRender evaluates the template of a file or package and prints it.
+func runRender(path string, opts renderOptions) error {
+	if opts.format != "json" && opts.format != "yaml" {
+		return fmt.Errorf("unknown format: %s", opts.format)
+	}
+	evalOpts, err := evaluatorOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := loadTemplateOrPackage(path)
+	if err != nil {
+		return fmt.Errorf("render failed: %w", err)
+	}
+	result, err := evaluator.Evaluate(tmpl, evalOpts)
+	if err != nil {
+		return fmt.Errorf("render failed: %w", err)
+	}
+
+	var data []byte
+	if opts.format == "yaml" {
+		data, err = template.ToYAML(result.Template)
+	} else {
+		data, err = template.ToJSON(result.Template)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimRight(string(data), "\n"))
+
+	// Notes go to stderr so stdout stays a valid template
+	fmt.Fprint(os.Stderr, formatRenderNotes(result))
+	return nil
+}
+
+// evaluatorOptions parses the --param and --azs flags.
+func evaluatorOptions(opts renderOptions) (evaluator.Options, error) {
+	evalOpts := evaluator.Options{
+		Region:    opts.region,
+		AccountID: opts.accountID,
+		StackName: opts.stackName,
+	}
+
+	for _, param := range opts.params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || name == "" {
+			return evalOpts, fmt.Errorf("invalid --param %q: expected NAME=VALUE", param)
+		}
+		if evalOpts.Parameters == nil {
+			evalOpts.Parameters = make(map[string]string)
+		}
+		evalOpts.Parameters[name] = value
+	}
+
+	for _, azs := range opts.azs {
+		region, zones, ok := strings.Cut(azs, "=")
+		if !ok || region == "" || zones == "" {
+			return evalOpts, fmt.Errorf("invalid --azs %q: expected REGION=AZ,AZ", azs)
+		}
+		if evalOpts.AZs == nil {
+			evalOpts.AZs = make(map[string][]string)
+		}
+		for _, zone := range strings.Split(zones, ",") {
+			evalOpts.AZs[region] = append(evalOpts.AZs[region], strings.TrimSpace(zone))
+		}
+	}
+
+	return evalOpts, nil
+}
+
+// formatRenderNotes lists the condition values and the dropped resources
+// and outputs, e.g. "Condition IsProd: false".
+func formatRenderNotes(result *evaluator.Result) string {
+	var sb strings.Builder
+
+	names := make([]string, 0, len(result.Conditions))
+	for name := range result.Conditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "Condition %s: %t\n", name, result.Conditions[name])
+	}
+	for _, dropped := range result.Dropped {
+		fmt.Fprintf(&sb, "Dropped %s (condition is false)\n", dropped)
+	}
+	return sb.String()
+}
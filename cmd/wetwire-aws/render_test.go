@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/internal/evaluator"
+)
+
+func TestNewRenderCmd(t *testing.T) {
+	cmd := newRenderCmd()
+
+	if cmd.Use != "render <template|package>" {
+		t.Errorf("Use = %q", cmd.Use)
+	}
+	for _, name := range []string{"format", "param", "region", "account", "stack-name", "azs"} {
+		if cmd.Flags().Lookup(name) == nil {
+			t.Errorf("missing --%s flag", name)
+		}
+	}
+}
+
+func TestEvaluatorOptions(t *testing.T) {
+	got, err := evaluatorOptions(renderOptions{
+		params:    []string{"Env=prod", "Query=a=b"},
+		region:    "eu-west-1",
+		accountID: "111122223333",
+		stackName: "app",
+		azs:       []string{"eu-west-1=eu-west-1a, eu-west-1b"},
+	})
+	if err != nil {
+		t.Fatalf("evaluatorOptions() error = %v", err)
+	}
+
+	want := evaluator.Options{
+		Parameters: map[string]string{"Env": "prod", "Query": "a=b"},
+		Region:     "eu-west-1",
+		AccountID:  "111122223333",
+		StackName:  "app",
+		AZs:        map[string][]string{"eu-west-1": {"eu-west-1a", "eu-west-1b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("evaluatorOptions() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, opts := range []renderOptions{
+		{params: []string{"Env"}},
+		{params: []string{"=prod"}},
+		{azs: []string{"us-east-1"}},
+	} {
+		if _, err := evaluatorOptions(opts); err == nil {
+			t.Errorf("evaluatorOptions(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+func TestFormatRenderNotes(t *testing.T) {
+	got := formatRenderNotes(&evaluator.Result{
+		Conditions: map[string]bool{"IsProd": false, "IsDev": true},
+		Dropped:    []string{"Resources.Replica"},
+	})
+	want := "Condition IsDev: true\nCondition IsProd: false\nDropped Resources.Replica (condition is false)\n"
+	if got != want {
+		t.Errorf("formatRenderNotes() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/domain"
+	"github.com/lex00/wetwire-aws-go/internal/report"
+)
+
+// reportTool identifies wetwire-aws in SARIF and JUnit reports.
+func reportTool() report.Tool {
+	return report.Tool{
+		Name:           "wetwire-aws",
+		Version:        domain.Version,
+		InformationURI: "https://github.com/lex00/wetwire-aws-go",
+	}
+}
+
+// reportRunner runs a command for a report format and reports whether the
+// command succeeded.
+type reportRunner func(cmd *cobra.Command, path string) (*report.Report, bool, error)
+
+// addReportFormats teaches the generated lint and validate commands to write
+// --format sarif and --format junit. Other formats are left to the
+// generated commands.
+func addReportFormats(root *cobra.Command, d *domain.AwsDomain) {
+	for _, cmd := range root.Commands() {
+		switch cmd.Name() {
+		case "lint":
+			withReportFormats(cmd, func(cmd *cobra.Command, path string) (*report.Report, bool, error) {
+				return lintReport(cmd, d, path)
+			})
+		case "validate":
+			withReportFormats(cmd, func(cmd *cobra.Command, path string) (*report.Report, bool, error) {
+				return validateReport(cmd, d, path)
+			})
+		}
+	}
+
+	if format := root.PersistentFlags().Lookup("format"); format != nil {
+		format.Usage = "Output format (text, json, yaml; sarif or junit for lint and validate; dot, mermaid or html for graph)"
+	}
+}
+
+// withReportFormats wraps the RunE of cmd so report formats are handled by run.
+func withReportFormats(cmd *cobra.Command, run reportRunner) {
+	generated := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if !report.IsFormat(format) {
+			return generated(cmd, args)
+		}
+
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		r, success, err := run(cmd, path)
+		if err != nil {
+			return err
+		}
+		if err := r.Write(os.Stdout, format); err != nil {
+			return err
+		}
+
+		// Match the generated commands: findings fail the command
+		if !success {
+			return fmt.Errorf("operation failed")
+		}
+		return nil
+	}
+}
+
+// lintReport lints path with the lint command's flags.
+func lintReport(cmd *cobra.Command, d *domain.AwsDomain, path string) (*report.Report, bool, error) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	format, _ := cmd.Flags().GetString("format")
+	fix, _ := cmd.Flags().GetBool("fix")
+	disable, _ := cmd.Flags().GetStringSlice("disable")
+
+	ctx := domain.NewContextWithVerbose(context.Background(), path, verbose)
+	result, err := d.Linter().Lint(ctx, path, domain.LintOpts{
+		Format:  format,
+		Fix:     fix,
+		Disable: disable,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("lint failed: %w", err)
+	}
+
+	issues := make([]wetwire.LintIssue, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		issues = append(issues, wetwire.LintIssue{
+			File:     e.Path,
+			Line:     e.Line,
+			Column:   e.Column,
+			Severity: e.Severity,
+			Message:  e.Message,
+			Rule:     e.Code,
+		})
+	}
+
+	return report.Lint(reportTool(), issues), result.Success, nil
+}
+
+// validateReport validates path; the validator returns the schema result as data.
+func validateReport(cmd *cobra.Command, d *domain.AwsDomain, path string) (*report.Report, bool, error) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+
+	ctx := domain.NewContextWithVerbose(context.Background(), path, verbose)
+	result, err := d.Validator().Validate(ctx, path, domain.ValidateOpts{})
+	if err != nil {
+		return nil, false, fmt.Errorf("validate failed: %w", err)
+	}
+
+	schemaResult, ok := result.Data.(wetwire.SchemaResult)
+	if !ok {
+		return nil, false, fmt.Errorf("validate failed: no schema result")
+	}
+
+	return report.Validate(reportTool(), schemaResult), result.Success, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/domain"
+)
+
+func TestAddReportFormats(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+
+	generated := make(map[string]bool)
+	for _, cmd := range root.Commands() {
+		generated[cmd.Name()] = cmd.RunE != nil
+	}
+	addReportFormats(root, d)
+
+	for _, name := range []string{"lint", "validate"} {
+		if !generated[name] {
+			t.Errorf("expected generated %s command", name)
+		}
+	}
+
+	usage := root.PersistentFlags().Lookup("format").Usage
+	if !strings.Contains(usage, "sarif") || !strings.Contains(usage, "junit") {
+		t.Errorf("format usage = %q, want sarif and junit listed", usage)
+	}
+}
+
+func TestLintReportFormatError(t *testing.T) {
+	d := &domain.AwsDomain{}
+	root := domain.CreateRootCommand(d)
+	addReportFormats(root, d)
+
+	root.SetArgs([]string{"lint", "--format", "sarif", "./does-not-exist"})
+	root.SilenceUsage = true
+	root.SilenceErrors = true
+	if err := root.Execute(); err == nil {
+		t.Error("expected an error linting a missing package")
+	}
+}
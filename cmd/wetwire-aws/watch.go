@@ -12,9 +12,10 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 
+	"github.com/lex00/wetwire-aws-go/internal/build"
+	"github.com/lex00/wetwire-aws-go/internal/config"
 	"github.com/lex00/wetwire-aws-go/internal/discover"
 	"github.com/lex00/wetwire-aws-go/internal/lint"
-	"github.com/lex00/wetwire-aws-go/internal/runner"
 	"github.com/lex00/wetwire-aws-go/internal/template"
 )
 
@@ -245,7 +246,14 @@ func runWatchLint(packages []string) bool {
 	// Run lint rules
 	hasIssues := false
 	for _, pkg := range packages {
-		lintResult, err := lint.LintPackage(pkg, lint.Options{})
+		lintOpts := lint.Options{}
+		if cfg, err := config.Load(pkg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			lintOpts = cfg.LintOptions()
+		}
+
+		lintResult, err := lint.LintPackage(pkg, lintOpts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to lint %s: %v\n", pkg, err)
 			continue
@@ -285,57 +293,14 @@ func runWatchBuild(packages []string, opts watchOptions) {
 	}
 
 	// Build template
-	builder := template.NewBuilderFull(
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
-
-	// Set VarAttrRefs for recursive AttrRef resolution
-	varAttrRefs := make(map[string]template.VarAttrRefInfo)
-	for name, info := range result.VarAttrRefs {
-		varAttrRefs[name] = template.VarAttrRefInfo{
-			AttrRefs: info.AttrRefs,
-			VarRefs:  info.VarRefs,
-		}
-	}
-	builder.SetVarAttrRefs(varAttrRefs)
-
-	// Extract values
-	values, err := runner.ExtractAll(
-		packages[0],
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
+	tmpl, err := build.Template(packages[0], result)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Build error: %v\n", err)
 		return
 	}
-
-	// Set values
-	for name, props := range values.Resources {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Parameters {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Outputs {
-		builder.SetValue(name, props)
-	}
-	for name, val := range values.Mappings {
-		builder.SetValue(name, val)
-	}
-	for name, val := range values.Conditions {
-		builder.SetValue(name, val)
-	}
-
-	tmpl, err := builder.Build()
-	if err != nil {
+	if cfg, err := config.Load(packages[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if err := build.Configure(tmpl, cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Build error: %v\n", err)
 		return
 	}
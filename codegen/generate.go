@@ -131,7 +131,7 @@ var resourceTemplate = template.Must(template.New("resource").Parse(`// Code gen
 // Generated: {{ .Timestamp }}
 
 package {{ .PackageName }}
-{{ if .HasAttributes }}
+{{ if .ImportWetwire }}
 import (
 	wetwire "github.com/lex00/wetwire-aws-go"
 )
@@ -165,7 +165,8 @@ type resourceTemplateData struct {
 	Timestamp     string
 	Properties    []propertyData
 	Attributes    []attributeData
-	HasAttributes bool // true if there are any attributes (for conditional import)
+	HasAttributes bool // true if there are any attributes
+	ImportWetwire bool // true if attributes or typed properties reference the wetwire package
 }
 
 type propertyData struct {
@@ -288,6 +289,7 @@ func generateResource(svc *Service, res ParsedResource) ([]byte, error) {
 		Properties:    props,
 		Attributes:    attrs,
 		HasAttributes: len(attrs) > 0,
+		ImportWetwire: len(attrs) > 0 || usesWetwire(props),
 	}
 
 	var buf bytes.Buffer
@@ -359,6 +361,14 @@ func resolveTypeReferences(goType string, qualifiedNames map[string]string, isPo
 		return goType
 	}
 
+	// Handle typed values like wetwire.Value[SomeType]
+	if baseType := unwrapValueType(goType); baseType != goType {
+		if qn, ok := qualifiedNames[baseType]; ok {
+			return "wetwire.Value[" + qn + "]"
+		}
+		return goType
+	}
+
 	// Handle simple types
 	if qn, ok := qualifiedNames[goType]; ok {
 		goType = qn
@@ -374,6 +384,16 @@ func resolveTypeReferences(goType string, qualifiedNames map[string]string, isPo
 	return goType
 }
 
+// usesWetwire reports whether any property type references the wetwire package.
+func usesWetwire(props []propertyData) bool {
+	for _, p := range props {
+		if strings.Contains(p.GoType, "wetwire.") {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanDoc cleans up CloudFormation documentation for Go comments.
 func cleanDoc(doc string) string {
 	if doc == "" {
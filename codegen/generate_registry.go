@@ -90,9 +90,9 @@ func generateRegistry(services []*Service, outputDir string, dryRun bool) error
 				} else if prop.IsMap && prop.ItemType != "" && !isPrimitive(prop.ItemType) {
 					// Map of property types: map[string]SomeType
 					typeName = resolveForResource(prop.ItemType)
-				} else if !prop.IsList && !prop.IsMap && !isPrimitive(prop.GoType) {
+				} else if !prop.IsList && !prop.IsMap && !isPrimitive(unwrapValueType(prop.GoType)) {
 					// Direct property type reference
-					typeName = resolveForResource(prop.GoType)
+					typeName = resolveForResource(unwrapValueType(prop.GoType))
 				}
 
 				if typeName != "" {
@@ -131,9 +131,9 @@ func generateRegistry(services []*Service, outputDir string, dryRun bool) error
 				} else if prop.IsMap && prop.ItemType != "" && !isPrimitive(prop.ItemType) {
 					// Map of property types
 					typeName = resolveTypeName(prop.ItemType)
-				} else if !prop.IsList && !prop.IsMap && !isPrimitive(prop.GoType) {
+				} else if !prop.IsList && !prop.IsMap && !isPrimitive(unwrapValueType(prop.GoType)) {
 					// Direct property type reference
-					typeName = resolveTypeName(prop.GoType)
+					typeName = resolveTypeName(unwrapValueType(prop.GoType))
 				}
 
 				if typeName != "" {
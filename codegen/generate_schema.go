@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lex00/cloudformation-schema-go/enums"
+	"github.com/lex00/cloudformation-schema-go/spec"
+
+	"github.com/lex00/wetwire-aws-go/internal/schema"
+)
+
+// generateSchemaBundle writes internal/schema/spec.json.gz, the compact
+// specification embedded in the binary for offline validation. Constraints
+// the specification lacks are taken from the registry schemas.
+func generateSchemaBundle(cfnSpec *spec.Spec, registry map[string]*registrySchema, outputDir string, dryRun bool) error {
+	bundle := buildSchemaBundle(cfnSpec)
+	applyRegistrySchemas(bundle, registry)
+
+	bundleFile := filepath.Join(outputDir, "internal", "schema", "spec.json.gz")
+	if dryRun {
+		fmt.Printf("Would write: %s (%d resource types, %d property types)\n",
+			bundleFile, len(bundle.ResourceTypes), len(bundle.PropertyTypes))
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := schema.WriteSpec(&buf, bundle); err != nil {
+		return err
+	}
+	return os.WriteFile(bundleFile, buf.Bytes(), 0644)
+}
+
+// buildSchemaBundle converts the CloudFormation spec to the bundle format.
+// Enum allowed values are attached to top-level resource properties.
+func buildSchemaBundle(cfnSpec *spec.Spec) *schema.Spec {
+	bundle := &schema.Spec{
+		Version:       cfnSpec.ResourceSpecificationVersion,
+		ResourceTypes: make(map[string]schema.ResourceSpec),
+		PropertyTypes: make(map[string]schema.PropertyTypeSpec),
+	}
+
+	for cfType, resDef := range cfnSpec.ResourceTypes {
+		service := ""
+		if parts := strings.Split(cfType, "::"); len(parts) == 3 {
+			service = strings.ToLower(parts[1])
+		}
+
+		res := schema.ResourceSpec{
+			Properties: make(map[string]schema.PropertySpec),
+			Attributes: make(map[string]schema.AttributeSpec),
+		}
+		for propName, propDef := range resDef.Properties {
+			prop := bundleProperty(propDef)
+			if prop.PrimitiveType == "String" && service != "" {
+				if enumName := enums.GetEnumForProperty(service, propName); enumName != "" {
+					prop.AllowedValues = enums.GetAllowedValues(service, enumName)
+				}
+			}
+			res.Properties[propName] = prop
+		}
+		for attrName, attrDef := range resDef.Attributes {
+			res.Attributes[attrName] = schema.AttributeSpec{PrimitiveType: attrDef.PrimitiveType}
+		}
+		bundle.ResourceTypes[cfType] = res
+	}
+
+	for cfType, ptDef := range cfnSpec.PropertyTypes {
+		pt := schema.PropertyTypeSpec{Properties: make(map[string]schema.PropertySpec)}
+		for propName, propDef := range ptDef.Properties {
+			pt.Properties[propName] = bundleProperty(propDef)
+		}
+		bundle.PropertyTypes[cfType] = pt
+	}
+
+	return bundle
+}
+
+// bundleProperty converts a spec property to the bundle format.
+func bundleProperty(def spec.Property) schema.PropertySpec {
+	return schema.PropertySpec{
+		Required:          def.Required,
+		PrimitiveType:     def.PrimitiveType,
+		Type:              def.Type,
+		ItemType:          def.ItemType,
+		PrimitiveItemType: def.PrimitiveItemType,
+		UpdateType:        def.UpdateType,
+	}
+}
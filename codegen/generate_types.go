@@ -13,7 +13,11 @@ var propertyTypesTemplate = template.Must(template.New("types").Parse(`// Code g
 // Generated: {{ .Timestamp }}
 
 package {{ .PackageName }}
-{{ if not .SkipTag }}
+{{ if .ImportWetwire }}
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+{{ end }}{{ if not .SkipTag }}
 // Tag represents a CloudFormation tag.
 // This is a shared type used across all services.
 type Tag struct {
@@ -38,6 +42,8 @@ type typesTemplateData struct {
 	Timestamp   string
 	Types       []typeData
 	SkipTag     bool // True if the service has a Tag resource (to avoid conflict)
+	// ImportWetwire is true if typed properties reference the wetwire package
+	ImportWetwire bool
 }
 
 type typeData struct {
@@ -123,11 +129,17 @@ func generateResourcePropertyTypes(svc *Service, resourceName string, typeNames
 		})
 	}
 
+	importWetwire := false
+	for _, t := range types {
+		importWetwire = importWetwire || usesWetwire(t.Properties)
+	}
+
 	data := typesTemplateData{
-		PackageName: svc.Name,
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Types:       types,
-		SkipTag:     true, // Always skip Tag in per-resource files (it's in types.go)
+		PackageName:   svc.Name,
+		Timestamp:     time.Now().Format(time.RFC3339),
+		Types:         types,
+		SkipTag:       true, // Always skip Tag in per-resource files (it's in types.go)
+		ImportWetwire: importWetwire,
 	}
 
 	var buf bytes.Buffer
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lex00/cloudformation-schema-go/spec"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/runner"
+)
+
+func TestParseTypedProperty(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      spec.Property
+		expected string
+	}{
+		{"string", spec.Property{PrimitiveType: "String"}, "wetwire.Value[string]"},
+		{"integer", spec.Property{PrimitiveType: "Integer"}, "wetwire.Value[int]"},
+		{"boolean", spec.Property{PrimitiveType: "Boolean"}, "wetwire.Value[bool]"},
+		{"json", spec.Property{PrimitiveType: "Json"}, "any"},
+		{"primitive list", spec.Property{Type: "List", PrimitiveItemType: "String"}, "wetwire.Value[[]string]"},
+		{"property type list", spec.Property{Type: "List", ItemType: "CorsRule"}, "[]CorsRule"},
+		{"primitive map", spec.Property{Type: "Map", PrimitiveItemType: "String"}, "wetwire.Value[map[string]string]"},
+		{"property type", spec.Property{Type: "VersioningConfiguration"}, "wetwire.Value[VersioningConfiguration]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prop := parseTypedProperty(ParsedProperty{Name: "Prop"}, tt.def)
+			if prop.GoType != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, prop.GoType)
+			}
+			if prop.IsPointer {
+				t.Error("typed properties should not be pointers")
+			}
+		})
+	}
+}
+
+func TestGenerateResource_TypedProperties(t *testing.T) {
+	svc := &Service{
+		Name: "s3",
+		PropertyTypes: map[string]ParsedPropertyType{
+			"Bucket_VersioningConfiguration": {Name: "VersioningConfiguration", ParentResource: "Bucket"},
+		},
+	}
+	res := ParsedResource{
+		Name:   "Bucket",
+		CFType: "AWS::S3::Bucket",
+		Properties: map[string]ParsedProperty{
+			"BucketName":              parseTypedProperty(ParsedProperty{Name: "BucketName"}, spec.Property{PrimitiveType: "String"}),
+			"VersioningConfiguration": parseTypedProperty(ParsedProperty{Name: "VersioningConfiguration"}, spec.Property{Type: "VersioningConfiguration"}),
+		},
+	}
+
+	code, err := generateResource(svc, res)
+	if err != nil {
+		t.Fatalf("generating resource: %v", err)
+	}
+	output := string(code)
+
+	if !strings.Contains(output, `wetwire "github.com/lex00/wetwire-aws-go"`) {
+		t.Error("typed resource should import wetwire even without attributes")
+	}
+	if !strings.Contains(output, "wetwire.Value[string]") {
+		t.Errorf("BucketName should be wetwire.Value[string]:\n%s", output)
+	}
+	if !strings.Contains(output, "wetwire.Value[Bucket_VersioningConfiguration]") {
+		t.Errorf("property type reference should resolve to qualified name:\n%s", output)
+	}
+}
+
+// typedBucketService is a typed S3 service with a resource, a nested
+// property type and a list of property types.
+func typedBucketService() *Service {
+	return bucketService(true)
+}
+
+// bucketService builds the S3 bucket service used by the round-trip tests,
+// with strongly typed or untyped (--typed=false) properties.
+func bucketService(typedProps bool) *Service {
+	saved := typedProperties
+	typedProperties = typedProps
+	defer func() { typedProperties = saved }()
+	return &Service{
+		Name:     "s3",
+		CFPrefix: "AWS::S3",
+		Resources: map[string]ParsedResource{
+			"Bucket": {
+				Name:   "Bucket",
+				CFType: "AWS::S3::Bucket",
+				Properties: map[string]ParsedProperty{
+					"BucketName":              parseProperty("BucketName", spec.Property{PrimitiveType: "String"}),
+					"ObjectLockEnabled":       parseProperty("ObjectLockEnabled", spec.Property{PrimitiveType: "Boolean"}),
+					"CorsConfiguration":       parseProperty("CorsConfiguration", spec.Property{Type: "CorsConfiguration"}),
+					"VersioningConfiguration": parseProperty("VersioningConfiguration", spec.Property{Type: "VersioningConfiguration"}),
+				},
+				Attributes: map[string]ParsedAttribute{"Arn": {Name: "Arn"}},
+			},
+		},
+		PropertyTypes: map[string]ParsedPropertyType{
+			"Bucket_CorsConfiguration": {
+				Name: "CorsConfiguration", CFType: "AWS::S3::Bucket.CorsConfiguration", ParentResource: "Bucket",
+				Properties: map[string]ParsedProperty{
+					"CorsRules": parseProperty("CorsRules", spec.Property{Type: "List", ItemType: "CorsRule"}),
+				},
+			},
+			"Bucket_CorsRule": {
+				Name: "CorsRule", CFType: "AWS::S3::Bucket.CorsRule", ParentResource: "Bucket",
+				Properties: map[string]ParsedProperty{
+					"AllowedMethods": parseProperty("AllowedMethods", spec.Property{Type: "List", PrimitiveItemType: "String"}),
+					"MaxAge":         parseProperty("MaxAge", spec.Property{PrimitiveType: "Integer"}),
+				},
+			},
+			"Bucket_VersioningConfiguration": {
+				Name: "VersioningConfiguration", CFType: "AWS::S3::Bucket.VersioningConfiguration", ParentResource: "Bucket",
+				Properties: map[string]ParsedProperty{
+					"Status": parseProperty("Status", spec.Property{PrimitiveType: "String"}),
+				},
+			},
+		},
+	}
+}
+
+// TestGenerateService_TypedRoundTrip generates typed resources, declares
+// resources with them and checks the CloudFormation the runner serializes.
+func TestGenerateService_TypedRoundTrip(t *testing.T) {
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := generateService(typedBucketService(), dir, false, &GenerationStats{}); err != nil {
+		t.Fatalf("generating service: %v", err)
+	}
+
+	goMod := "module example.com/typed\n\ngo 1.23.0\n\nrequire github.com/lex00/wetwire-aws-go v1.9.0\n\nreplace github.com/lex00/wetwire-aws-go => " + root + "\n"
+	infra := `package infra
+
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+
+	"example.com/typed/resources/s3"
+)
+
+var Logs = s3.Bucket{
+	BucketName:        wetwire.Intrinsic[string](Sub{"${AWS::StackName}-logs"}),
+	ObjectLockEnabled: wetwire.Literal(false),
+}
+
+var Data = s3.Bucket{
+	BucketName: wetwire.Ref[string](Logs),
+	CorsConfiguration: wetwire.Literal(s3.Bucket_CorsConfiguration{
+		CorsRules: []s3.Bucket_CorsRule{{
+			AllowedMethods: wetwire.Literal([]string{"GET", "PUT"}),
+			MaxAge:         wetwire.Literal(0),
+		}},
+	}),
+	VersioningConfiguration: wetwire.Literal(s3.Bucket_VersioningConfiguration{}),
+}
+`
+	for file, content := range map[string]string{"go.mod": goMod, "infra/infra.go": infra} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resources := map[string]wetwire.DiscoveredResource{
+		"Logs": {Name: "Logs", Type: "s3.Bucket", Package: "s3"},
+		"Data": {Name: "Data", Type: "s3.Bucket", Package: "s3", Dependencies: []string{"Logs"}},
+	}
+	result, err := runner.ExtractAll(filepath.Join(dir, "infra"), resources, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("ExtractAll failed: %v", err)
+	}
+
+	want := map[string]map[string]any{
+		"Logs": {
+			"BucketName":        map[string]any{"Fn::Sub": "${AWS::StackName}-logs"},
+			"ObjectLockEnabled": false,
+		},
+		"Data": {
+			"BucketName": map[string]any{"Ref": "Logs"},
+			"CorsConfiguration": map[string]any{
+				"CorsRules": []any{map[string]any{
+					"AllowedMethods": []any{"GET", "PUT"},
+					"MaxAge":         float64(0),
+				}},
+			},
+			"VersioningConfiguration": map[string]any{},
+		},
+	}
+	for name, props := range want {
+		if !reflect.DeepEqual(result.Resources[name], props) {
+			t.Errorf("%s = %v, want %v", name, result.Resources[name], props)
+		}
+	}
+}
+
+// TestGenerateService_TypedMatchesUntyped declares the same resources against
+// untyped (--typed=false) and typed generated code and checks both serialize
+// to the CloudFormation in testdata/bucket_resources.golden.json, so moving
+// declarations to wetwire.Literal and wetwire.Intrinsic leaves templates
+// unchanged. Empty literals, which only the typed form keeps, are covered by
+// TestGenerateService_TypedRoundTrip.
+func TestGenerateService_TypedMatchesUntyped(t *testing.T) {
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := generateService(bucketService(false), filepath.Join(dir, "untyped"), false, &GenerationStats{}); err != nil {
+		t.Fatalf("generating untyped service: %v", err)
+	}
+	if err := generateService(bucketService(true), filepath.Join(dir, "typed"), false, &GenerationStats{}); err != nil {
+		t.Fatalf("generating typed service: %v", err)
+	}
+
+	goMod := "module example.com/golden\n\ngo 1.23.0\n\nrequire github.com/lex00/wetwire-aws-go v1.9.0\n\nreplace github.com/lex00/wetwire-aws-go => " + root + "\n"
+	untypedInfra := `package infra
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+
+	"example.com/golden/untyped/resources/s3"
+)
+
+var Logs = s3.Bucket{
+	BucketName:        Sub{"${AWS::StackName}-logs"},
+	ObjectLockEnabled: false,
+}
+
+var Data = s3.Bucket{
+	BucketName: Logs,
+	CorsConfiguration: &s3.Bucket_CorsConfiguration{
+		CorsRules: []any{s3.Bucket_CorsRule{
+			AllowedMethods: []any{"GET", "PUT"},
+			MaxAge:         0,
+		}},
+	},
+}
+`
+	typedInfra := `package infra
+
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+
+	"example.com/golden/typed/resources/s3"
+)
+
+var Logs = s3.Bucket{
+	BucketName:        wetwire.Intrinsic[string](Sub{"${AWS::StackName}-logs"}),
+	ObjectLockEnabled: wetwire.Literal(false),
+}
+
+var Data = s3.Bucket{
+	BucketName: wetwire.Ref[string](Logs),
+	CorsConfiguration: wetwire.Literal(s3.Bucket_CorsConfiguration{
+		CorsRules: []s3.Bucket_CorsRule{{
+			AllowedMethods: wetwire.Literal([]string{"GET", "PUT"}),
+			MaxAge:         wetwire.Literal(0),
+		}},
+	}),
+}
+`
+	files := map[string]string{
+		"go.mod":                 goMod,
+		"untyped/infra/infra.go": untypedInfra,
+		"typed/infra/infra.go":   typedInfra,
+	}
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	golden, err := os.ReadFile(filepath.Join("testdata", "bucket_resources.golden.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, variant := range []string{"untyped", "typed"} {
+		resources := map[string]wetwire.DiscoveredResource{
+			"Logs": {Name: "Logs", Type: "s3.Bucket", Package: "s3"},
+			"Data": {Name: "Data", Type: "s3.Bucket", Package: "s3", Dependencies: []string{"Logs"}},
+		}
+		result, err := runner.ExtractAll(filepath.Join(dir, variant, "infra"), resources, nil, nil, nil, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: ExtractAll failed: %v", variant, err)
+		}
+		got, err := json.MarshalIndent(result.Resources, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got)+"\n" != string(golden) {
+			t.Errorf("%s resources differ from golden file:\n%s\nwant:\n%s", variant, got, golden)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 //	go run ./codegen                    # Generate all resource types
 //	go run ./codegen --service s3       # Generate only S3 resources
 //	go run ./codegen --dry-run          # Show what would be generated
+//	go run ./codegen --typed=false      # Generate every property as any
 package main
 
 import (
@@ -22,6 +23,10 @@ var (
 	service    = ""
 	dryRun     = false
 	forceRegen = false
+
+	// typedProperties generates wetwire.Value[T] and property type fields
+	// instead of `any` for every property.
+	typedProperties = true
 )
 
 func init() {
@@ -29,6 +34,7 @@ func init() {
 	flag.StringVar(&service, "service", "", "Generate only this service (e.g., s3)")
 	flag.BoolVar(&dryRun, "dry-run", false, "Show what would be generated without writing files")
 	flag.BoolVar(&forceRegen, "force", false, "Force regeneration even if spec hasn't changed")
+	flag.BoolVar(&typedProperties, "typed", true, "Generate strongly typed properties (wetwire.Value[T]); false generates any")
 }
 
 func main() {
@@ -87,6 +93,19 @@ func main() {
 		log.Fatalf("generating registry: %v", err)
 	}
 
+	// Step 5: Bundle the spec for offline schema validation
+	fmt.Println("\nFetching CloudFormation registry schemas...")
+	registry, err := fetchRegistrySchemas(forceRegen)
+	if err != nil {
+		log.Fatalf("fetching registry schemas: %v", err)
+	}
+	fmt.Printf("Registry schemas: %d\n", len(registry))
+
+	fmt.Println("\nGenerating schema bundle...")
+	if err := generateSchemaBundle(cfnSpec, registry, outputDir, dryRun); err != nil {
+		log.Fatalf("generating schema bundle: %v", err)
+	}
+
 	fmt.Printf("\nGeneration complete:\n")
 	fmt.Printf("  Services: %d\n", stats.Services)
 	fmt.Printf("  Resources: %d\n", stats.Resources)
@@ -177,6 +177,10 @@ func parseProperty(name string, def spec.Property) ParsedProperty {
 		Required:      def.Required,
 	}
 
+	if typedProperties {
+		return parseTypedProperty(prop, def)
+	}
+
 	// Determine Go type
 	if def.PrimitiveType != "" {
 		prop.GoType = primitiveToGo(def.PrimitiveType)
@@ -228,3 +232,77 @@ func primitiveToGo(cfType string) string {
 	// All types are `any` to accept intrinsic functions
 	return "any"
 }
+
+// valueType wraps a Go type in wetwire.Value so the field accepts either a
+// literal of that type or an intrinsic function.
+func valueType(goType string) string {
+	return "wetwire.Value[" + goType + "]"
+}
+
+// unwrapValueType returns T for "wetwire.Value[T]" and goType unchanged otherwise.
+func unwrapValueType(goType string) string {
+	if strings.HasPrefix(goType, "wetwire.Value[") && strings.HasSuffix(goType, "]") {
+		return strings.TrimSuffix(strings.TrimPrefix(goType, "wetwire.Value["), "]")
+	}
+	return goType
+}
+
+// parseTypedProperty determines strongly typed Go types for a property.
+// Primitives, primitive lists and primitive maps are wrapped in wetwire.Value,
+// property type references become wetwire.Value[PropertyType], and lists or
+// maps of property types use the property type as element type.
+func parseTypedProperty(prop ParsedProperty, def spec.Property) ParsedProperty {
+	switch {
+	case def.PrimitiveType != "":
+		prop.CFType = def.PrimitiveType
+		if goType := typedPrimitiveToGo(def.PrimitiveType); goType != "any" {
+			prop.GoType = valueType(goType)
+		} else {
+			prop.GoType = "any"
+		}
+	case def.Type == "List":
+		prop.IsList = true
+		if def.ItemType != "" {
+			prop.ItemType = def.ItemType
+			prop.GoType = "[]" + def.ItemType
+		} else {
+			prop.ItemType = "any"
+			prop.GoType = valueType("[]" + typedPrimitiveToGo(def.PrimitiveItemType))
+		}
+	case def.Type == "Map":
+		prop.IsMap = true
+		if def.ItemType != "" {
+			prop.ItemType = def.ItemType
+			prop.GoType = "map[string]" + def.ItemType
+		} else {
+			prop.ItemType = typedPrimitiveToGo(def.PrimitiveItemType)
+			prop.GoType = valueType("map[string]" + prop.ItemType)
+		}
+	case def.Type != "":
+		// Property type reference; Value handles optionality and If{}
+		prop.CFType = def.Type
+		prop.GoType = valueType(def.Type)
+	default:
+		prop.GoType = "any"
+	}
+	return prop
+}
+
+// typedPrimitiveToGo converts CloudFormation primitive types to concrete Go
+// types for typed generation. Json and unknown types map to `any`.
+func typedPrimitiveToGo(cfType string) string {
+	switch cfType {
+	case "String", "Timestamp":
+		return "string"
+	case "Integer":
+		return "int"
+	case "Long":
+		return "int64"
+	case "Double":
+		return "float64"
+	case "Boolean":
+		return "bool"
+	default:
+		return "any"
+	}
+}
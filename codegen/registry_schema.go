@@ -0,0 +1,229 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lex00/wetwire-aws-go/internal/schema"
+)
+
+// registrySchemasURL is the archive of CloudFormation registry schemas.
+// Unlike the resource specification, registry schemas carry the patterns,
+// length limits and ranges of properties and the shape of read-only attributes.
+const registrySchemasURL = "https://schema.cloudformation.us-east-1.amazonaws.com/CloudformationSchema.zip"
+
+// registrySchema is the part of a registry resource schema used by the bundle.
+type registrySchema struct {
+	TypeName    string                      `json:"typeName"`
+	Properties  map[string]registryProperty `json:"properties"`
+	Definitions map[string]registryProperty `json:"definitions"`
+}
+
+// registryProperty is a JSON Schema property or definition.
+type registryProperty struct {
+	Ref        string                      `json:"$ref"`
+	Type       json.RawMessage             `json:"type"`
+	Pattern    string                      `json:"pattern"`
+	MinLength  *int                        `json:"minLength"`
+	MaxLength  *int                        `json:"maxLength"`
+	Minimum    *float64                    `json:"minimum"`
+	Maximum    *float64                    `json:"maximum"`
+	Items      *registryProperty           `json:"items"`
+	Properties map[string]registryProperty `json:"properties"`
+}
+
+// jsonType returns the JSON Schema type, or "" when it is missing or a
+// union such as ["string", "object"].
+func (p registryProperty) jsonType() string {
+	var t string
+	if err := json.Unmarshal(p.Type, &t); err != nil {
+		return ""
+	}
+	return t
+}
+
+// fetchRegistrySchemas downloads the registry schema archive, caching it in
+// the user cache directory, and parses every schema in it.
+func fetchRegistrySchemas(force bool) (map[string]*registrySchema, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("locating cache directory: %w", err)
+	}
+	cacheFile := filepath.Join(cacheDir, "wetwire-aws", "CloudformationSchema.zip")
+
+	data, err := os.ReadFile(cacheFile)
+	if err != nil || force {
+		resp, err := http.Get(registrySchemasURL)
+		if err != nil {
+			return nil, fmt.Errorf("downloading registry schemas: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading registry schemas: %s", resp.Status)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("downloading registry schemas: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return readRegistrySchemas(data)
+}
+
+// readRegistrySchemas parses a registry schema archive, keyed by type name.
+func readRegistrySchemas(data []byte) (map[string]*registrySchema, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("reading registry schemas: %w", err)
+	}
+
+	schemas := make(map[string]*registrySchema)
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".json") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+		var rs registrySchema
+		err = json.NewDecoder(rc).Decode(&rs)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", f.Name, err)
+		}
+		if rs.TypeName != "" {
+			schemas[rs.TypeName] = &rs
+		}
+	}
+	return schemas, nil
+}
+
+// resolve follows "#/definitions/..." references to the definition they name.
+func (rs *registrySchema) resolve(p registryProperty) registryProperty {
+	for i := 0; i < 8 && p.Ref != ""; i++ {
+		def, ok := rs.Definitions[strings.TrimPrefix(p.Ref, "#/definitions/")]
+		if !ok {
+			break
+		}
+		p = def
+	}
+	return p
+}
+
+// lookup finds a property by its dotted path, as used by attribute names
+// such as "Endpoint.Address".
+func (rs *registrySchema) lookup(path string) (registryProperty, bool) {
+	props := rs.Properties
+	var p registryProperty
+	for _, part := range strings.Split(path, ".") {
+		found, ok := props[part]
+		if !ok {
+			return registryProperty{}, false
+		}
+		p = rs.resolve(found)
+		props = p.Properties
+	}
+	return p, true
+}
+
+// applyRegistrySchemas adds the constraints of the registry schemas to the
+// bundle: patterns and length limits of string properties, ranges of numeric
+// properties, and the types of attributes the specification leaves untyped.
+func applyRegistrySchemas(bundle *schema.Spec, schemas map[string]*registrySchema) {
+	for cfType, res := range bundle.ResourceTypes {
+		rs, ok := schemas[cfType]
+		if !ok {
+			continue
+		}
+		for propName, prop := range res.Properties {
+			if rp, ok := rs.Properties[propName]; ok {
+				res.Properties[propName] = withConstraints(prop, rs.resolve(rp))
+			}
+		}
+		for attrName, attr := range res.Attributes {
+			if attr.PrimitiveType != "" || attr.Type != "" {
+				continue
+			}
+			if rp, ok := rs.lookup(attrName); ok {
+				res.Attributes[attrName] = attributeSpec(rs, rp)
+			}
+		}
+	}
+
+	for ptName, pt := range bundle.PropertyTypes {
+		idx := strings.LastIndex(ptName, ".")
+		if idx < 0 {
+			continue
+		}
+		rs, ok := schemas[ptName[:idx]]
+		if !ok {
+			continue
+		}
+		def, ok := rs.Definitions[ptName[idx+1:]]
+		if !ok {
+			continue
+		}
+		for propName, prop := range pt.Properties {
+			if rp, ok := def.Properties[propName]; ok {
+				pt.Properties[propName] = withConstraints(prop, rs.resolve(rp))
+			}
+		}
+	}
+}
+
+// withConstraints copies the limits of a registry property that apply to
+// the property's primitive type.
+func withConstraints(prop schema.PropertySpec, rp registryProperty) schema.PropertySpec {
+	switch prop.PrimitiveType {
+	case "String":
+		prop.Pattern = rp.Pattern
+		prop.MinLength = rp.MinLength
+		prop.MaxLength = rp.MaxLength
+	case "Integer", "Long", "Double":
+		prop.Minimum = rp.Minimum
+		prop.Maximum = rp.Maximum
+	}
+	return prop
+}
+
+// attributeSpec converts a read-only registry property to an attribute.
+func attributeSpec(rs *registrySchema, rp registryProperty) schema.AttributeSpec {
+	if rp.jsonType() == "array" {
+		attr := schema.AttributeSpec{Type: "List"}
+		if rp.Items != nil {
+			attr.PrimitiveItemType = primitiveType(rs.resolve(*rp.Items).jsonType())
+		}
+		return attr
+	}
+	return schema.AttributeSpec{PrimitiveType: primitiveType(rp.jsonType())}
+}
+
+// primitiveType maps a JSON Schema type to a specification primitive type.
+func primitiveType(jsonType string) string {
+	switch jsonType {
+	case "string":
+		return "String"
+	case "integer":
+		return "Integer"
+	case "number":
+		return "Double"
+	case "boolean":
+		return "Boolean"
+	case "object":
+		return "Json"
+	}
+	return ""
+}
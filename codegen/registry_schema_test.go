@@ -0,0 +1,146 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/lex00/wetwire-aws-go/internal/schema"
+)
+
+const queueRegistrySchema = `{
+  "typeName": "AWS::Test::Queue",
+  "definitions": {
+    "Name": {"type": "string", "pattern": "^[a-z]+$", "minLength": 1, "maxLength": 80},
+    "RedrivePolicy": {
+      "type": "object",
+      "properties": {
+        "MaxReceiveCount": {"type": "integer", "minimum": 1, "maximum": 1000}
+      }
+    },
+    "Endpoint": {
+      "type": "object",
+      "properties": {"Port": {"type": "integer"}}
+    }
+  },
+  "properties": {
+    "QueueName": {"$ref": "#/definitions/Name"},
+    "DelaySeconds": {"type": "integer", "minimum": 0, "maximum": 900},
+    "Tags": {"type": "array", "maxLength": 3},
+    "Arn": {"type": "string"},
+    "Endpoints": {"type": "array", "items": {"type": "string"}},
+    "Endpoint": {"$ref": "#/definitions/Endpoint"}
+  },
+  "readOnlyProperties": ["/properties/Arn", "/properties/Endpoints", "/properties/Endpoint/Port"]
+}`
+
+func registryArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadRegistrySchemas(t *testing.T) {
+	data := registryArchive(t, map[string]string{
+		"aws-test-queue.json": queueRegistrySchema,
+		"README.txt":          "not a schema",
+	})
+
+	schemas, err := readRegistrySchemas(data)
+	if err != nil {
+		t.Fatalf("readRegistrySchemas() error = %v", err)
+	}
+	if len(schemas) != 1 {
+		t.Fatalf("got %d schemas, want 1", len(schemas))
+	}
+	if _, ok := schemas["AWS::Test::Queue"]; !ok {
+		t.Error("schema not keyed by type name")
+	}
+}
+
+func TestApplyRegistrySchemas(t *testing.T) {
+	schemas, err := readRegistrySchemas(registryArchive(t, map[string]string{
+		"aws-test-queue.json": queueRegistrySchema,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := &schema.Spec{
+		ResourceTypes: map[string]schema.ResourceSpec{
+			"AWS::Test::Queue": {
+				Properties: map[string]schema.PropertySpec{
+					"QueueName":    {PrimitiveType: "String"},
+					"DelaySeconds": {PrimitiveType: "Integer"},
+					"Tags":         {Type: "List", ItemType: "Tag"},
+				},
+				Attributes: map[string]schema.AttributeSpec{
+					"Arn":           {PrimitiveType: "String"},
+					"Endpoints":     {},
+					"Endpoint.Port": {},
+				},
+			},
+		},
+		PropertyTypes: map[string]schema.PropertyTypeSpec{
+			"AWS::Test::Queue.RedrivePolicy": {
+				Properties: map[string]schema.PropertySpec{
+					"MaxReceiveCount": {PrimitiveType: "Integer"},
+				},
+			},
+		},
+	}
+
+	applyRegistrySchemas(bundle, schemas)
+
+	res := bundle.ResourceTypes["AWS::Test::Queue"]
+	name := res.Properties["QueueName"]
+	if name.Pattern != "^[a-z]+$" {
+		t.Errorf("QueueName pattern = %q, want it resolved through $ref", name.Pattern)
+	}
+	if name.MinLength == nil || *name.MinLength != 1 || name.MaxLength == nil || *name.MaxLength != 80 {
+		t.Errorf("QueueName lengths = %v, %v, want 1, 80", name.MinLength, name.MaxLength)
+	}
+	if name.PrimitiveType != "String" {
+		t.Errorf("QueueName primitive type = %q, want it kept", name.PrimitiveType)
+	}
+
+	delay := res.Properties["DelaySeconds"]
+	if delay.Minimum == nil || *delay.Minimum != 0 || delay.Maximum == nil || *delay.Maximum != 900 {
+		t.Errorf("DelaySeconds range = %v, %v, want 0, 900", delay.Minimum, delay.Maximum)
+	}
+	if delay.MinLength != nil || delay.Pattern != "" {
+		t.Error("DelaySeconds got string constraints")
+	}
+	if tags := res.Properties["Tags"]; tags.MaxLength != nil {
+		t.Error("Tags list got a string length limit")
+	}
+
+	receive := bundle.PropertyTypes["AWS::Test::Queue.RedrivePolicy"].Properties["MaxReceiveCount"]
+	if receive.Minimum == nil || *receive.Minimum != 1 || receive.Maximum == nil || *receive.Maximum != 1000 {
+		t.Errorf("MaxReceiveCount range = %v, %v, want 1, 1000", receive.Minimum, receive.Maximum)
+	}
+
+	wantAttrs := map[string]schema.AttributeSpec{
+		"Arn":           {PrimitiveType: "String"},
+		"Endpoints":     {Type: "List", PrimitiveItemType: "String"},
+		"Endpoint.Port": {PrimitiveType: "Integer"},
+	}
+	for name, want := range wantAttrs {
+		if got := res.Attributes[name]; got != want {
+			t.Errorf("attribute %s = %+v, want %+v", name, got, want)
+		}
+	}
+}
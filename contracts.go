@@ -17,6 +17,8 @@ package wetwire_aws
 
 import (
 	"encoding/json"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Resource represents a CloudFormation resource.
@@ -107,6 +109,13 @@ type DiscoveredOutput struct {
 	Line int
 	// AttrRefUsages tracks Resource.Attr patterns used in this output's properties
 	AttrRefUsages []AttrRefUsage
+	// Dependencies are logical names of referenced resources and parameters
+	Dependencies []string
+	// Exported reports whether the output declares an export
+	Exported bool
+	// ExportName is the export name when it is a string literal or the
+	// template of a Sub; empty for computed names
+	ExportName string
 }
 
 // DiscoveredMapping represents a mapping found by AST parsing.
@@ -131,10 +140,35 @@ type DiscoveredCondition struct {
 	Line int
 }
 
+// DiscoveredLoop represents a package-level ForEach resource loop found by
+// AST parsing.
+type DiscoveredLoop struct {
+	// Name is the variable name (the default loop name)
+	Name string
+	// File is the source file path
+	File string
+	// Line is the line number of the declaration
+	Line int
+}
+
+// DiscoveredAttributes represents a ResourceAttributes declaration found by AST parsing.
+type DiscoveredAttributes struct {
+	// Name is the variable name of the attributes declaration
+	Name string
+	// Resource is the logical name of the resource the attributes apply to
+	Resource string
+	// DependsOn are logical names of explicit DependsOn targets
+	DependsOn []string
+	// File is the source file path
+	File string
+	// Line is the line number of the declaration
+	Line int
+}
+
 // Template represents a CloudFormation template.
 type Template struct {
 	AWSTemplateFormatVersion string                 `json:"AWSTemplateFormatVersion" yaml:"AWSTemplateFormatVersion"`
-	Transform                string                 `json:"Transform,omitempty" yaml:"Transform,omitempty"`
+	Transform                Transforms             `json:"Transform,omitempty" yaml:"Transform,omitempty"`
 	Description              string                 `json:"Description,omitempty" yaml:"Description,omitempty"`
 	Parameters               map[string]Parameter   `json:"Parameters,omitempty" yaml:"Parameters,omitempty"`
 	Mappings                 map[string]any         `json:"Mappings,omitempty" yaml:"Mappings,omitempty"`
@@ -143,35 +177,159 @@ type Template struct {
 	Outputs                  map[string]Output      `json:"Outputs,omitempty" yaml:"Outputs,omitempty"`
 }
 
+// Macros of the Transform section.
+const (
+	// TransformServerless is the AWS SAM transform.
+	TransformServerless = "AWS::Serverless-2016-10-31"
+	// TransformLanguageExtensions enables Fn::ForEach, Fn::Length,
+	// Fn::ToJsonString and intrinsic functions in DeletionPolicy and
+	// UpdateReplacePolicy.
+	TransformLanguageExtensions = "AWS::LanguageExtensions"
+)
+
+// ForEachPrefix starts the key of an Fn::ForEach loop, Fn::ForEach::Name.
+// Loops need the TransformLanguageExtensions transform.
+const ForEachPrefix = "Fn::ForEach::"
+
+// Transforms are the macros of a template's Transform section, in the order
+// CloudFormation runs them. A single macro is serialized as a string and
+// several as a list, as CloudFormation accepts both.
+type Transforms []string
+
+// Has reports whether the macro is one of the transforms.
+func (t Transforms) Has(name string) bool {
+	for _, macro := range t {
+		if macro == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON serializes a single transform as a string.
+func (t Transforms) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON accepts a string or a list of strings.
+func (t *Transforms) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = Transforms{name}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// MarshalYAML serializes a single transform as a string.
+func (t Transforms) MarshalYAML() (any, error) {
+	if len(t) == 1 {
+		return t[0], nil
+	}
+	return []string(t), nil
+}
+
+// UnmarshalYAML accepts a string or a list of strings.
+func (t *Transforms) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Transforms{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(t))
+}
+
 // ResourceDef is a single resource in the CloudFormation template.
 type ResourceDef struct {
 	Type       string         `json:"Type" yaml:"Type"`
 	Properties map[string]any `json:"Properties,omitempty" yaml:"Properties,omitempty"`
 	DependsOn  []string       `json:"DependsOn,omitempty" yaml:"DependsOn,omitempty"`
+	Condition  string         `json:"Condition,omitempty" yaml:"Condition,omitempty"`
+	// DeletionPolicy and UpdateReplacePolicy are a policy name, or an
+	// intrinsic function such as Fn::If under AWS::LanguageExtensions.
+	DeletionPolicy      any            `json:"DeletionPolicy,omitempty" yaml:"DeletionPolicy,omitempty"`
+	UpdateReplacePolicy any            `json:"UpdateReplacePolicy,omitempty" yaml:"UpdateReplacePolicy,omitempty"`
+	Metadata            map[string]any `json:"Metadata,omitempty" yaml:"Metadata,omitempty"`
+	CreationPolicy      map[string]any `json:"CreationPolicy,omitempty" yaml:"CreationPolicy,omitempty"`
+	UpdatePolicy        map[string]any `json:"UpdatePolicy,omitempty" yaml:"UpdatePolicy,omitempty"`
+	// ForEach holds the [identifier, collection, outputs] arguments of an
+	// Fn::ForEach loop. It is set only on entries keyed ForEachPrefix+Name,
+	// which have no other fields; the loop's outputs are keyed by
+	// logical-ID templates such as "Topic${Name}".
+	ForEach []any `json:"-" yaml:"-"`
+}
+
+// resourceFields is ResourceDef without its marshaling methods.
+type resourceFields ResourceDef
+
+// IsLoop reports whether the entry is an Fn::ForEach loop. A loop is read
+// from any list value; template validation reports loops whose key does not
+// start with ForEachPrefix.
+func (r ResourceDef) IsLoop() bool {
+	return r.ForEach != nil
+}
+
+// MarshalJSON serializes a loop as its argument list.
+func (r ResourceDef) MarshalJSON() ([]byte, error) {
+	if r.IsLoop() {
+		return json.Marshal(r.ForEach)
+	}
+	return json.Marshal(resourceFields(r))
+}
+
+// UnmarshalJSON reads a resource, or the argument list of a loop.
+func (r *ResourceDef) UnmarshalJSON(data []byte) error {
+	var args []any
+	if err := json.Unmarshal(data, &args); err == nil {
+		*r = ResourceDef{ForEach: args}
+		return nil
+	}
+	return json.Unmarshal(data, (*resourceFields)(r))
+}
+
+// MarshalYAML serializes a loop as its argument list.
+func (r ResourceDef) MarshalYAML() (any, error) {
+	if r.IsLoop() {
+		return r.ForEach, nil
+	}
+	return resourceFields(r), nil
+}
+
+// UnmarshalYAML reads a resource, or the argument list of a loop.
+func (r *ResourceDef) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		*r = ResourceDef{}
+		return node.Decode(&r.ForEach)
+	}
+	return node.Decode((*resourceFields)(r))
 }
 
 // Parameter is a CloudFormation template parameter for output serialization.
 type Parameter struct {
-	Type                  string   `json:"Type"`
-	Description           string   `json:"Description,omitempty"`
-	Default               any      `json:"Default,omitempty"`
-	AllowedValues         []any    `json:"AllowedValues,omitempty"`
-	AllowedPattern        string   `json:"AllowedPattern,omitempty"`
-	ConstraintDescription string   `json:"ConstraintDescription,omitempty"`
-	MinLength             *int     `json:"MinLength,omitempty"`
-	MaxLength             *int     `json:"MaxLength,omitempty"`
-	MinValue              *float64 `json:"MinValue,omitempty"`
-	MaxValue              *float64 `json:"MaxValue,omitempty"`
-	NoEcho                bool     `json:"NoEcho,omitempty"`
+	Type                  string   `json:"Type" yaml:"Type"`
+	Description           string   `json:"Description,omitempty" yaml:"Description,omitempty"`
+	Default               any      `json:"Default,omitempty" yaml:"Default,omitempty"`
+	AllowedValues         []any    `json:"AllowedValues,omitempty" yaml:"AllowedValues,omitempty"`
+	AllowedPattern        string   `json:"AllowedPattern,omitempty" yaml:"AllowedPattern,omitempty"`
+	ConstraintDescription string   `json:"ConstraintDescription,omitempty" yaml:"ConstraintDescription,omitempty"`
+	MinLength             *int     `json:"MinLength,omitempty" yaml:"MinLength,omitempty"`
+	MaxLength             *int     `json:"MaxLength,omitempty" yaml:"MaxLength,omitempty"`
+	MinValue              *float64 `json:"MinValue,omitempty" yaml:"MinValue,omitempty"`
+	MaxValue              *float64 `json:"MaxValue,omitempty" yaml:"MaxValue,omitempty"`
+	NoEcho                bool     `json:"NoEcho,omitempty" yaml:"NoEcho,omitempty"`
 }
 
 // Output is a CloudFormation template output.
 type Output struct {
-	Description string `json:"Description,omitempty"`
-	Value       any    `json:"Value"`
+	Description string `json:"Description,omitempty" yaml:"Description,omitempty"`
+	Value       any    `json:"Value" yaml:"Value"`
 	Export      *struct {
-		Name string `json:"Name"`
-	} `json:"Export,omitempty"`
+		Name string `json:"Name" yaml:"Name"`
+	} `json:"Export,omitempty" yaml:"Export,omitempty"`
+	// Condition names the condition under which the output is created.
+	Condition string `json:"Condition,omitempty" yaml:"Condition,omitempty"`
 }
 
 // BuildResult is the JSON output from `wetwire-aws build`.
@@ -226,10 +384,14 @@ type OptimizeResult struct {
 	Suggestions   []OptimizeSuggestion `json:"suggestions,omitempty"`
 	ResourceCount int                  `json:"resource_count"`
 	Summary       OptimizeSummary      `json:"summary"`
+	// StaleSuppressions are //wetwire:ignore comments for optimizer rules
+	// that no longer match any suggestion.
+	StaleSuppressions []StaleSuppression `json:"stale_suppressions,omitempty"`
 }
 
 // OptimizeSuggestion is a single optimization suggestion.
 type OptimizeSuggestion struct {
+	Rule        string `json:"rule,omitempty"` // e.g. "OPT-S3-001"
 	Resource    string `json:"resource"`
 	Category    string `json:"category"` // "security", "cost", "performance", "reliability"
 	Severity    string `json:"severity"` // "high", "medium", "low"
@@ -240,6 +402,14 @@ type OptimizeSuggestion struct {
 	Line        int    `json:"line,omitempty"`
 }
 
+// StaleSuppression is a //wetwire:ignore comment that no longer silences anything.
+type StaleSuppression struct {
+	Rule    string `json:"rule"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
 // OptimizeSummary provides counts by category.
 type OptimizeSummary struct {
 	Security    int `json:"security"`
@@ -261,13 +431,60 @@ type TemplateDiff struct {
 	Added    []DiffEntry `json:"added,omitempty"`
 	Removed  []DiffEntry `json:"removed,omitempty"`
 	Modified []DiffEntry `json:"modified,omitempty"`
+	// Renames pairs removed and added resources that are probably the same
+	// resource under a new logical ID.
+	Renames []Rename `json:"renames,omitempty"`
+}
+
+// Rename is a removed resource that probably reappears under a new logical
+// ID. CloudFormation deletes the old resource and creates the new one.
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+	// Similarity is the share of identical properties, from 0 to 1.
+	Similarity float64 `json:"similarity"`
+	// Stateful is set for resource types that hold data, which is lost
+	// when the old resource is deleted.
+	Stateful bool `json:"stateful,omitempty"`
 }
 
 // DiffEntry represents a single difference.
 type DiffEntry struct {
+	// Section is the template section: Transform, Parameters, Mappings,
+	// Conditions, Resources or Outputs.
+	Section string `json:"section"`
+	// Resource is the logical name of the entry within its section.
 	Resource string   `json:"resource"`
 	Type     string   `json:"type"`
 	Changes  []string `json:"changes,omitempty"`
+	// Breaking is set when the change breaks other stacks, e.g. an export
+	// they import was removed or renamed.
+	Breaking bool `json:"breaking,omitempty"`
+	// Replacement tells how CloudFormation applies a modified resource:
+	// "will-replace", "may-replace", "unknown" (a changed property has no
+	// known update type) or "in-place".
+	Replacement string `json:"replacement,omitempty"`
+	// UpdateTypes maps each modified property of a resource to its update
+	// type: Mutable, Conditional, Immutable or Unknown.
+	UpdateTypes map[string]string `json:"update_types,omitempty"`
+	// PropertyChanges are the structured changes of a modified entry,
+	// sorted by path.
+	PropertyChanges []PropertyChange `json:"property_changes,omitempty"`
+}
+
+// PropertyChange is one change within a modified diff entry.
+type PropertyChange struct {
+	// Path is a JSON pointer into the entry, e.g. "/Properties/Tags/0/Value".
+	Path string `json:"path"`
+	// Kind is added, removed, modified or moved.
+	Kind string `json:"kind"`
+	// Old is the previous value; unset for added and moved elements.
+	Old any `json:"old,omitempty"`
+	// New is the new value; unset for removed elements.
+	New any `json:"new,omitempty"`
+	// From is the previous path of a moved list element.
+	From string `json:"from,omitempty"`
 }
 
 // DiffSummary provides counts of changes.
@@ -276,6 +493,12 @@ type DiffSummary struct {
 	Removed  int `json:"removed"`
 	Modified int `json:"modified"`
 	Total    int `json:"total"`
+	// Breaking counts the entries that break other stacks.
+	Breaking int `json:"breaking"`
+	// Replaced counts the resources that will or may be replaced.
+	Replaced int `json:"replaced"`
+	// Renamed counts the probable renames.
+	Renamed int `json:"renamed"`
 }
 
 // SchemaResult is the JSON output from `wetwire-aws schema`.
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestAttrRef_MarshalJSON(t *testing.T) {
@@ -165,6 +166,79 @@ func TestResourceDef_DependsOn(t *testing.T) {
 	assert.Equal(t, "MyBucket", dependsOn[1])
 }
 
+func TestTransforms_Serialization(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms Transforms
+		json       string
+		yaml       string
+	}{
+		{"single", Transforms{TransformServerless}, `"AWS::Serverless-2016-10-31"`, "AWS::Serverless-2016-10-31\n"},
+		{"list", Transforms{TransformLanguageExtensions, TransformServerless},
+			`["AWS::LanguageExtensions","AWS::Serverless-2016-10-31"]`,
+			"- AWS::LanguageExtensions\n- AWS::Serverless-2016-10-31\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.transforms)
+			require.NoError(t, err)
+			assert.Equal(t, tt.json, string(data))
+
+			var fromJSON Transforms
+			require.NoError(t, json.Unmarshal(data, &fromJSON))
+			assert.Equal(t, tt.transforms, fromJSON)
+
+			data, err = yaml.Marshal(tt.transforms)
+			require.NoError(t, err)
+			assert.Equal(t, tt.yaml, string(data))
+
+			var fromYAML Transforms
+			require.NoError(t, yaml.Unmarshal(data, &fromYAML))
+			assert.Equal(t, tt.transforms, fromYAML)
+		})
+	}
+
+	assert.True(t, Transforms{TransformLanguageExtensions}.Has(TransformLanguageExtensions))
+	assert.False(t, Transforms(nil).Has(TransformServerless))
+}
+
+func TestResourceDef_Loop(t *testing.T) {
+	template := Template{
+		Resources: map[string]ResourceDef{
+			"Fn::ForEach::Topics": {ForEach: []any{
+				"TopicName",
+				[]any{"Success", "Failure"},
+				map[string]any{"Topic${TopicName}": map[string]any{"Type": "AWS::SNS::Topic"}},
+			}},
+			"Queue": {Type: "AWS::SQS::Queue", DeletionPolicy: "Retain"},
+		},
+	}
+
+	data, err := json.Marshal(template)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"AWSTemplateFormatVersion": "",
+		"Resources": {
+			"Fn::ForEach::Topics": ["TopicName", ["Success", "Failure"], {"Topic${TopicName}": {"Type": "AWS::SNS::Topic"}}],
+			"Queue": {"Type": "AWS::SQS::Queue", "DeletionPolicy": "Retain"}
+		}
+	}`, string(data))
+
+	var fromJSON Template
+	require.NoError(t, json.Unmarshal(data, &fromJSON))
+	assert.True(t, fromJSON.Resources["Fn::ForEach::Topics"].IsLoop())
+	assert.False(t, fromJSON.Resources["Queue"].IsLoop())
+	assert.Equal(t, "AWS::SQS::Queue", fromJSON.Resources["Queue"].Type)
+
+	data, err = yaml.Marshal(template)
+	require.NoError(t, err)
+	var fromYAML Template
+	require.NoError(t, yaml.Unmarshal(data, &fromYAML))
+	assert.Equal(t, fromJSON.Resources["Fn::ForEach::Topics"], fromYAML.Resources["Fn::ForEach::Topics"])
+	assert.Equal(t, "Retain", fromYAML.Resources["Queue"].DeletionPolicy)
+}
+
 func TestBuildResult_Success(t *testing.T) {
 	result := BuildResult{
 		Success: true,
@@ -257,7 +331,7 @@ func TestOutput_WithExport(t *testing.T) {
 		Description: "Bucket ARN for cross-stack reference",
 		Value:       map[string][]string{"Fn::GetAtt": {"DataBucket", "Arn"}},
 		Export: &struct {
-			Name string `json:"Name"`
+			Name string `json:"Name" yaml:"Name"`
 		}{
 			Name: "MyStack-BucketArn",
 		},
@@ -316,3 +390,54 @@ func TestParameter_AllTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatePolicy_JSON(t *testing.T) {
+	policy := UpdatePolicy{
+		AutoScalingRollingUpdate: &AutoScalingRollingUpdate{
+			MaxBatchSize:          Ptr(2),
+			PauseTime:             "PT5M",
+			WaitOnResourceSignals: Ptr(true),
+		},
+		CodeDeployLambdaAliasUpdate: &CodeDeployLambdaAliasUpdate{
+			ApplicationName:     "my-app",
+			DeploymentGroupName: "my-group",
+		},
+	}
+
+	data, err := json.Marshal(policy)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"AutoScalingRollingUpdate": {"MaxBatchSize": 2, "PauseTime": "PT5M", "WaitOnResourceSignals": true},
+		"CodeDeployLambdaAliasUpdate": {"ApplicationName": "my-app", "DeploymentGroupName": "my-group"}
+	}`, string(data))
+}
+
+func TestPolicies_JSONKeepsZeroValues(t *testing.T) {
+	creation := CreationPolicy{
+		AutoScalingCreationPolicy: &AutoScalingCreationPolicy{MinSuccessfulInstancesPercent: Ptr(0)},
+		StartFleet:                Ptr(false),
+	}
+	data, err := json.Marshal(creation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"AutoScalingCreationPolicy": {"MinSuccessfulInstancesPercent": 0}, "StartFleet": false}`, string(data))
+
+	update := UpdatePolicy{
+		AutoScalingReplacingUpdate: &AutoScalingReplacingUpdate{WillReplace: Ptr(false)},
+		AutoScalingRollingUpdate: &AutoScalingRollingUpdate{
+			MinInstancesInService: Ptr(0),
+			WaitOnResourceSignals: Ptr(false),
+		},
+		UseOnlineResharding: Ptr(false),
+	}
+	data, err = json.Marshal(update)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"AutoScalingReplacingUpdate": {"WillReplace": false},
+		"AutoScalingRollingUpdate": {"MinInstancesInService": 0, "WaitOnResourceSignals": false},
+		"UseOnlineResharding": false
+	}`, string(data))
+
+	data, err = json.Marshal(UpdatePolicy{AutoScalingRollingUpdate: &AutoScalingRollingUpdate{}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"AutoScalingRollingUpdate": {}}`, string(data))
+}
@@ -1,22 +1,44 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/assets"
+	"github.com/lex00/wetwire-aws-go/internal/build"
+	"github.com/lex00/wetwire-aws-go/internal/config"
 	"github.com/lex00/wetwire-aws-go/internal/differ"
 	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/graph"
 	"github.com/lex00/wetwire-aws-go/internal/importer"
 	"github.com/lex00/wetwire-aws-go/internal/lint"
-	"github.com/lex00/wetwire-aws-go/internal/runner"
 	"github.com/lex00/wetwire-aws-go/internal/schema"
 	"github.com/lex00/wetwire-aws-go/internal/template"
 	coredomain "github.com/lex00/wetwire-core-go/domain"
 )
 
 // AwsDomain implements the Domain interface for AWS CloudFormation.
-type AwsDomain struct{}
+type AwsDomain struct {
+	// Uploader uploads the wetwire.Asset archives of builds to the bucket
+	// of wetwire.yaml; nil leaves them in the assets directory
+	Uploader AssetUploader
+	// ImageBuilder builds and pushes the wetwire.ImageAsset images of
+	// builds; nil only records them in the asset manifest
+	ImageBuilder ImageBuilder
+}
+
+type (
+	// AssetUploader stores packaged wetwire.Asset archives in S3.
+	AssetUploader = assets.Uploader
+	// ImageBuilder builds and pushes the images of wetwire.ImageAsset.
+	ImageBuilder = assets.ImageBuilder
+	// Image is a container image for an ImageBuilder to build.
+	Image = assets.Image
+)
 
 // Compile-time check that AwsDomain implements Domain and all optional interfaces
 var (
@@ -39,7 +61,7 @@ func (d *AwsDomain) Version() string {
 
 // Builder returns the AWS CloudFormation builder implementation
 func (d *AwsDomain) Builder() coredomain.Builder {
-	return &awsBuilder{}
+	return &awsBuilder{uploader: d.Uploader, imageBuilder: d.ImageBuilder}
 }
 
 // Linter returns the AWS linter implementation
@@ -77,12 +99,41 @@ func (d *AwsDomain) Differ() coredomain.Differ {
 	return differ.New()
 }
 
+// SplittingBuilder returns a Builder that splits templates over the
+// CloudFormation resource and size limits into nested stacks.
+func (d *AwsDomain) SplittingBuilder() coredomain.Builder {
+	return &awsBuilder{autoSplit: true, uploader: d.Uploader, imageBuilder: d.ImageBuilder}
+}
+
 // awsBuilder implements domain.Builder for AWS
-type awsBuilder struct{}
+type awsBuilder struct {
+	// autoSplit splits templates over the CloudFormation limits into
+	// nested stacks
+	autoSplit bool
+	// uploader uploads packaged assets
+	uploader assets.Uploader
+	// imageBuilder builds image assets
+	imageBuilder assets.ImageBuilder
+}
 
 func (b *awsBuilder) Build(ctx *Context, path string, opts BuildOpts) (*Result, error) {
+	// A "dir/..." pattern builds a stack per package
+	if strings.HasSuffix(path, "...") {
+		if b.autoSplit {
+			return NewErrorResult("auto-split failed", Error{
+				Message: "auto-split does not support multi-stack builds; build each package with --auto-split",
+			}), nil
+		}
+		return b.buildStacks(ctx, path, opts)
+	}
+
 	packages := []string{path}
 
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
 	// Discover resources
 	result, err := discover.Discover(discover.Options{
 		Packages: packages,
@@ -102,60 +153,33 @@ func (b *awsBuilder) Build(ctx *Context, path string, opts BuildOpts) (*Result,
 		return NewErrorResultMultiple("discovery errors", errs), nil
 	}
 
-	// Build template
-	builder := template.NewBuilderFull(
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
-
-	// Set VarAttrRefs
-	varAttrRefs := make(map[string]template.VarAttrRefInfo)
-	for name, info := range result.VarAttrRefs {
-		varAttrRefs[name] = template.VarAttrRefInfo{
-			AttrRefs: info.AttrRefs,
-			VarRefs:  info.VarRefs,
-		}
-	}
-	builder.SetVarAttrRefs(varAttrRefs)
-
-	// Extract all values
-	values, err := runner.ExtractAll(
-		packages[0],
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("extracting values: %w", err)
+	// Stack resources may nest the templates of child packages
+	if build.HasNestedStacks(result) {
+		return b.buildNested(ctx, path, cfg, opts)
 	}
 
-	// Set all extracted values
-	for name, props := range values.Resources {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Parameters {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Outputs {
-		builder.SetValue(name, props)
-	}
-	for name, val := range values.Mappings {
-		builder.SetValue(name, val)
+	// Build template
+	tmpl, err := build.Template(packages[0], result)
+	if err != nil {
+		return nil, err
 	}
-	for name, val := range values.Conditions {
-		builder.SetValue(name, val)
+	if err := build.Configure(tmpl, cfg); err != nil {
+		return nil, err
 	}
-
-	tmpl, err := builder.Build()
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("building template: %w", err)
-	}
+		return nil, err
+	}
+	return b.nestedResult(ctx, cfg, []*build.NestedTemplate{{
+		Path:     filepath.Base(abs) + ".json",
+		Package:  packages[0],
+		Dir:      abs,
+		Template: tmpl,
+	}}, opts)
+}
 
+// templateResult writes or returns the template of a single-stack build.
+func templateResult(tmpl *wetwire.Template, opts BuildOpts) (*Result, error) {
 	// Serialize template to JSON for the result
 	data, err := template.ToJSON(tmpl)
 	if err != nil {
@@ -179,45 +203,287 @@ func (b *awsBuilder) Build(ctx *Context, path string, opts BuildOpts) (*Result,
 	return NewResultWithData("Build completed", string(data)), nil
 }
 
+// buildNested builds a package with nested stacks. Output is a directory
+// that receives the templates and their manifest; otherwise the manifest is
+// returned with the templates inlined.
+func (b *awsBuilder) buildNested(ctx *Context, path string, cfg *config.Config, opts BuildOpts) (*Result, error) {
+	templates, err := build.Nested(path)
+	if err != nil {
+		return nil, err
+	}
+	return b.nestedResult(ctx, cfg, templates, opts)
+}
+
+// nestedResult packages the assets of the templates of a build, splits
+// those over the limits when autoSplit is set, and writes or returns them.
+// A single template is written to the Output file.
+func (b *awsBuilder) nestedResult(ctx *Context, cfg *config.Config, templates []*build.NestedTemplate, opts BuildOpts) (*Result, error) {
+	single := len(templates) == 1 && !(b.autoSplit && build.NeedsSplit(templates[0].Template))
+	outDir := opts.Output
+	if single {
+		outDir = filepath.Dir(opts.Output)
+	}
+	packager := assets.New(b.assetOptions(cfg, opts, outDir))
+	for _, t := range templates {
+		name := t.Path
+		if len(templates) == 1 {
+			name = ""
+		}
+		if err := packager.Package(ctx, name, t.Template, t.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.autoSplit {
+		var err error
+		if templates, err = splitTemplates(templates); err != nil {
+			return nil, err
+		}
+	}
+	if len(templates) == 1 {
+		return withAssets(packager)(templateResult(templates[0].Template, opts))
+	}
+	return withAssets(packager)(nestedTemplatesResult(templates, opts))
+}
+
+// assetOptions returns the packaging options of a build. Archives go to
+// the assets directory under outDir, unless the build writes no files, and
+// nothing is uploaded or built in a dry run.
+func (b *awsBuilder) assetOptions(cfg *config.Config, opts BuildOpts, outDir string) assets.Options {
+	options := build.AssetOptions(cfg)
+	if opts.DryRun {
+		return options
+	}
+	options.Uploader = b.uploader
+	options.ImageBuilder = b.imageBuilder
+	if opts.Output != "" {
+		options.OutDir = filepath.Join(outDir, assets.Dir)
+	}
+	return options
+}
+
+// withAssets writes the asset manifest after a successful build and counts
+// the packaged assets in its message.
+func withAssets(packager *assets.Packager) func(*Result, error) (*Result, error) {
+	return func(result *Result, err error) (*Result, error) {
+		if err != nil || packager.Len() == 0 {
+			return result, err
+		}
+		if err := packager.WriteManifest(); err != nil {
+			return nil, err
+		}
+		result.Message += fmt.Sprintf(", %d assets packaged", packager.Len())
+		return result, nil
+	}
+}
+
+// nestedTemplatesResult writes or returns several templates with their
+// manifest.
+func nestedTemplatesResult(templates []*build.NestedTemplate, opts BuildOpts) (*Result, error) {
+
+	manifest := build.NewManifest(templates)
+	inlined := manifest
+	inlined.Templates = make([]build.ManifestEntry, len(manifest.Templates))
+	for i, entry := range manifest.Templates {
+		entry.Template = templates[i].Template
+		inlined.Templates[i] = entry
+	}
+	data, err := json.MarshalIndent(inlined, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serializing templates: %w", err)
+	}
+	if opts.DryRun {
+		return NewResultWithData(fmt.Sprintf("Built %d templates (dry run - no files written)", len(templates)), string(data)), nil
+	}
+	if opts.Output == "" {
+		return NewResultWithData(fmt.Sprintf("Built %d templates", len(templates)), string(data)), nil
+	}
+
+	if err := os.MkdirAll(opts.Output, 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", opts.Output, err)
+	}
+	for _, t := range templates {
+		tmplData, err := template.ToJSON(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("serializing %s: %w", t.Path, err)
+		}
+		file := filepath.Join(opts.Output, t.Path)
+		if err := os.WriteFile(file, tmplData, 0644); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", file, err)
+		}
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serializing manifest: %w", err)
+	}
+	manifestFile := filepath.Join(opts.Output, build.ManifestFile)
+	if err := os.WriteFile(manifestFile, manifestData, 0644); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", manifestFile, err)
+	}
+	return NewResultWithData(fmt.Sprintf("Built %d templates to %s, root template %s", len(templates), opts.Output, manifest.Root), string(data)), nil
+}
+
+// splitTemplates replaces each template over the CloudFormation limits with
+// a root template that nests the parts of its resources.
+func splitTemplates(templates []*build.NestedTemplate) ([]*build.NestedTemplate, error) {
+	var result []*build.NestedTemplate
+	for _, t := range templates {
+		if !build.NeedsSplit(t.Template) {
+			result = append(result, t)
+			continue
+		}
+		parts, err := build.Split(strings.TrimSuffix(t.Path, ".json"), t.Template, build.SplitOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("splitting %s: %w", t.Path, err)
+		}
+		parts[0].NestedIn = t.NestedIn
+		for _, part := range parts {
+			part.Package, part.Dir = t.Package, t.Dir
+		}
+		result = append(result, parts...)
+	}
+	return result, nil
+}
+
+// stackResult is a stack in the output of a multi-stack build.
+type stackResult struct {
+	Name      string            `json:"name"`
+	StackName string            `json:"stack_name"`
+	Package   string            `json:"package"`
+	DependsOn []string          `json:"depends_on,omitempty"`
+	Template  *wetwire.Template `json:"template"`
+}
+
+// buildStacks builds the stacks of a "dir/..." pattern. With several
+// stacks, Output is a directory that receives a <stack>.json template per
+// stack; otherwise the stacks are returned in deployment order.
+func (b *awsBuilder) buildStacks(ctx *Context, path string, opts BuildOpts) (*Result, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	stacks, err := build.Stacks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	outDir := opts.Output
+	if len(stacks) == 1 {
+		outDir = filepath.Dir(opts.Output)
+	}
+	packager := assets.New(b.assetOptions(cfg, opts, outDir))
+	for _, stack := range stacks {
+		name := stack.Name + ".json"
+		if len(stacks) == 1 {
+			name = ""
+		}
+		if err := packager.Package(ctx, name, stack.Template, stack.Dir); err != nil {
+			return nil, err
+		}
+	}
+	return withAssets(packager)(stacksResult(stacks, opts))
+}
+
+// stacksResult writes or returns the stacks of a multi-stack build.
+func stacksResult(stacks []*build.Stack, opts BuildOpts) (*Result, error) {
+	if len(stacks) == 1 {
+		return templateResult(stacks[0].Template, opts)
+	}
+
+	order := make([]string, 0, len(stacks))
+	results := make([]stackResult, 0, len(stacks))
+	for _, stack := range stacks {
+		order = append(order, stack.Name)
+		results = append(results, stackResult{
+			Name:      stack.Name,
+			StackName: stack.StackName,
+			Package:   stack.Package,
+			DependsOn: stack.DependsOn,
+			Template:  stack.Template,
+		})
+	}
+	deployOrder := strings.Join(order, ", ")
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("serializing stacks: %w", err)
+	}
+	if opts.DryRun {
+		return NewResultWithData(fmt.Sprintf("Built %d stacks, deploy order: %s (dry run - no files written)", len(stacks), deployOrder), string(data)), nil
+	}
+	if opts.Output == "" {
+		return NewResultWithData(fmt.Sprintf("Built %d stacks, deploy order: %s", len(stacks), deployOrder), string(data)), nil
+	}
+
+	if err := os.MkdirAll(opts.Output, 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", opts.Output, err)
+	}
+	for _, stack := range stacks {
+		tmplData, err := template.ToJSON(stack.Template)
+		if err != nil {
+			return nil, fmt.Errorf("serializing stack %s: %w", stack.Name, err)
+		}
+		file := filepath.Join(opts.Output, stack.Name+".json")
+		if err := os.WriteFile(file, tmplData, 0644); err != nil {
+			return nil, fmt.Errorf("writing stack %s to %s: %w", stack.Name, file, err)
+		}
+	}
+	return NewResultWithData(fmt.Sprintf("Built %d stacks to %s, deploy order: %s", len(stacks), opts.Output, deployOrder), string(data)), nil
+}
+
 // awsLinter implements domain.Linter for AWS
 type awsLinter struct{}
 
 func (l *awsLinter) Lint(ctx *Context, path string, opts LintOpts) (*Result, error) {
-	// Build lint options from LintOpts
-	lintOpts := lint.Options{
-		DisabledRules: opts.Disable,
-		Fix:           opts.Fix,
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
 	}
 
+	// Start from wetwire.yaml; rules disabled on the command line take precedence
+	lintOpts := cfg.LintOptions()
+	lintOpts.DisabledRules = opts.Disable
+	lintOpts.Fix = opts.Fix
+
 	result, err := lint.LintPackage(path, lintOpts)
 	if err != nil {
 		return nil, fmt.Errorf("linting failed: %w", err)
 	}
 
-	// Convert linter issues to domain.Error format
-	if len(result.Issues) > 0 {
-		errs := make([]Error, 0, len(result.Issues))
-		for _, issue := range result.Issues {
-			errs = append(errs, Error{
-				Path:     issue.File,
-				Line:     issue.Line,
-				Column:   issue.Column,
-				Severity: issue.Severity.String(),
-				Message:  issue.Message,
-				Code:     issue.Rule,
-			})
+	// With Fix, report how many issues were rewritten alongside any that remain
+	if opts.Fix && len(result.Fixed) > 0 {
+		msg := fmt.Sprintf("fixed %d lint issues", len(result.Fixed))
+		if len(result.Issues) > 0 {
+			res := NewErrorResultMultiple(fmt.Sprintf("%s, %d remaining", msg, len(result.Issues)), lintErrors(result.Issues))
+			res.Data = lintErrors(result.Fixed)
+			return res, nil
 		}
+		return NewResultWithData(msg, lintErrors(result.Fixed)), nil
+	}
 
-		// If Fix mode is enabled, add a note about auto-fixing
-		if opts.Fix {
-			return NewErrorResultMultiple("lint issues found (auto-fix not yet implemented for these issues)", errs), nil
-		}
-		return NewErrorResultMultiple("lint issues found", errs), nil
+	if len(result.Issues) > 0 {
+		return NewErrorResultMultiple("lint issues found", lintErrors(result.Issues)), nil
 	}
 
 	return NewResult("No lint issues found"), nil
 }
 
+// lintErrors converts linter issues to domain.Error format
+func lintErrors(issues []lint.Issue) []Error {
+	errs := make([]Error, 0, len(issues))
+	for _, issue := range issues {
+		errs = append(errs, Error{
+			Path:     issue.File,
+			Line:     issue.Line,
+			Column:   issue.Column,
+			Severity: issue.Severity.String(),
+			Message:  issue.Message,
+			Code:     issue.Rule,
+		})
+	}
+	return errs
+}
+
 // awsInitializer implements domain.Initializer for AWS
 type awsInitializer struct{}
 
@@ -381,6 +647,11 @@ type awsValidator struct{}
 func (v *awsValidator) Validate(ctx *Context, path string, opts ValidateOpts) (*Result, error) {
 	packages := []string{path}
 
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
 	// First build the template
 	result, err := discover.Discover(discover.Options{
 		Packages: packages,
@@ -389,80 +660,64 @@ func (v *awsValidator) Validate(ctx *Context, path string, opts ValidateOpts) (*
 		return nil, fmt.Errorf("discovery failed: %w", err)
 	}
 
-	// Build template
-	builder := template.NewBuilderFull(
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
-
-	// Set VarAttrRefs
-	varAttrRefs := make(map[string]template.VarAttrRefInfo)
-	for name, info := range result.VarAttrRefs {
-		varAttrRefs[name] = template.VarAttrRefInfo{
-			AttrRefs: info.AttrRefs,
-			VarRefs:  info.VarRefs,
+	// Build template; building nested stacks also checks the parameters
+	// passed to the child templates
+	var tmpl *wetwire.Template
+	if build.HasNestedStacks(result) {
+		templates, err := build.Nested(path)
+		if err != nil {
+			return nil, err
 		}
-	}
-	builder.SetVarAttrRefs(varAttrRefs)
-
-	// Extract all values
-	values, err := runner.ExtractAll(
-		packages[0],
-		result.Resources,
-		result.Parameters,
-		result.Outputs,
-		result.Mappings,
-		result.Conditions,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("extracting values: %w", err)
+		tmpl = templates[0].Template
+	} else if tmpl, err = build.Template(packages[0], result); err != nil {
+		return nil, err
 	}
 
-	// Set all extracted values
-	for name, props := range values.Resources {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Parameters {
-		builder.SetValue(name, props)
-	}
-	for name, props := range values.Outputs {
-		builder.SetValue(name, props)
-	}
-	for name, val := range values.Mappings {
-		builder.SetValue(name, val)
-	}
-	for name, val := range values.Conditions {
-		builder.SetValue(name, val)
-	}
-
-	tmpl, err := builder.Build()
-	if err != nil {
-		return nil, fmt.Errorf("building template: %w", err)
+	// Assets are validated as the S3 locations they are packaged to
+	if err := assets.New(build.AssetOptions(cfg)).Package(ctx, "", tmpl, path); err != nil {
+		return nil, err
 	}
 
 	// Validate the template
-	validationResult, err := schema.ValidateTemplate(tmpl, schema.Options{})
+	validationResult, err := schema.ValidateTemplate(tmpl, schema.Options{
+		Strict:    cfg.Validate.Strict,
+		Resources: result.Resources,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
-	// Convert validation errors to domain.Error format
+	// The full result, warnings included, is the data for report formats
+	schemaResult := wetwire.SchemaResult{
+		Success:   validationResult.Valid,
+		Valid:     validationResult.Valid,
+		Errors:    validationResult.Errors,
+		Warnings:  validationResult.Warnings,
+		Resources: len(tmpl.Resources),
+	}
+
+	// Convert validation errors to domain.Error format, pointing at the
+	// Go declaration of the resource when it is known
 	if len(validationResult.Errors) > 0 {
 		errs := make([]Error, 0, len(validationResult.Errors))
 		for _, verr := range validationResult.Errors {
+			path := fmt.Sprintf("%s.%s", verr.Resource, verr.Property)
+			if verr.File != "" {
+				path = verr.File
+			}
 			errs = append(errs, Error{
-				Path:    fmt.Sprintf("%s.%s", verr.Resource, verr.Property),
-				Message: verr.Message,
+				Path:    path,
+				Line:    verr.Line,
+				Message: fmt.Sprintf("%s.%s: %s", verr.Resource, verr.Property, verr.Message),
 				Code:    verr.Resource,
 			})
 		}
-		return NewErrorResultMultiple("validation errors", errs), nil
+		res := NewErrorResultMultiple("validation errors", errs)
+		res.Data = schemaResult
+		return res, nil
 	}
 
-	return NewResult("Validation passed"), nil
+	return NewResultWithData("Validation passed", schemaResult), nil
 }
 
 // awsImporter implements domain.Importer for AWS
@@ -538,7 +793,19 @@ func (l *awsLister) List(ctx *Context, path string, opts ListOpts) (*Result, err
 // awsGrapher implements domain.Grapher for AWS
 type awsGrapher struct{}
 
+// Graph renders the dependency graph of the built template. The dot, mermaid
+// and html formats return the rendered graph; json and yaml return its nodes
+// and edges.
 func (g *awsGrapher) Graph(ctx *Context, path string, opts GraphOpts) (*Result, error) {
+	format := opts.Format
+	switch format {
+	case "":
+		format = string(graph.FormatDOT)
+	case string(graph.FormatDOT), string(graph.FormatMermaid), string(graph.FormatHTML), "json", "yaml":
+	default:
+		return nil, fmt.Errorf("unknown format: %s (valid: dot, mermaid, html, json, yaml)", opts.Format)
+	}
+
 	result, err := discover.Discover(discover.Options{
 		Packages: []string{path},
 	})
@@ -546,21 +813,39 @@ func (g *awsGrapher) Graph(ctx *Context, path string, opts GraphOpts) (*Result,
 		return nil, fmt.Errorf("discovery failed: %w", err)
 	}
 
-	// Generate DOT format graph as string
-	var graph string
-	switch opts.Format {
-	case "dot", "":
-		graph = "digraph G {\n"
-		for name := range result.Resources {
-			graph += fmt.Sprintf("  %s;\n", name)
+	// Check for discovery errors
+	if len(result.Errors) > 0 {
+		errs := make([]Error, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			errs = append(errs, Error{
+				Message: e.Error(),
+			})
 		}
-		// TODO: Add edges based on dependencies
-		graph += "}"
-	case "mermaid":
-		return nil, fmt.Errorf("mermaid format not yet implemented")
-	default:
-		return nil, fmt.Errorf("unknown format: %s", opts.Format)
+		return NewErrorResultMultiple("discovery errors", errs), nil
+	}
+
+	// Build template so every reference kind is visible
+	tmpl, err := build.Template(path, result)
+	if err != nil {
+		return nil, err
+	}
+	deps := graph.New(tmpl, result)
+
+	if format == "json" || format == "yaml" {
+		return NewResultWithData(fmt.Sprintf("Graph generated: %d nodes, %d edges", len(deps.Nodes), len(deps.Edges)), deps), nil
+	}
+
+	gen := &graph.Generator{
+		IncludeParameters: true,
+		IncludeConditions: true,
+		IncludeOutputs:    true,
+		Format:            graph.Format(format),
+		Title:             "wetwire-aws graph " + path,
+	}
+	out, err := gen.GenerateString(deps)
+	if err != nil {
+		return nil, fmt.Errorf("generating graph: %w", err)
 	}
 
-	return NewResultWithData("Graph generated", graph), nil
+	return NewResultWithData("Graph generated", out), nil
 }
@@ -84,6 +84,47 @@ var TestBucket = s3.Bucket{
 	}
 }
 
+func TestAwsLinter_Lint_Config(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	code := `package main
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var TestBucket = s3.Bucket{
+	BucketName: "AWS::Region",
+	Tags:       map[string]any{"Ref": "Other"},
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(code), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "wetwire.yaml"), []byte(`lint:
+  rules:
+    WAW001: false
+  severity:
+    WAW002: info
+`), 0644))
+
+	linter := &awsLinter{}
+	result, err := linter.Lint(&Context{}, tmpDir, LintOpts{})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	foundWAW002 := false
+	for _, e := range result.Errors {
+		assert.NotEqual(t, "WAW001", e.Code, "WAW001 is disabled in wetwire.yaml")
+		if e.Code == "WAW002" {
+			foundWAW002 = true
+			assert.Equal(t, "info", e.Severity)
+		}
+	}
+	assert.True(t, foundWAW002)
+
+	// An invalid config file is reported
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "wetwire.yaml"), []byte("lint:\n  rules:\n    WAW999: false\n"), 0644))
+	_, err = linter.Lint(&Context{}, tmpDir, LintOpts{})
+	assert.ErrorContains(t, err, "WAW999")
+}
+
 func TestAwsLinter_Lint_Fix(t *testing.T) {
 	// Create a temp directory with a Go file that has lint issues
 	tmpDir := t.TempDir()
@@ -102,15 +143,30 @@ var TestBucket = s3.Bucket{
 	linter := &awsLinter{}
 	ctx := &Context{}
 
-	// Test with Fix=true - should include "auto-fix" in message
 	result, err := linter.Lint(ctx, tmpDir, LintOpts{
 		Fix: true,
 	})
 	require.NoError(t, err)
 	require.NotNil(t, result)
 
-	// If there are issues, the message should mention auto-fix
-	if len(result.Errors) > 0 {
-		assert.Contains(t, result.Message, "auto-fix")
-	}
+	assert.True(t, result.Success)
+	assert.Contains(t, result.Message, "fixed 1 lint issues")
+
+	fixed, ok := result.Data.([]Error)
+	require.True(t, ok)
+	require.Len(t, fixed, 1)
+	assert.Equal(t, "WAW001", fixed[0].Code)
+
+	// The file is rewritten to use the pseudo-parameter constant
+	out, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "BucketName: AWS_REGION")
+	assert.Contains(t, string(out), `. "github.com/lex00/wetwire-aws-go/intrinsics"`)
+}
+
+func TestAwsGrapher_Graph_UnknownFormat(t *testing.T) {
+	grapher := &awsGrapher{}
+	_, err := grapher.Graph(&Context{}, t.TempDir(), GraphOpts{Format: "png"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dot, mermaid, html, json, yaml")
 }
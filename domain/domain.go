@@ -83,6 +83,7 @@ var (
 	NewErrorResultMultiple = domain.NewErrorResultMultiple
 	NewContext             = domain.NewContext
 	NewContextWithVerbose  = domain.NewContextWithVerbose
+	FormatResult           = domain.FormatResult
 )
 
 // CreateRootCommand creates a root command with all standard domain commands.
@@ -0,0 +1,103 @@
+package assets
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// modified is the timestamp of every archive entry, the earliest a zip file
+// can record, so archives only change when their contents do.
+var modified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// archive returns the packaged form of the file or directory at path and
+// the extension of its S3 key.
+func archive(path string) ([]byte, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".zip", ".jar":
+			data, err := os.ReadFile(path)
+			return data, ext, err
+		}
+		data, err := zipFiles(filepath.Dir(path), []string{filepath.Base(path)})
+		return data, ".zip", err
+	}
+
+	// WalkDir visits entries in lexical order
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("%s is an empty directory", path)
+	}
+	data, err := zipFiles(path, files)
+	return data, ".zip", err
+}
+
+// zipFiles zips the files, relative to dir, with fixed timestamps. Only the
+// executable bit of the file mode is kept.
+func zipFiles(dir string, files []string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range files {
+		file := filepath.Join(dir, name)
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(name),
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		mode := fs.FileMode(0644)
+		if info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		header.SetMode(mode)
+
+		entry, err := w.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := entry.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,364 @@
+// Package assets packages the wetwire.Asset values of a template, the way
+// `sam package` does but offline: each local file or directory is zipped
+// deterministically, named by the SHA-256 of the archive, written to an
+// assets directory with a manifest, and replaced by its S3 location.
+//
+// The location uses the bucket configured in wetwire.yaml, or else the
+// AssetsBucket parameter and a key parameter per asset whose default is the
+// content-hashed key. Nothing is uploaded unless an Uploader is given.
+//
+// A wetwire.ImageAsset is replaced by an image URI tagged with the hash of
+// its Docker context. Images are only built by an ImageBuilder.
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+const (
+	// Dir is the assets directory, next to the templates a build writes.
+	Dir = "assets"
+	// ManifestFile is the manifest written to the assets directory.
+	ManifestFile = "assets.json"
+	// BucketParameter is the template parameter of the assets bucket when
+	// no bucket is configured.
+	BucketParameter = "AssetsBucket"
+)
+
+// Uploader stores packaged assets in S3. The build command has none; an
+// Uploader is injected by programs that embed the domain.
+type Uploader interface {
+	// Upload stores body under key in bucket.
+	Upload(ctx context.Context, bucket, key string, body io.Reader) error
+}
+
+// Options configures a Packager.
+type Options struct {
+	// Bucket is the S3 bucket of the assets; empty adds BucketParameter
+	Bucket string
+	// Prefix is prepended to every S3 key
+	Prefix string
+	// OutDir receives the archives and the manifest; empty writes nothing
+	OutDir string
+	// Uploader uploads each archive to Bucket; nil uploads nothing
+	Uploader Uploader
+	// Repository is the image repository URI or name of ImageAssets that
+	// name none; empty adds RepositoryParameter
+	Repository string
+	// ImageBuilder builds each image; nil builds nothing
+	ImageBuilder ImageBuilder
+}
+
+// Entry is a packaged asset in the manifest.
+type Entry struct {
+	// Path is the packaged file or directory
+	Path string `json:"path"`
+	// File is the archive in the assets directory
+	File   string `json:"file"`
+	Key    string `json:"key"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+	// UsedBy lists the properties that take the asset
+	UsedBy []Usage `json:"used_by"`
+}
+
+// Usage is a property set to an asset.
+type Usage struct {
+	// Template is the template file of a multi-template build
+	Template string `json:"template,omitempty"`
+	Resource string `json:"resource"`
+	Property string `json:"property"`
+	// Parameter is the key parameter, when no bucket is configured
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// Manifest lists the packaged assets.
+type Manifest struct {
+	Bucket          string       `json:"bucket,omitempty"`
+	BucketParameter string       `json:"bucket_parameter,omitempty"`
+	Assets          []Entry      `json:"assets"`
+	Images          []ImageEntry `json:"images,omitempty"`
+}
+
+// Packager packages the assets of one or more templates, storing each
+// distinct archive once.
+type Packager struct {
+	opts    Options
+	entries []*Entry
+	byHash  map[string]*Entry
+	// images are keyed by tag and repository
+	imageEntries []*ImageEntry
+	images       map[string]*ImageEntry
+}
+
+// New returns a Packager.
+func New(opts Options) *Packager {
+	return &Packager{
+		opts:   opts,
+		byHash: make(map[string]*Entry),
+		images: make(map[string]*ImageEntry),
+	}
+}
+
+// nonAlphanumeric matches the characters a logical ID cannot contain.
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// Package replaces the assets in the resource properties of tmpl with
+// their S3 locations, and the image assets with their URIs. Paths are
+// relative to dir; name is the template's file in a multi-template build,
+// or empty.
+func (p *Packager) Package(ctx context.Context, name string, tmpl *wetwire.Template, dir string) error {
+	for _, id := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[id]
+		if def.Properties == nil {
+			continue
+		}
+		useImage := func(property []string, spec map[string]any) (any, error) {
+			return p.useImage(ctx, name, tmpl, dir, id, property, spec)
+		}
+		props, err := rewriteImages(def.Properties, nil, useImage)
+		if err != nil {
+			return err
+		}
+		use := func(property []string, path string) (any, any, error) {
+			return p.use(ctx, name, tmpl, dir, id, property, path)
+		}
+		if props, err = rewrite(props, nil, use); err != nil {
+			return err
+		}
+		def.Properties = props.(map[string]any)
+		tmpl.Resources[id] = def
+	}
+	return nil
+}
+
+// use packages the asset at path, set on a property of a resource, and
+// returns the bucket and key of its location.
+func (p *Packager) use(ctx context.Context, name string, tmpl *wetwire.Template, dir, id string, property []string, path string) (any, any, error) {
+	field := fmt.Sprintf("Resources.%s.%s", id, strings.Join(property, "."))
+	if path == "" {
+		return nil, nil, fmt.Errorf("%s: asset has no Path", field)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	data, ext, err := archive(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: asset: %w", field, err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	entry, seen := p.byHash[hash]
+	if !seen {
+		entry = &Entry{
+			Path:   displayPath(path),
+			File:   hash + ext,
+			Key:    p.opts.Prefix + hash + ext,
+			SHA256: hash,
+			Size:   len(data),
+		}
+		if err := p.store(ctx, entry, data); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", field, err)
+		}
+		p.byHash[hash] = entry
+		p.entries = append(p.entries, entry)
+	}
+	usage := Usage{Template: name, Resource: id, Property: strings.Join(property, ".")}
+
+	if p.opts.Bucket != "" {
+		entry.UsedBy = append(entry.UsedBy, usage)
+		return p.opts.Bucket, entry.Key, nil
+	}
+
+	// Without a configured bucket, the template takes the location as
+	// parameters; the key defaults to the packaged one
+	usage.Parameter = id + nonAlphanumeric.ReplaceAllString(strings.Join(property, ""), "")
+	if !strings.HasSuffix(usage.Parameter, "S3Key") {
+		usage.Parameter += "S3Key"
+	}
+	for _, param := range []string{BucketParameter, usage.Parameter} {
+		if _, clash := tmpl.Resources[param]; clash {
+			return nil, nil, fmt.Errorf("%s: asset parameter %s has the name of a resource", field, param)
+		}
+	}
+	if _, exists := tmpl.Parameters[usage.Parameter]; exists {
+		return nil, nil, fmt.Errorf("%s: asset parameter %s is already declared", field, usage.Parameter)
+	}
+	if tmpl.Parameters == nil {
+		tmpl.Parameters = make(map[string]wetwire.Parameter)
+	}
+	if _, exists := tmpl.Parameters[BucketParameter]; !exists {
+		tmpl.Parameters[BucketParameter] = wetwire.Parameter{
+			Type:        "String",
+			Description: "S3 bucket of the packaged assets",
+		}
+	}
+	tmpl.Parameters[usage.Parameter] = wetwire.Parameter{
+		Type:        "String",
+		Description: "S3 key of " + entry.Path,
+		Default:     entry.Key,
+	}
+	entry.UsedBy = append(entry.UsedBy, usage)
+	return map[string]any{"Ref": BucketParameter}, map[string]any{"Ref": usage.Parameter}, nil
+}
+
+// store writes an archive to the assets directory and uploads it.
+func (p *Packager) store(ctx context.Context, entry *Entry, data []byte) error {
+	if p.opts.OutDir != "" {
+		if err := os.MkdirAll(p.opts.OutDir, 0755); err != nil {
+			return fmt.Errorf("creating %s: %w", p.opts.OutDir, err)
+		}
+		file := filepath.Join(p.opts.OutDir, entry.File)
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", file, err)
+		}
+	}
+	if p.opts.Uploader != nil {
+		if p.opts.Bucket == "" {
+			return fmt.Errorf("uploading %s needs build.assets.bucket in wetwire.yaml", entry.Path)
+		}
+		if err := p.opts.Uploader.Upload(ctx, p.opts.Bucket, entry.Key, bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("uploading %s to s3://%s/%s: %w", entry.Path, p.opts.Bucket, entry.Key, err)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of distinct assets and images packaged.
+func (p *Packager) Len() int {
+	return len(p.entries) + len(p.imageEntries)
+}
+
+// Manifest returns the packaged assets and images in the order they were
+// found.
+func (p *Packager) Manifest() Manifest {
+	manifest := Manifest{Bucket: p.opts.Bucket, Assets: make([]Entry, 0, len(p.entries))}
+	if p.opts.Bucket == "" && len(p.entries) > 0 {
+		manifest.BucketParameter = BucketParameter
+	}
+	for _, entry := range p.entries {
+		manifest.Assets = append(manifest.Assets, *entry)
+	}
+	for _, entry := range p.imageEntries {
+		manifest.Images = append(manifest.Images, *entry)
+	}
+	return manifest
+}
+
+// WriteManifest writes the manifest to the assets directory, if there is
+// one and any asset was packaged.
+func (p *Packager) WriteManifest() error {
+	if p.opts.OutDir == "" || p.Len() == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.Manifest(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializing asset manifest: %w", err)
+	}
+	file := filepath.Join(p.opts.OutDir, ManifestFile)
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", file, err)
+	}
+	return nil
+}
+
+// rewrite replaces the asset markers in a value. A marker in an S3Key field
+// sets the key and, unless it is set, the S3Bucket next to it; any other
+// marker becomes a {"Bucket", "Key"} location.
+func rewrite(value any, property []string, use func(property []string, path string) (any, any, error)) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if path, ok := assetPath(v); ok {
+			bucket, key, err := use(property, path)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"Bucket": bucket, "Key": key}, nil
+		}
+		result := make(map[string]any, len(v))
+		for _, name := range sortedKeys(v) {
+			child := append(append([]string(nil), property...), name)
+			if path, ok := assetPath(v[name]); ok && name == "S3Key" {
+				bucket, key, err := use(child, path)
+				if err != nil {
+					return nil, err
+				}
+				result[name] = key
+				if v["S3Bucket"] == nil {
+					result["S3Bucket"] = bucket
+				}
+				continue
+			}
+			rewritten, err := rewrite(v[name], child, use)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = rewritten
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			rewritten, err := rewrite(item, append(append([]string(nil), property...), fmt.Sprint(i)), use)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rewritten
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// assetPath returns the path of an asset marker.
+func assetPath(value any) (string, bool) {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	path, ok := m[wetwire.AssetMarker].(string)
+	return path, ok
+}
+
+// displayPath returns path relative to the working directory when it is
+// inside it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+	return filepath.ToSlash(rel)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,214 @@
+package assets
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func marker(path string) map[string]any {
+	return map[string]any{wetwire.AssetMarker: path}
+}
+
+// fakeUploader records the uploaded objects.
+type fakeUploader struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, bucket, key string, body io.Reader) error {
+	if u.err != nil {
+		return u.err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if u.objects == nil {
+		u.objects = make(map[string][]byte)
+	}
+	u.objects["s3://"+bucket+"/"+key] = data
+	return nil
+}
+
+func newTemplate() *wetwire.Template {
+	return &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Handler": {Type: "AWS::Lambda::Function", Properties: map[string]any{
+				"Code":    map[string]any{"S3Key": marker("./handler")},
+				"Runtime": "python3.12",
+			}},
+			"Api": {Type: "AWS::Serverless::Function", Properties: map[string]any{
+				"CodeUri": marker("./handler"),
+			}},
+			"Layer": {Type: "AWS::Lambda::LayerVersion", Properties: map[string]any{
+				"Content": map[string]any{"S3Bucket": "shared-layers", "S3Key": marker("layer.zip")},
+			}},
+		},
+	}
+}
+
+func TestPackage_Parameters(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "handler", "index.py"), "def handler(event, context): pass\n")
+	writeFile(t, filepath.Join(dir, "layer.zip"), "not really a zip")
+
+	out := filepath.Join(t.TempDir(), "assets")
+	p := New(Options{OutDir: out})
+	tmpl := newTemplate()
+	require.NoError(t, p.Package(context.Background(), "", tmpl, dir))
+	require.NoError(t, p.WriteManifest())
+
+	// The same directory is packaged once
+	require.Equal(t, 2, p.Len())
+	manifest := p.Manifest()
+	assert.Equal(t, BucketParameter, manifest.BucketParameter)
+	handler, layer := manifest.Assets[0], manifest.Assets[1]
+	assert.Equal(t, handler.SHA256+".zip", handler.File)
+	assert.Equal(t, handler.File, handler.Key)
+	assert.Equal(t, []Usage{
+		{Resource: "Api", Property: "CodeUri", Parameter: "ApiCodeUriS3Key"},
+		{Resource: "Handler", Property: "Code.S3Key", Parameter: "HandlerCodeS3Key"},
+	}, handler.UsedBy)
+
+	// A .zip file is stored as-is
+	assert.Equal(t, len("not really a zip"), layer.Size)
+	data, err := os.ReadFile(filepath.Join(out, layer.File))
+	require.NoError(t, err)
+	assert.Equal(t, "not really a zip", string(data))
+
+	assert.Equal(t, map[string]any{
+		"S3Bucket": map[string]any{"Ref": BucketParameter},
+		"S3Key":    map[string]any{"Ref": "HandlerCodeS3Key"},
+	}, tmpl.Resources["Handler"].Properties["Code"])
+	assert.Equal(t, map[string]any{
+		"Bucket": map[string]any{"Ref": BucketParameter},
+		"Key":    map[string]any{"Ref": "ApiCodeUriS3Key"},
+	}, tmpl.Resources["Api"].Properties["CodeUri"])
+	assert.Equal(t, map[string]any{
+		"S3Bucket": "shared-layers",
+		"S3Key":    map[string]any{"Ref": "LayerContentS3Key"},
+	}, tmpl.Resources["Layer"].Properties["Content"])
+	assert.Equal(t, "python3.12", tmpl.Resources["Handler"].Properties["Runtime"])
+
+	assert.Equal(t, wetwire.Parameter{Type: "String", Description: "S3 bucket of the packaged assets"}, tmpl.Parameters[BucketParameter])
+	assert.Equal(t, handler.Key, tmpl.Parameters["HandlerCodeS3Key"].Default)
+	assert.Equal(t, layer.Key, tmpl.Parameters["LayerContentS3Key"].Default)
+
+	var written Manifest
+	data, err = os.ReadFile(filepath.Join(out, ManifestFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, manifest, written)
+}
+
+func TestPackage_Bucket(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "handler", "index.py"), "def handler(event, context): pass\n")
+	writeFile(t, filepath.Join(dir, "layer.zip"), "PK")
+
+	uploader := &fakeUploader{}
+	p := New(Options{Bucket: "my-artifacts", Prefix: "app/", Uploader: uploader})
+	tmpl := newTemplate()
+	require.NoError(t, p.Package(context.Background(), "app.json", tmpl, dir))
+
+	handler := p.Manifest().Assets[0]
+	assert.Equal(t, "app/"+handler.File, handler.Key)
+	assert.Equal(t, "app.json", handler.UsedBy[0].Template)
+	assert.Equal(t, map[string]any{"S3Bucket": "my-artifacts", "S3Key": handler.Key}, tmpl.Resources["Handler"].Properties["Code"])
+	assert.Equal(t, map[string]any{"Bucket": "my-artifacts", "Key": handler.Key}, tmpl.Resources["Api"].Properties["CodeUri"])
+	assert.Nil(t, tmpl.Parameters)
+
+	// Each archive is uploaded once
+	assert.Len(t, uploader.objects, 2)
+	assert.Equal(t, []byte("PK"), uploader.objects["s3://my-artifacts/"+p.Manifest().Assets[1].Key])
+}
+
+func TestPackage_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "handler", "index.py"), "pass\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0755))
+
+	tests := []struct {
+		name string
+		path string
+		opts Options
+		want string
+	}{
+		{name: "missing", path: "./missing", want: "Resources.Handler.Code.S3Key: asset: stat"},
+		{name: "empty directory", path: "./empty", want: "is an empty directory"},
+		{name: "no path", path: "", want: "Resources.Handler.Code.S3Key: asset has no Path"},
+		{name: "upload without bucket", path: "./handler", opts: Options{Uploader: &fakeUploader{}}, want: "needs build.assets.bucket in wetwire.yaml"},
+		{name: "upload failure", path: "./handler", opts: Options{Bucket: "b", Uploader: &fakeUploader{err: errors.New("denied")}}, want: "to s3://b/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+				"Handler": {Type: "AWS::Lambda::Function", Properties: map[string]any{
+					"Code": map[string]any{"S3Key": marker(tt.path)},
+				}},
+			}}
+			err := New(tt.opts).Package(context.Background(), "", tmpl, dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestArchive_Deterministic(t *testing.T) {
+	build := func(mtime time.Time) []byte {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "b.py"), "b\n")
+		writeFile(t, filepath.Join(dir, "lib", "a.py"), "a\n")
+		require.NoError(t, os.Chmod(filepath.Join(dir, "b.py"), 0700))
+		for _, file := range []string{"b.py", "lib/a.py"} {
+			require.NoError(t, os.Chtimes(filepath.Join(dir, file), mtime, mtime))
+		}
+		data, ext, err := archive(dir)
+		require.NoError(t, err)
+		assert.Equal(t, ".zip", ext)
+		return data
+	}
+
+	first := build(time.Now())
+	assert.Equal(t, first, build(time.Now().Add(-time.Hour)))
+
+	r, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	require.NoError(t, err)
+	require.Len(t, r.File, 2)
+	assert.Equal(t, "b.py", r.File[0].Name)
+	assert.Equal(t, os.FileMode(0755), r.File[0].Mode())
+	assert.Equal(t, "lib/a.py", r.File[1].Name)
+	assert.Equal(t, os.FileMode(0644), r.File[1].Mode())
+}
+
+func TestArchive_File(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "index.js"), "exports.handler = async () => {}\n")
+
+	data, ext, err := archive(filepath.Join(dir, "index.js"))
+	require.NoError(t, err)
+	assert.Equal(t, ".zip", ext)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, r.File, 1)
+	assert.Equal(t, "index.js", r.File[0].Name)
+}
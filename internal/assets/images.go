@@ -0,0 +1,354 @@
+package assets
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// RepositoryParameter is the template parameter of the image repository URI
+// when neither the asset nor wetwire.yaml names a repository.
+const RepositoryParameter = "AssetsRepositoryUri"
+
+// ImageBuilder builds and pushes the images of ImageAssets. The build
+// command has none; an ImageBuilder is injected by programs that embed the
+// domain, and tests use a fake.
+type ImageBuilder interface {
+	// Build builds the image and pushes it to the repository with its tag.
+	Build(ctx context.Context, image Image) error
+}
+
+// Image is a container image to build.
+type Image struct {
+	// Context is the absolute path of the build context
+	Context string
+	// Dockerfile is relative to Context
+	Dockerfile string
+	BuildArgs  map[string]string
+	Platform   string
+	// Tag is the content hash of the image inputs
+	Tag string
+	// Repository is the repository URI or name, or the template expression
+	// of a repository resource or parameter
+	Repository any
+}
+
+// ImageEntry is a container image in the manifest.
+type ImageEntry struct {
+	// Context is the build context
+	Context    string            `json:"context"`
+	Dockerfile string            `json:"dockerfile"`
+	BuildArgs  map[string]string `json:"build_args,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	Tag        string            `json:"tag"`
+	// Repository is the repository as written, and Image the URI in the
+	// template; both may be intrinsic functions
+	Repository any     `json:"repository"`
+	Image      any     `json:"image"`
+	UsedBy     []Usage `json:"used_by"`
+}
+
+// useImage hashes the image of an ImageAsset marker, set on a property of
+// a resource, and returns its URI.
+func (p *Packager) useImage(ctx context.Context, name string, tmpl *wetwire.Template, dir, id string, property []string, spec map[string]any) (any, error) {
+	field := fmt.Sprintf("Resources.%s.%s", id, strings.Join(property, "."))
+	image, err := imageOf(spec, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: image asset: %w", field, err)
+	}
+	if image.Repository == nil && p.opts.Repository != "" {
+		image.Repository = p.opts.Repository
+	}
+	if image.Tag, err = imageTag(image); err != nil {
+		return nil, fmt.Errorf("%s: image asset: %w", field, err)
+	}
+
+	if image.Repository == nil {
+		if _, clash := tmpl.Resources[RepositoryParameter]; clash {
+			return nil, fmt.Errorf("%s: image parameter %s has the name of a resource", field, RepositoryParameter)
+		}
+		if tmpl.Parameters == nil {
+			tmpl.Parameters = make(map[string]wetwire.Parameter)
+		}
+		if _, exists := tmpl.Parameters[RepositoryParameter]; !exists {
+			tmpl.Parameters[RepositoryParameter] = wetwire.Parameter{
+				Type:        "String",
+				Description: "ECR repository URI of the container image assets",
+			}
+		}
+		image.Repository = map[string]any{"Ref": RepositoryParameter}
+	}
+	uri, err := imageURI(tmpl, image.Repository, image.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	usage := Usage{Template: name, Resource: id, Property: strings.Join(property, ".")}
+
+	// Images with the same inputs and repository are built once
+	repoKey, _ := json.Marshal(image.Repository)
+	key := image.Tag + " " + string(repoKey)
+	if entry, seen := p.images[key]; seen {
+		entry.UsedBy = append(entry.UsedBy, usage)
+		return uri, nil
+	}
+
+	if p.opts.ImageBuilder != nil {
+		if err := p.opts.ImageBuilder.Build(ctx, image); err != nil {
+			return nil, fmt.Errorf("%s: building image %s: %w", field, displayPath(image.Context), err)
+		}
+	}
+	entry := &ImageEntry{
+		Context:    displayPath(image.Context),
+		Dockerfile: image.Dockerfile,
+		BuildArgs:  image.BuildArgs,
+		Platform:   image.Platform,
+		Tag:        image.Tag,
+		Repository: image.Repository,
+		Image:      uri,
+		UsedBy:     []Usage{usage},
+	}
+	p.images[key] = entry
+	p.imageEntries = append(p.imageEntries, entry)
+	return uri, nil
+}
+
+// imageOf reads the Image of an ImageAsset marker.
+func imageOf(spec map[string]any, dir string) (Image, error) {
+	image := Image{Dockerfile: "Dockerfile"}
+	contextDir, _ := spec["Context"].(string)
+	if contextDir == "" {
+		return image, fmt.Errorf("no Context")
+	}
+	if !filepath.IsAbs(contextDir) {
+		contextDir = filepath.Join(dir, contextDir)
+	}
+	abs, err := filepath.Abs(contextDir)
+	if err != nil {
+		return image, err
+	}
+	image.Context = abs
+	if dockerfile, ok := spec["Dockerfile"].(string); ok && dockerfile != "" {
+		image.Dockerfile = filepath.ToSlash(dockerfile)
+	}
+	if args, ok := spec["BuildArgs"].(map[string]any); ok {
+		image.BuildArgs = make(map[string]string, len(args))
+		for k, v := range args {
+			s, ok := v.(string)
+			if !ok {
+				return image, fmt.Errorf("BuildArgs.%s must be a string", k)
+			}
+			image.BuildArgs[k] = s
+		}
+	}
+	image.Platform, _ = spec["Platform"].(string)
+	image.Repository = spec["Repository"]
+	return image, nil
+}
+
+// imageURI returns the URI of a tagged image in a repository. A template
+// repository resource gives its RepositoryUri; a name without a registry is
+// in the stack's account and region.
+func imageURI(tmpl *wetwire.Template, repository any, tag string) (any, error) {
+	switch repo := repository.(type) {
+	case string:
+		if strings.Contains(repo, "/") {
+			return repo + ":" + tag, nil
+		}
+		return map[string]any{
+			"Fn::Sub": "${AWS::AccountId}.dkr.ecr.${AWS::Region}.${AWS::URLSuffix}/" + repo + ":" + tag,
+		}, nil
+	case map[string]any:
+		if ref, ok := repo["Ref"].(string); ok && len(repo) == 1 {
+			if def, isResource := tmpl.Resources[ref]; isResource {
+				if def.Type != "AWS::ECR::Repository" {
+					return nil, fmt.Errorf("image repository %s is a %s, not an AWS::ECR::Repository", ref, def.Type)
+				}
+				repository = map[string]any{"Fn::GetAtt": []string{ref, "RepositoryUri"}}
+			}
+		}
+		return map[string]any{"Fn::Join": []any{":", []any{repository, tag}}}, nil
+	default:
+		return nil, fmt.Errorf("image repository must be a repository resource, URI, name or intrinsic, got %T", repository)
+	}
+}
+
+// imageTag hashes the inputs of an image build: the Dockerfile, build
+// arguments and platform, and the path, executable bit and contents of each
+// file of the context that .dockerignore does not exclude.
+func imageTag(image Image) (string, error) {
+	info, err := os.Stat(image.Context)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", image.Context)
+	}
+	if _, err := os.Stat(filepath.Join(image.Context, filepath.FromSlash(image.Dockerfile))); err != nil {
+		return "", err
+	}
+	ignore, err := readDockerignore(image.Context)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "dockerfile %s\nplatform %s\n", image.Dockerfile, image.Platform)
+	for _, k := range sortedKeys(image.BuildArgs) {
+		fmt.Fprintf(h, "arg %s=%s\n", k, image.BuildArgs[k])
+	}
+
+	// WalkDir visits entries in lexical order
+	err = filepath.WalkDir(image.Context, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(image.Context, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if entry.IsDir() {
+			return nil
+		}
+		if ignore.excludes(rel) && rel != image.Dockerfile && rel != ".dockerignore" {
+			return nil
+		}
+		info, err := os.Stat(file)
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "file %s %t %d\n", rel, info.Mode()&0111 != 0, info.Size())
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dockerignore holds the patterns of a .dockerignore file. The last
+// matching pattern wins; a "!" pattern re-includes what an earlier one
+// excluded.
+type dockerignore []string
+
+// readDockerignore reads the .dockerignore of a context, if it has one.
+func readDockerignore(contextDir string) (dockerignore, error) {
+	f, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns dockerignore
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// excludes reports whether the file at rel, a slash-separated path, is
+// excluded from the context.
+func (d dockerignore) excludes(rel string) bool {
+	excluded := false
+	for _, pattern := range d {
+		include := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+		if matchesPath(pattern, rel) {
+			excluded = !include
+		}
+	}
+	return excluded
+}
+
+// matchesPath reports whether pattern matches rel or one of its parent
+// directories. A "**" segment matches any number of directories.
+func matchesPath(pattern, rel string) bool {
+	segments := strings.Split(rel, "/")
+	for i := len(segments); i > 0; i-- {
+		if matchSegments(strings.Split(pattern, "/"), segments[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// rewriteImages replaces the ImageAsset markers in a value with the URIs
+// returned by use.
+func rewriteImages(value any, property []string, use func(property []string, spec map[string]any) (any, error)) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			if spec, ok := v[wetwire.ImageAssetMarker].(map[string]any); ok {
+				return use(property, spec)
+			}
+		}
+		result := make(map[string]any, len(v))
+		for _, name := range sortedKeys(v) {
+			rewritten, err := rewriteImages(v[name], append(append([]string(nil), property...), name), use)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = rewritten
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			rewritten, err := rewriteImages(item, append(append([]string(nil), property...), fmt.Sprint(i)), use)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rewritten
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
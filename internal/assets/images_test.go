@@ -0,0 +1,194 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// fakeImageBuilder records the images it is asked to build.
+type fakeImageBuilder struct {
+	images []Image
+	err    error
+}
+
+func (b *fakeImageBuilder) Build(ctx context.Context, image Image) error {
+	b.images = append(b.images, image)
+	return b.err
+}
+
+func imageMarker(spec map[string]any) map[string]any {
+	return map[string]any{wetwire.ImageAssetMarker: spec}
+}
+
+func writeContext(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app", "Dockerfile"), "FROM scratch\nCOPY main /\n")
+	writeFile(t, filepath.Join(dir, "app", "main"), "binary")
+	writeFile(t, filepath.Join(dir, "app", ".dockerignore"), "# local files\n*.log\ntmp\n!tmp/keep\n")
+	writeFile(t, filepath.Join(dir, "app", "debug.log"), "noise")
+	writeFile(t, filepath.Join(dir, "app", "tmp", "scratch"), "noise")
+	writeFile(t, filepath.Join(dir, "app", "tmp", "keep"), "kept")
+	return dir
+}
+
+func TestPackage_Images(t *testing.T) {
+	dir := writeContext(t)
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Repo": {Type: "AWS::ECR::Repository"},
+			"Task": {Type: "AWS::ECS::TaskDefinition", Properties: map[string]any{
+				"ContainerDefinitions": []any{
+					map[string]any{"Name": "app", "Image": imageMarker(map[string]any{"Context": "./app", "Repository": map[string]any{"Ref": "Repo"}})},
+				},
+			}},
+			"Worker": {Type: "AWS::Lambda::Function", Properties: map[string]any{
+				"Code": map[string]any{"ImageUri": imageMarker(map[string]any{
+					"Context":    "./app",
+					"Repository": "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker",
+					"Platform":   "linux/arm64",
+				})},
+			}},
+			"Batch": {Type: "AWS::Batch::JobDefinition", Properties: map[string]any{
+				"ContainerProperties": map[string]any{"Image": imageMarker(map[string]any{"Context": "./app"})},
+			}},
+		},
+	}
+
+	builder := &fakeImageBuilder{}
+	p := New(Options{ImageBuilder: builder})
+	require.NoError(t, p.Package(context.Background(), "", tmpl, dir))
+
+	manifest := p.Manifest()
+	require.Len(t, manifest.Images, 3)
+	assert.Empty(t, manifest.BucketParameter)
+	batch, task, worker := manifest.Images[0], manifest.Images[1], manifest.Images[2]
+	assert.Equal(t, batch.Tag, task.Tag)
+	assert.NotEqual(t, task.Tag, worker.Tag, "the platform is part of the tag")
+	assert.Len(t, task.Tag, 64)
+
+	assert.Equal(t, map[string]any{"Fn::Join": []any{":", []any{
+		map[string]any{"Fn::GetAtt": []string{"Repo", "RepositoryUri"}}, task.Tag,
+	}}}, tmpl.Resources["Task"].Properties["ContainerDefinitions"].([]any)[0].(map[string]any)["Image"])
+	assert.Equal(t, []Usage{{Resource: "Task", Property: "ContainerDefinitions.0.Image"}}, task.UsedBy)
+	assert.Equal(t, map[string]any{"Ref": "Repo"}, task.Repository)
+
+	assert.Equal(t, "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:"+worker.Tag, tmpl.Resources["Worker"].Properties["Code"].(map[string]any)["ImageUri"])
+	assert.Equal(t, "linux/arm64", worker.Platform)
+
+	// Without a repository, the template takes it as a parameter
+	assert.Equal(t, map[string]any{"Ref": RepositoryParameter}, batch.Repository)
+	assert.Equal(t, "String", tmpl.Parameters[RepositoryParameter].Type)
+
+	require.Len(t, builder.images, 3)
+	assert.Equal(t, Image{
+		Context:    filepath.Join(dir, "app"),
+		Dockerfile: "Dockerfile",
+		Tag:        task.Tag,
+		Repository: map[string]any{"Ref": "Repo"},
+	}, builder.images[1])
+}
+
+func TestPackage_ImageRepositoryOption(t *testing.T) {
+	dir := writeContext(t)
+	tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Task": {Type: "AWS::ECS::TaskDefinition", Properties: map[string]any{
+			"Image": imageMarker(map[string]any{"Context": "./app"}),
+		}},
+		"Other": {Type: "AWS::ECS::TaskDefinition", Properties: map[string]any{
+			"Image": imageMarker(map[string]any{"Context": "./app"}),
+		}},
+	}}
+
+	builder := &fakeImageBuilder{}
+	p := New(Options{Repository: "app", ImageBuilder: builder})
+	require.NoError(t, p.Package(context.Background(), "", tmpl, dir))
+
+	// The same image is built once
+	require.Len(t, builder.images, 1)
+	entry := p.Manifest().Images[0]
+	assert.Len(t, entry.UsedBy, 2)
+	assert.Equal(t, map[string]any{
+		"Fn::Sub": "${AWS::AccountId}.dkr.ecr.${AWS::Region}.${AWS::URLSuffix}/app:" + entry.Tag,
+	}, tmpl.Resources["Task"].Properties["Image"])
+	assert.Nil(t, tmpl.Parameters)
+}
+
+func TestPackage_ImageErrors(t *testing.T) {
+	dir := writeContext(t)
+	tests := []struct {
+		name string
+		spec map[string]any
+		opts Options
+		want string
+	}{
+		{name: "no context", spec: map[string]any{}, want: "Resources.Task.Image: image asset: no Context"},
+		{name: "missing Dockerfile", spec: map[string]any{"Context": "./app", "Dockerfile": "Dockerfile.prod"}, want: "Dockerfile.prod"},
+		{name: "not a repository", spec: map[string]any{"Context": "./app", "Repository": map[string]any{"Ref": "Bucket"}}, want: "image repository Bucket is a AWS::S3::Bucket, not an AWS::ECR::Repository"},
+		{name: "build failure", spec: map[string]any{"Context": "./app", "Repository": "app"}, opts: Options{ImageBuilder: &fakeImageBuilder{err: errors.New("daemon not running")}}, want: "daemon not running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+				"Bucket": {Type: "AWS::S3::Bucket"},
+				"Task":   {Type: "AWS::ECS::TaskDefinition", Properties: map[string]any{"Image": imageMarker(tt.spec)}},
+			}}
+			err := New(tt.opts).Package(context.Background(), "", tmpl, dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	dir := writeContext(t)
+	image := Image{Context: filepath.Join(dir, "app"), Dockerfile: "Dockerfile"}
+	tag, err := imageTag(image)
+	require.NoError(t, err)
+
+	// Timestamps and ignored files do not change the tag
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "app", "main"), past, past))
+	writeFile(t, filepath.Join(dir, "app", "debug.log"), "more noise")
+	writeFile(t, filepath.Join(dir, "app", "tmp", "scratch"), "more noise")
+	same, err := imageTag(image)
+	require.NoError(t, err)
+	assert.Equal(t, tag, same)
+
+	// Contents, re-included files and build arguments do
+	writeFile(t, filepath.Join(dir, "app", "tmp", "keep"), "changed")
+	changed, err := imageTag(image)
+	require.NoError(t, err)
+	assert.NotEqual(t, tag, changed)
+
+	image.BuildArgs = map[string]string{"VERSION": "2"}
+	withArgs, err := imageTag(image)
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, withArgs)
+}
+
+func TestDockerignore(t *testing.T) {
+	ignore := dockerignore{"*.log", "node_modules", "**/*.tmp", "docs/", "!docs/README.md"}
+
+	tests := map[string]bool{
+		"debug.log":               true,
+		"logs/debug.log":          false,
+		"node_modules/x/index.js": true,
+		"a/b/c.tmp":               true,
+		"docs/guide.md":           true,
+		"docs/README.md":          false,
+		"main.go":                 false,
+	}
+	for rel, want := range tests {
+		assert.Equal(t, want, ignore.excludes(rel), rel)
+	}
+}
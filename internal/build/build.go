@@ -0,0 +1,148 @@
+// Package build assembles the CloudFormation template of a Go package: it
+// extracts the values of the discovered declarations by compiling and running
+// the package, then hands them to the template builder.
+package build
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/assets"
+	"github.com/lex00/wetwire-aws-go/internal/config"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/runner"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// Template builds the template for the package at pkgPath from its
+// discovery result.
+func Template(pkgPath string, result *discover.Result) (*wetwire.Template, error) {
+	return buildTemplate(pkgPath, result, nil)
+}
+
+// buildTemplate builds the template for the package at pkgPath. References
+// to the external variables are kept as Refs to their qualified names, for
+// the stack linker to rewire.
+func buildTemplate(pkgPath string, result *discover.Result, external []runner.ExternalVar) (*wetwire.Template, error) {
+	builder := template.NewBuilderFull(
+		result.Resources,
+		result.Parameters,
+		result.Outputs,
+		result.Mappings,
+		result.Conditions,
+	)
+
+	// Set VarAttrRefs for recursive AttrRef resolution
+	varAttrRefs := make(map[string]template.VarAttrRefInfo)
+	for name, info := range result.VarAttrRefs {
+		varAttrRefs[name] = template.VarAttrRefInfo{
+			AttrRefs: info.AttrRefs,
+			VarRefs:  info.VarRefs,
+		}
+	}
+	builder.SetVarAttrRefs(varAttrRefs)
+	builder.SetAttributes(result.Attributes)
+	builder.SetLoops(result.Loops)
+
+	// Extract all values
+	values, err := runner.ExtractAll(
+		pkgPath,
+		result.Resources,
+		result.Parameters,
+		result.Outputs,
+		result.Mappings,
+		result.Conditions,
+		result.Attributes,
+		result.Loops,
+		external,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("extracting values: %w", err)
+	}
+
+	// Set all extracted values
+	for name, props := range values.Resources {
+		builder.SetValue(name, props)
+	}
+	for name, props := range values.Parameters {
+		builder.SetValue(name, props)
+	}
+	for name, props := range values.Outputs {
+		builder.SetValue(name, props)
+	}
+	for name, val := range values.Mappings {
+		builder.SetValue(name, val)
+	}
+	for name, val := range values.Conditions {
+		builder.SetValue(name, val)
+	}
+	for name, props := range values.Attributes {
+		builder.SetValue(name, props)
+	}
+	for name, val := range values.Loops {
+		builder.SetValue(name, val)
+	}
+
+	tmpl, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("building template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Package discovers and builds the template for the package at pkgPath and
+// applies its wetwire.yaml build settings, as the build command does. Its
+// assets are given their S3 locations without writing the archives.
+func Package(pkgPath string) (*wetwire.Template, error) {
+	cfg, err := config.Load(pkgPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
+	result, err := discover.Discover(discover.Options{
+		Packages: []string{pkgPath},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("discovery errors: %w", errors.Join(result.Errors...))
+	}
+
+	tmpl, err := Template(pkgPath, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := Configure(tmpl, cfg); err != nil {
+		return nil, err
+	}
+	if err := assets.New(AssetOptions(cfg)).Package(context.Background(), "", tmpl, pkgPath); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// AssetOptions returns the asset bucket, key prefix and image repository of
+// the wetwire.yaml build settings. Callers that write, upload or build the
+// assets set OutDir, Uploader and ImageBuilder.
+func AssetOptions(cfg *config.Config) assets.Options {
+	return assets.Options{
+		Bucket:     cfg.Build.Assets.Bucket,
+		Prefix:     cfg.Build.Assets.Prefix,
+		Repository: cfg.Build.Assets.Repository,
+	}
+}
+
+// Configure applies the build section of cfg to tmpl: the description and
+// the logical-ID aliases.
+func Configure(tmpl *wetwire.Template, cfg *config.Config) error {
+	if cfg.Build.Description != "" {
+		tmpl.Description = cfg.Build.Description
+	}
+	if err := template.ApplyAliases(tmpl, cfg.Build.Aliases); err != nil {
+		return fmt.Errorf("build.aliases: %w", err)
+	}
+	return nil
+}
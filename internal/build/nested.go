@@ -0,0 +1,356 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/config"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/runner"
+)
+
+// ManifestFile is the name of the manifest written next to the templates of
+// a build with nested stacks.
+const ManifestFile = "manifest.json"
+
+// stackType is the resource type of a nested stack.
+const stackType = "AWS::CloudFormation::Stack"
+
+// NestedTemplate is a template of a build with nested stacks: that of the
+// root package, or that of a child package named by the TemplateURL of an
+// AWS::CloudFormation::Stack resource.
+type NestedTemplate struct {
+	// Path is the template file, relative to the build output directory
+	Path string
+	// Package is the import path of the package
+	Package string
+	// Dir is the package directory
+	Dir string
+	// Template is the package's template
+	Template *wetwire.Template
+	// NestedIn lists the stack resources that create the template
+	NestedIn []NestedStackRef
+}
+
+// NestedStackRef is an AWS::CloudFormation::Stack resource of a template.
+type NestedStackRef struct {
+	Template string `json:"template"`
+	Resource string `json:"resource"`
+}
+
+// Manifest describes the templates of a build with nested stacks.
+type Manifest struct {
+	// Root is the path of the root template
+	Root      string          `json:"root"`
+	Templates []ManifestEntry `json:"templates"`
+}
+
+// ManifestEntry is a template of a Manifest. Template is only set when the
+// templates are returned rather than written.
+type ManifestEntry struct {
+	Path     string            `json:"path"`
+	Package  string            `json:"package"`
+	NestedIn []NestedStackRef  `json:"nested_in,omitempty"`
+	Template *wetwire.Template `json:"template,omitempty"`
+}
+
+// NewManifest returns the manifest of the templates of Nested.
+func NewManifest(templates []*NestedTemplate) Manifest {
+	m := Manifest{Templates: make([]ManifestEntry, 0, len(templates))}
+	for _, t := range templates {
+		if m.Root == "" {
+			m.Root = t.Path
+		}
+		m.Templates = append(m.Templates, ManifestEntry{
+			Path:     t.Path,
+			Package:  t.Package,
+			NestedIn: t.NestedIn,
+		})
+	}
+	return m
+}
+
+// HasNestedStacks reports whether a discovery result declares
+// AWS::CloudFormation::Stack resources, which may nest the stacks of child
+// packages.
+func HasNestedStacks(result *discover.Result) bool {
+	for _, res := range result.Resources {
+		if res.Type == "cloudformation.Stack" {
+			return true
+		}
+	}
+	return false
+}
+
+// nestedBuilder builds a package and the child packages of its nested
+// stacks.
+type nestedBuilder struct {
+	byDir     map[string]*NestedTemplate
+	byPath    map[string]*NestedTemplate
+	templates []*NestedTemplate
+}
+
+// Nested builds the package at pkgPath and, recursively, the child package
+// of each AWS::CloudFormation::Stack resource whose TemplateURL is the
+// relative path of a package directory, such as "./data". It returns the
+// root template first.
+//
+// Each template is written as <package name>.json and the TemplateURL of
+// the stack resources is set to that path. A child output referenced from
+// the parent, data.TableName, becomes Fn::GetAtt [Stack, Outputs.TableName],
+// and the Parameters of a stack resource are checked against the child's
+// parameters.
+func Nested(pkgPath string) ([]*NestedTemplate, error) {
+	b := &nestedBuilder{
+		byDir:  make(map[string]*NestedTemplate),
+		byPath: make(map[string]*NestedTemplate),
+	}
+	if _, err := b.build(pkgPath, nil); err != nil {
+		return nil, err
+	}
+	return b.templates, nil
+}
+
+// build builds the package in dir and its children. parents are the
+// directories of the packages being built that nest it.
+func (b *nestedBuilder) build(dir string, parents []string) (*NestedTemplate, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	for _, parent := range parents {
+		if parent == absDir {
+			return nil, fmt.Errorf("nested stacks form a cycle at %s", dir)
+		}
+	}
+	if t, ok := b.byDir[absDir]; ok {
+		return t, nil
+	}
+
+	pkg, err := loadStackPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkg.result.Errors) > 0 {
+		return nil, fmt.Errorf("discovery errors: %w", errors.Join(pkg.result.Errors...))
+	}
+	if pkg.stack.Package == "" {
+		if pkg.stack.Package, err = runner.ImportPath(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpl, err := buildTemplate(dir, pkg.result, childOutputs(dir, pkg.imports))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pkg.stack.Package, err)
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if err := Configure(tmpl, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", pkg.stack.Package, err)
+	}
+
+	t := &NestedTemplate{
+		Path:     pkg.stack.Name + ".json",
+		Package:  pkg.stack.Package,
+		Dir:      dir,
+		Template: tmpl,
+	}
+	if other, ok := b.byPath[t.Path]; ok {
+		return nil, fmt.Errorf("packages %s and %s would both be written to %s", other.Package, t.Package, t.Path)
+	}
+	b.byDir[absDir] = t
+	b.byPath[t.Path] = t
+	b.templates = append(b.templates, t)
+
+	children := make(map[string]*NestedTemplate)
+	stacks := make(map[string][]string) // child package -> stack resources
+	for _, id := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[id]
+		childDir, ok := childPackageDir(dir, def)
+		if !ok {
+			continue
+		}
+		child, err := b.build(childDir, append(parents, absDir))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStackParameters(tmpl, id, def, child); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Path, err)
+		}
+
+		def.Properties["TemplateURL"] = child.Path
+		tmpl.Resources[id] = def
+		child.NestedIn = append(child.NestedIn, NestedStackRef{Template: t.Path, Resource: id})
+		children[child.Package] = child
+		stacks[child.Package] = append(stacks[child.Package], id)
+	}
+
+	if err := linkChildOutputs(tmpl, children, stacks); err != nil {
+		return nil, fmt.Errorf("%s: %w", t.Path, err)
+	}
+	return t, nil
+}
+
+// childOutputs returns the outputs of the packages of the module that a
+// package imports, which may be the children of its nested stacks.
+func childOutputs(dir string, imports map[string]bool) []runner.ExternalVar {
+	var external []runner.ExternalVar
+	for _, importPath := range sortedKeys(imports) {
+		if importPath == "github.com/lex00/wetwire-aws-go" || strings.HasPrefix(importPath, "github.com/lex00/wetwire-aws-go/") {
+			continue
+		}
+		childDir, ok := runner.PackageDir(dir, importPath)
+		if !ok {
+			continue
+		}
+		result, err := discover.Discover(discover.Options{
+			Packages: []string{childDir},
+		})
+		if err != nil {
+			continue
+		}
+		for _, name := range sortedKeys(result.Outputs) {
+			external = append(external, runner.ExternalVar{ImportPath: importPath, Name: name})
+		}
+	}
+	return external
+}
+
+// childPackageDir returns the package directory named by the TemplateURL
+// of a nested stack resource, such as "./data".
+func childPackageDir(dir string, def wetwire.ResourceDef) (string, bool) {
+	if def.Type != stackType {
+		return "", false
+	}
+	url, ok := def.Properties["TemplateURL"].(string)
+	if !ok || !(strings.HasPrefix(url, "./") || strings.HasPrefix(url, "../")) {
+		return "", false
+	}
+	childDir := filepath.Join(dir, filepath.FromSlash(url))
+	if info, err := os.Stat(childDir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return childDir, true
+}
+
+// linkChildOutputs replaces the references to outputs of child packages,
+// Refs of "importpath.Name", with Fn::GetAtt of the stack resource that
+// creates the child.
+func linkChildOutputs(tmpl *wetwire.Template, children map[string]*NestedTemplate, stacks map[string][]string) error {
+	return rewriteReferences(tmpl, func(ref, attr string) (any, error) {
+		pkgPath, name := splitQualifiedName(ref)
+		child, ok := children[pkgPath]
+		if !ok || attr != "" {
+			return nil, fmt.Errorf("references %s, which is not an output of a nested stack", ref)
+		}
+		if _, ok := child.Template.Outputs[name]; !ok {
+			return nil, fmt.Errorf("references %s, which is not an output of %s", ref, child.Path)
+		}
+		ids := stacks[pkgPath]
+		if len(ids) > 1 {
+			return nil, fmt.Errorf("references output %s of %s, which is nested by %s; use GetAtt to pick one",
+				name, child.Path, strings.Join(ids, " and "))
+		}
+		return map[string]any{"Fn::GetAtt": []string{ids[0], "Outputs." + name}}, nil
+	})
+}
+
+// checkStackParameters checks the Parameters of the nested stack resource
+// id against the parameters of the child template: each must be declared
+// by the child, fit its type and allowed values, and those without a
+// default must be set.
+func checkStackParameters(parent *wetwire.Template, id string, def wetwire.ResourceDef, child *NestedTemplate) error {
+	params, _ := def.Properties["Parameters"].(map[string]any)
+	for _, name := range sortedKeys(params) {
+		param, ok := child.Template.Parameters[name]
+		if !ok {
+			return fmt.Errorf("Resources.%s.Parameters.%s: %s has no parameter %s", id, name, child.Path, name)
+		}
+		if err := checkParameterValue(parent, param, params[name]); err != nil {
+			return fmt.Errorf("Resources.%s.Parameters.%s: %w", id, name, err)
+		}
+	}
+
+	var missing []string
+	for _, name := range sortedKeys(child.Template.Parameters) {
+		if _, set := params[name]; !set && child.Template.Parameters[name].Default == nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Resources.%s: missing parameters of %s: %s", id, child.Path, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// checkParameterValue checks a value passed to a parameter of a nested
+// stack.
+func checkParameterValue(parent *wetwire.Template, param wetwire.Parameter, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		ref, isRef := v["Ref"].(string)
+		if !isRef || len(v) != 1 {
+			// Other intrinsic functions are resolved at deploy time
+			return nil
+		}
+		source, ok := parent.Parameters[ref]
+		if !ok {
+			return nil
+		}
+		if isListParameter(source.Type) {
+			return fmt.Errorf("%s is a %s parameter; nested stacks take lists as comma-delimited strings, use Fn::Join", ref, source.Type)
+		}
+		if source.Type != param.Type && param.Type != "String" {
+			return fmt.Errorf("%s is a %s parameter, but the nested stack expects %s", ref, source.Type, param.Type)
+		}
+		return nil
+	case []any:
+		return fmt.Errorf("nested stacks take lists as comma-delimited strings, not a list")
+	case nil:
+		return fmt.Errorf("value is empty")
+	}
+
+	literal := fmt.Sprint(value)
+	if f, ok := value.(float64); ok {
+		literal = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	switch {
+	case param.Type == "Number":
+		if _, err := strconv.ParseFloat(literal, 64); err != nil {
+			return fmt.Errorf("%q is not a Number", literal)
+		}
+	case param.Type == "List<Number>":
+		for _, item := range strings.Split(literal, ",") {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(item), 64); err != nil {
+				return fmt.Errorf("%q is not a List<Number>", literal)
+			}
+		}
+	}
+
+	if len(param.AllowedValues) > 0 {
+		for _, allowed := range param.AllowedValues {
+			if fmt.Sprint(allowed) == literal {
+				return nil
+			}
+		}
+		allowed := make([]string, len(param.AllowedValues))
+		for i, a := range param.AllowedValues {
+			allowed[i] = fmt.Sprint(a)
+		}
+		return fmt.Errorf("%q is not one of %s", literal, strings.Join(allowed, ", "))
+	}
+	return nil
+}
+
+// isListParameter reports whether a parameter type is a list.
+func isListParameter(paramType string) bool {
+	return paramType == "CommaDelimitedList" || strings.Contains(paramType, "List<")
+}
@@ -0,0 +1,220 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func newChild(params map[string]wetwire.Parameter, outputs map[string]wetwire.Output) *NestedTemplate {
+	return &NestedTemplate{
+		Path:    "data.json",
+		Package: "example.com/infra/app/data",
+		Template: &wetwire.Template{
+			Parameters: params,
+			Resources:  map[string]wetwire.ResourceDef{},
+			Outputs:    outputs,
+		},
+	}
+}
+
+func TestCheckStackParameters(t *testing.T) {
+	child := newChild(map[string]wetwire.Parameter{
+		"Environment": {Type: "String", AllowedValues: []any{"dev", "prod"}},
+		"Capacity":    {Type: "Number", Default: 5},
+		"Subnets":     {Type: "List<AWS::EC2::Subnet::Id>"},
+		"Ports":       {Type: "List<Number>", Default: "80"},
+	}, nil)
+	parent := &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{
+			"Stage":         {Type: "String"},
+			"SubnetIds":     {Type: "List<AWS::EC2::Subnet::Id>"},
+			"SubnetList":    {Type: "CommaDelimitedList"},
+			"MaxCapacity":   {Type: "Number"},
+			"InstanceCount": {Type: "String"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{
+			name: "valid",
+			params: map[string]any{
+				"Environment": "prod",
+				"Capacity":    map[string]any{"Ref": "MaxCapacity"},
+				"Subnets":     map[string]any{"Fn::Join": []any{",", map[string]any{"Ref": "SubnetIds"}}},
+				"Ports":       "80,443",
+			},
+		},
+		{
+			name: "Ref to String parameter passed to String",
+			params: map[string]any{
+				"Environment": map[string]any{"Ref": "Stage"},
+				"Subnets":     "subnet-1,subnet-2",
+			},
+		},
+		{
+			name:   "unknown parameter",
+			params: map[string]any{"Environment": "dev", "Subnets": "subnet-1", "Region": "us-east-1"},
+			want:   "Resources.Data.Parameters.Region: data.json has no parameter Region",
+		},
+		{
+			name:   "missing parameter",
+			params: map[string]any{"Capacity": 3},
+			want:   "Resources.Data: missing parameters of data.json: Environment, Subnets",
+		},
+		{
+			name:   "not allowed",
+			params: map[string]any{"Environment": "staging", "Subnets": "subnet-1"},
+			want:   `Resources.Data.Parameters.Environment: "staging" is not one of dev, prod`,
+		},
+		{
+			name:   "not a number",
+			params: map[string]any{"Environment": "dev", "Subnets": "subnet-1", "Capacity": "lots"},
+			want:   `Resources.Data.Parameters.Capacity: "lots" is not a Number`,
+		},
+		{
+			name:   "not a list of numbers",
+			params: map[string]any{"Environment": "dev", "Subnets": "subnet-1", "Ports": "80,http"},
+			want:   `Resources.Data.Parameters.Ports: "80,http" is not a List<Number>`,
+		},
+		{
+			name:   "literal list",
+			params: map[string]any{"Environment": "dev", "Subnets": []any{"subnet-1", "subnet-2"}},
+			want:   "Resources.Data.Parameters.Subnets: nested stacks take lists as comma-delimited strings",
+		},
+		{
+			name:   "Ref to list parameter",
+			params: map[string]any{"Environment": "dev", "Subnets": map[string]any{"Ref": "SubnetList"}},
+			want:   "SubnetList is a CommaDelimitedList parameter; nested stacks take lists as comma-delimited strings, use Fn::Join",
+		},
+		{
+			name:   "Ref to parameter of another type",
+			params: map[string]any{"Environment": "dev", "Subnets": "subnet-1", "Capacity": map[string]any{"Ref": "InstanceCount"}},
+			want:   "InstanceCount is a String parameter, but the nested stack expects Number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := wetwire.ResourceDef{
+				Type:       stackType,
+				Properties: map[string]any{"TemplateURL": "./data", "Parameters": tt.params},
+			}
+			err := checkStackParameters(parent, "Data", def, child)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestLinkChildOutputs(t *testing.T) {
+	child := newChild(nil, map[string]wetwire.Output{
+		"TableName": {Value: map[string]any{"Ref": "Table"}},
+	})
+	children := map[string]*NestedTemplate{child.Package: child}
+
+	parent := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Handler": {
+				Type: "AWS::Lambda::Function",
+				Properties: map[string]any{
+					"Environment": map[string]any{
+						"Variables": map[string]any{
+							"TABLE": map[string]any{"Ref": "example.com/infra/app/data.TableName"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := linkChildOutputs(parent, children, map[string][]string{child.Package: {"Data"}})
+	require.NoError(t, err)
+	vars := parent.Resources["Handler"].Properties["Environment"].(map[string]any)["Variables"].(map[string]any)
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []string{"Data", "Outputs.TableName"}}, vars["TABLE"])
+
+	tests := []struct {
+		name   string
+		ref    string
+		stacks []string
+		want   string
+	}{
+		{name: "unknown output", ref: "example.com/infra/app/data.TableArn", stacks: []string{"Data"}, want: "references example.com/infra/app/data.TableArn, which is not an output of data.json"},
+		{name: "not a child", ref: "example.com/infra/app/cache.Endpoint", stacks: []string{"Data"}, want: "which is not an output of a nested stack"},
+		{name: "ambiguous", ref: "example.com/infra/app/data.TableName", stacks: []string{"Blue", "Green"}, want: "nested by Blue and Green; use GetAtt to pick one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := &wetwire.Template{
+				Outputs: map[string]wetwire.Output{
+					"Table": {Value: map[string]any{"Ref": tt.ref}},
+				},
+			}
+			err := linkChildOutputs(parent, children, map[string][]string{child.Package: tt.stacks})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "Outputs.Table: ")
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestChildPackageDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.yaml"), []byte("Resources: {}\n"), 0644))
+
+	tests := []struct {
+		name string
+		def  wetwire.ResourceDef
+		want string
+	}{
+		{name: "package", def: wetwire.ResourceDef{Type: stackType, Properties: map[string]any{"TemplateURL": "./data"}}, want: filepath.Join(dir, "data")},
+		{name: "template file", def: wetwire.ResourceDef{Type: stackType, Properties: map[string]any{"TemplateURL": "./data.yaml"}}},
+		{name: "S3 URL", def: wetwire.ResourceDef{Type: stackType, Properties: map[string]any{"TemplateURL": "https://bucket.s3.amazonaws.com/data.json"}}},
+		{name: "missing", def: wetwire.ResourceDef{Type: stackType, Properties: map[string]any{"TemplateURL": "./cache"}}},
+		{name: "not a stack", def: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"TemplateURL": "./data"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := childPackageDir(dir, tt.def)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewManifest(t *testing.T) {
+	manifest := NewManifest([]*NestedTemplate{
+		{Path: "app.json", Package: "example.com/infra/app"},
+		{
+			Path:     "data.json",
+			Package:  "example.com/infra/app/data",
+			NestedIn: []NestedStackRef{{Template: "app.json", Resource: "Data"}},
+		},
+	})
+
+	assert.Equal(t, Manifest{
+		Root: "app.json",
+		Templates: []ManifestEntry{
+			{Path: "app.json", Package: "example.com/infra/app"},
+			{
+				Path:     "data.json",
+				Package:  "example.com/infra/app/data",
+				NestedIn: []NestedStackRef{{Template: "app.json", Resource: "Data"}},
+			},
+		},
+	}, manifest)
+}
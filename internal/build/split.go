@@ -0,0 +1,795 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/schema"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// SplitOptions sets the capacity of the nested stacks created by Split.
+type SplitOptions struct {
+	// MaxResources is the number of resources of a nested stack; zero is
+	// the CloudFormation limit
+	MaxResources int
+	// MaxSize is the size of a nested stack template in bytes; zero leaves
+	// a tenth of the CloudFormation limit for the added parameters and
+	// outputs
+	MaxSize int
+}
+
+// NeedsSplit reports whether a template has more resources or bytes than a
+// single CloudFormation stack allows.
+func NeedsSplit(tmpl *wetwire.Template) bool {
+	if schema.ResourceCount(tmpl) > schema.MaxResources {
+		return true
+	}
+	data, err := json.Marshal(tmpl)
+	return err == nil && len(data) > schema.MaxTemplateSize
+}
+
+// splitNode is a resource, or an Fn::ForEach loop, of a template to split.
+type splitNode struct {
+	id     string
+	weight int // resources created
+	size   int // bytes
+	deps   []string
+}
+
+// splitPart is a nested stack of a split template.
+type splitPart struct {
+	nodes  []string
+	weight int
+	size   int
+	chunk  bool // holds a piece of a component that did not fit whole
+}
+
+// Split partitions the resources of tmpl into nested stacks of at most
+// MaxResources resources and MaxSize bytes. It returns the root template,
+// name.json, whose resources are the AWS::CloudFormation::Stack resources
+// of the children name-1.json, name-2.json and so on, with the root's
+// parameters, conditions and outputs.
+//
+// Resources that reference each other are kept together when their
+// connected component fits in a stack. Larger components are cut along a
+// topological order, at the point with the fewest references across it, so
+// the stacks never depend on each other in a cycle. A reference across
+// stacks becomes an output of the stack of the target and a parameter of
+// the stack of the reference, wired through the root with Fn::GetAtt.
+func Split(name string, tmpl *wetwire.Template, opts SplitOptions) ([]*NestedTemplate, error) {
+	if opts.MaxResources <= 0 {
+		opts.MaxResources = schema.MaxResources
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = schema.MaxTemplateSize * 9 / 10
+	}
+
+	nodes, err := splitNodes(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := partition(nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) <= 1 {
+		return []*NestedTemplate{{Path: name + ".json", Template: tmpl}}, nil
+	}
+	return wireParts(name, tmpl, nodes, parts)
+}
+
+// splitNodes returns the resources of tmpl with the resources they
+// reference or depend on.
+func splitNodes(tmpl *wetwire.Template) (map[string]*splitNode, error) {
+	nodes := make(map[string]*splitNode, len(tmpl.Resources))
+	for _, id := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[id]
+		data, err := json.Marshal(def)
+		if err != nil {
+			return nil, fmt.Errorf("Resources.%s: %w", id, err)
+		}
+		weight := 1
+		if def.IsLoop() {
+			weight = schema.ResourceCount(&wetwire.Template{Resources: map[string]wetwire.ResourceDef{id: def}})
+		}
+		nodes[id] = &splitNode{id: id, weight: weight, size: len(data) + len(id) + 4}
+	}
+
+	for _, id := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[id]
+		deps := make(map[string]bool)
+		for ref := range collectReferences(def).refs {
+			if _, ok := nodes[ref.name]; ok && ref.name != id {
+				deps[ref.name] = true
+			}
+		}
+		for _, dep := range def.DependsOn {
+			if _, ok := nodes[dep]; ok {
+				deps[dep] = true
+			}
+		}
+		nodes[id].deps = sortedKeys(deps)
+	}
+	return nodes, nil
+}
+
+// partition packs the nodes into parts. Connected components that fit are
+// packed whole, largest first; the others are cut into chunks along a
+// topological order.
+func partition(nodes map[string]*splitNode, opts SplitOptions) ([]*splitPart, error) {
+	for _, id := range sortedKeys(nodes) {
+		if n := nodes[id]; n.weight > opts.MaxResources || n.size > opts.MaxSize {
+			return nil, fmt.Errorf("Resources.%s does not fit in a nested stack on its own", id)
+		}
+	}
+
+	components := connectedComponents(nodes)
+	sort.SliceStable(components, func(i, j int) bool {
+		return totalWeight(nodes, components[i]) > totalWeight(nodes, components[j])
+	})
+
+	var parts []*splitPart
+	for _, component := range components {
+		weight, size := totalWeight(nodes, component), totalSize(nodes, component)
+		if weight <= opts.MaxResources && size <= opts.MaxSize {
+			placed := false
+			for _, part := range parts {
+				if part.weight+weight <= opts.MaxResources && part.size+size <= opts.MaxSize {
+					part.add(nodes, component)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				part := &splitPart{}
+				part.add(nodes, component)
+				parts = append(parts, part)
+			}
+			continue
+		}
+
+		// Each chunk gets a part of its own, so the parts of a component
+		// only depend on the parts of earlier chunks
+		for _, chunk := range cutComponent(nodes, topologicalOrder(nodes, component), opts) {
+			part := &splitPart{chunk: true}
+			part.add(nodes, chunk)
+			parts = append(parts, part)
+		}
+	}
+	return parts, nil
+}
+
+// add adds nodes to a part.
+func (p *splitPart) add(nodes map[string]*splitNode, ids []string) {
+	p.nodes = append(p.nodes, ids...)
+	p.weight += totalWeight(nodes, ids)
+	p.size += totalSize(nodes, ids)
+}
+
+func totalWeight(nodes map[string]*splitNode, ids []string) int {
+	total := 0
+	for _, id := range ids {
+		total += nodes[id].weight
+	}
+	return total
+}
+
+func totalSize(nodes map[string]*splitNode, ids []string) int {
+	total := 0
+	for _, id := range ids {
+		total += nodes[id].size
+	}
+	return total
+}
+
+// connectedComponents returns the groups of nodes connected by references
+// in either direction, each sorted by ID.
+func connectedComponents(nodes map[string]*splitNode) [][]string {
+	parent := make(map[string]string, len(nodes))
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for id := range nodes {
+		parent[id] = id
+	}
+	for _, id := range sortedKeys(nodes) {
+		for _, dep := range nodes[id].deps {
+			a, b := find(id), find(dep)
+			if a != b {
+				parent[a] = b
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	for _, id := range sortedKeys(nodes) {
+		root := find(id)
+		groups[root] = append(groups[root], id)
+	}
+	components := make([][]string, 0, len(groups))
+	for _, root := range sortedKeys(groups) {
+		components = append(components, groups[root])
+	}
+	sort.SliceStable(components, func(i, j int) bool { return components[i][0] < components[j][0] })
+	return components
+}
+
+// topologicalOrder orders the nodes of a component so each comes after the
+// nodes it references. The depth-first walk keeps a node close to the
+// nodes it references.
+func topologicalOrder(nodes map[string]*splitNode, component []string) []string {
+	visited := make(map[string]bool, len(component))
+	order := make([]string, 0, len(component))
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		for _, dep := range nodes[id].deps {
+			visit(dep)
+		}
+		order = append(order, id)
+	}
+	for _, id := range component {
+		visit(id)
+	}
+	return order
+}
+
+// cutComponent cuts an ordered component into chunks that fit in a part.
+// Each cut is made in the second half of the largest chunk that fits, where
+// the fewest references cross it.
+func cutComponent(nodes map[string]*splitNode, order []string, opts SplitOptions) [][]string {
+	position := make(map[string]int, len(order))
+	for i, id := range order {
+		position[id] = i
+	}
+
+	var chunks [][]string
+	for start := 0; start < len(order); {
+		// The largest chunk that fits
+		end, weight, size := start, 0, 0
+		for end < len(order) {
+			n := nodes[order[end]]
+			if weight+n.weight > opts.MaxResources || size+n.size > opts.MaxSize {
+				break
+			}
+			weight += n.weight
+			size += n.size
+			end++
+		}
+		if end == len(order) {
+			chunks = append(chunks, order[start:end])
+			break
+		}
+
+		// crossing[i] counts the references from order[i:] to
+		// order[start:i]; each reference points backwards in the order
+		best, bestCrossing := end, -1
+		for cut := end; cut > start+(end-start)/2; cut-- {
+			crossing := 0
+			for _, id := range order[cut:] {
+				for _, dep := range nodes[id].deps {
+					if p := position[dep]; p >= start && p < cut {
+						crossing++
+					}
+				}
+			}
+			if bestCrossing < 0 || crossing < bestCrossing {
+				best, bestCrossing = cut, crossing
+			}
+		}
+		chunks = append(chunks, order[start:best])
+		start = best
+	}
+	return chunks
+}
+
+// wireParts builds the root and child templates of a partition.
+func wireParts(name string, tmpl *wetwire.Template, nodes map[string]*splitNode, parts []*splitPart) ([]*NestedTemplate, error) {
+	partOf := make(map[string]int, len(nodes))
+	for i, part := range parts {
+		sort.Strings(part.nodes)
+		for _, id := range part.nodes {
+			partOf[id] = i
+		}
+	}
+	stackID := func(i int) string { return fmt.Sprintf("Stack%d", i+1) }
+	for i := range parts {
+		if _, exists := tmpl.Parameters[stackID(i)]; exists {
+			return nil, fmt.Errorf("parameter %s has the name of a nested stack", stackID(i))
+		}
+	}
+
+	root := &wetwire.Template{
+		AWSTemplateFormatVersion: tmpl.AWSTemplateFormatVersion,
+		Description:              tmpl.Description,
+		Parameters:               tmpl.Parameters,
+		Mappings:                 tmpl.Mappings,
+		Conditions:               tmpl.Conditions,
+		Resources:                make(map[string]wetwire.ResourceDef, len(parts)),
+	}
+	children := make([]*wetwire.Template, len(parts))
+	stackParams := make([]map[string]any, len(parts))
+	stackDeps := make([]map[string]bool, len(parts))
+	for i := range parts {
+		children[i] = &wetwire.Template{
+			AWSTemplateFormatVersion: tmpl.AWSTemplateFormatVersion,
+			Transform:                tmpl.Transform,
+			Resources:                make(map[string]wetwire.ResourceDef, len(parts[i].nodes)),
+		}
+		stackParams[i] = make(map[string]any)
+		stackDeps[i] = make(map[string]bool)
+	}
+
+	// export adds an output for a resource, or one of its attributes, to
+	// the child that holds it and returns the output name
+	export := func(resource, attr string) (string, error) {
+		child := children[partOf[resource]]
+		outputID := resource + strings.ReplaceAll(attr, ".", "")
+		value := any(map[string]any{"Ref": resource})
+		if attr != "" {
+			value = map[string]any{"Fn::GetAtt": []string{resource, attr}}
+		}
+		if existing, ok := child.Outputs[outputID]; ok {
+			if !sameValue(existing.Value, value) {
+				return "", fmt.Errorf("outputs of %s and %s.%s are both named %s", resource, resource, attr, outputID)
+			}
+			return outputID, nil
+		}
+		if child.Outputs == nil {
+			child.Outputs = make(map[string]wetwire.Output)
+		}
+		child.Outputs[outputID] = wetwire.Output{Value: value}
+		return outputID, nil
+	}
+
+	for i, part := range parts {
+		child := children[i]
+		needed := &references{
+			refs:       make(map[reference]bool),
+			conditions: make(map[string]bool),
+			mappings:   make(map[string]bool),
+		}
+		for _, id := range part.nodes {
+			def := tmpl.Resources[id]
+			refs := collectReferences(def)
+			needed.merge(refs)
+			if def.Condition != "" {
+				needed.conditions[def.Condition] = true
+			}
+
+			// References to resources of other parts become parameters
+			// named after the resource, and its attribute
+			imported := make(map[reference]string)
+			for ref := range refs.refs {
+				j, isResource := partOf[ref.name]
+				if !isResource || j == i {
+					continue
+				}
+				output, err := export(ref.name, ref.attr)
+				if err != nil {
+					return nil, err
+				}
+				param := output
+				_, clashes := tmpl.Resources[param]
+				if _, isParam := tmpl.Parameters[param]; param != ref.name && (clashes || isParam) {
+					return nil, fmt.Errorf("Resources.%s: parameter %s for %s has the name of another declaration", id, param, ref.name)
+				}
+				imported[ref] = param
+				stackParams[i][param] = map[string]any{"Fn::GetAtt": []string{stackID(j), "Outputs." + output}}
+				if child.Parameters == nil {
+					child.Parameters = make(map[string]wetwire.Parameter)
+				}
+				child.Parameters[param] = wetwire.Parameter{Type: "String"}
+			}
+
+			// Explicit dependencies on other parts become dependencies
+			// between the stacks
+			var dependsOn []string
+			for _, dep := range def.DependsOn {
+				if j, ok := partOf[dep]; ok && j != i {
+					stackDeps[i][stackID(j)] = true
+					continue
+				}
+				dependsOn = append(dependsOn, dep)
+			}
+			def.DependsOn = dependsOn
+
+			def.Properties = rewriteImported(def.Properties, imported)
+			def.Metadata = rewriteImported(def.Metadata, imported)
+			if def.ForEach != nil {
+				def.ForEach = rewriteImportedValue(def.ForEach, imported).([]any)
+			}
+			child.Resources[id] = def
+		}
+
+		// Conditions, with those they use, and the root parameters and
+		// mappings the part needs
+		for _, cond := range sortedKeys(needed.conditions) {
+			addCondition(child, tmpl, cond, needed)
+		}
+		for ref := range needed.refs {
+			if _, isResource := partOf[ref.name]; isResource {
+				continue
+			}
+			if param, ok := tmpl.Parameters[ref.name]; ok {
+				if child.Parameters == nil {
+					child.Parameters = make(map[string]wetwire.Parameter)
+				}
+				child.Parameters[ref.name], stackParams[i][ref.name] = passParameter(ref.name, param)
+			}
+		}
+		for _, mapping := range sortedKeys(needed.mappings) {
+			if value, ok := tmpl.Mappings[mapping]; ok {
+				if child.Mappings == nil {
+					child.Mappings = make(map[string]any)
+				}
+				child.Mappings[mapping] = value
+			}
+		}
+	}
+
+	// The root's outputs read the outputs of the children
+	if len(tmpl.Outputs) > 0 {
+		root.Outputs = make(map[string]wetwire.Output, len(tmpl.Outputs))
+	}
+	for _, id := range sortedKeys(tmpl.Outputs) {
+		output := tmpl.Outputs[id]
+		value, err := rewriteRootValue(output.Value, func(resource, attr string) (any, bool, error) {
+			j, ok := partOf[resource]
+			if !ok {
+				return nil, false, nil
+			}
+			name, err := export(resource, attr)
+			if err != nil {
+				return nil, false, err
+			}
+			return map[string]any{"Fn::GetAtt": []string{stackID(j), "Outputs." + name}}, true, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Outputs.%s: %w", id, err)
+		}
+		output.Value = value
+		root.Outputs[id] = output
+	}
+	if template.UsesLanguageExtensions(root) {
+		root.Transform = wetwire.Transforms{wetwire.TransformLanguageExtensions}
+	}
+
+	templates := []*NestedTemplate{{Path: name + ".json", Template: root}}
+	for i, child := range children {
+		path := fmt.Sprintf("%s-%d.json", name, i+1)
+		stack := wetwire.ResourceDef{
+			Type:       stackType,
+			Properties: map[string]any{"TemplateURL": path},
+			DependsOn:  sortedKeys(stackDeps[i]),
+		}
+		if len(stackParams[i]) > 0 {
+			stack.Properties["Parameters"] = stackParams[i]
+		}
+		root.Resources[stackID(i)] = stack
+
+		if n := len(child.Parameters); n > schema.MaxParameters {
+			return nil, fmt.Errorf("nested stack %s needs %d parameters, over the limit of %d", path, n, schema.MaxParameters)
+		}
+		if n := len(child.Outputs); n > schema.MaxOutputs {
+			return nil, fmt.Errorf("nested stack %s needs %d outputs, over the limit of %d", path, n, schema.MaxOutputs)
+		}
+		templates = append(templates, &NestedTemplate{
+			Path:     path,
+			Template: child,
+			NestedIn: []NestedStackRef{{Template: name + ".json", Resource: stackID(i)}},
+		})
+	}
+	return templates, nil
+}
+
+// passParameter returns the parameter a child declares for a root
+// parameter and the value the root passes to it. Nested stacks take
+// parameters as strings: lists are joined with commas, and SSM parameter
+// types, which the root resolves, pass their value as a String or
+// CommaDelimitedList. The default and constraints of an SSM parameter apply
+// to the parameter name, so the child does not keep them.
+func passParameter(name string, param wetwire.Parameter) (wetwire.Parameter, any) {
+	value := any(map[string]any{"Ref": name})
+	if isListParameter(param.Type) {
+		value = map[string]any{"Fn::Join": []any{",", value}}
+	}
+	if strings.HasPrefix(param.Type, "AWS::SSM::Parameter::Value<") {
+		paramType := "String"
+		if isListParameter(param.Type) {
+			paramType = "CommaDelimitedList"
+		}
+		param = wetwire.Parameter{Type: paramType, Description: param.Description, NoEcho: param.NoEcho}
+	}
+	return param, value
+}
+
+// addCondition copies a condition of tmpl, with the conditions,
+// parameters and mappings it uses, to a child template.
+func addCondition(child, tmpl *wetwire.Template, name string, needed *references) {
+	if _, done := child.Conditions[name]; done {
+		return
+	}
+	cond, ok := tmpl.Conditions[name]
+	if !ok {
+		return
+	}
+	if child.Conditions == nil {
+		child.Conditions = make(map[string]any)
+	}
+	child.Conditions[name] = cond
+
+	refs := &references{
+		refs:       make(map[reference]bool),
+		conditions: make(map[string]bool),
+		mappings:   make(map[string]bool),
+	}
+	refs.collect(cond)
+	needed.merge(refs)
+	for _, other := range sortedKeys(refs.conditions) {
+		addCondition(child, tmpl, other, needed)
+	}
+}
+
+// reference is a Ref, with no attribute, or a GetAtt.
+type reference struct {
+	name string
+	attr string
+}
+
+// references are the names a value uses.
+type references struct {
+	refs       map[reference]bool
+	conditions map[string]bool
+	mappings   map[string]bool
+}
+
+// collectReferences returns the names a resource uses.
+func collectReferences(def wetwire.ResourceDef) *references {
+	r := &references{
+		refs:       make(map[reference]bool),
+		conditions: make(map[string]bool),
+		mappings:   make(map[string]bool),
+	}
+	r.collect(def.Properties)
+	r.collect(def.Metadata)
+	r.collect(def.DeletionPolicy)
+	r.collect(def.UpdateReplacePolicy)
+	r.collect(def.CreationPolicy)
+	r.collect(def.UpdatePolicy)
+	if def.ForEach != nil {
+		r.collect(def.ForEach)
+	}
+	return r
+}
+
+// merge adds the names of other.
+func (r *references) merge(other *references) {
+	for ref := range other.refs {
+		r.refs[ref] = true
+	}
+	for cond := range other.conditions {
+		r.conditions[cond] = true
+	}
+	for mapping := range other.mappings {
+		r.mappings[mapping] = true
+	}
+}
+
+// collect adds the names used in a value.
+func (r *references) collect(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, arg := range v {
+			switch key {
+			case "Ref":
+				if name, ok := arg.(string); ok && !strings.HasPrefix(name, "AWS::") {
+					r.refs[reference{name: name}] = true
+					continue
+				}
+			case "Fn::GetAtt":
+				if name, attr, ok := template.GetAttArgs(arg); ok {
+					r.refs[reference{name: name, attr: attr}] = true
+					continue
+				}
+			case "Fn::Sub":
+				r.collectSub(arg)
+				continue
+			case "Condition":
+				if name, ok := arg.(string); ok {
+					r.conditions[name] = true
+					continue
+				}
+			case "Fn::If":
+				if args, ok := arg.([]any); ok && len(args) > 0 {
+					if name, ok := args[0].(string); ok {
+						r.conditions[name] = true
+					}
+				}
+			case "Fn::FindInMap":
+				if args, ok := arg.([]any); ok && len(args) > 0 {
+					if name, ok := args[0].(string); ok {
+						r.mappings[name] = true
+					}
+				}
+			}
+			r.collect(arg)
+		}
+	case []any:
+		for _, item := range v {
+			r.collect(item)
+		}
+	case map[string][]any:
+		for _, items := range v {
+			r.collect(items)
+		}
+	}
+}
+
+// collectSub adds the variables of an Fn::Sub string that are not set by
+// its variable map.
+func (r *references) collectSub(arg any) {
+	str, vars := template.SubArgs(arg)
+	for _, ref := range template.SubReferences(str, vars) {
+		if !strings.HasPrefix(ref.Name, "AWS::") {
+			r.refs[reference{name: ref.Name, attr: ref.Attr}] = true
+		}
+	}
+	for _, value := range vars {
+		r.collect(value)
+	}
+}
+
+// rewriteImported replaces GetAtts of resources of other parts with Refs
+// to the parameters that pass them in. Refs need no change, as the
+// parameter takes the name of the resource.
+func rewriteImported(m map[string]any, imported map[reference]string) map[string]any {
+	if m == nil || len(imported) == 0 {
+		return m
+	}
+	return rewriteImportedValue(m, imported).(map[string]any)
+}
+
+func rewriteImportedValue(value any, imported map[reference]string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			if arg, ok := v["Fn::GetAtt"]; ok {
+				if name, attr, ok := template.GetAttArgs(arg); ok {
+					if param, ok := imported[reference{name: name, attr: attr}]; ok {
+						return map[string]any{"Ref": param}
+					}
+				}
+			}
+			if arg, ok := v["Fn::Sub"]; ok {
+				return map[string]any{"Fn::Sub": rewriteSub(arg, imported)}
+			}
+		}
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			result[key] = rewriteImportedValue(item, imported)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = rewriteImportedValue(item, imported)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// rewriteSub replaces the ${Resource.Attr} variables of an Fn::Sub string
+// for imported attributes with the parameters that pass them in.
+func rewriteSub(arg any, imported map[reference]string) any {
+	str, vars := template.SubArgs(arg)
+	str = template.ReplaceSubReferences(str, vars, func(ref template.SubReference) (string, bool) {
+		if ref.Attr == "" {
+			return "", false
+		}
+		param, ok := imported[reference{name: ref.Name, attr: ref.Attr}]
+		return "${" + param + "}", ok
+	})
+	if args, ok := arg.([]any); ok && len(args) == 2 {
+		return []any{str, rewriteImportedValue(vars, imported)}
+	}
+	return str
+}
+
+// rewriteRootValue replaces the Refs and GetAtts of resources in a value
+// of the root template with the value returned by resolve. Fn::Sub
+// variables of resources move to the variable map.
+func rewriteRootValue(value any, resolve func(resource, attr string) (any, bool, error)) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			for key, arg := range v {
+				switch key {
+				case "Ref":
+					if name, ok := arg.(string); ok {
+						if resolved, ok, err := resolve(name, ""); err != nil || ok {
+							return resolved, err
+						}
+					}
+				case "Fn::GetAtt":
+					if name, attr, ok := template.GetAttArgs(arg); ok {
+						if resolved, ok, err := resolve(name, attr); err != nil || ok {
+							return resolved, err
+						}
+					}
+				case "Fn::Sub":
+					return rewriteRootSub(arg, resolve)
+				}
+			}
+		}
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			rewritten, err := rewriteRootValue(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = rewritten
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			rewritten, err := rewriteRootValue(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rewritten
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// rewriteRootSub moves the resource variables of an Fn::Sub of the root
+// template to its variable map.
+func rewriteRootSub(arg any, resolve func(resource, attr string) (any, bool, error)) (any, error) {
+	str, vars := template.SubArgs(arg)
+	newVars := make(map[string]any, len(vars))
+	for key, value := range vars {
+		rewritten, err := rewriteRootValue(value, resolve)
+		if err != nil {
+			return nil, err
+		}
+		newVars[key] = rewritten
+	}
+
+	var resolveErr error
+	str = template.ReplaceSubReferences(str, vars, func(ref template.SubReference) (string, bool) {
+		resolved, ok, err := resolve(ref.Name, ref.Attr)
+		if err != nil {
+			resolveErr = err
+		}
+		if !ok {
+			return "", false
+		}
+		variable := ref.Name + strings.ReplaceAll(ref.Attr, ".", "")
+		newVars[variable] = resolved
+		return "${" + variable + "}", true
+	})
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+	if len(newVars) == 0 {
+		return map[string]any{"Fn::Sub": str}, nil
+	}
+	return map[string]any{"Fn::Sub": []any{str, newVars}}, nil
+}
@@ -0,0 +1,212 @@
+package build
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func TestSplit(t *testing.T) {
+	tmpl := &wetwire.Template{
+		AWSTemplateFormatVersion: "2010-09-09",
+		Parameters: map[string]wetwire.Parameter{
+			"Env": {Type: "String"},
+		},
+		Conditions: map[string]any{
+			"IsProd": map[string]any{"Fn::Equals": []any{map[string]any{"Ref": "Env"}, "prod"}},
+		},
+		Mappings: map[string]any{
+			"Sizes": map[string]any{"dev": map[string]any{"Size": "small"}},
+		},
+		Resources: map[string]wetwire.ResourceDef{
+			"A": {Type: "AWS::S3::Bucket"},
+			"B": {Type: "AWS::SNS::Topic", Properties: map[string]any{
+				"TopicName": map[string]any{"Fn::GetAtt": []any{"A", "Arn"}},
+			}},
+			"C": {Type: "AWS::SQS::Queue", DependsOn: []string{"A"}, Properties: map[string]any{
+				"QueueName": map[string]any{"Ref": "B"},
+				"Tags":      []any{map[string]any{"Key": "Bucket", "Value": map[string]any{"Fn::Sub": "${A.Arn}/logs"}}},
+			}},
+			"D": {Type: "AWS::SQS::QueuePolicy", Condition: "IsProd", DependsOn: []string{"C"}},
+			"E": {Type: "AWS::SNS::Topic", Properties: map[string]any{
+				"TopicName": map[string]any{"Fn::FindInMap": []any{"Sizes", "dev", "Size"}},
+			}},
+		},
+		Outputs: map[string]wetwire.Output{
+			"BucketArn": {Value: map[string]any{"Fn::GetAtt": []any{"A", "Arn"}}},
+			"Url":       {Value: map[string]any{"Fn::Sub": "https://${D}/${Env}"}},
+		},
+	}
+
+	templates, err := Split("app", tmpl, SplitOptions{MaxResources: 2})
+	require.NoError(t, err)
+	require.Len(t, templates, 4)
+
+	root := templates[0]
+	assert.Equal(t, "app.json", root.Path)
+	assert.Equal(t, tmpl.Parameters, root.Template.Parameters)
+	assert.Equal(t, []string{"Stack1", "Stack2", "Stack3"}, sortedKeys(root.Template.Resources))
+	for i, child := range templates[1:] {
+		assert.Equal(t, []NestedStackRef{{Template: "app.json", Resource: sortedKeys(root.Template.Resources)[i]}}, child.NestedIn)
+	}
+	first, second, third := templates[1].Template, templates[2].Template, templates[3].Template
+	assert.Equal(t, []string{"A", "B"}, sortedKeys(first.Resources))
+	assert.Equal(t, []string{"C", "D"}, sortedKeys(second.Resources))
+	assert.Equal(t, []string{"E"}, sortedKeys(third.Resources))
+
+	// References to the first stack pass through the root
+	assert.Equal(t, map[string]wetwire.Output{
+		"AArn": {Value: map[string]any{"Fn::GetAtt": []string{"A", "Arn"}}},
+		"B":    {Value: map[string]any{"Ref": "B"}},
+	}, first.Outputs)
+	stack2 := root.Template.Resources["Stack2"]
+	assert.Equal(t, "app-2.json", stack2.Properties["TemplateURL"])
+	assert.Equal(t, []string{"Stack1"}, stack2.DependsOn)
+	assert.Equal(t, map[string]any{
+		"AArn": map[string]any{"Fn::GetAtt": []string{"Stack1", "Outputs.AArn"}},
+		"B":    map[string]any{"Fn::GetAtt": []string{"Stack1", "Outputs.B"}},
+		"Env":  map[string]any{"Ref": "Env"},
+	}, stack2.Properties["Parameters"])
+
+	c := second.Resources["C"]
+	assert.Empty(t, c.DependsOn)
+	assert.Equal(t, map[string]any{"Ref": "B"}, c.Properties["QueueName"])
+	assert.Equal(t, map[string]any{"Fn::Sub": "${AArn}/logs"}, c.Properties["Tags"].([]any)[0].(map[string]any)["Value"])
+	assert.Equal(t, []string{"C"}, second.Resources["D"].DependsOn)
+	assert.Equal(t, []string{"AArn", "B", "Env"}, sortedKeys(second.Parameters))
+	assert.Equal(t, tmpl.Conditions, second.Conditions)
+	assert.Equal(t, tmpl.Mappings, third.Mappings)
+	assert.Nil(t, third.Parameters)
+
+	// The root's outputs read the children's outputs
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []string{"Stack1", "Outputs.AArn"}}, root.Template.Outputs["BucketArn"].Value)
+	assert.Equal(t, map[string]any{"Fn::Sub": []any{"https://${D}/${Env}", map[string]any{
+		"D": map[string]any{"Fn::GetAtt": []string{"Stack2", "Outputs.D"}},
+	}}}, root.Template.Outputs["Url"].Value)
+
+	// Every name a child uses is declared in it
+	for _, child := range templates[1:] {
+		for id, def := range child.Template.Resources {
+			for ref := range collectReferences(def).refs {
+				_, isResource := child.Template.Resources[ref.name]
+				_, isParam := child.Template.Parameters[ref.name]
+				assert.True(t, isResource || isParam, "%s: %s.%s references %s", child.Path, id, ref.name, ref.name)
+			}
+		}
+	}
+
+	// The original template is left as it was
+	assert.Equal(t, []string{"A"}, tmpl.Resources["C"].DependsOn)
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []any{"A", "Arn"}}, tmpl.Outputs["BucketArn"].Value)
+}
+
+func TestSplit_ListAndSSMParameters(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{
+			"Subnets": {Type: "List<AWS::EC2::Subnet::Id>"},
+			"Zones":   {Type: "CommaDelimitedList"},
+			"Image":   {Type: "AWS::SSM::Parameter::Value<AWS::EC2::Image::Id>", Default: "/images/latest"},
+			"Hosts":   {Type: "AWS::SSM::Parameter::Value<List<String>>", Default: "/app/hosts"},
+		},
+		Resources: map[string]wetwire.ResourceDef{
+			"A": {Type: "AWS::SNS::Topic"},
+			"B": {Type: "AWS::EC2::Instance", Properties: map[string]any{
+				"SubnetId":         map[string]any{"Fn::Select": []any{0, map[string]any{"Ref": "Subnets"}}},
+				"AvailabilityZone": map[string]any{"Fn::Select": []any{0, map[string]any{"Ref": "Zones"}}},
+				"ImageId":          map[string]any{"Ref": "Image"},
+				"Tags":             []any{map[string]any{"Key": "Hosts", "Value": map[string]any{"Fn::Join": []any{",", map[string]any{"Ref": "Hosts"}}}}},
+			}},
+		},
+	}
+
+	templates, err := Split("app", tmpl, SplitOptions{MaxResources: 1})
+	require.NoError(t, err)
+	require.Len(t, templates, 3)
+
+	root, child := templates[0], templates[2]
+	stack := root.Template.Resources["Stack2"]
+	join := func(name string) map[string]any {
+		return map[string]any{"Fn::Join": []any{",", map[string]any{"Ref": name}}}
+	}
+	assert.Equal(t, map[string]any{
+		"Subnets": join("Subnets"),
+		"Zones":   join("Zones"),
+		"Image":   map[string]any{"Ref": "Image"},
+		"Hosts":   join("Hosts"),
+	}, stack.Properties["Parameters"])
+	assert.Equal(t, map[string]wetwire.Parameter{
+		"Subnets": {Type: "List<AWS::EC2::Subnet::Id>"},
+		"Zones":   {Type: "CommaDelimitedList"},
+		"Image":   {Type: "String"},
+		"Hosts":   {Type: "CommaDelimitedList"},
+	}, child.Template.Parameters)
+
+	// The values pass the checks of nested stack parameters
+	assert.NoError(t, checkStackParameters(root.Template, "Stack2", stack, child))
+}
+
+func TestSplit_WithinLimits(t *testing.T) {
+	tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"A": {Type: "AWS::S3::Bucket"},
+		"B": {Type: "AWS::S3::Bucket"},
+	}}
+	assert.False(t, NeedsSplit(tmpl))
+
+	templates, err := Split("app", tmpl, SplitOptions{})
+	require.NoError(t, err)
+	require.Len(t, templates, 1)
+	assert.Equal(t, "app.json", templates[0].Path)
+	assert.Same(t, tmpl, templates[0].Template)
+}
+
+func TestSplit_ResourceTooLarge(t *testing.T) {
+	tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"A": {Type: "AWS::Lambda::Function", Properties: map[string]any{"Code": map[string]any{"ZipFile": "exports.handler = async () => {}"}}},
+	}}
+	_, err := Split("app", tmpl, SplitOptions{MaxSize: 20})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Resources.A does not fit in a nested stack on its own")
+}
+
+func TestCutComponent(t *testing.T) {
+	// c and e reference across the only cut with a single edge
+	nodes := map[string]*splitNode{
+		"a": {id: "a", weight: 1},
+		"b": {id: "b", weight: 1, deps: []string{"a"}},
+		"c": {id: "c", weight: 1, deps: []string{"a", "b"}},
+		"d": {id: "d", weight: 1},
+		"e": {id: "e", weight: 1, deps: []string{"c", "d"}},
+	}
+	order := topologicalOrder(nodes, []string{"a", "b", "c", "d", "e"})
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, order)
+
+	chunks := cutComponent(nodes, order, SplitOptions{MaxResources: 4, MaxSize: 1 << 20})
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d", "e"}}, chunks)
+}
+
+func TestCollectReferences(t *testing.T) {
+	refs := collectReferences(wetwire.ResourceDef{
+		Type: "AWS::Lambda::Function",
+		Properties: map[string]any{
+			"Role":   map[string]any{"Fn::GetAtt": "Role.Arn"},
+			"Layers": []any{map[string]any{"Ref": "Layer"}, map[string]any{"Ref": "AWS::NoValue"}},
+			"Handler": map[string]any{"Fn::Sub": []any{
+				"${Prefix}-${Bucket.Arn}-${!Literal}-${AWS::Region}",
+				map[string]any{"Prefix": map[string]any{"Ref": "Stage"}},
+			}},
+			"Timeout": map[string]any{"Fn::If": []any{"IsProd", 30, map[string]any{"Fn::FindInMap": []any{"Timeouts", "dev", "Value"}}}},
+		},
+	})
+
+	assert.Equal(t, map[reference]bool{
+		{name: "Role", attr: "Arn"}:   true,
+		{name: "Layer"}:               true,
+		{name: "Bucket", attr: "Arn"}: true,
+		{name: "Stage"}:               true,
+	}, refs.refs)
+	assert.Equal(t, map[string]bool{"IsProd": true}, refs.conditions)
+	assert.Equal(t, map[string]bool{"Timeouts": true}, refs.mappings)
+}
@@ -0,0 +1,476 @@
+package build
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/config"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/runner"
+)
+
+// Stack is the template of one Go package of a build. A reference to a
+// resource of another stack is an Fn::ImportValue of an output that the
+// other stack exports.
+type Stack struct {
+	// Name is the Go package name, which names the stack in the build
+	Name string
+	// StackName is the name the stack is deployed as, the build's
+	// build.stack_prefix followed by Name. Export names start with it, so
+	// the stacks of several environments can share a region.
+	StackName string
+	// Package is the import path of the package
+	Package string
+	// Dir is the package directory
+	Dir string
+	// Template is the stack's template
+	Template *wetwire.Template
+	// DependsOn names the stacks whose exports the stack imports
+	DependsOn []string
+}
+
+// stackPackage is a package of a build before its template is built.
+type stackPackage struct {
+	stack   *Stack
+	result  *discover.Result
+	imports map[string]bool
+}
+
+// Stacks discovers and builds the stacks of pattern, a package directory or
+// a "dir/..." pattern, and returns them in deployment order. Every package
+// of a "dir/..." pattern that declares resources, parameters or outputs is
+// a stack.
+func Stacks(pattern string) ([]*Stack, error) {
+	dirs, err := stackDirs(pattern)
+	if err != nil {
+		return nil, err
+	}
+	buildCfg, err := config.Load(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
+	var packages []*stackPackage
+	names := make(map[string]string)
+	var discoveryErrs []error
+	for _, dir := range dirs {
+		pkg, err := loadStackPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		discoveryErrs = append(discoveryErrs, pkg.result.Errors...)
+		if len(dirs) > 1 && !declaresStack(pkg.result) {
+			continue
+		}
+		if other, ok := names[pkg.stack.Name]; ok {
+			return nil, fmt.Errorf("packages %s and %s are both stack %s", other, pkg.stack.Package, pkg.stack.Name)
+		}
+		names[pkg.stack.Name] = pkg.stack.Package
+		pkg.stack.StackName = buildCfg.Build.StackPrefix + pkg.stack.Name
+		packages = append(packages, pkg)
+	}
+	if len(discoveryErrs) > 0 {
+		return nil, fmt.Errorf("discovery errors: %w", errors.Join(discoveryErrs...))
+	}
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no resources found in %s", pattern)
+	}
+
+	stacks := make([]*Stack, 0, len(packages))
+	for _, pkg := range packages {
+		var external []runner.ExternalVar
+		for _, other := range packages {
+			if !pkg.imports[other.stack.Package] {
+				continue
+			}
+			for name := range other.result.Resources {
+				external = append(external, runner.ExternalVar{ImportPath: other.stack.Package, Name: name})
+			}
+		}
+
+		tmpl, err := buildTemplate(pkg.stack.Dir, pkg.result, external)
+		if err != nil {
+			return nil, fmt.Errorf("stack %s: %w", pkg.stack.Name, err)
+		}
+		pkg.stack.Template = tmpl
+		stacks = append(stacks, pkg.stack)
+	}
+
+	stacks, err = linkStacks(stacks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aliases rename logical IDs after linking, so the exported outputs
+	// follow them
+	for _, stack := range stacks {
+		cfg, err := config.Load(stack.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
+		}
+		if err := Configure(stack.Template, cfg); err != nil {
+			return nil, fmt.Errorf("stack %s: %w", stack.Name, err)
+		}
+	}
+	return stacks, nil
+}
+
+// stackDirs returns the package directories of pattern.
+func stackDirs(pattern string) ([]string, error) {
+	root, recursive := strings.CutSuffix(pattern, "...")
+	if !recursive {
+		return []string{pattern}, nil
+	}
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		root = "."
+	}
+
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", root, err)
+	}
+	return dirs, nil
+}
+
+// loadStackPackage discovers the package in dir and reads its name and
+// imports.
+func loadStackPackage(dir string) (*stackPackage, error) {
+	result, err := discover.Discover(discover.Options{
+		Packages: []string{dir},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	pkg := &stackPackage{
+		stack:   &Stack{Dir: dir},
+		result:  result,
+		imports: make(map[string]bool),
+	}
+
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", dir, err)
+	}
+	if len(parsed) > 1 {
+		return nil, fmt.Errorf("%s holds packages %s; a stack directory must hold one package", dir, strings.Join(sortedKeys(parsed), ", "))
+	}
+	for name, p := range parsed {
+		pkg.stack.Name = name
+		for _, file := range p.Files {
+			for _, imp := range file.Imports {
+				if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+					pkg.imports[path] = true
+				}
+			}
+		}
+	}
+
+	if declaresStack(result) {
+		if pkg.stack.Package, err = runner.ImportPath(dir); err != nil {
+			return nil, err
+		}
+	}
+	return pkg, nil
+}
+
+// declaresStack reports whether a package declares resources, parameters
+// or outputs.
+func declaresStack(result *discover.Result) bool {
+	return len(result.Resources) > 0 || len(result.Parameters) > 0 || len(result.Outputs) > 0
+}
+
+// stackLinker rewires the references between stacks.
+type stackLinker struct {
+	byPackage map[string]*Stack
+	dependsOn map[*Stack]map[string]bool
+}
+
+// linkStacks replaces the references to resources of other stacks, Refs and
+// GetAtts of "importpath.Name", with Fn::ImportValue of an output exported
+// by the other stack, and returns the stacks in deployment order.
+func linkStacks(stacks []*Stack) ([]*Stack, error) {
+	l := &stackLinker{
+		byPackage: make(map[string]*Stack, len(stacks)),
+		dependsOn: make(map[*Stack]map[string]bool, len(stacks)),
+	}
+	for _, stack := range stacks {
+		l.byPackage[stack.Package] = stack
+		l.dependsOn[stack] = make(map[string]bool)
+	}
+
+	for _, stack := range stacks {
+		err := rewriteReferences(stack.Template, func(ref, attr string) (any, error) {
+			return l.importValue(stack, ref, attr)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("stack %s: %w", stack.Name, err)
+		}
+	}
+
+	for _, stack := range stacks {
+		stack.DependsOn = sortedKeys(l.dependsOn[stack])
+	}
+	return deploymentOrder(stacks)
+}
+
+// rewriteReferences replaces the Refs and GetAtts of qualified names in the
+// resources and outputs of tmpl with the value returned by rewrite.
+func rewriteReferences(tmpl *wetwire.Template, rewrite func(ref, attr string) (any, error)) error {
+	for _, id := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[id]
+		var err error
+		if def.Properties, err = rewriteMap(def.Properties, rewrite); err != nil {
+			return fmt.Errorf("Resources.%s: %w", id, err)
+		}
+		if def.Metadata, err = rewriteMap(def.Metadata, rewrite); err != nil {
+			return fmt.Errorf("Resources.%s: %w", id, err)
+		}
+		if def.ForEach != nil {
+			value, err := rewriteValue(def.ForEach, rewrite)
+			if err != nil {
+				return fmt.Errorf("Resources.%s: %w", id, err)
+			}
+			def.ForEach = value.([]any)
+		}
+		tmpl.Resources[id] = def
+	}
+	for _, id := range sortedKeys(tmpl.Outputs) {
+		output := tmpl.Outputs[id]
+		value, err := rewriteValue(output.Value, rewrite)
+		if err != nil {
+			return fmt.Errorf("Outputs.%s: %w", id, err)
+		}
+		output.Value = value
+		tmpl.Outputs[id] = output
+	}
+	return nil
+}
+
+// rewriteMap rewrites the references in the values of a map.
+func rewriteMap(m map[string]any, rewrite func(ref, attr string) (any, error)) (map[string]any, error) {
+	if m == nil {
+		return nil, nil
+	}
+	value, err := rewriteValue(m, rewrite)
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]any), nil
+}
+
+// rewriteValue rewrites the references in a value.
+func rewriteValue(value any, rewrite func(ref, attr string) (any, error)) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			if ref, attr, ok := externalReference(v); ok {
+				return rewrite(ref, attr)
+			}
+		}
+		result := make(map[string]any, len(v))
+		for key, val := range v {
+			rewritten, err := rewriteValue(val, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = rewritten
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, val := range v {
+			rewritten, err := rewriteValue(val, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = rewritten
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// externalReference returns the qualified resource name and attribute of a
+// Ref or GetAtt of a resource of another package.
+func externalReference(m map[string]any) (ref, attr string, ok bool) {
+	if name, isRef := m["Ref"].(string); isRef {
+		return name, "", isQualifiedName(name)
+	}
+	switch args := m["Fn::GetAtt"].(type) {
+	case []string:
+		if len(args) == 2 {
+			return args[0], args[1], isQualifiedName(args[0])
+		}
+	case []any:
+		if len(args) == 2 {
+			name, _ := args[0].(string)
+			attr, _ := args[1].(string)
+			return name, attr, isQualifiedName(name) && attr != ""
+		}
+	}
+	return "", "", false
+}
+
+// isQualifiedName reports whether a Ref or GetAtt target is the
+// "importpath.Name" of a resource of another package rather than a logical
+// ID or pseudo parameter.
+func isQualifiedName(name string) bool {
+	return strings.Contains(name, ".") && !strings.Contains(name, "::")
+}
+
+// splitQualifiedName splits "importpath.Name" into the import path and the
+// variable name.
+func splitQualifiedName(ref string) (pkgPath, name string) {
+	i := strings.LastIndex(ref, ".")
+	return ref[:i], ref[i+1:]
+}
+
+// importValue returns the Fn::ImportValue of a resource of another stack,
+// exporting it from that stack if needed.
+func (l *stackLinker) importValue(stack *Stack, ref, attr string) (any, error) {
+	pkgPath, name := splitQualifiedName(ref)
+	producer, ok := l.byPackage[pkgPath]
+	if !ok {
+		return nil, fmt.Errorf("references %s, which is not part of the build", ref)
+	}
+	if producer == stack {
+		return nil, fmt.Errorf("references %s of its own package", ref)
+	}
+	if _, ok := producer.Template.Resources[name]; !ok {
+		return nil, fmt.Errorf("references %s, which is not a resource of stack %s", ref, producer.Name)
+	}
+
+	exportName, err := export(producer, name, attr)
+	if err != nil {
+		return nil, err
+	}
+	l.dependsOn[stack][producer.Name] = true
+	return map[string]any{"Fn::ImportValue": exportName}, nil
+}
+
+// export returns the export name of a Ref, or a GetAtt if attr is set, of
+// a resource of the stack. An exported output with the same value is
+// reused; otherwise an output named after the resource and attribute is
+// added, exported as "<StackName>-<Resource>[-<Attr>]".
+func export(stack *Stack, name, attr string) (string, error) {
+	var value any = map[string]any{"Ref": name}
+	id := name
+	exportName := stack.StackName + "-" + name
+	if attr != "" {
+		value = map[string]any{"Fn::GetAtt": []string{name, attr}}
+		id = name + strings.ReplaceAll(attr, ".", "")
+		// Export names allow only alphanumerics, colons and hyphens
+		exportName += "-" + strings.ReplaceAll(attr, ".", "-")
+	}
+
+	tmpl := stack.Template
+	for _, outputID := range sortedKeys(tmpl.Outputs) {
+		output := tmpl.Outputs[outputID]
+		if output.Export != nil && sameValue(output.Value, value) {
+			return output.Export.Name, nil
+		}
+	}
+	if _, exists := tmpl.Outputs[id]; exists {
+		return "", fmt.Errorf("stack %s cannot export %s: output %s already exists", stack.Name, exportName, id)
+	}
+
+	if tmpl.Outputs == nil {
+		tmpl.Outputs = make(map[string]wetwire.Output)
+	}
+	output := wetwire.Output{Value: value}
+	output.Export = &struct {
+		Name string `json:"Name" yaml:"Name"`
+	}{Name: exportName}
+	tmpl.Outputs[id] = output
+	return exportName, nil
+}
+
+// sameValue compares two template values by their JSON form, so a GetAtt of
+// []string equals one of []any.
+func sameValue(a, b any) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
+
+// deploymentOrder sorts stacks so each comes after the stacks it depends
+// on, by name among independent stacks.
+func deploymentOrder(stacks []*Stack) ([]*Stack, error) {
+	byName := make(map[string]*Stack, len(stacks))
+	for _, stack := range stacks {
+		byName[stack.Name] = stack
+	}
+
+	var order []*Stack
+	state := make(map[string]int) // 1 visiting, 2 done
+	var visit func(stack *Stack, path []string) error
+	visit = func(stack *Stack, path []string) error {
+		switch state[stack.Name] {
+		case 1:
+			return fmt.Errorf("stacks depend on each other: %s", strings.Join(append(path, stack.Name), " -> "))
+		case 2:
+			return nil
+		}
+		state[stack.Name] = 1
+		for _, dep := range stack.DependsOn {
+			if err := visit(byName[dep], append(path, stack.Name)); err != nil {
+				return err
+			}
+		}
+		state[stack.Name] = 2
+		order = append(order, stack)
+		return nil
+	}
+
+	names := make([]string, 0, len(stacks))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(byName[name], nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,235 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func newStack(name string, resources map[string]wetwire.ResourceDef) *Stack {
+	return &Stack{
+		Name:      name,
+		StackName: name,
+		Package:   "example.com/infra/" + name,
+		Template: &wetwire.Template{
+			Resources: resources,
+		},
+	}
+}
+
+func TestLinkStacks(t *testing.T) {
+	network := newStack("network", map[string]wetwire.ResourceDef{
+		"Vpc": {Type: "AWS::EC2::VPC"},
+	})
+	data := newStack("data", map[string]wetwire.ResourceDef{
+		"Table": {Type: "AWS::DynamoDB::Table"},
+	})
+	app := newStack("app", map[string]wetwire.ResourceDef{
+		"AppGroup": {
+			Type: "AWS::EC2::SecurityGroup",
+			Properties: map[string]any{
+				"VpcId": map[string]any{"Ref": "example.com/infra/network.Vpc"},
+				"Tags": []any{
+					map[string]any{"Key": "table", "Value": map[string]any{"Fn::GetAtt": []string{"example.com/infra/data.Table", "Arn"}}},
+				},
+			},
+		},
+	})
+	app.Template.Outputs = map[string]wetwire.Output{
+		"Vpc": {Value: map[string]any{"Fn::GetAtt": []any{"example.com/infra/network.Vpc", "CidrBlock"}}},
+	}
+
+	stacks, err := linkStacks([]*Stack{app, data, network})
+	require.NoError(t, err)
+
+	var order []string
+	for _, stack := range stacks {
+		order = append(order, stack.Name)
+	}
+	assert.Equal(t, []string{"data", "network", "app"}, order)
+	assert.Equal(t, []string{"data", "network"}, app.DependsOn)
+	assert.Empty(t, network.DependsOn)
+
+	props := app.Template.Resources["AppGroup"].Properties
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "network-Vpc"}, props["VpcId"])
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "data-Table-Arn"}, props["Tags"].([]any)[0].(map[string]any)["Value"])
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "network-Vpc-CidrBlock"}, app.Template.Outputs["Vpc"].Value)
+
+	vpc := network.Template.Outputs["Vpc"]
+	require.NotNil(t, vpc.Export)
+	assert.Equal(t, "network-Vpc", vpc.Export.Name)
+	assert.Equal(t, map[string]any{"Ref": "Vpc"}, vpc.Value)
+
+	cidr := network.Template.Outputs["VpcCidrBlock"]
+	require.NotNil(t, cidr.Export)
+	assert.Equal(t, "network-Vpc-CidrBlock", cidr.Export.Name)
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []string{"Vpc", "CidrBlock"}}, cidr.Value)
+
+	assert.Equal(t, "data-Table-Arn", data.Template.Outputs["TableArn"].Export.Name)
+}
+
+func TestLinkStacks_ReusesExport(t *testing.T) {
+	network := newStack("network", map[string]wetwire.ResourceDef{
+		"Vpc": {Type: "AWS::EC2::VPC"},
+	})
+	output := wetwire.Output{Value: map[string]any{"Ref": "Vpc"}}
+	output.Export = &struct {
+		Name string `json:"Name" yaml:"Name"`
+	}{Name: "shared-vpc"}
+	network.Template.Outputs = map[string]wetwire.Output{"VpcId": output}
+
+	app := newStack("app", map[string]wetwire.ResourceDef{
+		"AppGroup": {
+			Type:       "AWS::EC2::SecurityGroup",
+			Properties: map[string]any{"VpcId": map[string]any{"Ref": "example.com/infra/network.Vpc"}},
+		},
+	})
+
+	_, err := linkStacks([]*Stack{app, network})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "shared-vpc"}, app.Template.Resources["AppGroup"].Properties["VpcId"])
+	assert.Len(t, network.Template.Outputs, 1)
+}
+
+func TestLinkStacks_StackPrefix(t *testing.T) {
+	network := newStack("network", map[string]wetwire.ResourceDef{
+		"Vpc": {Type: "AWS::EC2::VPC"},
+	})
+	network.StackName = "prod-network"
+	app := newStack("app", map[string]wetwire.ResourceDef{
+		"AppGroup": {
+			Type:       "AWS::EC2::SecurityGroup",
+			Properties: map[string]any{"VpcId": map[string]any{"Fn::GetAtt": []string{"example.com/infra/network.Vpc", "VpcId"}}},
+		},
+	})
+	app.StackName = "prod-app"
+
+	_, err := linkStacks([]*Stack{app, network})
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod-network-Vpc-VpcId", network.Template.Outputs["VpcVpcId"].Export.Name)
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "prod-network-Vpc-VpcId"}, app.Template.Resources["AppGroup"].Properties["VpcId"])
+	assert.Equal(t, []string{"network"}, app.DependsOn)
+}
+
+func TestLinkStacks_NestedAttribute(t *testing.T) {
+	data := newStack("data", map[string]wetwire.ResourceDef{
+		"Db": {Type: "AWS::RDS::DBInstance"},
+	})
+	app := newStack("app", map[string]wetwire.ResourceDef{
+		"Config": {
+			Type:       "AWS::SSM::Parameter",
+			Properties: map[string]any{"Value": map[string]any{"Fn::GetAtt": []string{"example.com/infra/data.Db", "Endpoint.Address"}}},
+		},
+	})
+
+	_, err := linkStacks([]*Stack{app, data})
+	require.NoError(t, err)
+
+	output, ok := data.Template.Outputs["DbEndpointAddress"]
+	require.True(t, ok)
+	require.NotNil(t, output.Export)
+	assert.Equal(t, "data-Db-Endpoint-Address", output.Export.Name)
+	assert.Regexp(t, `^[A-Za-z0-9:-]+$`, output.Export.Name)
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []string{"Db", "Endpoint.Address"}}, output.Value)
+	assert.Equal(t, map[string]any{"Fn::ImportValue": "data-Db-Endpoint-Address"}, app.Template.Resources["Config"].Properties["Value"])
+}
+
+func TestLinkStacks_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{name: "unknown package", ref: "example.com/infra/cache.Cluster", want: "references example.com/infra/cache.Cluster, which is not part of the build"},
+		{name: "unknown resource", ref: "example.com/infra/network.Subnet", want: "not a resource of stack network"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := newStack("network", map[string]wetwire.ResourceDef{
+				"Vpc": {Type: "AWS::EC2::VPC"},
+			})
+			app := newStack("app", map[string]wetwire.ResourceDef{
+				"AppGroup": {
+					Type:       "AWS::EC2::SecurityGroup",
+					Properties: map[string]any{"VpcId": map[string]any{"Ref": tt.ref}},
+				},
+			})
+
+			_, err := linkStacks([]*Stack{app, network})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "stack app: Resources.AppGroup: ")
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestLinkStacks_Cycle(t *testing.T) {
+	a := newStack("a", map[string]wetwire.ResourceDef{
+		"Topic": {Type: "AWS::SNS::Topic", Properties: map[string]any{"TopicName": map[string]any{"Ref": "example.com/infra/b.Queue"}}},
+	})
+	b := newStack("b", map[string]wetwire.ResourceDef{
+		"Queue": {Type: "AWS::SQS::Queue", Properties: map[string]any{"QueueName": map[string]any{"Ref": "example.com/infra/a.Topic"}}},
+	})
+
+	_, err := linkStacks([]*Stack{a, b})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stacks depend on each other: a -> b -> a")
+}
+
+func TestLinkStacks_KeepsLocalReferences(t *testing.T) {
+	app := newStack("app", map[string]wetwire.ResourceDef{
+		"Queue": {
+			Type: "AWS::SQS::Queue",
+			Properties: map[string]any{
+				"QueueName":     map[string]any{"Fn::Sub": "${AWS::StackName}-queue"},
+				"RedrivePolicy": map[string]any{"deadLetterTargetArn": map[string]any{"Fn::GetAtt": []string{"DeadLetters", "Arn"}}},
+				"Region":        map[string]any{"Ref": "AWS::Region"},
+			},
+		},
+	})
+
+	stacks, err := linkStacks([]*Stack{app})
+	require.NoError(t, err)
+	require.Len(t, stacks, 1)
+	assert.Equal(t, map[string]any{"Ref": "AWS::Region"}, app.Template.Resources["Queue"].Properties["Region"])
+	assert.Empty(t, app.Template.Outputs)
+	assert.Empty(t, app.DependsOn)
+}
+
+func TestStackDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"network", "app/api", ".git", "testdata", "_wetwire_runner"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	}
+
+	dirs, err := stackDirs(root + "/...")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		root,
+		filepath.Join(root, "app"),
+		filepath.Join(root, "app/api"),
+		filepath.Join(root, "network"),
+	}, dirs)
+
+	dirs, err = stackDirs(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{root}, dirs)
+}
+
+func TestLoadStackPackage_SeveralPackages(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "network.go"), []byte("package network\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.go"), []byte("package app\n"), 0644))
+
+	_, err := loadStackPackage(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "holds packages app, network; a stack directory must hold one package")
+}
@@ -0,0 +1,353 @@
+// Package config loads the wetwire.yaml project configuration.
+//
+// The file is discovered by walking up from the package being built, linted
+// or validated, stopping at the module root (the directory with go.mod). It
+// supplies defaults for lint rules and severities, per-directory overrides,
+// the template description, the default output format and optimizer
+// category filters. Command-line flags take precedence over the file.
+//
+// Example:
+//
+//	format: json
+//	build:
+//	  description: Production stack
+//	  aliases:
+//	    LogsBucket: LoggingBucket
+//	  assets:
+//	    bucket: my-artifacts
+//	    prefix: app/
+//	    repository: 123456789012.dkr.ecr.us-east-1.amazonaws.com/app
+//	  stack_prefix: prod-
+//	lint:
+//	  max_resources: 25
+//	  rules:
+//	    WAW004: false
+//	  severity:
+//	    WAW008: info
+//	  overrides:
+//	    - path: legacy
+//	      rules:
+//	        WAW009: false
+//	validate:
+//	  strict: true
+//	optimize:
+//	  categories: [security, cost]
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lex00/wetwire-aws-go/internal/lint"
+)
+
+// FileName is the name of the project configuration file.
+const FileName = "wetwire.yaml"
+
+// Config is the project configuration read from wetwire.yaml.
+type Config struct {
+	// Domain and Version are shared with the other wetwire domains.
+	Domain  string `yaml:"domain,omitempty"`
+	Version string `yaml:"version,omitempty"`
+
+	// Format is the default output format for command results: text, json or yaml.
+	Format string `yaml:"format,omitempty"`
+
+	Build    BuildConfig    `yaml:"build,omitempty"`
+	Lint     LintConfig     `yaml:"lint,omitempty"`
+	Validate ValidateConfig `yaml:"validate,omitempty"`
+	Optimize OptimizeConfig `yaml:"optimize,omitempty"`
+
+	// Path is the file the configuration was loaded from, empty if none was found.
+	Path string `yaml:"-"`
+}
+
+// BuildConfig configures template generation.
+type BuildConfig struct {
+	// Description is written to the template's Description section.
+	Description string `yaml:"description,omitempty"`
+	// Aliases maps a resource's Go variable name to the logical ID it keeps
+	// in the template, so renaming the variable does not replace the resource.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+	// Assets configures where packaged wetwire.Asset archives are stored.
+	Assets AssetsConfig `yaml:"assets,omitempty"`
+	// StackPrefix is prepended to the package name to form the deployed
+	// name of each stack of a multi-stack build, e.g. "prod-" deploys
+	// package network as prod-network and exports prod-network-Vpc.
+	StackPrefix string `yaml:"stack_prefix,omitempty"`
+}
+
+// AssetsConfig configures asset packaging.
+type AssetsConfig struct {
+	// Bucket is the S3 bucket of the assets. Without it, templates take
+	// the bucket and keys as parameters.
+	Bucket string `yaml:"bucket,omitempty"`
+	// Prefix is prepended to the S3 key of each asset.
+	Prefix string `yaml:"prefix,omitempty"`
+	// Repository is the ECR repository URI or name of image assets that
+	// name none. Without it, templates take the URI as a parameter.
+	Repository string `yaml:"repository,omitempty"`
+}
+
+// LintConfig configures the lint rules.
+type LintConfig struct {
+	// Rules enables (true) or disables (false) rules by ID.
+	Rules map[string]bool `yaml:"rules,omitempty"`
+	// Severity overrides the severity of a rule: error, warning or info.
+	Severity map[string]string `yaml:"severity,omitempty"`
+	// MaxResources is the WAW004 limit on resources per file.
+	MaxResources int `yaml:"max_resources,omitempty"`
+	// Overrides adjust rules and severities for a directory.
+	Overrides []LintOverride `yaml:"overrides,omitempty"`
+}
+
+// LintOverride adjusts lint rules for the files under Path.
+type LintOverride struct {
+	// Path is a directory relative to the configuration file.
+	Path     string            `yaml:"path"`
+	Rules    map[string]bool   `yaml:"rules,omitempty"`
+	Severity map[string]string `yaml:"severity,omitempty"`
+}
+
+// ValidateConfig configures template validation.
+type ValidateConfig struct {
+	// Strict reports unknown resource types and properties as errors.
+	Strict bool `yaml:"strict,omitempty"`
+}
+
+// OptimizeConfig configures the optimizer.
+type OptimizeConfig struct {
+	// Categories limits suggestions to these categories.
+	Categories []string `yaml:"categories,omitempty"`
+}
+
+// validFormats lists the output formats Format may name.
+var validFormats = map[string]bool{"text": true, "json": true, "yaml": true}
+
+// logicalID matches a valid CloudFormation logical ID.
+var logicalID = regexp.MustCompile(`^[A-Za-z0-9]{1,255}$`)
+
+// bucketName matches a valid S3 bucket name.
+var bucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// stackPrefix matches the start of a CloudFormation stack name.
+var stackPrefix = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*$`)
+
+// validCategories lists the optimizer categories.
+var validCategories = map[string]bool{
+	"all":         true,
+	"security":    true,
+	"cost":        true,
+	"performance": true,
+	"reliability": true,
+}
+
+// Load finds and loads the configuration for target, a package directory,
+// a Go file or a "dir/..." pattern. It returns an empty Config if no
+// wetwire.yaml is found.
+func Load(target string) (*Config, error) {
+	start := strings.TrimSuffix(strings.TrimSuffix(target, "..."), "/")
+	if start == "" {
+		start = "."
+	}
+	if info, err := os.Stat(start); err == nil && !info.IsDir() {
+		start = filepath.Dir(start)
+	}
+
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", target, err)
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return LoadFile(path)
+		}
+
+		// Stop at the module root so configs outside the project are ignored
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return &Config{}, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return &Config{}, nil
+		}
+		dir = parent
+	}
+}
+
+// LoadFile loads and validates the configuration file at path.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	cfg.Path = path
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validate checks rule IDs, severities, formats and categories.
+func (c *Config) validate() error {
+	var errs []string
+
+	if c.Format != "" && !validFormats[c.Format] {
+		errs = append(errs, fmt.Sprintf("format: invalid format %q (valid: text, json, yaml)", c.Format))
+	}
+
+	errs = append(errs, checkAliases(c.Build.Aliases)...)
+	if bucket := c.Build.Assets.Bucket; bucket != "" && !bucketName.MatchString(bucket) {
+		errs = append(errs, fmt.Sprintf("build.assets.bucket: invalid bucket name %q", bucket))
+	}
+	if strings.HasPrefix(c.Build.Assets.Prefix, "/") {
+		errs = append(errs, "build.assets.prefix: must not start with /")
+	}
+	if prefix := c.Build.StackPrefix; prefix != "" && !stackPrefix.MatchString(prefix) {
+		errs = append(errs, fmt.Sprintf("build.stack_prefix: invalid stack name prefix %q", prefix))
+	}
+
+	if c.Lint.MaxResources < 0 {
+		errs = append(errs, "lint.max_resources: must not be negative")
+	}
+	errs = append(errs, checkRules("lint", c.Lint.Rules, c.Lint.Severity)...)
+	for i, ov := range c.Lint.Overrides {
+		field := fmt.Sprintf("lint.overrides[%d]", i)
+		if ov.Path == "" {
+			errs = append(errs, field+".path: required")
+		} else if filepath.IsAbs(ov.Path) {
+			errs = append(errs, field+".path: must be relative to "+FileName)
+		}
+		errs = append(errs, checkRules(field, ov.Rules, ov.Severity)...)
+	}
+
+	for _, category := range c.Optimize.Categories {
+		if !validCategories[category] {
+			errs = append(errs, fmt.Sprintf("optimize.categories: invalid category %q (valid: all, security, cost, performance, reliability)", category))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checkAliases validates the logical IDs of the build aliases.
+func checkAliases(aliases map[string]string) []string {
+	var errs []string
+	used := make(map[string]string)
+	for _, name := range sortedKeys(aliases) {
+		id := aliases[name]
+		switch {
+		case !logicalID.MatchString(id):
+			errs = append(errs, fmt.Sprintf("build.aliases.%s: invalid logical ID %q (letters and digits only)", name, id))
+		case used[id] != "":
+			errs = append(errs, fmt.Sprintf("build.aliases.%s: logical ID %q is also used for %s", name, id, used[id]))
+		default:
+			used[id] = name
+		}
+	}
+	return errs
+}
+
+// checkRules validates the rule IDs and severities of a lint section.
+func checkRules(field string, rules map[string]bool, severity map[string]string) []string {
+	known := make(map[string]bool)
+	for _, rule := range lint.AllRules() {
+		known[rule.ID()] = true
+	}
+
+	var errs []string
+	for _, id := range sortedKeys(rules) {
+		if !known[id] {
+			errs = append(errs, fmt.Sprintf("%s.rules: unknown rule %q", field, id))
+		}
+	}
+	for _, id := range sortedKeys(severity) {
+		if !known[id] {
+			errs = append(errs, fmt.Sprintf("%s.severity: unknown rule %q", field, id))
+		}
+		if _, err := lint.ParseSeverity(severity[id]); err != nil {
+			errs = append(errs, fmt.Sprintf("%s.severity.%s: %v", field, id, err))
+		}
+	}
+	return errs
+}
+
+// Dir returns the directory containing the configuration file.
+func (c *Config) Dir() string {
+	if c.Path == "" {
+		return ""
+	}
+	return filepath.Dir(c.Path)
+}
+
+// LintOptions returns lint options for the configuration. Callers add
+// command-line settings, such as disabled rules, on top.
+func (c *Config) LintOptions() lint.Options {
+	opts := lint.Options{
+		MaxResources: c.Lint.MaxResources,
+		Severity:     severities(c.Lint.Severity),
+	}
+
+	if len(c.Lint.Rules) > 0 {
+		opts.Overrides = append(opts.Overrides, lint.Override{Dir: c.Dir(), Rules: c.Lint.Rules})
+	}
+	for _, ov := range c.Lint.Overrides {
+		opts.Overrides = append(opts.Overrides, lint.Override{
+			Dir:      filepath.Join(c.Dir(), ov.Path),
+			Rules:    ov.Rules,
+			Severity: severities(ov.Severity),
+		})
+	}
+
+	return opts
+}
+
+// severities parses a validated severity map.
+func severities(m map[string]string) map[string]lint.Severity {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]lint.Severity, len(m))
+	for id, s := range m {
+		if sev, err := lint.ParseSeverity(s); err == nil {
+			out[id] = sev
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
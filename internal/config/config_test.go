@@ -0,0 +1,188 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lex00/wetwire-aws-go/internal/lint"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoad_WalksUpToConfig(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(root, FileName), `
+format: json
+build:
+  description: Production stack
+  aliases:
+    LogsBucket: LoggingBucket
+  assets:
+    bucket: my-artifacts
+    prefix: app/
+    repository: app
+lint:
+  max_resources: 25
+  rules:
+    WAW004: false
+  severity:
+    WAW008: info
+  overrides:
+    - path: infra/legacy
+      rules:
+        WAW009: false
+validate:
+  strict: true
+optimize:
+  categories: [security, cost]
+`)
+	infra := filepath.Join(root, "infra")
+	require.NoError(t, os.MkdirAll(infra, 0755))
+
+	cfg, err := Load(infra + "/...")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(root, FileName), cfg.Path)
+	assert.Equal(t, root, cfg.Dir())
+	assert.Equal(t, "json", cfg.Format)
+	assert.Equal(t, "Production stack", cfg.Build.Description)
+	assert.Equal(t, map[string]string{"LogsBucket": "LoggingBucket"}, cfg.Build.Aliases)
+	assert.Equal(t, AssetsConfig{Bucket: "my-artifacts", Prefix: "app/", Repository: "app"}, cfg.Build.Assets)
+	assert.Equal(t, 25, cfg.Lint.MaxResources)
+	assert.Equal(t, map[string]bool{"WAW004": false}, cfg.Lint.Rules)
+	assert.True(t, cfg.Validate.Strict)
+	assert.Equal(t, []string{"security", "cost"}, cfg.Optimize.Categories)
+}
+
+func TestLoad_StopsAtModuleRoot(t *testing.T) {
+	outer := t.TempDir()
+	writeFile(t, filepath.Join(outer, FileName), "format: json\n")
+	project := filepath.Join(outer, "project")
+	writeFile(t, filepath.Join(project, "go.mod"), "module example.com/app\n")
+
+	cfg, err := Load(project)
+	require.NoError(t, err)
+
+	assert.Empty(t, cfg.Path)
+	assert.Empty(t, cfg.Format)
+}
+
+func TestLoad_FromFile(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+	writeFile(t, filepath.Join(root, FileName), "build:\n  description: From file\n")
+	writeFile(t, filepath.Join(root, "main.go"), "package main\n")
+
+	cfg, err := Load(filepath.Join(root, "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "From file", cfg.Build.Description)
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		message string
+	}{
+		{name: "unknown field", content: "lint:\n  disabled: [WAW001]\n", message: "field disabled not found"},
+		{name: "unknown rule", content: "lint:\n  rules:\n    WAW999: false\n", message: `lint.rules: unknown rule "WAW999"`},
+		{name: "bad severity", content: "lint:\n  severity:\n    WAW001: fatal\n", message: `lint.severity.WAW001: invalid severity "fatal"`},
+		{name: "bad format", content: "format: xml\n", message: `invalid format "xml"`},
+		{name: "bad category", content: "optimize:\n  categories: [speed]\n", message: `invalid category "speed"`},
+		{name: "bad alias", content: "build:\n  aliases:\n    Bucket: my-bucket\n", message: `build.aliases.Bucket: invalid logical ID "my-bucket"`},
+		{name: "duplicate alias", content: "build:\n  aliases:\n    A: Old\n    B: Old\n", message: `build.aliases.B: logical ID "Old" is also used for A`},
+		{name: "bad bucket", content: "build:\n  assets:\n    bucket: My_Bucket\n", message: `build.assets.bucket: invalid bucket name "My_Bucket"`},
+		{name: "absolute prefix", content: "build:\n  assets:\n    prefix: /app\n", message: "build.assets.prefix: must not start with /"},
+		{name: "bad stack prefix", content: "build:\n  stack_prefix: prod_\n", message: `build.stack_prefix: invalid stack name prefix "prod_"`},
+		{name: "override without path", content: "lint:\n  overrides:\n    - rules:\n        WAW001: false\n", message: "lint.overrides[0].path: required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			writeFile(t, path, tt.content)
+
+			_, err := LoadFile(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestLoadFile_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, "")
+
+	cfg, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, cfg.Path)
+}
+
+func TestConfig_LintOptions(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), `
+lint:
+  max_resources: 3
+  rules:
+    WAW001: false
+  severity:
+    WAW002: info
+  overrides:
+    - path: legacy
+      rules:
+        WAW001: true
+      severity:
+        WAW002: error
+`)
+	src := `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var Bucket = s3.Bucket{
+	BucketName: "AWS::Region",
+	Tags:       map[string]any{"Ref": "Other"},
+}
+`
+	writeFile(t, filepath.Join(root, "app", "main.go"), src)
+	writeFile(t, filepath.Join(root, "legacy", "main.go"), src)
+
+	cfg, err := LoadFile(filepath.Join(root, FileName))
+	require.NoError(t, err)
+	opts := cfg.LintOptions()
+	assert.Equal(t, 3, opts.MaxResources)
+
+	severities := func(result lint.Result) map[string]lint.Severity {
+		out := make(map[string]lint.Severity)
+		for _, issue := range result.Issues {
+			out[issue.Rule] = issue.Severity
+		}
+		return out
+	}
+
+	app, err := lint.LintPackage(filepath.Join(root, "app"), opts)
+	require.NoError(t, err)
+	got := severities(app)
+	assert.NotContains(t, got, "WAW001")
+	assert.Equal(t, lint.SeverityInfo, got["WAW002"])
+
+	legacy, err := lint.LintPackage(filepath.Join(root, "legacy"), opts)
+	require.NoError(t, err)
+	got = severities(legacy)
+	assert.Contains(t, got, "WAW001")
+	assert.Equal(t, lint.SeverityError, got["WAW002"])
+
+	// Rules disabled on the command line win over the file
+	opts.DisabledRules = []string{"WAW001"}
+	legacy, err = lint.LintPackage(filepath.Join(root, "legacy"), opts)
+	require.NoError(t, err)
+	assert.NotContains(t, severities(legacy), "WAW001")
+}
@@ -0,0 +1,213 @@
+package differ
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// Change kinds of wetwire.PropertyChange.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+	// ChangeMoved is a list element found at another index; only reported
+	// with IgnoreOrder.
+	ChangeMoved = "moved"
+)
+
+// diffValues returns the changes between two values, with paths as JSON
+// pointers below path. Maps are compared key by key and lists element by
+// element; with IgnoreOrder, list elements are matched by value wherever
+// they are and reported as moved when their index changed.
+func diffValues(path string, old, new any, opts Options) []wetwire.PropertyChange {
+	var changes []wetwire.PropertyChange
+
+	oldMap, oldIsMap := old.(map[string]any)
+	newMap, newIsMap := new.(map[string]any)
+	oldList, oldIsList := old.([]any)
+	newList, newIsList := new.([]any)
+
+	switch {
+	case oldIsMap && newIsMap:
+		for _, key := range unionKeys(oldMap, newMap) {
+			keyPath := path + "/" + escapePointer(key)
+			oldVal, inOld := oldMap[key]
+			newVal, inNew := newMap[key]
+			switch {
+			case !inOld:
+				changes = append(changes, wetwire.PropertyChange{Path: keyPath, Kind: ChangeAdded, New: newVal})
+			case !inNew:
+				changes = append(changes, wetwire.PropertyChange{Path: keyPath, Kind: ChangeRemoved, Old: oldVal})
+			default:
+				changes = append(changes, diffValues(keyPath, oldVal, newVal, opts)...)
+			}
+		}
+
+	case oldIsList && newIsList && opts.IgnoreOrder:
+		changes = diffUnorderedLists(path, oldList, newList)
+
+	case oldIsList && newIsList:
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			indexPath := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(oldList):
+				changes = append(changes, wetwire.PropertyChange{Path: indexPath, Kind: ChangeAdded, New: newList[i]})
+			case i >= len(newList):
+				changes = append(changes, wetwire.PropertyChange{Path: indexPath, Kind: ChangeRemoved, Old: oldList[i]})
+			default:
+				changes = append(changes, diffValues(indexPath, oldList[i], newList[i], opts)...)
+			}
+		}
+
+	case !reflect.DeepEqual(old, new):
+		changes = append(changes, wetwire.PropertyChange{Path: path, Kind: ChangeModified, Old: old, New: new})
+	}
+
+	return changes
+}
+
+// diffUnorderedLists matches the elements of two lists by value. Matched
+// elements at another index are moved; the rest are removed or added.
+func diffUnorderedLists(path string, oldList, newList []any) []wetwire.PropertyChange {
+	var changes []wetwire.PropertyChange
+	matched := make([]bool, len(newList))
+
+	for i, oldVal := range oldList {
+		found := -1
+		// Prefer the element at the same index
+		if i < len(newList) && !matched[i] && equalIgnoringOrder(oldVal, newList[i]) {
+			found = i
+		}
+		for j := 0; found < 0 && j < len(newList); j++ {
+			if !matched[j] && equalIgnoringOrder(oldVal, newList[j]) {
+				found = j
+			}
+		}
+
+		oldPath := path + "/" + strconv.Itoa(i)
+		switch {
+		case found < 0:
+			changes = append(changes, wetwire.PropertyChange{Path: oldPath, Kind: ChangeRemoved, Old: oldVal})
+		case found != i:
+			matched[found] = true
+			changes = append(changes, wetwire.PropertyChange{
+				Path: path + "/" + strconv.Itoa(found),
+				Kind: ChangeMoved,
+				From: oldPath,
+				New:  newList[found],
+			})
+		default:
+			matched[found] = true
+		}
+	}
+
+	for j, newVal := range newList {
+		if !matched[j] {
+			changes = append(changes, wetwire.PropertyChange{Path: path + "/" + strconv.Itoa(j), Kind: ChangeAdded, New: newVal})
+		}
+	}
+	return changes
+}
+
+// equalIgnoringOrder compares two values, treating lists as multisets.
+func equalIgnoringOrder(a, b any) bool {
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		used := make([]bool, len(bv))
+	next:
+		for _, x := range av {
+			for j, y := range bv {
+				if !used[j] && equalIgnoringOrder(x, y) {
+					used[j] = true
+					continue next
+				}
+			}
+			return false
+		}
+		return true
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, exists := bv[k]
+			if !exists || !equalIgnoringOrder(x, y) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// sortChanges orders changes by path so output is stable.
+func sortChanges(changes []wetwire.PropertyChange) []wetwire.PropertyChange {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return comparePointers(changes[i].Path, changes[j].Path) < 0
+	})
+	return changes
+}
+
+// comparePointers orders JSON pointers segment by segment, comparing list
+// indexes numerically so "/Tags/2" sorts before "/Tags/10".
+func comparePointers(a, b string) int {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		if aErr == nil && bErr == nil {
+			return an - bn
+		}
+		return strings.Compare(as[i], bs[i])
+	}
+	return len(as) - len(bs)
+}
+
+// escapePointer escapes a key for use as a JSON pointer segment (RFC 6901).
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// unionKeys returns the keys of both maps in sorted order.
+func unionKeys(a, b map[string]any) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]any{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toValue converts a typed value to its generic JSON form, so templates
+// loaded from JSON and YAML and built in Go compare alike.
+func toValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
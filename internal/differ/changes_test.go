@@ -0,0 +1,122 @@
+package differ
+
+import (
+	"reflect"
+	"testing"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func TestDiffValues(t *testing.T) {
+	old := map[string]any{
+		"BucketName": "logs",
+		"Tags": []any{
+			map[string]any{"Key": "team", "Value": "data"},
+		},
+		"Versioning": map[string]any{"Status": "Enabled"},
+		"a/b~c":      "x",
+	}
+	new := map[string]any{
+		"BucketName": "logs-v2",
+		"Tags": []any{
+			map[string]any{"Key": "team", "Value": "platform"},
+			map[string]any{"Key": "env", "Value": "prod"},
+		},
+		"a/b~c": "x",
+		"Cors":  true,
+	}
+
+	got := sortChanges(diffValues("/Properties", old, new, Options{}))
+	want := []wetwire.PropertyChange{
+		{Path: "/Properties/BucketName", Kind: ChangeModified, Old: "logs", New: "logs-v2"},
+		{Path: "/Properties/Cors", Kind: ChangeAdded, New: true},
+		{Path: "/Properties/Tags/0/Value", Kind: ChangeModified, Old: "data", New: "platform"},
+		{Path: "/Properties/Tags/1", Kind: ChangeAdded, New: map[string]any{"Key": "env", "Value": "prod"}},
+		{Path: "/Properties/Versioning", Kind: ChangeRemoved, Old: map[string]any{"Status": "Enabled"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffValues() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiffValues_EscapesPointers(t *testing.T) {
+	got := diffValues("", map[string]any{"a/b~c": 1.0}, map[string]any{"a/b~c": 2.0}, Options{})
+	if len(got) != 1 || got[0].Path != "/a~1b~0c" {
+		t.Errorf("diffValues() = %+v, want path /a~1b~0c", got)
+	}
+}
+
+func TestDiffValues_IgnoreOrder(t *testing.T) {
+	old := []any{"a", "b", "c", "d"}
+	new := []any{"c", "b", "a", "e"}
+
+	got := sortChanges(diffValues("/Subnets", old, new, Options{IgnoreOrder: true}))
+	want := []wetwire.PropertyChange{
+		{Path: "/Subnets/0", Kind: ChangeMoved, From: "/Subnets/2", New: "c"},
+		{Path: "/Subnets/2", Kind: ChangeMoved, From: "/Subnets/0", New: "a"},
+		{Path: "/Subnets/3", Kind: ChangeRemoved, Old: "d"},
+		{Path: "/Subnets/3", Kind: ChangeAdded, New: "e"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffValues() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestEqualIgnoringOrder(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want bool
+	}{
+		{[]any{"a", "b"}, []any{"b", "a"}, true},
+		{[]any{"a", "a"}, []any{"a", "b"}, false},
+		{[]any{"a"}, []any{"a", "a"}, false},
+		{map[string]any{"L": []any{1.0, 2.0}}, map[string]any{"L": []any{2.0, 1.0}}, true},
+		{map[string]any{"K": "v"}, map[string]any{"K": "w"}, false},
+		{"x", "x", true},
+	}
+	for _, tt := range tests {
+		if got := equalIgnoringOrder(tt.a, tt.b); got != tt.want {
+			t.Errorf("equalIgnoringOrder(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestComparePointers(t *testing.T) {
+	if comparePointers("/Tags/2", "/Tags/10") >= 0 {
+		t.Error("/Tags/2 should sort before /Tags/10")
+	}
+	if comparePointers("/Properties/A", "/Properties/B") >= 0 {
+		t.Error("/Properties/A should sort before /Properties/B")
+	}
+	if comparePointers("/Properties", "/Properties/A") >= 0 {
+		t.Error("a parent should sort before its children")
+	}
+}
+
+func TestComparePropertyChanges(t *testing.T) {
+	t1 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Bucket": {Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": "logs"}, DependsOn: []string{"Key"}},
+		"Queue":  {Type: "AWS::SQS::Queue", Properties: map[string]any{"Tags": []any{"a", "b"}}},
+	}}
+	t2 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Bucket": {Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": "logs-v2"}},
+		"Queue":  {Type: "AWS::SQS::Queue", Properties: map[string]any{"Tags": []any{"b", "a"}}},
+	}}
+
+	result, err := Compare(t1, t2, Options{IgnoreOrder: true})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	// Reordering alone is not a difference with IgnoreOrder
+	if len(result.Diff.Modified) != 1 {
+		t.Fatalf("Modified = %d, want 1", len(result.Diff.Modified))
+	}
+	want := []wetwire.PropertyChange{
+		{Path: "/DependsOn", Kind: ChangeRemoved, Old: []any{"Key"}},
+		{Path: "/Properties/BucketName", Kind: ChangeModified, Old: "logs", New: "logs-v2"},
+	}
+	if got := result.Diff.Modified[0].PropertyChanges; !reflect.DeepEqual(got, want) {
+		t.Errorf("PropertyChanges =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/schema"
 	coredomain "github.com/lex00/wetwire-core-go/domain"
 	"gopkg.in/yaml.v3"
 )
@@ -45,49 +46,129 @@ func (d *CFDiffer) Diff(ctx *coredomain.Context, file1, file2 string, opts cored
 
 	// Convert entries
 	for _, e := range result.Diff.Added {
-		domainResult.Entries = append(domainResult.Entries, coredomain.DiffEntry{
-			Resource: e.Resource,
-			Type:     e.Type,
-			Action:   "added",
-			Changes:  e.Changes,
-		})
+		domainResult.Entries = append(domainResult.Entries, domainEntry(e, "added"))
 	}
 	for _, e := range result.Diff.Removed {
-		domainResult.Entries = append(domainResult.Entries, coredomain.DiffEntry{
-			Resource: e.Resource,
-			Type:     e.Type,
-			Action:   "removed",
-			Changes:  e.Changes,
-		})
+		domainResult.Entries = append(domainResult.Entries, domainEntry(e, "removed"))
 	}
 	for _, e := range result.Diff.Modified {
-		domainResult.Entries = append(domainResult.Entries, coredomain.DiffEntry{
-			Resource: e.Resource,
-			Type:     e.Type,
-			Action:   "modified",
-			Changes:  e.Changes,
-		})
+		domainResult.Entries = append(domainResult.Entries, domainEntry(e, "modified"))
 	}
 
 	return domainResult, nil
 }
 
+// domainEntry converts a diff entry to a domain entry. Entries outside the
+// Resources section are named by section, e.g. "Outputs.VpcId"; breaking
+// changes and replacements are noted in the changes.
+func domainEntry(e wetwire.DiffEntry, action string) coredomain.DiffEntry {
+	name := e.Resource
+	if e.Section != SectionResources {
+		name = e.Section + "." + e.Resource
+	}
+	changes := e.Changes
+	if e.Replacement == ReplacementAlways || e.Replacement == ReplacementConditional || e.Replacement == ReplacementUnknown {
+		changes = append([]string{"replacement: " + e.Replacement}, changes...)
+	}
+	if e.Breaking {
+		changes = append([]string{"breaking: other stacks may import this export"}, changes...)
+	}
+	return coredomain.DiffEntry{
+		Resource: name,
+		Type:     e.Type,
+		Action:   action,
+		Changes:  changes,
+	}
+}
+
 // Options configures the differ.
 type Options struct {
 	// IgnoreOrder ignores array element order in comparisons
 	IgnoreOrder bool
+	// Spec provides property update types; defaults to the bundled
+	// specification
+	Spec *schema.Spec
+	// RenameThreshold is the similarity from which a removed and an added
+	// resource are reported as a probable rename; defaults to
+	// DefaultRenameThreshold
+	RenameThreshold float64
 }
 
+// Replacement values of wetwire.DiffEntry.
+const (
+	// ReplacementNone means the resource is updated in place.
+	ReplacementNone = "in-place"
+	// ReplacementConditional means the resource may be replaced, depending
+	// on the old and new values of a Conditional property.
+	ReplacementConditional = "may-replace"
+	// ReplacementAlways means the resource will be replaced.
+	ReplacementAlways = "will-replace"
+	// ReplacementUnknown means a changed property has no known update type,
+	// so the resource may be replaced.
+	ReplacementUnknown = "unknown"
+)
+
+// UpdateUnknown is the update type recorded for a changed property that
+// neither the specification nor the hand-written table describes.
+const UpdateUnknown = "Unknown"
+
 // Result contains the difference between two templates.
 type Result struct {
 	Diff    wetwire.TemplateDiff
 	Summary wetwire.DiffSummary
 }
 
+// Template sections, in the order they are reported.
+const (
+	SectionTransform  = "Transform"
+	SectionParameters = "Parameters"
+	SectionMappings   = "Mappings"
+	SectionConditions = "Conditions"
+	SectionResources  = "Resources"
+	SectionOutputs    = "Outputs"
+)
+
+// Sections lists the template sections in report order.
+var Sections = []string{
+	SectionTransform,
+	SectionParameters,
+	SectionMappings,
+	SectionConditions,
+	SectionResources,
+	SectionOutputs,
+}
+
 // Compare compares two CloudFormation templates and returns differences.
+//
+// Every template section is compared. Removing an exported output, or
+// removing or renaming its export, is flagged as breaking since other stacks
+// may import the export. Removed and added resources of the same type with
+// near-identical properties are reported as probable renames.
 func Compare(template1, template2 *wetwire.Template, opts Options) (*Result, error) {
 	result := &Result{}
 
+	spec := opts.Spec
+	if spec == nil {
+		var err error
+		if spec, err = schema.BundledSpec(); err != nil {
+			return nil, err
+		}
+	}
+
+	compareTransforms(&result.Diff, template1.Transform, template2.Transform)
+
+	params1, err := toSection(template1.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	params2, err := toSection(template2.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	compareSection(&result.Diff, SectionParameters, params1, params2, opts)
+	compareSection(&result.Diff, SectionMappings, template1.Mappings, template2.Mappings, opts)
+	compareSection(&result.Diff, SectionConditions, template1.Conditions, template2.Conditions, opts)
+
 	// Build resource maps
 	res1 := template1.Resources
 	res2 := template2.Resources
@@ -96,6 +177,7 @@ func Compare(template1, template2 *wetwire.Template, opts Options) (*Result, err
 	for name, def := range res2 {
 		if _, exists := res1[name]; !exists {
 			result.Diff.Added = append(result.Diff.Added, wetwire.DiffEntry{
+				Section:  SectionResources,
 				Resource: name,
 				Type:     def.Type,
 			})
@@ -106,6 +188,7 @@ func Compare(template1, template2 *wetwire.Template, opts Options) (*Result, err
 	for name, def := range res1 {
 		if _, exists := res2[name]; !exists {
 			result.Diff.Removed = append(result.Diff.Removed, wetwire.DiffEntry{
+				Section:  SectionResources,
 				Resource: name,
 				Type:     def.Type,
 			})
@@ -117,15 +200,32 @@ func Compare(template1, template2 *wetwire.Template, opts Options) (*Result, err
 		if def2, exists := res2[name]; exists {
 			changes := compareResources(name, def1, def2, opts)
 			if len(changes) > 0 {
+				propertyChanges, err := resourceChanges(def1, def2, opts)
+				if err != nil {
+					return nil, err
+				}
+				replacement, updateTypes := classifyUpdate(def1, def2, spec, opts)
 				result.Diff.Modified = append(result.Diff.Modified, wetwire.DiffEntry{
-					Resource: name,
-					Type:     def1.Type,
-					Changes:  changes,
+					Section:         SectionResources,
+					Resource:        name,
+					Type:            def1.Type,
+					Changes:         changes,
+					Replacement:     replacement,
+					UpdateTypes:     updateTypes,
+					PropertyChanges: propertyChanges,
 				})
 			}
 		}
 	}
 
+	if err := detectRenames(&result.Diff, res1, res2, opts); err != nil {
+		return nil, err
+	}
+
+	if err := compareOutputs(&result.Diff, template1.Outputs, template2.Outputs, opts); err != nil {
+		return nil, err
+	}
+
 	// Sort entries for consistent output
 	sortEntries(result.Diff.Added)
 	sortEntries(result.Diff.Removed)
@@ -136,8 +236,19 @@ func Compare(template1, template2 *wetwire.Template, opts Options) (*Result, err
 		Added:    len(result.Diff.Added),
 		Removed:  len(result.Diff.Removed),
 		Modified: len(result.Diff.Modified),
+		Renamed:  len(result.Diff.Renames),
 	}
 	result.Summary.Total = result.Summary.Added + result.Summary.Removed + result.Summary.Modified
+	for _, entries := range [][]wetwire.DiffEntry{result.Diff.Added, result.Diff.Removed, result.Diff.Modified} {
+		for _, e := range entries {
+			if e.Breaking {
+				result.Summary.Breaking++
+			}
+			if e.Replacement == ReplacementAlways || e.Replacement == ReplacementConditional {
+				result.Summary.Replaced++
+			}
+		}
+	}
 
 	return result, nil
 }
@@ -168,8 +279,13 @@ func LoadTemplate(path string) (*wetwire.Template, error) {
 
 	// Try JSON first
 	if err := json.Unmarshal(data, &template); err != nil {
-		// Try YAML
-		if err := yaml.Unmarshal(data, &template); err != nil {
+		// Try YAML, expanding short-form intrinsics such as !Ref
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse as JSON or YAML: %w", err)
+		}
+		ExpandShortForms(&doc)
+		if err := doc.Decode(&template); err != nil {
 			return nil, fmt.Errorf("failed to parse as JSON or YAML: %w", err)
 		}
 	}
@@ -195,9 +311,162 @@ func compareResources(name string, def1, def2 wetwire.ResourceDef, opts Options)
 		changes = append(changes, "DependsOn changed")
 	}
 
+	// Compare the resource attributes; a changed DeletionPolicy or a removed
+	// Condition deletes or keeps data without touching a property
+	for _, attr := range []struct {
+		name       string
+		val1, val2 any
+	}{
+		{"Condition", def1.Condition, def2.Condition},
+		{"DeletionPolicy", def1.DeletionPolicy, def2.DeletionPolicy},
+		{"UpdateReplacePolicy", def1.UpdateReplacePolicy, def2.UpdateReplacePolicy},
+		{"Metadata", def1.Metadata, def2.Metadata},
+		{"CreationPolicy", def1.CreationPolicy, def2.CreationPolicy},
+		{"UpdatePolicy", def1.UpdatePolicy, def2.UpdatePolicy},
+	} {
+		if attributeEqual(attr.val1, attr.val2, opts) {
+			continue
+		}
+		s1, ok1 := attributeString(attr.val1)
+		s2, ok2 := attributeString(attr.val2)
+		if ok1 && ok2 {
+			changes = append(changes, fmt.Sprintf("%s changed: %s → %s", attr.name, s1, s2))
+		} else {
+			changes = append(changes, attr.name+" changed")
+		}
+	}
+
+	// Compare the arguments of Fn::ForEach loops
+	if !deepEqual(def1.ForEach, def2.ForEach, opts) {
+		changes = append(changes, "Fn::ForEach changed")
+	}
+
 	return changes
 }
 
+// attributeEqual compares resource attribute values, treating nil and
+// empty maps alike.
+func attributeEqual(a, b any, opts Options) bool {
+	if isEmptyAttribute(a) && isEmptyAttribute(b) {
+		return true
+	}
+	return deepEqual(a, b, opts)
+}
+
+// isEmptyAttribute reports whether a resource attribute is unset.
+func isEmptyAttribute(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// attributeString returns a policy name, or "(none)" when it is unset, and
+// reports false for intrinsic functions and objects.
+func attributeString(v any) (string, bool) {
+	if isEmptyAttribute(v) {
+		return "(none)", true
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// resourceChanges returns the structured changes between two definitions
+// of a resource, with paths such as "/Properties/BucketName".
+func resourceChanges(def1, def2 wetwire.ResourceDef, opts Options) ([]wetwire.PropertyChange, error) {
+	val1, err := toValue(def1)
+	if err != nil {
+		return nil, err
+	}
+	val2, err := toValue(def2)
+	if err != nil {
+		return nil, err
+	}
+	return sortChanges(diffValues("", val1, val2, opts)), nil
+}
+
+// classifyUpdate tells how CloudFormation would apply the changes to a
+// resource, from the update types of its modified properties. Properties
+// without a known update type are reported as UpdateUnknown and, unless
+// another property decides the replacement, make it ReplacementUnknown;
+// changing the resource type always replaces it.
+func classifyUpdate(def1, def2 wetwire.ResourceDef, spec *schema.Spec, opts Options) (string, map[string]string) {
+	replacement := ReplacementNone
+	if def1.Type != def2.Type {
+		replacement = ReplacementAlways
+	}
+
+	updateTypes := make(map[string]string)
+	for _, key := range modifiedKeys(def1.Properties, def2.Properties, opts) {
+		updateType := spec.UpdateType(def1.Type, key)
+		if updateType == "" {
+			updateType = UpdateUnknown
+		}
+		updateTypes[key] = updateType
+
+		switch {
+		case updateType == schema.UpdateImmutable:
+			replacement = ReplacementAlways
+		case updateType == schema.UpdateConditional && (replacement == ReplacementNone || replacement == ReplacementUnknown):
+			replacement = ReplacementConditional
+		case updateType == UpdateUnknown && replacement == ReplacementNone:
+			replacement = ReplacementUnknown
+		}
+	}
+	if len(updateTypes) == 0 {
+		updateTypes = nil
+	}
+	return replacement, updateTypes
+}
+
+// modifiedKeys returns the keys added, removed or changed between two maps.
+func modifiedKeys(props1, props2 map[string]any, opts Options) []string {
+	var keys []string
+	for key, val2 := range props2 {
+		if val1, exists := props1[key]; !exists || !deepEqual(val1, val2, opts) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range props1 {
+		if _, exists := props2[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Replacements returns the modified resources that will or may be replaced,
+// except those allowed by logical name or resource type. Resources with
+// changed properties of unknown update type are included only when strict
+// is set.
+func Replacements(result *Result, allow []string, strict bool) []wetwire.DiffEntry {
+	allowed := make(map[string]bool, len(allow))
+	for _, a := range allow {
+		allowed[a] = true
+	}
+
+	var replaced []wetwire.DiffEntry
+	for _, e := range result.Diff.Modified {
+		if e.Replacement == ReplacementNone || e.Replacement == "" {
+			continue
+		}
+		if e.Replacement == ReplacementUnknown && !strict {
+			continue
+		}
+		if allowed[e.Resource] || allowed[e.Type] {
+			continue
+		}
+		replaced = append(replaced, e)
+	}
+	return replaced
+}
+
 // compareProperties recursively compares property maps.
 func compareProperties(prefix string, props1, props2 map[string]any, opts Options) []string {
 	var changes []string
@@ -234,35 +503,14 @@ func compareProperties(prefix string, props1, props2 map[string]any, opts Option
 	return changes
 }
 
-// deepEqual compares two values deeply, optionally ignoring order.
+// deepEqual compares two values deeply, optionally ignoring list order.
 func deepEqual(a, b any, opts Options) bool {
 	if opts.IgnoreOrder {
-		// Normalize slices for comparison
-		a = normalizeValue(a)
-		b = normalizeValue(b)
+		return equalIgnoringOrder(a, b)
 	}
 	return reflect.DeepEqual(a, b)
 }
 
-// normalizeValue normalizes a value for comparison.
-func normalizeValue(v any) any {
-	switch val := v.(type) {
-	case []any:
-		// Sort string slices for comparison
-		result := make([]any, len(val))
-		copy(result, val)
-		return result
-	case map[string]any:
-		result := make(map[string]any)
-		for k, v := range val {
-			result[k] = normalizeValue(v)
-		}
-		return result
-	default:
-		return v
-	}
-}
-
 // equalStringSlices compares two string slices for equality.
 func equalStringSlices(a, b []string) bool {
 	if len(a) != len(b) {
@@ -276,9 +524,198 @@ func equalStringSlices(a, b []string) bool {
 	return true
 }
 
-// sortEntries sorts diff entries by resource name.
+// compareTransforms compares the macros named in the Transform sections.
+func compareTransforms(diff *wetwire.TemplateDiff, transforms1, transforms2 wetwire.Transforms) {
+	for _, macro := range transforms2 {
+		if !transforms1.Has(macro) {
+			diff.Added = append(diff.Added, wetwire.DiffEntry{Section: SectionTransform, Resource: macro})
+		}
+	}
+	for _, macro := range transforms1 {
+		if !transforms2.Has(macro) {
+			diff.Removed = append(diff.Removed, wetwire.DiffEntry{Section: SectionTransform, Resource: macro})
+		}
+	}
+}
+
+// compareSection compares the named entries of a template section.
+func compareSection(diff *wetwire.TemplateDiff, section string, entries1, entries2 map[string]any, opts Options) {
+	for name, val2 := range entries2 {
+		val1, exists := entries1[name]
+		if !exists {
+			diff.Added = append(diff.Added, wetwire.DiffEntry{
+				Section:  section,
+				Resource: name,
+				Type:     entryType(section, val2),
+			})
+			continue
+		}
+		if deepEqual(val1, val2, opts) {
+			continue
+		}
+		diff.Modified = append(diff.Modified, wetwire.DiffEntry{
+			Section:         section,
+			Resource:        name,
+			Type:            entryType(section, val2),
+			Changes:         compareValues(val1, val2, opts),
+			PropertyChanges: sortChanges(diffValues("", val1, val2, opts)),
+		})
+	}
+
+	for name, val1 := range entries1 {
+		if _, exists := entries2[name]; !exists {
+			diff.Removed = append(diff.Removed, wetwire.DiffEntry{
+				Section:  section,
+				Resource: name,
+				Type:     entryType(section, val1),
+			})
+		}
+	}
+}
+
+// compareOutputs compares the Outputs sections. Exports are compared
+// separately from the rest of each output so that removing or renaming one
+// can be flagged as breaking.
+func compareOutputs(diff *wetwire.TemplateDiff, outputs1, outputs2 map[string]wetwire.Output, opts Options) error {
+	entries1, err := toSection(outputs1)
+	if err != nil {
+		return err
+	}
+	entries2, err := toSection(outputs2)
+	if err != nil {
+		return err
+	}
+	for _, entries := range []map[string]any{entries1, entries2} {
+		for _, val := range entries {
+			delete(val.(map[string]any), "Export")
+		}
+	}
+	compareSection(diff, SectionOutputs, entries1, entries2, opts)
+
+	for name, out1 := range outputs1 {
+		export1 := exportName(out1)
+		out2, exists := outputs2[name]
+		if !exists {
+			if export1 != "" {
+				entry := findEntry(diff.Removed, name)
+				entry.Changes = append(entry.Changes, fmt.Sprintf("Export %s removed", export1))
+				entry.Breaking = true
+			}
+			continue
+		}
+
+		export2 := exportName(out2)
+		if export1 == export2 {
+			continue
+		}
+		var change string
+		switch {
+		case export1 == "":
+			change = fmt.Sprintf("Export %s added", export2)
+		case export2 == "":
+			change = fmt.Sprintf("Export %s removed", export1)
+		default:
+			change = fmt.Sprintf("Export renamed: %s → %s", export1, export2)
+		}
+
+		entry := findEntry(diff.Modified, name)
+		if entry == nil {
+			diff.Modified = append(diff.Modified, wetwire.DiffEntry{Section: SectionOutputs, Resource: name})
+			entry = &diff.Modified[len(diff.Modified)-1]
+		}
+		entry.Changes = append(entry.Changes, change)
+		sort.Strings(entry.Changes)
+		entry.PropertyChanges = sortChanges(append(entry.PropertyChanges, exportChange(export1, export2)))
+		if export1 != "" {
+			entry.Breaking = true
+		}
+	}
+
+	for name, out2 := range outputs2 {
+		if _, exists := outputs1[name]; !exists {
+			if export2 := exportName(out2); export2 != "" {
+				entry := findEntry(diff.Added, name)
+				entry.Changes = append(entry.Changes, fmt.Sprintf("Export %s added", export2))
+			}
+		}
+	}
+
+	return nil
+}
+
+// exportChange returns the structured change of an output's export name.
+func exportChange(export1, export2 string) wetwire.PropertyChange {
+	switch {
+	case export1 == "":
+		return wetwire.PropertyChange{Path: "/Export/Name", Kind: ChangeAdded, New: export2}
+	case export2 == "":
+		return wetwire.PropertyChange{Path: "/Export/Name", Kind: ChangeRemoved, Old: export1}
+	}
+	return wetwire.PropertyChange{Path: "/Export/Name", Kind: ChangeModified, Old: export1, New: export2}
+}
+
+// exportName returns the export name of an output, or "" if it is not exported.
+func exportName(out wetwire.Output) string {
+	if out.Export == nil {
+		return ""
+	}
+	return out.Export.Name
+}
+
+// findEntry returns the Outputs entry with the given name, or nil.
+func findEntry(entries []wetwire.DiffEntry, name string) *wetwire.DiffEntry {
+	for i := range entries {
+		if entries[i].Section == SectionOutputs && entries[i].Resource == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// compareValues describes the changes between two section entries.
+func compareValues(val1, val2 any, opts Options) []string {
+	map1, ok1 := val1.(map[string]any)
+	map2, ok2 := val2.(map[string]any)
+	if ok1 && ok2 {
+		return compareProperties("", map1, map2, opts)
+	}
+	return []string{"Value modified"}
+}
+
+// entryType returns the type shown for a section entry: the Type of a
+// parameter, and nothing for other sections.
+func entryType(section string, val any) string {
+	if section != SectionParameters {
+		return ""
+	}
+	if m, ok := val.(map[string]any); ok {
+		typ, _ := m["Type"].(string)
+		return typ
+	}
+	return ""
+}
+
+// toSection converts typed section entries to generic values, keyed by the
+// JSON field names used in templates.
+func toSection[V any](entries map[string]V) (map[string]any, error) {
+	value, err := toValue(entries)
+	if err != nil {
+		return nil, err
+	}
+	section, _ := value.(map[string]any)
+	return section, nil
+}
+
+// sortEntries sorts diff entries by section, then resource name.
 func sortEntries(entries []wetwire.DiffEntry) {
+	order := make(map[string]int, len(Sections))
+	for i, section := range Sections {
+		order[section] = i
+	}
 	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Section != entries[j].Section {
+			return order[entries[i].Section] < order[entries[j].Section]
+		}
 		return entries[i].Resource < entries[j].Resource
 	})
 }
@@ -1,9 +1,13 @@
 package differ
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/schema"
 )
 
 func TestCompare(t *testing.T) {
@@ -118,6 +122,92 @@ func TestCompareTypeChange(t *testing.T) {
 	}
 }
 
+func TestCompareResourceAttributes(t *testing.T) {
+	retainInProd := map[string]any{"Fn::If": []any{"IsProd", "Retain", "Delete"}}
+	tests := []struct {
+		name       string
+		old, new   wetwire.ResourceDef
+		wantChange string
+	}{
+		{
+			name:       "DeletionPolicy",
+			old:        wetwire.ResourceDef{DeletionPolicy: "Retain"},
+			new:        wetwire.ResourceDef{DeletionPolicy: "Delete"},
+			wantChange: "DeletionPolicy changed: Retain → Delete",
+		},
+		{
+			name:       "DeletionPolicy intrinsic",
+			old:        wetwire.ResourceDef{DeletionPolicy: "Retain"},
+			new:        wetwire.ResourceDef{DeletionPolicy: retainInProd},
+			wantChange: "DeletionPolicy changed",
+		},
+		{
+			name:       "UpdateReplacePolicy",
+			old:        wetwire.ResourceDef{UpdateReplacePolicy: "Snapshot"},
+			new:        wetwire.ResourceDef{},
+			wantChange: "UpdateReplacePolicy changed: Snapshot → (none)",
+		},
+		{
+			name:       "Condition",
+			old:        wetwire.ResourceDef{Condition: "IsProd"},
+			new:        wetwire.ResourceDef{},
+			wantChange: "Condition changed: IsProd → (none)",
+		},
+		{
+			name:       "Metadata",
+			old:        wetwire.ResourceDef{Metadata: map[string]any{"Owner": "data"}},
+			new:        wetwire.ResourceDef{Metadata: map[string]any{"Owner": "web"}},
+			wantChange: "Metadata changed",
+		},
+		{
+			name:       "CreationPolicy",
+			old:        wetwire.ResourceDef{},
+			new:        wetwire.ResourceDef{CreationPolicy: map[string]any{"ResourceSignal": map[string]any{"Count": 2}}},
+			wantChange: "CreationPolicy changed",
+		},
+		{
+			name:       "UpdatePolicy",
+			old:        wetwire.ResourceDef{UpdatePolicy: map[string]any{"AutoScalingReplacingUpdate": map[string]any{"WillReplace": true}}},
+			new:        wetwire.ResourceDef{UpdatePolicy: map[string]any{"AutoScalingReplacingUpdate": map[string]any{"WillReplace": false}}},
+			wantChange: "UpdatePolicy changed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.old.Type, tt.new.Type = "AWS::S3::Bucket", "AWS::S3::Bucket"
+			old := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{"Data": tt.old}}
+			updated := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{"Data": tt.new}}
+
+			result, err := Compare(old, updated, Options{})
+			if err != nil {
+				t.Fatalf("Compare() error = %v", err)
+			}
+			if result.Summary.Total != 1 {
+				t.Fatalf("Summary.Total = %d, want 1", result.Summary.Total)
+			}
+			entry := result.Diff.Modified[0]
+			if !equalStringSlices(entry.Changes, []string{tt.wantChange}) {
+				t.Errorf("Changes = %v, want [%s]", entry.Changes, tt.wantChange)
+			}
+			if entry.Replacement != ReplacementNone {
+				t.Errorf("Replacement = %q, want %q", entry.Replacement, ReplacementNone)
+			}
+		})
+	}
+
+	// Unset and empty attributes are the same
+	old := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{"Data": {Type: "AWS::S3::Bucket"}}}
+	updated := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{"Data": {Type: "AWS::S3::Bucket", Metadata: map[string]any{}}}}
+	result, err := Compare(old, updated, Options{})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if result.Summary.Total != 0 {
+		t.Errorf("Summary.Total = %d, want 0 for an empty Metadata", result.Summary.Total)
+	}
+}
+
 func TestCompareProperties(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -180,3 +270,356 @@ func TestEqualStringSlices(t *testing.T) {
 		}
 	}
 }
+
+// writeTemplate writes template content to a file in dir.
+func writeTemplate(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// findDiffEntry returns the entry for a section and name, or nil.
+func findDiffEntry(entries []wetwire.DiffEntry, section, name string) *wetwire.DiffEntry {
+	for i := range entries {
+		if entries[i].Section == section && entries[i].Resource == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func TestCompareAllSections(t *testing.T) {
+	dir := t.TempDir()
+	old := writeTemplate(t, dir, "old.yaml", `
+Transform: AWS::Serverless-2016-10-31
+Parameters:
+  Environment:
+    Type: String
+    Default: dev
+  Retired:
+    Type: Number
+Mappings:
+  RegionMap:
+    us-east-1:
+      AMI: ami-111
+Conditions:
+  IsProd:
+    Fn::Equals: [!Ref Environment, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+Outputs:
+  BucketName:
+    Value: !Ref Bucket
+`)
+	updated := writeTemplate(t, dir, "new.yaml", `
+Parameters:
+  Environment:
+    Type: String
+    Default: staging
+  Added:
+    Type: String
+Mappings:
+  RegionMap:
+    us-east-1:
+      AMI: ami-222
+Conditions:
+  IsProd:
+    Fn::Equals: [!Ref Environment, production]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+Outputs:
+  BucketName:
+    Description: The bucket
+    Value: !Ref Bucket
+`)
+
+	result, err := CompareFiles(old, updated, Options{})
+	if err != nil {
+		t.Fatalf("CompareFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		entries []wetwire.DiffEntry
+		section string
+		name    string
+		changes []string
+	}{
+		{result.Diff.Removed, SectionTransform, "AWS::Serverless-2016-10-31", nil},
+		{result.Diff.Added, SectionParameters, "Added", nil},
+		{result.Diff.Removed, SectionParameters, "Retired", nil},
+		{result.Diff.Modified, SectionParameters, "Environment", []string{"Default modified"}},
+		{result.Diff.Modified, SectionMappings, "RegionMap", []string{"us-east-1 modified"}},
+		{result.Diff.Modified, SectionConditions, "IsProd", []string{"Fn::Equals modified"}},
+		{result.Diff.Modified, SectionOutputs, "BucketName", []string{"Description added"}},
+	}
+	for _, tt := range tests {
+		entry := findDiffEntry(tt.entries, tt.section, tt.name)
+		if entry == nil {
+			t.Errorf("missing %s entry %s", tt.section, tt.name)
+			continue
+		}
+		if tt.changes != nil && !equalStringSlices(entry.Changes, tt.changes) {
+			t.Errorf("%s %s changes = %v, want %v", tt.section, tt.name, entry.Changes, tt.changes)
+		}
+	}
+
+	if findDiffEntry(result.Diff.Added, SectionParameters, "Added").Type != "String" {
+		t.Error("added parameter should carry its Type")
+	}
+	if result.Summary.Total != 7 {
+		t.Errorf("Summary.Total = %d, want 7", result.Summary.Total)
+	}
+	if result.Summary.Breaking != 0 {
+		t.Errorf("Summary.Breaking = %d, want 0", result.Summary.Breaking)
+	}
+}
+
+func TestCompareLanguageExtensions(t *testing.T) {
+	dir := t.TempDir()
+	old := writeTemplate(t, dir, "old.yaml", `
+Transform: AWS::Serverless-2016-10-31
+Resources:
+  Fn::ForEach::Topics:
+    - TopicName
+    - [Success, Failure]
+    - Topic${TopicName}:
+        Type: AWS::SNS::Topic
+`)
+	updated := writeTemplate(t, dir, "new.yaml", `
+Transform:
+  - AWS::LanguageExtensions
+  - AWS::Serverless-2016-10-31
+Resources:
+  Fn::ForEach::Topics:
+    - TopicName
+    - [Success, Failure, Retry]
+    - Topic${TopicName}:
+        Type: AWS::SNS::Topic
+`)
+
+	result, err := CompareFiles(old, updated, Options{})
+	if err != nil {
+		t.Fatalf("CompareFiles() error = %v", err)
+	}
+
+	if findDiffEntry(result.Diff.Added, SectionTransform, "AWS::LanguageExtensions") == nil {
+		t.Error("missing added AWS::LanguageExtensions transform")
+	}
+	if findDiffEntry(result.Diff.Removed, SectionTransform, "AWS::Serverless-2016-10-31") != nil {
+		t.Error("the SAM transform is in both templates and should not be removed")
+	}
+	loop := findDiffEntry(result.Diff.Modified, SectionResources, "Fn::ForEach::Topics")
+	if loop == nil {
+		t.Fatal("missing modified loop")
+	}
+	if !equalStringSlices(loop.Changes, []string{"Fn::ForEach changed"}) {
+		t.Errorf("loop changes = %v", loop.Changes)
+	}
+	if result.Summary.Total != 2 {
+		t.Errorf("Summary.Total = %d, want 2", result.Summary.Total)
+	}
+}
+
+func TestCompareSectionOrder(t *testing.T) {
+	t1 := &wetwire.Template{}
+	t2 := &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{"Zone": {Type: "String"}},
+		Resources:  map[string]wetwire.ResourceDef{"Bucket": {Type: "AWS::S3::Bucket"}},
+		Conditions: map[string]any{"Always": map[string]any{"Fn::Equals": []any{"a", "a"}}},
+	}
+
+	result, err := Compare(t1, t2, Options{})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	var got []string
+	for _, e := range result.Diff.Added {
+		got = append(got, e.Section+"/"+e.Resource)
+	}
+	want := []string{"Parameters/Zone", "Conditions/Always", "Resources/Bucket"}
+	if !equalStringSlices(got, want) {
+		t.Errorf("Added = %v, want %v", got, want)
+	}
+}
+
+func TestCompareExports(t *testing.T) {
+	dir := t.TempDir()
+	old := writeTemplate(t, dir, "old.json", `{
+  "Resources": {},
+  "Outputs": {
+    "VpcId": {"Value": "vpc-1", "Export": {"Name": "shared-vpc"}},
+    "SubnetId": {"Value": "subnet-1", "Export": {"Name": "shared-subnet"}},
+    "Unshared": {"Value": "x", "Export": {"Name": "unshared"}},
+    "Internal": {"Value": "y"},
+    "Published": {"Value": "z"}
+  }
+}`)
+	updated := writeTemplate(t, dir, "new.json", `{
+  "Resources": {},
+  "Outputs": {
+    "VpcId": {"Value": "vpc-1", "Export": {"Name": "network-vpc"}},
+    "Unshared": {"Value": "x"},
+    "Published": {"Value": "z", "Export": {"Name": "published"}}
+  }
+}`)
+
+	result, err := CompareFiles(old, updated, Options{})
+	if err != nil {
+		t.Fatalf("CompareFiles() error = %v", err)
+	}
+
+	tests := []struct {
+		entries  []wetwire.DiffEntry
+		name     string
+		changes  []string
+		breaking bool
+	}{
+		{result.Diff.Modified, "VpcId", []string{"Export renamed: shared-vpc → network-vpc"}, true},
+		{result.Diff.Removed, "SubnetId", []string{"Export shared-subnet removed"}, true},
+		{result.Diff.Modified, "Unshared", []string{"Export unshared removed"}, true},
+		{result.Diff.Removed, "Internal", nil, false},
+		{result.Diff.Modified, "Published", []string{"Export published added"}, false},
+	}
+	for _, tt := range tests {
+		entry := findDiffEntry(tt.entries, SectionOutputs, tt.name)
+		if entry == nil {
+			t.Errorf("missing output entry %s", tt.name)
+			continue
+		}
+		if !equalStringSlices(entry.Changes, tt.changes) {
+			t.Errorf("%s changes = %v, want %v", tt.name, entry.Changes, tt.changes)
+		}
+		if entry.Breaking != tt.breaking {
+			t.Errorf("%s Breaking = %v, want %v", tt.name, entry.Breaking, tt.breaking)
+		}
+	}
+
+	if result.Summary.Breaking != 3 {
+		t.Errorf("Summary.Breaking = %d, want 3", result.Summary.Breaking)
+	}
+
+	vpc := findDiffEntry(result.Diff.Modified, SectionOutputs, "VpcId")
+	want := []wetwire.PropertyChange{{Path: "/Export/Name", Kind: ChangeModified, Old: "shared-vpc", New: "network-vpc"}}
+	if !reflect.DeepEqual(vpc.PropertyChanges, want) {
+		t.Errorf("VpcId PropertyChanges = %+v, want %+v", vpc.PropertyChanges, want)
+	}
+}
+
+func TestDomainEntry(t *testing.T) {
+	entry := domainEntry(wetwire.DiffEntry{
+		Section:  SectionOutputs,
+		Resource: "VpcId",
+		Changes:  []string{"Export shared-vpc removed"},
+		Breaking: true,
+	}, "modified")
+
+	if entry.Resource != "Outputs.VpcId" {
+		t.Errorf("Resource = %q, want Outputs.VpcId", entry.Resource)
+	}
+	if len(entry.Changes) != 2 || entry.Changes[1] != "Export shared-vpc removed" {
+		t.Errorf("Changes = %v", entry.Changes)
+	}
+
+	resource := domainEntry(wetwire.DiffEntry{Section: SectionResources, Resource: "Bucket"}, "added")
+	if resource.Resource != "Bucket" {
+		t.Errorf("Resource = %q, want Bucket", resource.Resource)
+	}
+}
+
+func TestCompareReplacement(t *testing.T) {
+	spec := &schema.Spec{
+		ResourceTypes: map[string]schema.ResourceSpec{
+			"AWS::RDS::DBInstance": {Properties: map[string]schema.PropertySpec{
+				"AllocatedStorage":     {UpdateType: schema.UpdateMutable},
+				"DBInstanceIdentifier": {UpdateType: schema.UpdateImmutable},
+				"Engine":               {UpdateType: schema.UpdateConditional},
+			}},
+		},
+	}
+
+	db := func(props map[string]any) wetwire.ResourceDef {
+		return wetwire.ResourceDef{Type: "AWS::RDS::DBInstance", Properties: props}
+	}
+	t1 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Resized":   db(map[string]any{"AllocatedStorage": 20}),
+		"Renamed":   db(map[string]any{"DBInstanceIdentifier": "orders", "AllocatedStorage": 20}),
+		"Migrated":  db(map[string]any{"Engine": "mysql"}),
+		"Bucket":    {Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": "logs"}},
+		"Retyped":   {Type: "AWS::SNS::Topic"},
+		"Reordered": {Type: "AWS::S3::Bucket", DependsOn: []string{"Bucket"}},
+		"Delayed":   {Type: "AWS::SQS::Queue", Properties: map[string]any{"DelaySeconds": 0}},
+		"Tuned":     db(map[string]any{"Engine": "mysql", "MonitoringInterval": 0}),
+	}}
+	t2 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Resized":   db(map[string]any{"AllocatedStorage": 50}),
+		"Renamed":   db(map[string]any{"DBInstanceIdentifier": "orders-v2", "AllocatedStorage": 50}),
+		"Migrated":  db(map[string]any{"Engine": "postgres"}),
+		"Bucket":    {Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": "logs-v2"}},
+		"Retyped":   {Type: "AWS::SQS::Queue"},
+		"Reordered": {Type: "AWS::S3::Bucket"},
+		"Delayed":   {Type: "AWS::SQS::Queue", Properties: map[string]any{"DelaySeconds": 30}},
+		"Tuned":     db(map[string]any{"Engine": "postgres", "MonitoringInterval": 60}),
+	}}
+
+	result, err := Compare(t1, t2, Options{Spec: spec})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		replacement string
+		updateTypes map[string]string
+	}{
+		{"Resized", ReplacementNone, map[string]string{"AllocatedStorage": "Mutable"}},
+		{"Renamed", ReplacementAlways, map[string]string{"AllocatedStorage": "Mutable", "DBInstanceIdentifier": "Immutable"}},
+		{"Migrated", ReplacementConditional, map[string]string{"Engine": "Conditional"}},
+		// Not in the spec: the hand-written table applies
+		{"Bucket", ReplacementAlways, map[string]string{"BucketName": "Immutable"}},
+		{"Retyped", ReplacementAlways, nil},
+		{"Reordered", ReplacementNone, nil},
+		// No update type known: not assumed to be in place
+		{"Delayed", ReplacementUnknown, map[string]string{"DelaySeconds": "Unknown"}},
+		{"Tuned", ReplacementConditional, map[string]string{"Engine": "Conditional", "MonitoringInterval": "Unknown"}},
+	}
+	for _, tt := range tests {
+		entry := findDiffEntry(result.Diff.Modified, SectionResources, tt.name)
+		if entry == nil {
+			t.Errorf("missing modified resource %s", tt.name)
+			continue
+		}
+		if entry.Replacement != tt.replacement {
+			t.Errorf("%s Replacement = %q, want %q", tt.name, entry.Replacement, tt.replacement)
+		}
+		if !reflect.DeepEqual(entry.UpdateTypes, tt.updateTypes) {
+			t.Errorf("%s UpdateTypes = %v, want %v", tt.name, entry.UpdateTypes, tt.updateTypes)
+		}
+	}
+
+	if result.Summary.Replaced != 5 {
+		t.Errorf("Summary.Replaced = %d, want 5", result.Summary.Replaced)
+	}
+
+	var blocked []string
+	for _, e := range Replacements(result, []string{"Retyped", "AWS::S3::Bucket"}, true) {
+		blocked = append(blocked, e.Resource)
+	}
+	if want := []string{"Delayed", "Migrated", "Renamed", "Tuned"}; !equalStringSlices(blocked, want) {
+		t.Errorf("Replacements(strict) = %v, want %v", blocked, want)
+	}
+
+	// Unknown update types only fail the gate in strict mode
+	blocked = nil
+	for _, e := range Replacements(result, []string{"Retyped", "AWS::S3::Bucket"}, false) {
+		blocked = append(blocked, e.Resource)
+	}
+	if want := []string{"Migrated", "Renamed", "Tuned"}; !equalStringSlices(blocked, want) {
+		t.Errorf("Replacements() = %v, want %v", blocked, want)
+	}
+}
@@ -0,0 +1,193 @@
+package differ
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// DefaultRenameThreshold is the default similarity from which a removed and
+// an added resource are reported as a probable rename.
+const DefaultRenameThreshold = 0.8
+
+// statefulTypes lists resource types that hold data. Renaming one deletes
+// the old resource, and its data, once the new one is created.
+var statefulTypes = map[string]bool{
+	"AWS::Backup::BackupVault":           true,
+	"AWS::Cognito::UserPool":             true,
+	"AWS::DocDB::DBCluster":              true,
+	"AWS::DynamoDB::GlobalTable":         true,
+	"AWS::DynamoDB::Table":               true,
+	"AWS::EC2::Volume":                   true,
+	"AWS::ECR::Repository":               true,
+	"AWS::EFS::FileSystem":               true,
+	"AWS::ElastiCache::CacheCluster":     true,
+	"AWS::ElastiCache::ReplicationGroup": true,
+	"AWS::Elasticsearch::Domain":         true,
+	"AWS::KMS::Key":                      true,
+	"AWS::Kinesis::Stream":               true,
+	"AWS::Logs::LogGroup":                true,
+	"AWS::Neptune::DBCluster":            true,
+	"AWS::OpenSearchService::Domain":     true,
+	"AWS::RDS::DBCluster":                true,
+	"AWS::RDS::DBInstance":               true,
+	"AWS::Redshift::Cluster":             true,
+	"AWS::S3::Bucket":                    true,
+	"AWS::SQS::Queue":                    true,
+	"AWS::SecretsManager::Secret":        true,
+	"AWS::Timestream::Table":             true,
+}
+
+// IsStateful reports whether resources of the given type hold data that is
+// lost when CloudFormation replaces them.
+func IsStateful(resourceType string) bool {
+	return statefulTypes[resourceType]
+}
+
+// detectRenames pairs removed and added resources of the same type whose
+// definitions are at least opts.RenameThreshold similar, best matches first.
+// The pairs are recorded in diff.Renames and noted in the changes of the
+// removed and added entries.
+func detectRenames(diff *wetwire.TemplateDiff, res1, res2 map[string]wetwire.ResourceDef, opts Options) error {
+	threshold := opts.RenameThreshold
+	if threshold <= 0 {
+		threshold = DefaultRenameThreshold
+	}
+
+	var candidates []wetwire.Rename
+	for _, removed := range diff.Removed {
+		if removed.Section != SectionResources {
+			continue
+		}
+		for _, added := range diff.Added {
+			if added.Section != SectionResources || added.Type != removed.Type {
+				continue
+			}
+			score, err := similarity(res1[removed.Resource], res2[added.Resource], opts)
+			if err != nil {
+				return err
+			}
+			if score >= threshold {
+				candidates = append(candidates, wetwire.Rename{
+					From:       removed.Resource,
+					To:         added.Resource,
+					Type:       removed.Type,
+					Similarity: score,
+					Stateful:   IsStateful(removed.Type),
+				})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+
+	paired := make(map[string]bool)
+	for _, rename := range candidates {
+		if paired["-"+rename.From] || paired["+"+rename.To] {
+			continue
+		}
+		paired["-"+rename.From] = true
+		paired["+"+rename.To] = true
+		diff.Renames = append(diff.Renames, rename)
+
+		percent := similarityPercent(rename.Similarity)
+		if e := findResourceEntry(diff.Removed, rename.From); e != nil {
+			e.Changes = append(e.Changes, fmt.Sprintf("Probably renamed to %s (%s similar)", rename.To, percent))
+			if rename.Stateful {
+				e.Changes = append(e.Changes, fmt.Sprintf("Stateful resource: CloudFormation creates %s and deletes %s with its data", rename.To, rename.From))
+			}
+		}
+		if e := findResourceEntry(diff.Added, rename.To); e != nil {
+			e.Changes = append(e.Changes, fmt.Sprintf("Probably renamed from %s (%s similar)", rename.From, percent))
+		}
+	}
+
+	sort.Slice(diff.Renames, func(i, j int) bool {
+		return diff.Renames[i].From < diff.Renames[j].From
+	})
+	return nil
+}
+
+// similarity scores how alike two resource definitions are, from 0 to 1: the
+// share of leaf values, such as "/Properties/Tags/0/Key", that are present
+// and equal in both. Two definitions without properties are identical.
+func similarity(def1, def2 wetwire.ResourceDef, opts Options) (float64, error) {
+	val1, err := toValue(def1)
+	if err != nil {
+		return 0, err
+	}
+	val2, err := toValue(def2)
+	if err != nil {
+		return 0, err
+	}
+
+	leaves1 := make(map[string]any)
+	leaves2 := make(map[string]any)
+	flattenLeaves("", val1, leaves1, opts)
+	flattenLeaves("", val2, leaves2, opts)
+	delete(leaves1, "/Type")
+	delete(leaves2, "/Type")
+
+	total := len(leaves1) + len(leaves2)
+	if total == 0 {
+		return 1, nil
+	}
+	matching := 0
+	for path, v1 := range leaves1 {
+		if v2, ok := leaves2[path]; ok && deepEqual(v1, v2, opts) {
+			matching++
+		}
+	}
+	return float64(2*matching) / float64(total), nil
+}
+
+// flattenLeaves records the scalar values of v, and its empty maps and
+// lists, by JSON pointer. With IgnoreOrder, lists are compared whole.
+func flattenLeaves(path string, v any, leaves map[string]any, opts Options) {
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 {
+			leaves[path] = val
+		}
+		for k, item := range val {
+			flattenLeaves(path+"/"+escapePointer(k), item, leaves, opts)
+		}
+	case []any:
+		if len(val) == 0 || opts.IgnoreOrder {
+			leaves[path] = val
+			return
+		}
+		for i, item := range val {
+			flattenLeaves(path+"/"+strconv.Itoa(i), item, leaves, opts)
+		}
+	default:
+		leaves[path] = val
+	}
+}
+
+// similarityPercent formats a similarity as a whole percentage, rounded
+// down so only identical definitions show 100%.
+func similarityPercent(score float64) string {
+	return fmt.Sprintf("%d%%", int(score*100))
+}
+
+// findResourceEntry finds the Resources entry with the given name.
+func findResourceEntry(entries []wetwire.DiffEntry, name string) *wetwire.DiffEntry {
+	for i := range entries {
+		if entries[i].Section == SectionResources && entries[i].Resource == name {
+			return &entries[i]
+		}
+	}
+	return nil
+}
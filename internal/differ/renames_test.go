@@ -0,0 +1,114 @@
+package differ
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func TestCompareRenames(t *testing.T) {
+	bucketProps := func(name string) map[string]any {
+		return map[string]any{
+			"BucketName":              name,
+			"VersioningConfiguration": map[string]any{"Status": "Enabled"},
+			"Tags": []any{
+				map[string]any{"Key": "team", "Value": "data"},
+				map[string]any{"Key": "env", "Value": "prod"},
+			},
+		}
+	}
+	t1 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"DataBucket": {Type: "AWS::S3::Bucket", Properties: bucketProps("data")},
+		"Handler":    {Type: "AWS::Lambda::Function", Properties: map[string]any{"Runtime": "go1.x", "Handler": "main"}},
+		"Topic":      {Type: "AWS::SNS::Topic", Properties: map[string]any{"TopicName": "alerts"}},
+	}}
+	t2 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"LogsBucket": {Type: "AWS::S3::Bucket", Properties: bucketProps("data-v2")},
+		"Worker":     {Type: "AWS::Lambda::Function", Properties: map[string]any{"Runtime": "go1.x", "Handler": "main"}},
+		"Queue":      {Type: "AWS::SQS::Queue", Properties: map[string]any{"QueueName": "alerts"}},
+	}}
+
+	result, err := Compare(t1, t2, Options{})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	want := []wetwire.Rename{
+		{From: "DataBucket", To: "LogsBucket", Type: "AWS::S3::Bucket", Similarity: 10.0 / 12, Stateful: true},
+		{From: "Handler", To: "Worker", Type: "AWS::Lambda::Function", Similarity: 1},
+	}
+	if !reflect.DeepEqual(result.Diff.Renames, want) {
+		t.Errorf("Renames =\n%+v\nwant\n%+v", result.Diff.Renames, want)
+	}
+	if result.Summary.Renamed != 2 {
+		t.Errorf("Summary.Renamed = %d, want 2", result.Summary.Renamed)
+	}
+	// Renamed resources are still deleted and created
+	if result.Summary.Added != 3 || result.Summary.Removed != 3 {
+		t.Errorf("Summary = %+v, want 3 added and 3 removed", result.Summary)
+	}
+
+	removed := findDiffEntry(result.Diff.Removed, SectionResources, "DataBucket")
+	if removed == nil || len(removed.Changes) != 2 ||
+		removed.Changes[0] != "Probably renamed to LogsBucket (83% similar)" ||
+		!strings.Contains(removed.Changes[1], "deletes DataBucket with its data") {
+		t.Errorf("removed DataBucket = %+v", removed)
+	}
+	added := findDiffEntry(result.Diff.Added, SectionResources, "Worker")
+	if added == nil || len(added.Changes) != 1 || added.Changes[0] != "Probably renamed from Handler (100% similar)" {
+		t.Errorf("added Worker = %+v", added)
+	}
+	if e := findDiffEntry(result.Diff.Added, SectionResources, "Queue"); e == nil || len(e.Changes) != 0 {
+		t.Errorf("added Queue = %+v, want no rename", e)
+	}
+}
+
+func TestCompareRenames_BestMatch(t *testing.T) {
+	t1 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Old": {Type: "AWS::SNS::Topic", Properties: map[string]any{"TopicName": "a", "DisplayName": "A"}},
+	}}
+	t2 := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Close": {Type: "AWS::SNS::Topic", Properties: map[string]any{"TopicName": "a", "DisplayName": "B"}},
+		"Exact": {Type: "AWS::SNS::Topic", Properties: map[string]any{"TopicName": "a", "DisplayName": "A"}},
+	}}
+
+	result, err := Compare(t1, t2, Options{RenameThreshold: 0.5})
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if len(result.Diff.Renames) != 1 || result.Diff.Renames[0].To != "Exact" {
+		t.Errorf("Renames = %+v, want Old → Exact only", result.Diff.Renames)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name   string
+		props1 map[string]any
+		props2 map[string]any
+		opts   Options
+		want   float64
+	}{
+		{"identical", map[string]any{"A": "x"}, map[string]any{"A": "x"}, Options{}, 1},
+		{"no properties", nil, nil, Options{}, 1},
+		{"disjoint", map[string]any{"A": "x"}, map[string]any{"B": "x"}, Options{}, 0},
+		{"half", map[string]any{"A": "x", "B": "y"}, map[string]any{"A": "x", "B": "z"}, Options{}, 0.5},
+		{"reordered", map[string]any{"L": []any{"a", "b"}}, map[string]any{"L": []any{"b", "a"}}, Options{}, 0},
+		{"reordered ignoring order", map[string]any{"L": []any{"a", "b"}}, map[string]any{"L": []any{"b", "a"}}, Options{IgnoreOrder: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def1 := wetwire.ResourceDef{Type: "AWS::SNS::Topic", Properties: tt.props1}
+			def2 := wetwire.ResourceDef{Type: "AWS::SNS::Topic", Properties: tt.props2}
+			got, err := similarity(def1, def2, tt.opts)
+			if err != nil {
+				t.Fatalf("similarity() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("similarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
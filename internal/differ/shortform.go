@@ -0,0 +1,45 @@
+package differ
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExpandShortForms rewrites the short-form intrinsic functions of a YAML
+// template, such as !Ref Name or !GetAtt Name.Arn, to their long form,
+// {"Ref": "Name"} or {"Fn::GetAtt": ["Name", "Arn"]}.
+func ExpandShortForms(node *yaml.Node) {
+	for _, child := range node.Content {
+		ExpandShortForms(child)
+	}
+
+	if !strings.HasPrefix(node.Tag, "!") || strings.HasPrefix(node.Tag, "!!") {
+		return
+	}
+	name := strings.TrimPrefix(node.Tag, "!")
+	fn := "Fn::" + name
+	if name == "Ref" || name == "Condition" {
+		fn = name
+	}
+
+	value := *node
+	value.Tag = ""
+	if name == "GetAtt" && node.Kind == yaml.ScalarNode {
+		// !GetAtt Name.Attr is the list [Name, Attr]
+		resource, attr, _ := strings.Cut(node.Value, ".")
+		value = yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: resource},
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: attr},
+		}}
+	}
+
+	*node = yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: fn},
+			&value,
+		},
+	}
+}
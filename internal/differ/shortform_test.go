@@ -0,0 +1,47 @@
+package differ
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadTemplate_ShortForms(t *testing.T) {
+	path := writeTemplate(t, t.TempDir(), "template.yaml", `
+Conditions:
+  IsProd: !Equals [!Ref Env, prod]
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "app-${Env}"
+      Arn: !GetAtt Key.Arn
+      Zone: !Select [0, !GetAZs ""]
+      Enabled: !If [IsProd, true, !Ref "AWS::NoValue"]
+`)
+
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+
+	wantCondition := map[string]any{"Fn::Equals": []any{map[string]any{"Ref": "Env"}, "prod"}}
+	if !reflect.DeepEqual(tmpl.Conditions["IsProd"], wantCondition) {
+		t.Errorf("IsProd = %#v, want %#v", tmpl.Conditions["IsProd"], wantCondition)
+	}
+	wantProps := map[string]any{
+		"BucketName": map[string]any{"Fn::Sub": "app-${Env}"},
+		"Arn":        map[string]any{"Fn::GetAtt": []any{"Key", "Arn"}},
+		"Zone":       map[string]any{"Fn::Select": []any{0, map[string]any{"Fn::GetAZs": ""}}},
+		"Enabled":    map[string]any{"Fn::If": []any{"IsProd", true, map[string]any{"Ref": "AWS::NoValue"}}},
+	}
+	if got := tmpl.Resources["Bucket"].Properties; !reflect.DeepEqual(got, wantProps) {
+		t.Errorf("Properties =\n%#v\nwant\n%#v", got, wantProps)
+	}
+}
+
+func TestLoadTemplate_EmptyYAML(t *testing.T) {
+	path := writeTemplate(t, t.TempDir(), "empty.yaml", "")
+	if _, err := LoadTemplate(path); err != nil {
+		t.Errorf("LoadTemplate() error = %v", err)
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
@@ -310,6 +312,10 @@ type Result struct {
 	Mappings map[string]wetwire.DiscoveredMapping
 	// Conditions maps logical name to discovered condition
 	Conditions map[string]wetwire.DiscoveredCondition
+	// Attributes maps variable name to discovered ResourceAttributes declaration
+	Attributes map[string]wetwire.DiscoveredAttributes
+	// Loops maps variable name to discovered ForEach resource loop
+	Loops map[string]wetwire.DiscoveredLoop
 	// AllVars tracks all package-level var declarations (including non-resources)
 	// Used to avoid false positives when checking dependencies
 	AllVars map[string]bool
@@ -335,6 +341,8 @@ func Discover(opts Options) (*Result, error) {
 		Outputs:     make(map[string]wetwire.DiscoveredOutput),
 		Mappings:    make(map[string]wetwire.DiscoveredMapping),
 		Conditions:  make(map[string]wetwire.DiscoveredCondition),
+		Attributes:  make(map[string]wetwire.DiscoveredAttributes),
+		Loops:       make(map[string]wetwire.DiscoveredLoop),
 		AllVars:     make(map[string]bool),
 		VarAttrRefs: make(map[string]VarAttrRefInfo),
 	}
@@ -364,9 +372,55 @@ func Discover(opts Options) (*Result, error) {
 		}
 	}
 
+	// Validate resource attribute declarations - targets and DependsOn entries
+	// must be resources, and each resource may have at most one declaration
+	attributeTargets := make(map[string]string)
+	for _, name := range sortedAttributeNames(result.Attributes) {
+		attrs := result.Attributes[name]
+		if attrs.Resource == "" {
+			result.Errors = append(result.Errors, fmt.Errorf(
+				"%s:%d: %s does not name a target Resource",
+				attrs.File, attrs.Line, name,
+			))
+			continue
+		}
+		if _, ok := result.Resources[attrs.Resource]; !ok {
+			result.Errors = append(result.Errors, fmt.Errorf(
+				"%s:%d: %s targets undefined resource %q",
+				attrs.File, attrs.Line, name, attrs.Resource,
+			))
+			continue
+		}
+		if other, dup := attributeTargets[attrs.Resource]; dup {
+			result.Errors = append(result.Errors, fmt.Errorf(
+				"%s:%d: %s and %s both declare attributes for %q",
+				attrs.File, attrs.Line, other, name, attrs.Resource,
+			))
+			continue
+		}
+		attributeTargets[attrs.Resource] = name
+		for _, dep := range attrs.DependsOn {
+			if _, ok := result.Resources[dep]; !ok {
+				result.Errors = append(result.Errors, fmt.Errorf(
+					"%s:%d: %s depends on undefined resource %q",
+					attrs.File, attrs.Line, name, dep,
+				))
+			}
+		}
+	}
+
 	return result, nil
 }
 
+func sortedAttributeNames(attributes map[string]wetwire.DiscoveredAttributes) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func discoverPackage(pattern string, result *Result, opts Options) error {
 	// Handle ./... pattern
 	pattern = strings.TrimSuffix(pattern, "/...")
@@ -461,6 +515,18 @@ func discoverFile(fset *token.FileSet, filename string, file *ast.File, result *
 
 			pos := fset.Position(valueSpec.Pos())
 
+			// Check for resource attribute declarations (wetwire.ResourceAttributes{...})
+			if typeName == "ResourceAttributes" && isRootPackage(pkgName, imports) {
+				result.Attributes[name] = extractAttributes(name, compLit, filename, pos.Line)
+				// Track AttrRefs so GetAtts in Metadata and policies resolve
+				_, attrRefs, varRefs := extractDependenciesWithVarRefs(compLit, imports)
+				result.VarAttrRefs[name] = VarAttrRefInfo{
+					AttrRefs: attrRefs,
+					VarRefs:  varRefs,
+				}
+				continue
+			}
+
 			// Check for intrinsic types (Parameter, Output, Mapping, Condition types)
 			if isIntrinsicPackage(pkgName, imports) || pkgName == "" {
 				switch typeName {
@@ -472,13 +538,17 @@ func discoverFile(fset *token.FileSet, filename string, file *ast.File, result *
 					}
 					continue
 				case "Output":
-					// Extract AttrRef usages from output fields
-					_, attrRefs := extractDependencies(compLit, imports)
+					// Extract references and AttrRef usages from output fields
+					deps, attrRefs := extractDependencies(compLit, imports)
+					exported, exportName := extractExport(compLit)
 					result.Outputs[name] = wetwire.DiscoveredOutput{
 						Name:          name,
 						File:          filename,
 						Line:          pos.Line,
 						AttrRefUsages: attrRefs,
+						Dependencies:  deps,
+						Exported:      exported,
+						ExportName:    exportName,
 					}
 					continue
 				case "Mapping":
@@ -488,6 +558,19 @@ func discoverFile(fset *token.FileSet, filename string, file *ast.File, result *
 						Line: pos.Line,
 					}
 					continue
+				case "ForEach":
+					result.Loops[name] = wetwire.DiscoveredLoop{
+						Name: name,
+						File: filename,
+						Line: pos.Line,
+					}
+					// Track AttrRefs so GetAtts in the loop's resources resolve
+					_, attrRefs, varRefs := extractDependenciesWithVarRefs(compLit, imports)
+					result.VarAttrRefs[name] = VarAttrRefInfo{
+						AttrRefs: attrRefs,
+						VarRefs:  varRefs,
+					}
+					continue
 				case "Equals", "And", "Or", "Not":
 					result.Conditions[name] = wetwire.DiscoveredCondition{
 						Name: name,
@@ -533,6 +616,28 @@ func discoverFile(fset *token.FileSet, filename string, file *ast.File, result *
 	}
 }
 
+// externalResourceName returns the qualified name, import path and variable
+// name, of a pkg.Var selector naming a variable of another user package.
+func externalResourceName(expr ast.Expr, imports map[string]string) (string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	path, isImport := imports[ident.Name]
+	if !isImport || path == "github.com/lex00/wetwire-aws-go" || strings.HasPrefix(path, "github.com/lex00/wetwire-aws-go/") {
+		return "", false
+	}
+	name := sel.Sel.Name
+	if len(name) == 0 || name[0] < 'A' || name[0] > 'Z' {
+		return "", false
+	}
+	return path + "." + name, true
+}
+
 // isIntrinsicPackage checks if the package is the intrinsics package.
 func isIntrinsicPackage(pkgName string, imports map[string]string) bool {
 	if pkgName == "" {
@@ -554,6 +659,129 @@ func isIntrinsicPackage(pkgName string, imports map[string]string) bool {
 	return false
 }
 
+// isRootPackage checks if the package alias points to the wetwire-aws root package.
+func isRootPackage(pkgName string, imports map[string]string) bool {
+	if pkgName == "" {
+		return false
+	}
+	path, ok := imports[pkgName]
+	return ok && path == "github.com/lex00/wetwire-aws-go"
+}
+
+// extractAttributes reads the target resource and DependsOn entries from a
+// ResourceAttributes composite literal. The remaining fields are plain values
+// that are extracted at runtime by the runner.
+func extractAttributes(name string, lit *ast.CompositeLit, filename string, line int) wetwire.DiscoveredAttributes {
+	attrs := wetwire.DiscoveredAttributes{
+		Name: name,
+		File: filename,
+		Line: line,
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		switch key.Name {
+		case "Resource":
+			attrs.Resource = referencedVarName(kv.Value)
+		case "DependsOn":
+			deps, ok := kv.Value.(*ast.CompositeLit)
+			if !ok {
+				continue
+			}
+			for _, dep := range deps.Elts {
+				if depName := referencedVarName(dep); depName != "" {
+					attrs.DependsOn = append(attrs.DependsOn, depName)
+				}
+			}
+		}
+	}
+
+	return attrs
+}
+
+// extractExport reads the export of an Output composite literal, declared
+// either as Export: Output_Export{Name: ...} or as ExportName: .... The name
+// is returned when it is a string literal or a Sub template.
+func extractExport(lit *ast.CompositeLit) (exported bool, name string) {
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+
+		switch key.Name {
+		case "ExportName":
+			return true, literalName(kv.Value)
+		case "Export":
+			value := kv.Value
+			if unary, ok := value.(*ast.UnaryExpr); ok {
+				value = unary.X
+			}
+			export, ok := value.(*ast.CompositeLit)
+			if !ok {
+				return true, ""
+			}
+			for _, field := range export.Elts {
+				if fkv, ok := field.(*ast.KeyValueExpr); ok {
+					if fkey, ok := fkv.Key.(*ast.Ident); ok && fkey.Name == "Name" {
+						return true, literalName(fkv.Value)
+					}
+				}
+			}
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// literalName returns the value of a string literal, or the template of a
+// Sub{"..."} or Sub{String: "..."} expression.
+func literalName(expr ast.Expr) string {
+	switch v := expr.(type) {
+	case *ast.BasicLit:
+		if s, err := strconv.Unquote(v.Value); err == nil {
+			return s
+		}
+	case *ast.CompositeLit:
+		if typeName, _ := coreast.ExtractTypeName(v.Type); typeName != "Sub" || len(v.Elts) == 0 {
+			return ""
+		}
+		for _, elt := range v.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return literalName(elt)
+			}
+			if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "String" {
+				return literalName(kv.Value)
+			}
+		}
+	}
+	return ""
+}
+
+// referencedVarName returns the variable name for X or &X expressions.
+func referencedVarName(expr ast.Expr) string {
+	switch v := expr.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.UnaryExpr:
+		return referencedVarName(v.X)
+	}
+	return ""
+}
+
 // extractDependencies finds references to other resources in composite literal fields.
 // It looks for patterns like:
 //   - OtherResource (identifier reference)
@@ -637,6 +865,14 @@ func findDepsWithVarRefs(expr ast.Expr, deps *[]string, attrRefs *[]wetwire.Attr
 					FieldPath:    fieldPath,
 				})
 			}
+		} else if ref, ok := externalResourceName(v.X, imports); ok {
+			// Resource.Attr of another package (e.g., network.Vpc.VpcId), for
+			// the stack linker to import
+			*attrRefs = append(*attrRefs, wetwire.AttrRefUsage{
+				ResourceName: ref,
+				Attribute:    v.Sel.Name,
+				FieldPath:    fieldPath,
+			})
 		}
 
 	case *ast.CompositeLit:
@@ -738,6 +974,7 @@ func isCommonIdent(name string) bool {
 		"And": true, "Or": true, "Not": true, "Condition": true,
 		"FindInMap": true, "Base64": true, "Cidr": true, "GetAZs": true,
 		"ImportValue": true, "Transform": true, "Json": true,
+		"Length": true, "ToJsonString": true, "ForEach": true,
 		"Parameter": true, "Output": true, "Mapping": true,
 
 		// Pseudo-parameter constants (from intrinsics package)
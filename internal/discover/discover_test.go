@@ -117,6 +117,70 @@ var ProcessorFunction = lambda.Function{
 	assert.Contains(t, result.Errors[0].Error(), "UndefinedRole")
 }
 
+func TestDiscover_WithResourceAttributes(t *testing.T) {
+	dir := t.TempDir()
+
+	code := `package infra
+
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/s3"
+)
+
+var LogBucket = s3.Bucket{}
+
+var DataBucket = s3.Bucket{}
+
+var DataBucketAttributes = wetwire.ResourceAttributes{
+	Resource:       DataBucket,
+	DeletionPolicy: wetwire.Retain,
+	DependsOn:      []wetwire.Resource{LogBucket},
+}
+`
+	err := os.WriteFile(filepath.Join(dir, "storage.go"), []byte(code), 0644)
+	require.NoError(t, err)
+
+	result, err := Discover(Options{
+		Packages: []string{dir},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+
+	// The attributes declaration is not a resource
+	assert.Len(t, result.Resources, 2)
+	assert.NotContains(t, result.Resources, "DataBucketAttributes")
+
+	require.Contains(t, result.Attributes, "DataBucketAttributes")
+	attrs := result.Attributes["DataBucketAttributes"]
+	assert.Equal(t, "DataBucket", attrs.Resource)
+	assert.Equal(t, []string{"LogBucket"}, attrs.DependsOn)
+	assert.Equal(t, 12, attrs.Line)
+}
+
+func TestDiscover_ResourceAttributesUndefinedTarget(t *testing.T) {
+	dir := t.TempDir()
+
+	code := `package infra
+
+import wetwire "github.com/lex00/wetwire-aws-go"
+
+var DataBucketAttributes = wetwire.ResourceAttributes{
+	Resource:       DataBucket,
+	DeletionPolicy: wetwire.Retain,
+}
+`
+	err := os.WriteFile(filepath.Join(dir, "storage.go"), []byte(code), 0644)
+	require.NoError(t, err)
+
+	result, err := Discover(Options{
+		Packages: []string{dir},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Error(), `targets undefined resource "DataBucket"`)
+}
+
 func TestDiscover_MultipleFiles(t *testing.T) {
 	dir := t.TempDir()
 
@@ -319,6 +383,38 @@ var RegionMap = Mapping{
 	assert.Contains(t, result.Mappings, "RegionMap")
 }
 
+func TestDiscover_WithForEachLoop(t *testing.T) {
+	dir := t.TempDir()
+
+	code := `package infra
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/sns"
+)
+
+var Topics = ForEach{
+	Identifier: "TopicName",
+	Collection: []any{"Success", "Failure"},
+	Outputs: map[string]any{
+		"Topic${TopicName}": sns.Topic{TopicName: Sub{"${TopicName}"}},
+	},
+}
+`
+	err := os.WriteFile(filepath.Join(dir, "infra.go"), []byte(code), 0644)
+	require.NoError(t, err)
+
+	result, err := Discover(Options{
+		Packages: []string{dir},
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Errors)
+	assert.Contains(t, result.Loops, "Topics")
+	// The loop's resource templates are not resources of their own
+	assert.Empty(t, result.Resources)
+}
+
 func TestDiscover_EmptyPackage(t *testing.T) {
 	dir := t.TempDir()
 
@@ -687,6 +783,38 @@ var MyBucket = s3.Bucket{
 	assert.Contains(t, result.Resources, "MyBucket")
 }
 
+func TestDiscover_ExternalAttrRef(t *testing.T) {
+	// Test that pkg.Resource.Attr of another package is recorded for the
+	// stack linker without becoming a dependency
+	dir := t.TempDir()
+
+	code := `package app
+
+import (
+	"example.com/infra/network"
+	"github.com/lex00/wetwire-aws-go/resources/ec2"
+)
+
+var AppGroup = ec2.SecurityGroup{
+	VpcId: network.Vpc.VpcId,
+}
+`
+	err := os.WriteFile(filepath.Join(dir, "app.go"), []byte(code), 0644)
+	require.NoError(t, err)
+
+	result, err := Discover(Options{
+		Packages: []string{dir},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+
+	group := result.Resources["AppGroup"]
+	assert.Empty(t, group.Dependencies)
+	assert.Equal(t, []wetwire.AttrRefUsage{
+		{ResourceName: "example.com/infra/network.Vpc", Attribute: "VpcId", FieldPath: "VpcId"},
+	}, group.AttrRefUsages)
+}
+
 func TestDiscover_CallExprWithResourceArg(t *testing.T) {
 	// Test that function call arguments are scanned for dependencies
 	dir := t.TempDir()
@@ -824,3 +952,56 @@ This is not valid Go code at all!
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "expected declaration")
 }
+
+func TestDiscover_OutputReferencesAndExport(t *testing.T) {
+	dir := t.TempDir()
+
+	code := `package infra
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/s3"
+)
+
+var DataBucket = s3.Bucket{}
+
+var BucketNameOutput = Output{
+	Value:  DataBucket,
+	Export: &Output_Export{Name: Sub{"${AWS::StackName}-BucketName"}},
+}
+
+var BucketArnOutput = Output{
+	Value:      DataBucket.Arn,
+	ExportName: "shared-bucket-arn",
+}
+
+var ComputedOutput = Output{
+	Value:      DataBucket,
+	ExportName: Join{"-", []any{AWS_STACK_NAME, "bucket"}},
+}
+
+var PlainOutput = Output{
+	Value: DataBucket,
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "infra.go"), []byte(code), 0644))
+
+	result, err := Discover(Options{Packages: []string{dir}})
+	require.NoError(t, err)
+
+	name := result.Outputs["BucketNameOutput"]
+	assert.Equal(t, []string{"DataBucket"}, name.Dependencies)
+	assert.True(t, name.Exported)
+	assert.Equal(t, "${AWS::StackName}-BucketName", name.ExportName)
+
+	arn := result.Outputs["BucketArnOutput"]
+	assert.Contains(t, arn.Dependencies, "DataBucket")
+	assert.True(t, arn.Exported)
+	assert.Equal(t, "shared-bucket-arn", arn.ExportName)
+
+	computed := result.Outputs["ComputedOutput"]
+	assert.True(t, computed.Exported)
+	assert.Empty(t, computed.ExportName)
+
+	assert.False(t, result.Outputs["PlainOutput"].Exported)
+}
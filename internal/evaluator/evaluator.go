@@ -0,0 +1,464 @@
+// Package evaluator resolves the intrinsic functions of a CloudFormation
+// template offline, for a set of parameter values, a region and an account,
+// giving the effective template CloudFormation would deploy.
+//
+// Conditions are evaluated, and resources and outputs whose condition is
+// false are dropped. Ref to parameters and pseudo-parameters, Fn::If,
+// Fn::FindInMap, Fn::Sub, Fn::Join, Fn::Select, Fn::Split, Fn::Base64,
+// Fn::Cidr and Fn::GetAZs are resolved, as are the Fn::ForEach loops,
+// Fn::Length and Fn::ToJsonString of the AWS::LanguageExtensions transform.
+// Values only known after deployment, such as Ref to a resource,
+// Fn::GetAtt and Fn::ImportValue, are left in place as placeholders.
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// Defaults for Options.
+const (
+	DefaultRegion    = "us-east-1"
+	DefaultAccountID = "123456789012"
+	DefaultStackName = "wetwire-stack"
+)
+
+// Options configures the evaluation.
+type Options struct {
+	// Parameters are parameter values by name. Parameters without a value
+	// use their Default.
+	Parameters map[string]string
+	// Region is the value of AWS::Region; defaults to DefaultRegion.
+	Region string
+	// AccountID is the value of AWS::AccountId; defaults to DefaultAccountID.
+	AccountID string
+	// StackName is the value of AWS::StackName; defaults to DefaultStackName.
+	StackName string
+	// AZs maps a region to the availability zones Fn::GetAZs returns.
+	// Regions not listed have the zones a, b and c, e.g. us-east-1a.
+	AZs map[string][]string
+}
+
+// Result is an evaluated template.
+type Result struct {
+	// Template is the effective template. Its Mappings and Conditions are
+	// applied and removed; only parameters without a value are kept.
+	Template *wetwire.Template `json:"template"`
+	// Parameters are the parameter values used.
+	Parameters map[string]any `json:"parameters,omitempty"`
+	// Conditions are the values of the template's conditions.
+	Conditions map[string]bool `json:"conditions,omitempty"`
+	// Dropped lists the resources and outputs removed because their
+	// condition is false, e.g. "Resources.Replica".
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// evaluator holds the state of one evaluation.
+type evaluator struct {
+	opts       Options
+	partition  string
+	urlSuffix  string
+	params     map[string]any
+	unresolved map[string]bool
+	mappings   map[string]any
+	conditions map[string]any
+	values     map[string]bool
+	evaluating map[string]bool
+	resources  map[string]bool
+	dropped    map[string]string
+}
+
+// Evaluate resolves the intrinsic functions of t for the given options.
+func Evaluate(t *wetwire.Template, opts Options) (*Result, error) {
+	if opts.Region == "" {
+		opts.Region = DefaultRegion
+	}
+	if opts.AccountID == "" {
+		opts.AccountID = DefaultAccountID
+	}
+	if opts.StackName == "" {
+		opts.StackName = DefaultStackName
+	}
+
+	// Work on a generic copy, so typed intrinsics built in Go and values
+	// loaded from JSON or YAML look alike
+	tmpl, err := normalize(t)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &evaluator{
+		opts:       opts,
+		params:     make(map[string]any),
+		unresolved: make(map[string]bool),
+		mappings:   tmpl.Mappings,
+		conditions: tmpl.Conditions,
+		values:     make(map[string]bool),
+		evaluating: make(map[string]bool),
+		resources:  make(map[string]bool),
+		dropped:    make(map[string]string),
+	}
+	e.partition, e.urlSuffix = partition(opts.Region)
+	if tmpl.AWSTemplateFormatVersion == "" {
+		tmpl.AWSTemplateFormatVersion = "2010-09-09"
+	}
+
+	if err := e.resolveParameters(tmpl.Parameters); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(tmpl.Conditions) {
+		if _, err := e.condition(name); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &Result{
+		Template: &wetwire.Template{
+			AWSTemplateFormatVersion: tmpl.AWSTemplateFormatVersion,
+			Transform:                tmpl.Transform,
+			Description:              tmpl.Description,
+			Resources:                make(map[string]wetwire.ResourceDef),
+		},
+		Conditions: e.values,
+	}
+	if len(e.params) > 0 {
+		result.Parameters = e.params
+	}
+	for name := range e.unresolved {
+		if result.Template.Parameters == nil {
+			result.Template.Parameters = make(map[string]wetwire.Parameter)
+		}
+		result.Template.Parameters[name] = tmpl.Parameters[name]
+	}
+
+	resources, err := e.expandResources(tmpl.Resources)
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Resources = resources
+
+	// Decide which resources exist before resolving references to them
+	for _, name := range sortedKeys(tmpl.Resources) {
+		def := tmpl.Resources[name]
+		if def.Condition != "" {
+			value, err := e.condition(def.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("Resources.%s: %w", name, err)
+			}
+			if !value {
+				e.dropped[name] = def.Condition
+				result.Dropped = append(result.Dropped, "Resources."+name)
+				continue
+			}
+		}
+		e.resources[name] = true
+	}
+
+	for _, name := range sortedKeys(tmpl.Resources) {
+		if !e.resources[name] {
+			continue
+		}
+		def, err := e.resource("Resources."+name, tmpl.Resources[name])
+		if err != nil {
+			return nil, err
+		}
+		result.Template.Resources[name] = def
+	}
+
+	for _, name := range sortedKeys(tmpl.Outputs) {
+		out := tmpl.Outputs[name]
+		if out.Condition != "" {
+			value, err := e.condition(out.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("Outputs.%s: %w", name, err)
+			}
+			if !value {
+				result.Dropped = append(result.Dropped, "Outputs."+name)
+				continue
+			}
+			out.Condition = ""
+		}
+		value, err := e.eval("Outputs."+name+".Value", out.Value)
+		if err != nil {
+			return nil, err
+		}
+		if value == noValue {
+			continue
+		}
+		out.Value = value
+		if result.Template.Outputs == nil {
+			result.Template.Outputs = make(map[string]wetwire.Output)
+		}
+		result.Template.Outputs[name] = out
+	}
+
+	// The loops and functions of AWS::LanguageExtensions are applied
+	if !template.UsesLanguageExtensions(result.Template) {
+		var transforms wetwire.Transforms
+		for _, macro := range result.Template.Transform {
+			if macro != wetwire.TransformLanguageExtensions {
+				transforms = append(transforms, macro)
+			}
+		}
+		result.Template.Transform = transforms
+	}
+
+	sort.Strings(result.Dropped)
+	return result, nil
+}
+
+// resource evaluates a resource definition.
+func (e *evaluator) resource(path string, def wetwire.ResourceDef) (wetwire.ResourceDef, error) {
+	def.Condition = ""
+
+	var err error
+	if def.Properties, err = e.evalMap(path+".Properties", def.Properties); err != nil {
+		return def, err
+	}
+	if def.CreationPolicy, err = e.evalMap(path+".CreationPolicy", def.CreationPolicy); err != nil {
+		return def, err
+	}
+	if def.UpdatePolicy, err = e.evalMap(path+".UpdatePolicy", def.UpdatePolicy); err != nil {
+		return def, err
+	}
+	if def.DeletionPolicy, err = e.eval(path+".DeletionPolicy", def.DeletionPolicy); err != nil {
+		return def, err
+	}
+	if def.UpdateReplacePolicy, err = e.eval(path+".UpdateReplacePolicy", def.UpdateReplacePolicy); err != nil {
+		return def, err
+	}
+
+	// A dependency on a dropped resource is dropped with it
+	var dependsOn []string
+	for _, dep := range def.DependsOn {
+		if _, dropped := e.dropped[dep]; !dropped {
+			dependsOn = append(dependsOn, dep)
+		}
+	}
+	def.DependsOn = dependsOn
+	return def, nil
+}
+
+// evalMap evaluates a map, keeping nil maps nil.
+func (e *evaluator) evalMap(path string, m map[string]any) (map[string]any, error) {
+	if m == nil {
+		return nil, nil
+	}
+	value, err := e.eval(path, m)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := value.(map[string]any)
+	return result, nil
+}
+
+// resolveParameters determines the value of each parameter from the options
+// or its default, checking it against the allowed values.
+func (e *evaluator) resolveParameters(params map[string]wetwire.Parameter) error {
+	for _, name := range sortedKeys(e.opts.Parameters) {
+		if _, ok := params[name]; !ok {
+			return fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+
+	var missing []string
+	for _, name := range sortedKeys(params) {
+		param := params[name]
+		value, ok := e.opts.Parameters[name]
+		if !ok && param.Default != nil {
+			value, ok = scalarString(param.Default)
+			if !ok {
+				return fmt.Errorf("parameter %s: default must be a string, number or boolean", name)
+			}
+		}
+		if !ok {
+			if isSSMParameter(param.Type) {
+				// Resolved by CloudFormation from Systems Manager at deploy time
+				e.unresolved[name] = true
+				continue
+			}
+			missing = append(missing, name)
+			continue
+		}
+
+		if len(param.AllowedValues) > 0 && !allowed(value, param.AllowedValues) {
+			return fmt.Errorf("parameter %s: value %q is not an allowed value", name, value)
+		}
+
+		if isListParameter(param.Type) {
+			var list []any
+			for _, item := range strings.Split(value, ",") {
+				list = append(list, strings.TrimSpace(item))
+			}
+			e.params[name] = list
+		} else {
+			e.params[name] = value
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("no value for parameters: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// allowed reports whether value is one of the allowed values.
+func allowed(value string, values []any) bool {
+	for _, v := range values {
+		if s, ok := scalarString(v); ok && s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isListParameter reports whether a parameter type holds a list.
+func isListParameter(typ string) bool {
+	typ = strings.TrimSuffix(strings.TrimPrefix(typ, "AWS::SSM::Parameter::Value<"), ">")
+	return typ == "CommaDelimitedList" || strings.HasPrefix(typ, "List<")
+}
+
+// isSSMParameter reports whether a parameter is resolved from Systems Manager.
+func isSSMParameter(typ string) bool {
+	return strings.HasPrefix(typ, "AWS::SSM::Parameter::")
+}
+
+// condition returns the value of a named condition.
+func (e *evaluator) condition(name string) (bool, error) {
+	if value, ok := e.values[name]; ok {
+		return value, nil
+	}
+	def, ok := e.conditions[name]
+	if !ok {
+		return false, fmt.Errorf("unknown condition %s", name)
+	}
+	if e.evaluating[name] {
+		return false, fmt.Errorf("condition %s refers to itself", name)
+	}
+
+	e.evaluating[name] = true
+	value, err := e.conditionValue("Conditions."+name, def)
+	delete(e.evaluating, name)
+	if err != nil {
+		return false, err
+	}
+	e.values[name] = value
+	return value, nil
+}
+
+// conditionValue evaluates a condition function: Fn::Equals, Fn::And,
+// Fn::Or, Fn::Not or a reference to another condition.
+func (e *evaluator) conditionValue(path string, v any) (bool, error) {
+	fn, arg, ok := intrinsic(v)
+	if !ok {
+		return false, fmt.Errorf("%s: expected a condition function", path)
+	}
+
+	switch fn {
+	case "Condition":
+		name, ok := arg.(string)
+		if !ok {
+			return false, fmt.Errorf("%s: Condition must name a condition", path)
+		}
+		return e.condition(name)
+
+	case "Fn::Equals":
+		args, ok := arg.([]any)
+		if !ok || len(args) != 2 {
+			return false, fmt.Errorf("%s: Fn::Equals takes two values", path)
+		}
+		var values [2]any
+		for i, a := range args {
+			value, err := e.eval(path, a)
+			if err != nil {
+				return false, err
+			}
+			if !isResolved(value) {
+				return false, fmt.Errorf("%s: Fn::Equals compares a value only known after deployment", path)
+			}
+			values[i] = value
+		}
+		return equalValues(values[0], values[1]), nil
+
+	case "Fn::And", "Fn::Or":
+		args, ok := arg.([]any)
+		if !ok || len(args) < 2 || len(args) > 10 {
+			return false, fmt.Errorf("%s: %s takes 2 to 10 conditions", path, fn)
+		}
+		for _, a := range args {
+			value, err := e.conditionValue(path, a)
+			if err != nil {
+				return false, err
+			}
+			if fn == "Fn::And" && !value {
+				return false, nil
+			}
+			if fn == "Fn::Or" && value {
+				return true, nil
+			}
+		}
+		return fn == "Fn::And", nil
+
+	case "Fn::Not":
+		args, ok := arg.([]any)
+		if !ok || len(args) != 1 {
+			return false, fmt.Errorf("%s: Fn::Not takes one condition", path)
+		}
+		value, err := e.conditionValue(path, args[0])
+		return !value, err
+	}
+
+	return false, fmt.Errorf("%s: %s is not a condition function", path, fn)
+}
+
+// equalValues compares two resolved values, treating numbers and booleans
+// as the strings CloudFormation compares.
+func equalValues(a, b any) bool {
+	sa, okA := scalarString(a)
+	sb, okB := scalarString(b)
+	if okA && okB {
+		return sa == sb
+	}
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
+
+// normalize returns a copy of t with its values in generic JSON form.
+func normalize(t *wetwire.Template) (*wetwire.Template, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	var tmpl wetwire.Template
+	if err := json.Unmarshal(data, &tmpl); err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+	return &tmpl, nil
+}
+
+// partition returns the partition and URL suffix of a region.
+func partition(region string) (string, string) {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn", "amazonaws.com.cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov", "amazonaws.com"
+	default:
+		return "aws", "amazonaws.com"
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,355 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+const stack = `{
+  "AWSTemplateFormatVersion": "2010-09-09",
+  "Parameters": {
+    "Env": {"Type": "String", "Default": "dev", "AllowedValues": ["dev", "prod"]},
+    "Subnets": {"Type": "CommaDelimitedList", "Default": "subnet-1, subnet-2"},
+    "ImageId": {"Type": "AWS::SSM::Parameter::Value<AWS::EC2::Image::Id>"}
+  },
+  "Mappings": {
+    "EnvConfig": {
+      "dev": {"InstanceType": "t3.micro", "Replicas": 1},
+      "prod": {"InstanceType": "m5.large", "Replicas": 3}
+    }
+  },
+  "Conditions": {
+    "IsProd": {"Fn::Equals": [{"Ref": "Env"}, "prod"]},
+    "IsDev": {"Fn::Not": [{"Condition": "IsProd"}]},
+    "ProdInUsEast": {"Fn::And": [{"Condition": "IsProd"}, {"Fn::Equals": [{"Ref": "AWS::Region"}, "us-east-1"]}]}
+  },
+  "Resources": {
+    "Bucket": {
+      "Type": "AWS::S3::Bucket",
+      "Properties": {
+        "BucketName": {"Fn::Sub": "app-${Env}-${AWS::AccountId}-${AWS::Region}"},
+        "Tags": [
+          {"Key": "env", "Value": {"Ref": "Env"}},
+          {"Fn::If": ["IsProd", {"Key": "backup", "Value": "daily"}, {"Ref": "AWS::NoValue"}]}
+        ]
+      }
+    },
+    "Replica": {
+      "Type": "AWS::S3::Bucket",
+      "Condition": "IsProd"
+    },
+    "Server": {
+      "Type": "AWS::EC2::Instance",
+      "DependsOn": ["Bucket", "Replica"],
+      "Properties": {
+        "ImageId": {"Ref": "ImageId"},
+        "InstanceType": {"Fn::FindInMap": ["EnvConfig", {"Ref": "Env"}, "InstanceType"]},
+        "SubnetId": {"Fn::Select": [1, {"Ref": "Subnets"}]},
+        "AvailabilityZone": {"Fn::Select": ["0", {"Fn::GetAZs": ""}]},
+        "UserData": {"Fn::Base64": {"Fn::Join": ["", ["#!/bin/sh\n", "echo ", {"Ref": "AWS::StackName"}]]}},
+        "Description": {"Fn::Join": ["-", [{"Ref": "Bucket"}, {"Fn::GetAtt": ["Bucket", "Arn"]}]]},
+        "Role": {"Fn::Sub": "arn:${AWS::Partition}:iam::${AWS::AccountId}:role/${Bucket}-${!Literal}"},
+        "Parts": {"Fn::Split": [",", "a,b,c"]},
+        "Cidrs": {"Fn::Cidr": ["10.0.0.0/16", 3, 8]},
+        "Debug": {"Fn::If": ["IsDev", true, {"Ref": "AWS::NoValue"}]}
+      }
+    }
+  },
+  "Outputs": {
+    "BucketArn": {"Value": {"Fn::GetAtt": ["Bucket", "Arn"]}},
+    "ReplicaName": {"Value": {"Ref": "Replica"}, "Condition": "IsProd"}
+  }
+}`
+
+func loadStack(t *testing.T) *wetwire.Template {
+	t.Helper()
+	var tmpl wetwire.Template
+	require.NoError(t, json.Unmarshal([]byte(stack), &tmpl))
+	return &tmpl
+}
+
+func TestEvaluate_Dev(t *testing.T) {
+	result, err := Evaluate(loadStack(t), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"IsProd": false, "IsDev": true, "ProdInUsEast": false}, result.Conditions)
+	assert.Equal(t, []string{"Outputs.ReplicaName", "Resources.Replica"}, result.Dropped)
+	assert.Equal(t, "dev", result.Parameters["Env"])
+	assert.Equal(t, []any{"subnet-1", "subnet-2"}, result.Parameters["Subnets"])
+
+	tmpl := result.Template
+	assert.NotContains(t, tmpl.Resources, "Replica")
+	assert.Nil(t, tmpl.Mappings)
+	assert.Nil(t, tmpl.Conditions)
+	// Only the parameter resolved at deploy time is kept
+	assert.Equal(t, []string{"ImageId"}, sortedKeys(tmpl.Parameters))
+
+	bucket := tmpl.Resources["Bucket"].Properties
+	assert.Equal(t, "app-dev-123456789012-us-east-1", bucket["BucketName"])
+	assert.Equal(t, []any{map[string]any{"Key": "env", "Value": "dev"}}, bucket["Tags"])
+
+	server := tmpl.Resources["Server"]
+	assert.Equal(t, []string{"Bucket"}, server.DependsOn)
+	props := server.Properties
+	assert.Equal(t, map[string]any{"Ref": "ImageId"}, props["ImageId"])
+	assert.Equal(t, "t3.micro", props["InstanceType"])
+	assert.Equal(t, "subnet-2", props["SubnetId"])
+	assert.Equal(t, "us-east-1a", props["AvailabilityZone"])
+	assert.Equal(t, "IyEvYmluL3NoCmVjaG8gd2V0d2lyZS1zdGFjaw==", props["UserData"])
+	assert.Equal(t, map[string]any{"Fn::Join": []any{"-", []any{
+		map[string]any{"Ref": "Bucket"},
+		map[string]any{"Fn::GetAtt": []any{"Bucket", "Arn"}},
+	}}}, props["Description"])
+	assert.Equal(t, map[string]any{"Fn::Sub": "arn:aws:iam::123456789012:role/${Bucket}-${!Literal}"}, props["Role"])
+	assert.Equal(t, []any{"a", "b", "c"}, props["Parts"])
+	assert.Equal(t, []any{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"}, props["Cidrs"])
+	assert.Equal(t, true, props["Debug"])
+
+	assert.Equal(t, []string{"BucketArn"}, sortedKeys(tmpl.Outputs))
+}
+
+func TestEvaluate_Prod(t *testing.T) {
+	result, err := Evaluate(loadStack(t), Options{
+		Parameters: map[string]string{"Env": "prod", "ImageId": "ami-123"},
+		Region:     "eu-west-1",
+		AccountID:  "111122223333",
+		StackName:  "app",
+		AZs:        map[string][]string{"eu-west-1": {"eu-west-1b", "eu-west-1c"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"IsProd": true, "IsDev": false, "ProdInUsEast": false}, result.Conditions)
+	assert.Empty(t, result.Dropped)
+	assert.Empty(t, result.Template.Parameters)
+
+	tmpl := result.Template
+	assert.Contains(t, tmpl.Resources, "Replica")
+	assert.Equal(t, "app-prod-111122223333-eu-west-1", tmpl.Resources["Bucket"].Properties["BucketName"])
+	assert.Len(t, tmpl.Resources["Bucket"].Properties["Tags"], 2)
+
+	server := tmpl.Resources["Server"]
+	assert.Equal(t, []string{"Bucket", "Replica"}, server.DependsOn)
+	assert.Equal(t, "ami-123", server.Properties["ImageId"])
+	assert.Equal(t, "m5.large", server.Properties["InstanceType"])
+	assert.Equal(t, "eu-west-1b", server.Properties["AvailabilityZone"])
+	assert.NotContains(t, server.Properties, "Debug")
+
+	assert.Equal(t, map[string]any{"Ref": "Replica"}, tmpl.Outputs["ReplicaName"].Value)
+	assert.Empty(t, tmpl.Outputs["ReplicaName"].Condition)
+}
+
+func TestEvaluate_SubLiteral(t *testing.T) {
+	tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"Topic": {Type: "AWS::SNS::Topic", Properties: map[string]any{
+			"DisplayName": map[string]any{"Fn::Sub": "${!Name} in ${AWS::Region}"},
+			"TopicName": map[string]any{"Fn::Sub": []any{"${Prefix}-${Topic.TopicName}", map[string]any{
+				"Prefix": map[string]any{"Ref": "AWS::StackName"},
+			}}},
+		}},
+	}}
+
+	result, err := Evaluate(tmpl, Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2010-09-09", result.Template.AWSTemplateFormatVersion)
+	props := result.Template.Resources["Topic"].Properties
+	assert.Equal(t, "${Name} in us-east-1", props["DisplayName"])
+	assert.Equal(t, map[string]any{"Fn::Sub": "wetwire-stack-${Topic.TopicName}"}, props["TopicName"])
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		params   map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "missing parameter",
+			template: `{"Parameters": {"Env": {"Type": "String"}}, "Resources": {}}`,
+			wantErr:  "no value for parameters: Env",
+		},
+		{
+			name:     "unknown parameter",
+			template: `{"Resources": {}}`,
+			params:   map[string]string{"Env": "prod"},
+			wantErr:  "unknown parameter Env",
+		},
+		{
+			name:     "disallowed value",
+			template: `{"Parameters": {"Env": {"Type": "String", "AllowedValues": ["dev"]}}, "Resources": {}}`,
+			params:   map[string]string{"Env": "prod"},
+			wantErr:  `parameter Env: value "prod" is not an allowed value`,
+		},
+		{
+			name:     "missing mapping key",
+			template: `{"Mappings": {"M": {"a": {"b": "c"}}}, "Resources": {"Q": {"Type": "AWS::SQS::Queue", "Properties": {"QueueName": {"Fn::FindInMap": ["M", "a", "x"]}}}}}`,
+			wantErr:  "Resources.Q.Properties.QueueName: Fn::FindInMap: mapping M has no a.x",
+		},
+		{
+			name:     "unknown resource condition",
+			template: `{"Resources": {"Q": {"Type": "AWS::SQS::Queue", "Condition": "Missing"}}}`,
+			wantErr:  "Resources.Q: unknown condition Missing",
+		},
+		{
+			name:     "condition cycle",
+			template: `{"Conditions": {"A": {"Fn::Not": [{"Condition": "A"}]}}, "Resources": {}}`,
+			wantErr:  "condition A refers to itself",
+		},
+		{
+			name:     "ref to dropped resource",
+			template: `{"Conditions": {"Never": {"Fn::Equals": ["a", "b"]}}, "Resources": {"A": {"Type": "AWS::SQS::Queue", "Condition": "Never"}, "B": {"Type": "AWS::SQS::Queue", "Properties": {"QueueName": {"Ref": "A"}}}}}`,
+			wantErr:  "Ref to A, which is not created because condition Never is false",
+		},
+		{
+			name:     "select out of range",
+			template: `{"Resources": {"Q": {"Type": "AWS::SQS::Queue", "Properties": {"QueueName": {"Fn::Select": [3, ["a"]]}}}}}`,
+			wantErr:  "Fn::Select index 3 is out of range for a list of 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpl wetwire.Template
+			require.NoError(t, json.Unmarshal([]byte(tt.template), &tmpl))
+
+			_, err := Evaluate(&tmpl, Options{Parameters: tt.params})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEvaluate_FindInMapDefault(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Mappings: map[string]any{"M": map[string]any{"a": map[string]any{"b": "c"}}},
+		Resources: map[string]wetwire.ResourceDef{
+			"Q": {Type: "AWS::SQS::Queue", Properties: map[string]any{
+				"QueueName": map[string]any{"Fn::FindInMap": []any{"M", "a", "x", map[string]any{"DefaultValue": "fallback"}}},
+			}},
+		},
+	}
+
+	result, err := Evaluate(tmpl, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", result.Template.Resources["Q"].Properties["QueueName"])
+}
+
+func TestEvaluate_LanguageExtensions(t *testing.T) {
+	var tmpl wetwire.Template
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "Transform": ["AWS::LanguageExtensions", "AWS::Serverless-2016-10-31"],
+  "Parameters": {
+    "Env": {"Type": "String", "Default": "prod"},
+    "Topics": {"Type": "CommaDelimitedList", "Default": "order-created, order.shipped"}
+  },
+  "Conditions": {
+    "IsProd": {"Fn::Equals": [{"Ref": "Env"}, "prod"]}
+  },
+  "Resources": {
+    "Fn::ForEach::Topics": ["Name", {"Ref": "Topics"}, {
+      "Topic&{Name}": {
+        "Type": "AWS::SNS::Topic",
+        "DeletionPolicy": {"Fn::If": ["IsProd", "Retain", "Delete"]},
+        "Properties": {
+          "TopicName": {"Fn::Sub": "${Env}-${Name}"},
+          "DisplayName": {"Ref": "Name"},
+          "Tags": [{"Key": "count", "Value": {"Fn::Length": {"Ref": "Topics"}}}]
+        }
+      }
+    }],
+    "Config": {
+      "Type": "AWS::SSM::Parameter",
+      "Properties": {
+        "Value": {"Fn::ToJsonString": {"env": {"Ref": "Env"}, "bucket": {"Ref": "Config"}}},
+        "Description": {"Fn::ToJsonString": {"env": {"Ref": "Env"}}}
+      }
+    }
+  }
+}`), &tmpl))
+
+	result, err := Evaluate(&tmpl, Options{})
+	require.NoError(t, err)
+
+	require.Len(t, result.Template.Resources, 3)
+	created := result.Template.Resources["Topicordercreated"]
+	assert.Equal(t, "AWS::SNS::Topic", created.Type)
+	assert.Equal(t, "Retain", created.DeletionPolicy)
+	assert.Equal(t, "prod-order-created", created.Properties["TopicName"])
+	assert.Equal(t, "order-created", created.Properties["DisplayName"])
+	assert.Equal(t, []any{map[string]any{"Key": "count", "Value": 2}}, created.Properties["Tags"])
+	assert.Equal(t, "prod-order.shipped", result.Template.Resources["Topicordershipped"].Properties["TopicName"])
+
+	config := result.Template.Resources["Config"].Properties
+	assert.Equal(t, `{"env":"prod"}`, config["Description"])
+	// A reference to a resource is only known at deploy time
+	assert.Equal(t, map[string]any{"Fn::ToJsonString": map[string]any{
+		"env": "prod", "bucket": map[string]any{"Ref": "Config"},
+	}}, config["Value"])
+
+	// The template still uses Fn::ToJsonString
+	assert.Equal(t, wetwire.Transforms{wetwire.TransformLanguageExtensions, wetwire.TransformServerless}, result.Template.Transform)
+
+	// Once all are applied, the transform is dropped
+	var loops wetwire.Template
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "Transform": "AWS::LanguageExtensions",
+  "Resources": {"Fn::ForEach::Queues": ["Name", ["A", "B"], {"Queue${Name}": {"Type": "AWS::SQS::Queue"}}]}
+}`), &loops))
+	result, err = Evaluate(&loops, Options{})
+	require.NoError(t, err)
+	assert.Contains(t, result.Template.Resources, "QueueA")
+	assert.Contains(t, result.Template.Resources, "QueueB")
+	assert.Empty(t, result.Template.Transform)
+}
+
+func TestEvaluate_ForEachErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{
+			name:     "not a list",
+			template: `{"Resources": {"Fn::ForEach::Q": ["Name", "A", {"Queue${Name}": {"Type": "AWS::SQS::Queue"}}]}}`,
+			wantErr:  "Resources.Fn::ForEach::Q: Fn::ForEach collection must resolve to a list",
+		},
+		{
+			name:     "duplicate logical ID",
+			template: `{"Resources": {"Fn::ForEach::Q": ["Name", ["a-b", "ab"], {"Queue&{Name}": {"Type": "AWS::SQS::Queue"}}]}}`,
+			wantErr:  "Fn::ForEach::Q creates Queueab twice",
+		},
+		{
+			name:     "clashes with a resource",
+			template: `{"Resources": {"QueueA": {"Type": "AWS::SQS::Queue"}, "Fn::ForEach::Q": ["Name", ["A"], {"Queue${Name}": {"Type": "AWS::SQS::Queue"}}]}}`,
+			wantErr:  "Resources.QueueA: logical ID is defined twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpl wetwire.Template
+			require.NoError(t, json.Unmarshal([]byte(tt.template), &tmpl))
+
+			_, err := Evaluate(&tmpl, Options{})
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCidrSubnets(t *testing.T) {
+	subnets, err := cidrSubnets(netip.MustParsePrefix("2001:db8::/56"), 2, 64)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"2001:db8::/64", "2001:db8:0:1::/64"}, subnets)
+
+	_, err = cidrSubnets(netip.MustParsePrefix("10.0.0.0/24"), 2, 9)
+	assert.ErrorContains(t, err, "do not fit")
+
+	_, err = cidrSubnets(netip.MustParsePrefix("10.0.0.0/24"), 5, 6)
+	assert.ErrorContains(t, err, "room for 4 subnets")
+}
@@ -0,0 +1,554 @@
+package evaluator
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// noValue is the result of Ref to AWS::NoValue; the enclosing property or
+// list element is removed.
+type noValueType struct{}
+
+var noValue any = noValueType{}
+
+// subVariable matches ${Name}, ${Name.Attr} and ${!Literal} in Fn::Sub strings.
+var subVariable = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// eval resolves the intrinsic functions in v. Functions whose value is only
+// known after deployment are returned with their arguments resolved.
+func (e *evaluator) eval(path string, v any) (any, error) {
+	if fn, arg, ok := intrinsic(v); ok {
+		switch fn {
+		case "Ref":
+			return e.ref(path, arg)
+		case "Fn::If":
+			return e.fnIf(path, arg)
+		case "Fn::Sub":
+			return e.fnSub(path, arg)
+		case "Fn::Join":
+			return e.fnJoin(path, arg)
+		case "Fn::Select":
+			return e.fnSelect(path, arg)
+		case "Fn::Split":
+			return e.fnSplit(path, arg)
+		case "Fn::Base64":
+			return e.fnBase64(path, arg)
+		case "Fn::Cidr":
+			return e.fnCidr(path, arg)
+		case "Fn::GetAZs":
+			return e.fnGetAZs(path, arg)
+		case "Fn::FindInMap":
+			return e.fnFindInMap(path, arg)
+		case "Fn::Length":
+			return e.fnLength(path, arg)
+		case "Fn::ToJsonString":
+			return e.fnToJsonString(path, arg)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		// Expand Fn::ForEach loops into the keys they create
+		expanded := make(map[string]any, len(val))
+		for k, item := range val {
+			if !strings.HasPrefix(k, wetwire.ForEachPrefix) {
+				expanded[k] = item
+				continue
+			}
+			outputs, err := e.forEach(path+"."+k, k, item)
+			if err != nil {
+				return nil, err
+			}
+			for outKey, outValue := range outputs {
+				expanded[outKey] = outValue
+			}
+		}
+
+		result := make(map[string]any, len(expanded))
+		for k, item := range expanded {
+			value, err := e.eval(path+"."+k, item)
+			if err != nil {
+				return nil, err
+			}
+			if value != noValue {
+				result[k] = value
+			}
+		}
+		return result, nil
+	case []any:
+		result := make([]any, 0, len(val))
+		for i, item := range val {
+			value, err := e.eval(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			if value != noValue {
+				result = append(result, value)
+			}
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+// ref resolves a Ref to a parameter or pseudo-parameter. A Ref to a
+// resource, or to a parameter resolved at deploy time, is kept.
+func (e *evaluator) ref(path string, arg any) (any, error) {
+	name, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: Ref must name a parameter or resource", path)
+	}
+
+	switch name {
+	case "AWS::NoValue":
+		return noValue, nil
+	case "AWS::Region":
+		return e.opts.Region, nil
+	case "AWS::AccountId":
+		return e.opts.AccountID, nil
+	case "AWS::Partition":
+		return e.partition, nil
+	case "AWS::URLSuffix":
+		return e.urlSuffix, nil
+	case "AWS::StackName":
+		return e.opts.StackName, nil
+	case "AWS::StackId":
+		return fmt.Sprintf("arn:%s:cloudformation:%s:%s:stack/%s/00000000-0000-0000-0000-000000000000",
+			e.partition, e.opts.Region, e.opts.AccountID, e.opts.StackName), nil
+	case "AWS::NotificationARNs":
+		return []any{}, nil
+	}
+
+	if value, ok := e.params[name]; ok {
+		return value, nil
+	}
+	if condition, ok := e.dropped[name]; ok {
+		return nil, fmt.Errorf("%s: Ref to %s, which is not created because condition %s is false", path, name, condition)
+	}
+	if e.resources[name] || e.unresolved[name] {
+		return map[string]any{"Ref": name}, nil
+	}
+	return nil, fmt.Errorf("%s: Ref to unknown parameter or resource %s", path, name)
+}
+
+// fnIf resolves Fn::If [condition, valueIfTrue, valueIfFalse].
+func (e *evaluator) fnIf(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 3 {
+		return nil, fmt.Errorf("%s: Fn::If takes a condition and two values", path)
+	}
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: Fn::If must name a condition", path)
+	}
+	value, err := e.condition(name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if value {
+		return e.eval(path, args[1])
+	}
+	return e.eval(path, args[2])
+}
+
+// fnSub resolves Fn::Sub in string or [string, variables] form. Variables
+// only known after deployment are kept, e.g. ${Bucket.Arn}.
+func (e *evaluator) fnSub(path string, arg any) (any, error) {
+	var str string
+	vars := map[string]any{}
+	switch val := arg.(type) {
+	case string:
+		str = val
+	case []any:
+		if len(val) != 2 {
+			return nil, fmt.Errorf("%s: Fn::Sub takes a string and a map of variables", path)
+		}
+		s, ok1 := val[0].(string)
+		m, ok2 := val[1].(map[string]any)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s: Fn::Sub takes a string and a map of variables", path)
+		}
+		str = s
+		for name, v := range m {
+			value, err := e.eval(path+"."+name, v)
+			if err != nil {
+				return nil, err
+			}
+			vars[name] = value
+		}
+	default:
+		return nil, fmt.Errorf("%s: Fn::Sub takes a string or a [string, variables] list", path)
+	}
+
+	var subErr error
+	unresolved := map[string]any{}
+	partial := false
+	result := subVariable.ReplaceAllStringFunc(str, func(match string) string {
+		name := strings.TrimSpace(match[2 : len(match)-1])
+		if strings.HasPrefix(name, "!") {
+			return match
+		}
+
+		var value any
+		if v, ok := vars[name]; ok {
+			value = v
+		} else if strings.Contains(name, ".") && !strings.HasPrefix(name, "AWS::") {
+			// ${Resource.Attr} is a Fn::GetAtt
+			partial = true
+			return match
+		} else {
+			v, err := e.ref(path, name)
+			if err != nil {
+				subErr = err
+				return match
+			}
+			value = v
+		}
+
+		if s, ok := scalarString(value); ok {
+			return s
+		}
+		if _, ok := vars[name]; ok {
+			unresolved[name] = value
+		}
+		partial = true
+		return match
+	})
+	if subErr != nil {
+		return nil, subErr
+	}
+
+	if partial {
+		if len(unresolved) > 0 {
+			return map[string]any{"Fn::Sub": []any{result, unresolved}}, nil
+		}
+		return map[string]any{"Fn::Sub": result}, nil
+	}
+	// ${!Literal} stands for ${Literal} once nothing is left to substitute
+	return subVariable.ReplaceAllStringFunc(result, func(match string) string {
+		return "${" + strings.TrimPrefix(strings.TrimSpace(match[2:len(match)-1]), "!") + "}"
+	}), nil
+}
+
+// fnJoin resolves Fn::Join [delimiter, values].
+func (e *evaluator) fnJoin(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 2 {
+		return nil, fmt.Errorf("%s: Fn::Join takes a delimiter and a list", path)
+	}
+	delimiter, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: Fn::Join delimiter must be a string", path)
+	}
+	value, err := e.eval(path, args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := value.([]any)
+	if !ok {
+		if isResolved(value) {
+			return nil, fmt.Errorf("%s: Fn::Join takes a list", path)
+		}
+		return map[string]any{"Fn::Join": []any{delimiter, value}}, nil
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		s, ok := scalarString(item)
+		if !ok {
+			return map[string]any{"Fn::Join": []any{delimiter, list}}, nil
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, delimiter), nil
+}
+
+// fnSelect resolves Fn::Select [index, list].
+func (e *evaluator) fnSelect(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 2 {
+		return nil, fmt.Errorf("%s: Fn::Select takes an index and a list", path)
+	}
+	index, err := e.eval(path, args[0])
+	if err != nil {
+		return nil, err
+	}
+	value, err := e.eval(path, args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := value.([]any)
+	if !ok || !isResolved(index) {
+		if isResolved(value) && !ok {
+			return nil, fmt.Errorf("%s: Fn::Select takes a list", path)
+		}
+		return map[string]any{"Fn::Select": []any{index, value}}, nil
+	}
+	i, err := toInt(index)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Fn::Select index: %w", path, err)
+	}
+	if i < 0 || i >= len(list) {
+		return nil, fmt.Errorf("%s: Fn::Select index %d is out of range for a list of %d", path, i, len(list))
+	}
+	return list[i], nil
+}
+
+// fnSplit resolves Fn::Split [delimiter, string].
+func (e *evaluator) fnSplit(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 2 {
+		return nil, fmt.Errorf("%s: Fn::Split takes a delimiter and a string", path)
+	}
+	delimiter, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: Fn::Split delimiter must be a string", path)
+	}
+	value, err := e.eval(path, args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := scalarString(value)
+	if !ok {
+		return map[string]any{"Fn::Split": []any{delimiter, value}}, nil
+	}
+	var list []any
+	for _, part := range strings.Split(s, delimiter) {
+		list = append(list, part)
+	}
+	return list, nil
+}
+
+// fnBase64 resolves Fn::Base64.
+func (e *evaluator) fnBase64(path string, arg any) (any, error) {
+	value, err := e.eval(path, arg)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := scalarString(value)
+	if !ok {
+		return map[string]any{"Fn::Base64": value}, nil
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+}
+
+// fnCidr resolves Fn::Cidr [ipBlock, count, cidrBits], the first count
+// subnets of ipBlock with cidrBits host bits each.
+func (e *evaluator) fnCidr(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 3 {
+		return nil, fmt.Errorf("%s: Fn::Cidr takes an IP block, a count and a number of CIDR bits", path)
+	}
+	value, err := e.eval(path, args)
+	if err != nil {
+		return nil, err
+	}
+	args = value.([]any)
+	if len(args) != 3 || !isResolved(args) {
+		return map[string]any{"Fn::Cidr": args}, nil
+	}
+
+	block, _ := scalarString(args[0])
+	prefix, err := netip.ParsePrefix(block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Fn::Cidr: %w", path, err)
+	}
+	count, err := toInt(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: Fn::Cidr count: %w", path, err)
+	}
+	bits, err := toInt(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: Fn::Cidr bits: %w", path, err)
+	}
+
+	subnets, err := cidrSubnets(prefix, count, bits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: Fn::Cidr: %w", path, err)
+	}
+	return subnets, nil
+}
+
+// cidrSubnets splits prefix into count consecutive subnets with the given
+// number of host bits.
+func cidrSubnets(prefix netip.Prefix, count, bits int) ([]any, error) {
+	prefix = prefix.Masked()
+	size := prefix.Addr().BitLen()
+	length := size - bits
+	if bits < 0 || length < prefix.Bits() {
+		return nil, fmt.Errorf("%d CIDR bits do not fit in %s", bits, prefix)
+	}
+	if count < 1 || count > 256 {
+		return nil, fmt.Errorf("count must be between 1 and 256, got %d", count)
+	}
+	if available := length - prefix.Bits(); available < 9 && count > 1<<available {
+		return nil, fmt.Errorf("%s has room for %d subnets of /%d, not %d", prefix, 1<<available, length, count)
+	}
+
+	base := new(big.Int).SetBytes(prefix.Addr().AsSlice())
+	step := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	subnets := make([]any, count)
+	for i := range subnets {
+		addr := new(big.Int).Add(base, new(big.Int).Mul(step, big.NewInt(int64(i))))
+		bytes := addr.FillBytes(make([]byte, size/8))
+		ip, _ := netip.AddrFromSlice(bytes)
+		subnets[i] = netip.PrefixFrom(ip, length).String()
+	}
+	return subnets, nil
+}
+
+// fnGetAZs resolves Fn::GetAZs from the availability zone table; an empty
+// region is the stack's region.
+func (e *evaluator) fnGetAZs(path string, arg any) (any, error) {
+	value, err := e.eval(path, arg)
+	if err != nil {
+		return nil, err
+	}
+	region, ok := scalarString(value)
+	if !ok {
+		return map[string]any{"Fn::GetAZs": value}, nil
+	}
+	if region == "" {
+		region = e.opts.Region
+	}
+
+	zones, ok := e.opts.AZs[region]
+	if !ok {
+		zones = []string{region + "a", region + "b", region + "c"}
+	}
+	list := make([]any, len(zones))
+	for i, zone := range zones {
+		list[i] = zone
+	}
+	return list, nil
+}
+
+// fnFindInMap resolves Fn::FindInMap [map, topLevelKey, secondLevelKey],
+// with an optional {"DefaultValue": value} used when a key is missing.
+func (e *evaluator) fnFindInMap(path string, arg any) (any, error) {
+	args, ok := arg.([]any)
+	if !ok || (len(args) != 3 && len(args) != 4) {
+		return nil, fmt.Errorf("%s: Fn::FindInMap takes a map name and two keys", path)
+	}
+
+	var keys [3]string
+	for i := range keys {
+		value, err := e.eval(path, args[i])
+		if err != nil {
+			return nil, err
+		}
+		s, ok := scalarString(value)
+		if !ok {
+			if isResolved(value) {
+				return nil, fmt.Errorf("%s: Fn::FindInMap keys must be strings", path)
+			}
+			resolved, err := e.eval(path, args)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"Fn::FindInMap": resolved}, nil
+		}
+		keys[i] = s
+	}
+
+	if mapping, ok := e.mappings[keys[0]].(map[string]any); ok {
+		if top, ok := mapping[keys[1]].(map[string]any); ok {
+			if value, ok := top[keys[2]]; ok {
+				return e.eval(path, value)
+			}
+		}
+	}
+	if len(args) == 4 {
+		if def, ok := args[3].(map[string]any); ok {
+			if value, ok := def["DefaultValue"]; ok {
+				return e.eval(path, value)
+			}
+		}
+	}
+	if _, ok := e.mappings[keys[0]]; !ok {
+		return nil, fmt.Errorf("%s: Fn::FindInMap: unknown mapping %s", path, keys[0])
+	}
+	return nil, fmt.Errorf("%s: Fn::FindInMap: mapping %s has no %s.%s", path, keys[0], keys[1], keys[2])
+}
+
+// intrinsic returns the function name and argument of an intrinsic
+// function, a map with a single Ref, Condition or Fn:: key.
+func intrinsic(v any) (string, any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", nil, false
+	}
+	for key, arg := range m {
+		if key == "Ref" || key == "Condition" || strings.HasPrefix(key, "Fn::") {
+			return key, arg, true
+		}
+	}
+	return "", nil, false
+}
+
+// isResolved reports whether v contains no intrinsic functions.
+func isResolved(v any) bool {
+	if _, _, ok := intrinsic(v); ok {
+		return false
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		for _, item := range val {
+			if !isResolved(item) {
+				return false
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if !isResolved(item) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scalarString formats a string, number or boolean as CloudFormation does.
+func scalarString(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(val), true
+	case bool:
+		return strconv.FormatBool(val), true
+	}
+	return "", false
+}
+
+// toInt converts a number or numeric string to an int.
+func toInt(v any) (int, error) {
+	switch val := v.(type) {
+	case float64:
+		if val != float64(int(val)) {
+			return 0, fmt.Errorf("%v is not an integer", val)
+		}
+		return int(val), nil
+	case int:
+		return val, nil
+	case string:
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", val)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("%v is not an integer", v)
+}
@@ -0,0 +1,172 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// fnLength resolves Fn::Length, the number of elements of a list.
+func (e *evaluator) fnLength(path string, arg any) (any, error) {
+	value, err := e.eval(path, arg)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := value.([]any)
+	if !ok {
+		if _, _, isFn := intrinsic(value); isFn {
+			return map[string]any{"Fn::Length": value}, nil
+		}
+		return nil, fmt.Errorf("%s: Fn::Length takes a list", path)
+	}
+	return len(list), nil
+}
+
+// fnToJsonString resolves Fn::ToJsonString, an object or list as a JSON
+// string.
+func (e *evaluator) fnToJsonString(path string, arg any) (any, error) {
+	value, err := e.eval(path, arg)
+	if err != nil {
+		return nil, err
+	}
+	if !isResolved(value) {
+		return map[string]any{"Fn::ToJsonString": value}, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// expandResources replaces the Fn::ForEach loops of the Resources section
+// with the resources they create.
+func (e *evaluator) expandResources(resources map[string]wetwire.ResourceDef) (map[string]wetwire.ResourceDef, error) {
+	result := make(map[string]wetwire.ResourceDef, len(resources))
+	add := func(name string, def wetwire.ResourceDef) error {
+		if _, exists := result[name]; exists {
+			return fmt.Errorf("Resources.%s: logical ID is defined twice", name)
+		}
+		result[name] = def
+		return nil
+	}
+
+	for _, name := range sortedKeys(resources) {
+		def := resources[name]
+		if !def.IsLoop() {
+			if err := add(name, def); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		expanded, err := e.forEach("Resources."+name, name, def.ForEach)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range sortedKeys(expanded) {
+			data, err := json.Marshal(expanded[id])
+			if err != nil {
+				return nil, fmt.Errorf("Resources.%s: %w", id, err)
+			}
+			var res wetwire.ResourceDef
+			if err := json.Unmarshal(data, &res); err != nil {
+				return nil, fmt.Errorf("Resources.%s: %w", id, err)
+			}
+			if err := add(id, res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// forEach expands the Fn::ForEach loop key with arguments [identifier,
+// collection, outputs] into the outputs for each element of the
+// collection. Loops nested in the outputs are expanded too.
+func (e *evaluator) forEach(path, key string, arg any) (map[string]any, error) {
+	args, ok := arg.([]any)
+	if !ok || len(args) != 3 {
+		return nil, fmt.Errorf("%s: Fn::ForEach takes an identifier, a collection and an output template", path)
+	}
+	identifier, ok := args[0].(string)
+	if !ok || identifier == "" {
+		return nil, fmt.Errorf("%s: Fn::ForEach must name an identifier", path)
+	}
+	outputs, ok := args[2].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: Fn::ForEach output template must be an object", path)
+	}
+	value, err := e.eval(path, args[1])
+	if err != nil {
+		return nil, err
+	}
+	collection, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: Fn::ForEach collection must resolve to a list", path)
+	}
+
+	result := make(map[string]any)
+	for _, item := range collection {
+		element, ok := scalarString(item)
+		if !ok {
+			return nil, fmt.Errorf("%s: Fn::ForEach collection must contain strings", path)
+		}
+		for _, name := range sortedKeys(outputs) {
+			outKey := replaceIdentifier(name, identifier, element).(string)
+			outValue := replaceIdentifier(outputs[name], identifier, element)
+
+			expanded := map[string]any{outKey: outValue}
+			if strings.HasPrefix(outKey, wetwire.ForEachPrefix) {
+				if expanded, err = e.forEach(path+"."+outKey, outKey, outValue); err != nil {
+					return nil, err
+				}
+			}
+			for k, v := range expanded {
+				if _, exists := result[k]; exists {
+					return nil, fmt.Errorf("%s: %s creates %s twice", path, key, k)
+				}
+				result[k] = v
+			}
+		}
+	}
+	return result, nil
+}
+
+// replaceIdentifier replaces a loop identifier with the current element:
+// ${Identifier} and &{Identifier} in keys and strings, such as those of
+// Fn::Sub, and Ref to the identifier. &{} drops the non-alphanumeric
+// characters of the element, so it can be used in logical IDs.
+func replaceIdentifier(v any, identifier, element string) any {
+	switch val := v.(type) {
+	case string:
+		alphanumeric := strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+			return -1
+		}, element)
+		val = strings.ReplaceAll(val, "${"+identifier+"}", element)
+		return strings.ReplaceAll(val, "&{"+identifier+"}", alphanumeric)
+	case map[string]any:
+		if ref, ok := val["Ref"]; ok && len(val) == 1 && ref == identifier {
+			return element
+		}
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			result[replaceIdentifier(k, identifier, element).(string)] = replaceIdentifier(item, identifier, element)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = replaceIdentifier(item, identifier, element)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -1,22 +1,25 @@
-// Package graph generates dependency graphs from discovered CloudFormation resources.
+// Package graph generates dependency graphs from built CloudFormation templates.
 //
-// The graph package analyzes resource dependencies (via Ref and GetAtt references)
-// and generates visualizations in DOT (Graphviz) or Mermaid format.
+// The graph package analyzes the references between template entries (Ref,
+// GetAtt, DependsOn, ImportValue and conditions) and generates visualizations
+// in DOT (Graphviz), Mermaid or interactive HTML format.
 //
 // # Supported Formats
 //
 //   - DOT: Standard Graphviz format, viewable with graphviz tools or online viewers
 //   - Mermaid: Markdown-compatible diagrams, rendered by GitHub and other tools
+//   - HTML: A self-contained interactive viewer with search, filtering by
+//     service and highlighting of what a node depends on and what depends on it
 //
 // # Example
 //
-// Generate a DOT graph from discovered resources:
+// Generate a DOT graph from a built template:
 //
 //	gen := &graph.Generator{
 //	    IncludeParameters: true,
 //	    Format:            graph.FormatDOT,
 //	}
-//	gen.Generate(os.Stdout, discoveredResources)
+//	gen.Generate(graph.New(tmpl, discoverResult), os.Stdout)
 //
 // The output can be rendered with Graphviz:
 //
@@ -24,9 +27,9 @@
 //
 // # Graph Contents
 //
-// Nodes represent CloudFormation resources, colored by service type.
-// Edges represent dependencies (Ref or GetAtt references between resources).
-// Parameters can optionally be included as nodes.
+// Nodes represent resources, parameters, conditions, outputs and values
+// imported from other stacks, each kind with its own shape. Edges point from a
+// node to what it refers to and are styled by reference kind.
 package graph
 
 import (
@@ -34,7 +37,6 @@ import (
 	"strings"
 
 	"github.com/emicklei/dot"
-	wetwire "github.com/lex00/wetwire-aws-go"
 )
 
 // Format specifies the output format for the graph.
@@ -45,34 +47,49 @@ const (
 	FormatDOT Format = "dot"
 	// FormatMermaid outputs Mermaid format for GitHub/markdown rendering.
 	FormatMermaid Format = "mermaid"
+	// FormatHTML outputs a self-contained interactive HTML page.
+	FormatHTML Format = "html"
 )
 
-// Generator creates dependency graphs from discovered resources.
+// Generator creates dependency graphs from template graphs.
 type Generator struct {
-	// IncludeParameters includes parameter references in the graph.
+	// IncludeParameters includes parameter nodes in the graph.
 	IncludeParameters bool
 
-	// Format specifies the output format (dot or mermaid). Defaults to dot.
+	// IncludeConditions includes condition nodes in the graph.
+	IncludeConditions bool
+
+	// IncludeOutputs includes output nodes in the graph.
+	IncludeOutputs bool
+
+	// Format specifies the output format (dot, mermaid or html). Defaults to dot.
 	Format Format
 
+	// Title is the page title of HTML output.
+	Title string
+
 	// ClusterByType groups resources by AWS service type.
 	ClusterByType bool
 }
 
-// Generate creates a dependency graph and writes it to w.
-func (g *Generator) Generate(resources map[string]wetwire.DiscoveredResource, parameters map[string]wetwire.DiscoveredParameter, w io.Writer) error {
-	graph := g.buildGraph(resources, parameters)
+// Generate renders the graph and writes it to w.
+func (g *Generator) Generate(graph *Graph, w io.Writer) error {
+	graph = g.filter(graph)
 
 	format := g.Format
 	if format == "" {
 		format = FormatDOT
 	}
 
+	if format == FormatHTML {
+		return writeHTML(w, graph, g.Title)
+	}
+
 	var output string
 	if format == FormatMermaid {
-		output = dot.MermaidGraph(graph, dot.MermaidTopToBottom)
+		output = g.mermaid(graph)
 	} else {
-		output = graph.String()
+		output = g.buildDOT(graph).String()
 	}
 
 	_, err := w.Write([]byte(output))
@@ -80,153 +97,142 @@ func (g *Generator) Generate(resources map[string]wetwire.DiscoveredResource, pa
 }
 
 // GenerateString is a convenience method that returns the graph as a string.
-func (g *Generator) GenerateString(resources map[string]wetwire.DiscoveredResource, parameters map[string]wetwire.DiscoveredParameter) (string, error) {
+func (g *Generator) GenerateString(graph *Graph) (string, error) {
 	var sb strings.Builder
-	if err := g.Generate(resources, parameters, &sb); err != nil {
+	if err := g.Generate(graph, &sb); err != nil {
 		return "", err
 	}
 	return sb.String(), nil
 }
 
-// buildGraph creates the dot.Graph structure from discovered resources.
-func (g *Generator) buildGraph(resources map[string]wetwire.DiscoveredResource, parameters map[string]wetwire.DiscoveredParameter) *dot.Graph {
-	graph := dot.NewGraph(dot.Directed)
-	graph.Attr("rankdir", "TB")
+// filter returns the graph without the node kinds the generator excludes.
+func (g *Generator) filter(graph *Graph) *Graph {
+	kept := make(map[string]bool, len(graph.Nodes))
+	filtered := &Graph{}
+	for _, n := range graph.Nodes {
+		switch {
+		case n.Kind == NodeParameter && !g.IncludeParameters,
+			n.Kind == NodeCondition && !g.IncludeConditions,
+			n.Kind == NodeOutput && !g.IncludeOutputs:
+			continue
+		}
+		kept[n.ID] = true
+		filtered.Nodes = append(filtered.Nodes, n)
+	}
+	for _, e := range graph.Edges {
+		if kept[e.From] && kept[e.To] {
+			filtered.Edges = append(filtered.Edges, e)
+		}
+	}
+	return filtered
+}
+
+// buildDOT creates the dot.Graph structure from the graph.
+func (g *Generator) buildDOT(graph *Graph) *dot.Graph {
+	dg := dot.NewGraph(dot.Directed)
+	dg.Attr("rankdir", "TB")
 
 	// Set default node style
-	graph.NodeInitializer(func(n dot.Node) {
+	dg.NodeInitializer(func(n dot.Node) {
 		n.Attr("shape", "box")
 		n.Attr("fontname", "Arial")
 	})
 
 	// Set default edge style
-	graph.EdgeInitializer(func(e dot.Edge) {
+	dg.EdgeInitializer(func(e dot.Edge) {
 		e.Attr("fontname", "Arial")
 		e.Attr("fontsize", "10")
 	})
 
-	// Build set of GetAtt references for edge styling
-	getAttRefs := g.buildGetAttSet(resources)
-
+	// Group resources by service when clustering
+	clusters := make(map[string]*dot.Graph)
 	if g.ClusterByType {
-		g.addClusteredNodes(graph, resources)
-	} else {
-		g.addNodes(graph, resources)
-	}
-
-	// Add parameter nodes if requested
-	if g.IncludeParameters && parameters != nil {
-		for name := range parameters {
-			n := graph.Node(name)
-			n.Attr("shape", "ellipse")
-			n.Attr("style", "dashed")
-			n.Label(name)
-		}
-	}
-
-	// Add edges
-	for name, res := range resources {
-		for _, dep := range res.Dependencies {
-			// Skip if dependency is a parameter and we're not including parameters
-			if _, isParam := parameters[dep]; isParam && !g.IncludeParameters {
-				continue
-			}
-			// Skip if dependency doesn't exist as resource or parameter
-			_, isResource := resources[dep]
-			_, isParam := parameters[dep]
-			if !isResource && !isParam {
-				continue
+		counts := make(map[string]int)
+		for _, n := range graph.Nodes {
+			if n.Kind == NodeResource {
+				counts[extractService(n.Type)]++
 			}
-
-			from := graph.Node(name)
-			to := graph.Node(dep)
-			e := graph.Edge(from, to)
-
-			// Style based on reference type
-			key := name + "->" + dep
-			if getAttRefs[key] {
-				e.Attr("color", "blue")
+		}
+		for service, count := range counts {
+			// Single resources need no cluster
+			if count > 1 {
+				cluster := dg.Subgraph("cluster_"+service, dot.ClusterOption{})
+				cluster.Attr("label", service)
+				cluster.Attr("style", "rounded")
+				cluster.Attr("bgcolor", "lightyellow")
+				clusters[service] = cluster
 			}
 		}
 	}
 
-	return graph
-}
-
-// buildGetAttSet creates a set of edges that are GetAtt references.
-func (g *Generator) buildGetAttSet(resources map[string]wetwire.DiscoveredResource) map[string]bool {
-	getAttRefs := make(map[string]bool)
-	for name, res := range resources {
-		for _, usage := range res.AttrRefUsages {
-			key := name + "->" + usage.ResourceName
-			getAttRefs[key] = true
+	nodes := make(map[string]dot.Node, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		parent := dg
+		if cluster, ok := clusters[extractService(n.Type)]; ok && n.Kind == NodeResource {
+			parent = cluster
 		}
+		dn := parent.Node(n.ID)
+		styleDOTNode(dn, n)
+		nodes[n.ID] = dn
 	}
-	return getAttRefs
-}
 
-// addNodes adds resource nodes without clustering.
-func (g *Generator) addNodes(graph *dot.Graph, resources map[string]wetwire.DiscoveredResource) {
-	for name, res := range resources {
-		n := graph.Node(name)
-		cfType := goTypeToCFType(res.Type)
-		n.Label(name + "\\n[" + cfType + "]")
+	for _, e := range graph.Edges {
+		de := dg.Edge(nodes[e.From], nodes[e.To])
+		styleDOTEdge(de, e)
 	}
-}
 
-// addClusteredNodes adds resource nodes grouped by AWS service type.
-func (g *Generator) addClusteredNodes(graph *dot.Graph, resources map[string]wetwire.DiscoveredResource) {
-	// Group resources by service
-	serviceResources := make(map[string][]string)
-	resourceTypes := make(map[string]string)
-
-	for name, res := range resources {
-		service := extractService(res.Type)
-		serviceResources[service] = append(serviceResources[service], name)
-		resourceTypes[name] = res.Type
-	}
+	return dg
+}
 
-	// Create clusters for each service with multiple resources
-	for service, resNames := range serviceResources {
-		if len(resNames) > 1 {
-			cluster := graph.Subgraph("cluster_"+service, dot.ClusterOption{})
-			cluster.Attr("label", service)
-			cluster.Attr("style", "rounded")
-			cluster.Attr("bgcolor", "lightyellow")
-
-			for _, name := range resNames {
-				n := cluster.Node(name)
-				cfType := goTypeToCFType(resourceTypes[name])
-				n.Label(name + "\\n[" + cfType + "]")
-			}
-		} else {
-			// Single resource, no cluster needed
-			for _, name := range resNames {
-				n := graph.Node(name)
-				cfType := goTypeToCFType(resourceTypes[name])
-				n.Label(name + "\\n[" + cfType + "]")
-			}
-		}
+// styleDOTNode sets the label and shape of a node by kind.
+func styleDOTNode(dn dot.Node, n Node) {
+	switch n.Kind {
+	case NodeResource:
+		dn.Label(n.Name + "\\n[" + n.Type + "]")
+	case NodeParameter:
+		dn.Attr("shape", "ellipse")
+		dn.Attr("style", "dashed")
+		dn.Label(n.Name)
+	case NodeCondition:
+		dn.Attr("shape", "diamond")
+		dn.Label(n.Name)
+	case NodeOutput:
+		dn.Attr("shape", "note")
+		dn.Label(n.Name)
+	case NodeImport:
+		dn.Attr("shape", "parallelogram")
+		dn.Attr("style", "dashed")
+		dn.Label("import\\n" + n.Name)
 	}
 }
 
-// extractService extracts the AWS service name from a Go type.
-// e.g., "s3.Bucket" -> "S3"
-func extractService(goType string) string {
-	parts := strings.Split(goType, ".")
-	if len(parts) >= 1 {
-		return strings.ToUpper(parts[0])
+// styleDOTEdge sets the style of an edge by reference kind. Ref edges keep
+// the default style.
+func styleDOTEdge(de dot.Edge, e Edge) {
+	switch e.Kind {
+	case EdgeGetAtt:
+		de.Attr("color", "blue")
+		de.Attr("label", e.Attribute)
+	case EdgeDependsOn:
+		de.Attr("style", "dashed")
+	case EdgeImportValue:
+		de.Attr("color", "darkgreen")
+		de.Attr("style", "bold")
+	case EdgeCondition:
+		de.Attr("color", "gray")
+		de.Attr("style", "dotted")
 	}
-	return "Other"
 }
 
-// goTypeToCFType converts a Go type to CloudFormation type format.
-// e.g., "s3.Bucket" -> "AWS::S3::Bucket"
-func goTypeToCFType(goType string) string {
-	parts := strings.Split(goType, ".")
+// extractService extracts the AWS service name from a resource type.
+// e.g., "AWS::S3::Bucket" or "s3.Bucket" -> "S3"
+func extractService(resourceType string) string {
+	if parts := strings.Split(resourceType, "::"); len(parts) == 3 {
+		return strings.ToUpper(parts[1])
+	}
+	parts := strings.Split(resourceType, ".")
 	if len(parts) == 2 {
-		service := strings.ToUpper(parts[0][:1]) + parts[0][1:]
-		return "AWS::" + service + "::" + parts[1]
+		return strings.ToUpper(parts[0])
 	}
-	return goType
+	return "Other"
 }
@@ -5,189 +5,300 @@ import (
 	"testing"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
 )
 
-func TestGenerator_Generate_SimpleGraph(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"MyBucket": {
-			Name: "MyBucket",
-			Type: "s3.Bucket",
+// testTemplate has one of each node kind and each reference kind.
+func testTemplate() *wetwire.Template {
+	return &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{
+			"Environment": {Type: "String"},
 		},
-		"MyFunction": {
-			Name:         "MyFunction",
-			Type:         "lambda.Function",
-			Dependencies: []string{"MyBucket"},
+		Conditions: map[string]any{
+			"IsProd":     map[string]any{"Fn::Equals": []any{map[string]any{"Ref": "Environment"}, "prod"}},
+			"IsProdEast": map[string]any{"Fn::And": []any{map[string]any{"Condition": "IsProd"}, map[string]any{"Fn::Equals": []any{map[string]any{"Ref": "AWS::Region"}, "us-east-1"}}}},
+		},
+		Resources: map[string]wetwire.ResourceDef{
+			"MyBucket": {
+				Type:      "AWS::S3::Bucket",
+				Condition: "IsProd",
+				Properties: map[string]any{
+					"BucketName": map[string]any{"Fn::Sub": "${Environment}-data-${AWS::AccountId}"},
+				},
+			},
+			"MyRole": {Type: "AWS::IAM::Role"},
+			"MyFunction": {
+				Type:      "AWS::Lambda::Function",
+				DependsOn: []string{"MyBucket"},
+				Properties: map[string]any{
+					"Role": map[string]any{"Fn::GetAtt": []any{"MyRole", "Arn"}},
+					"VpcConfig": map[string]any{
+						"SubnetIds": []any{map[string]any{"Fn::ImportValue": "shared-vpc-PrivateSubnet"}},
+					},
+					"Environment": map[string]any{
+						"Variables": map[string]any{
+							"BUCKET": map[string]any{"Ref": "MyBucket"},
+							"STAGE":  map[string]any{"Fn::If": []any{"IsProdEast", "prod", map[string]any{"Ref": "AWS::NoValue"}}},
+						},
+					},
+				},
+			},
+		},
+		Outputs: map[string]wetwire.Output{
+			"FunctionArn": {Value: map[string]any{"Fn::GetAtt": "MyFunction.Arn"}},
 		},
 	}
+}
 
-	gen := &Generator{}
-	var sb strings.Builder
-	err := gen.Generate(resources, nil, &sb)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func hasEdge(g *Graph, want Edge) bool {
+	for _, e := range g.Edges {
+		if e == want {
+			return true
+		}
 	}
+	return false
+}
 
-	output := sb.String()
+func TestNew_Nodes(t *testing.T) {
+	g := New(testTemplate(), &discover.Result{
+		Resources: map[string]wetwire.DiscoveredResource{
+			"MyBucket": {Name: "MyBucket", File: "storage.go", Line: 12},
+		},
+	})
 
-	// Should be a digraph
-	if !strings.Contains(output, "digraph") {
-		t.Error("expected digraph declaration")
+	var ids []string
+	for _, n := range g.Nodes {
+		ids = append(ids, string(n.Kind)+":"+n.ID)
+	}
+	want := []string{
+		"parameter:Environment",
+		"condition:IsProd",
+		"condition:IsProdEast",
+		"resource:MyBucket",
+		"resource:MyFunction",
+		"resource:MyRole",
+		"output:FunctionArn",
+		"import:Import:shared-vpc-PrivateSubnet",
+	}
+	if strings.Join(ids, " ") != strings.Join(want, " ") {
+		t.Errorf("nodes = %v, want %v", ids, want)
 	}
 
-	// Should have nodes for both resources
-	if !strings.Contains(output, "MyBucket") {
-		t.Error("expected MyBucket node")
+	bucket, ok := g.Node("MyBucket")
+	if !ok {
+		t.Fatal("expected MyBucket node")
 	}
-	if !strings.Contains(output, "MyFunction") {
-		t.Error("expected MyFunction node")
+	if bucket.Type != "AWS::S3::Bucket" || bucket.Service != "S3" || bucket.File != "storage.go" || bucket.Line != 12 {
+		t.Errorf("MyBucket = %+v, want type, service and location", bucket)
+	}
+	if props, ok := bucket.Definition.(map[string]any); !ok || props["BucketName"] == nil {
+		t.Errorf("MyBucket definition = %v, want its properties", bucket.Definition)
 	}
 
-	// Should have edge from MyFunction to MyBucket
-	if !strings.Contains(output, "MyFunction") || !strings.Contains(output, "MyBucket") {
-		t.Error("expected edge from MyFunction to MyBucket")
+	imp, _ := g.Node("Import:shared-vpc-PrivateSubnet")
+	if imp.Name != "shared-vpc-PrivateSubnet" {
+		t.Errorf("import name = %q, want the export name", imp.Name)
 	}
 }
 
-func TestGenerator_Generate_WithGetAtt(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"MyRole": {
-			Name: "MyRole",
-			Type: "iam.Role",
-		},
-		"MyFunction": {
-			Name:         "MyFunction",
-			Type:         "lambda.Function",
-			Dependencies: []string{"MyRole"},
-			AttrRefUsages: []wetwire.AttrRefUsage{
-				{ResourceName: "MyRole", Attribute: "Arn", FieldPath: "Role"},
+func TestNew_Edges(t *testing.T) {
+	g := New(testTemplate(), nil)
+
+	want := []Edge{
+		{From: "IsProd", To: "Environment", Kind: EdgeRef},
+		{From: "IsProdEast", To: "IsProd", Kind: EdgeCondition},
+		{From: "MyBucket", To: "Environment", Kind: EdgeRef},
+		{From: "MyBucket", To: "IsProd", Kind: EdgeCondition},
+		{From: "MyFunction", To: "MyBucket", Kind: EdgeDependsOn},
+		{From: "MyFunction", To: "MyBucket", Kind: EdgeRef},
+		{From: "MyFunction", To: "MyRole", Kind: EdgeGetAtt, Attribute: "Arn"},
+		{From: "MyFunction", To: "IsProdEast", Kind: EdgeCondition},
+		{From: "MyFunction", To: "Import:shared-vpc-PrivateSubnet", Kind: EdgeImportValue},
+		{From: "FunctionArn", To: "MyFunction", Kind: EdgeGetAtt, Attribute: "Arn"},
+	}
+	for _, e := range want {
+		if !hasEdge(g, e) {
+			t.Errorf("missing edge %+v", e)
+		}
+	}
+	if len(g.Edges) != len(want) {
+		t.Errorf("got %d edges, want %d: %+v", len(g.Edges), len(want), g.Edges)
+	}
+}
+
+func TestNew_SubAndImports(t *testing.T) {
+	g := New(&wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{"Stage": {Type: "String"}},
+		Resources: map[string]wetwire.ResourceDef{
+			"Queue": {Type: "AWS::SQS::Queue"},
+			"Topic": {
+				Type: "AWS::SNS::Topic",
+				Properties: map[string]any{
+					"DisplayName":    map[string]any{"Fn::Sub": []any{"${Queue.Arn}-${Name}-${!Literal}", map[string]any{"Name": map[string]any{"Ref": "Stage"}}}},
+					"KmsMasterKeyId": map[string]any{"Fn::ImportValue": map[string]any{"Fn::Sub": "${Stage}-KeyId"}},
+					"TopicName":      map[string]any{"Ref": "Missing"},
+				},
 			},
 		},
+	}, nil)
+
+	want := []Edge{
+		{From: "Topic", To: "Queue", Kind: EdgeGetAtt, Attribute: "Arn"},
+		{From: "Topic", To: "Stage", Kind: EdgeRef},
+		{From: "Topic", To: "Import:${Stage}-KeyId", Kind: EdgeImportValue},
+	}
+	for _, e := range want {
+		if !hasEdge(g, e) {
+			t.Errorf("missing edge %+v", e)
+		}
 	}
+	// References to undefined names, Sub variables and literals are not edges
+	if len(g.Edges) != len(want) {
+		t.Errorf("got %d edges, want %d: %+v", len(g.Edges), len(want), g.Edges)
+	}
+}
 
-	gen := &Generator{}
+func TestGenerator_Generate_DOT(t *testing.T) {
+	gen := &Generator{IncludeParameters: true, IncludeConditions: true, IncludeOutputs: true}
 	var sb strings.Builder
-	err := gen.Generate(resources, nil, &sb)
+	err := gen.Generate(New(testTemplate(), nil), &sb)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	output := sb.String()
 
-	// GetAtt edges should be blue
-	if !strings.Contains(output, "blue") {
-		t.Error("expected blue color for GetAtt edge")
+	// Should be a digraph
+	if !strings.Contains(output, "digraph") {
+		t.Error("expected digraph declaration")
 	}
-}
 
-func TestGenerator_Generate_WithParameters(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"MyBucket": {
-			Name:         "MyBucket",
-			Type:         "s3.Bucket",
-			Dependencies: []string{"BucketName"},
-		},
+	for _, name := range []string{"MyBucket", "MyFunction", "Environment", "IsProd", "FunctionArn", "shared-vpc-PrivateSubnet"} {
+		if !strings.Contains(output, name) {
+			t.Errorf("expected %s node", name)
+		}
 	}
 
-	parameters := map[string]wetwire.DiscoveredParameter{
-		"BucketName": {
-			Name: "BucketName",
-		},
+	// Node kinds and edge kinds are styled differently
+	for _, style := range []string{"ellipse", "diamond", "note", "parallelogram", "blue", "dashed", "dotted", "darkgreen"} {
+		if !strings.Contains(output, style) {
+			t.Errorf("expected %q in output", style)
+		}
 	}
+}
 
-	gen := &Generator{IncludeParameters: true}
-	var sb strings.Builder
-	err := gen.Generate(resources, parameters, &sb)
+func TestGenerator_Generate_ExcludedKinds(t *testing.T) {
+	gen := &Generator{}
+	output, err := gen.GenerateString(New(testTemplate(), nil))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	output := sb.String()
-
-	// Should include parameter node
-	if !strings.Contains(output, "BucketName") {
-		t.Error("expected BucketName parameter node")
+	for _, name := range []string{"Environment", "IsProd", "FunctionArn"} {
+		if strings.Contains(output, name) {
+			t.Errorf("expected %s to be excluded", name)
+		}
 	}
-
-	// Parameter nodes should be ellipse/dashed
-	if !strings.Contains(output, "ellipse") {
-		t.Error("expected ellipse shape for parameter")
+	// Imports are always shown
+	if !strings.Contains(output, "shared-vpc-PrivateSubnet") {
+		t.Error("expected import node")
 	}
 }
 
 func TestGenerator_Generate_ClusterByType(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"Bucket1": {
-			Name: "Bucket1",
-			Type: "s3.Bucket",
-		},
-		"Bucket2": {
-			Name: "Bucket2",
-			Type: "s3.Bucket",
-		},
-		"MyFunction": {
-			Name: "MyFunction",
-			Type: "lambda.Function",
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Bucket1":    {Type: "AWS::S3::Bucket"},
+			"Bucket2":    {Type: "AWS::S3::Bucket"},
+			"MyFunction": {Type: "AWS::Lambda::Function"},
 		},
 	}
 
 	gen := &Generator{ClusterByType: true}
 	var sb strings.Builder
-	err := gen.Generate(resources, nil, &sb)
+	err := gen.Generate(New(tmpl, nil), &sb)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	output := sb.String()
 
-	// Should have cluster for S3 (multiple resources)
-	if !strings.Contains(output, "cluster_") {
-		t.Error("expected cluster subgraph")
+	// Should have cluster for S3 (multiple resources); dot numbers cluster
+	// IDs, so the service is only in the label
+	if n := strings.Count(output, "subgraph cluster_"); n != 1 {
+		t.Errorf("expected 1 cluster subgraph, got %d", n)
+	}
+	if !strings.Contains(output, `label="S3"`) {
+		t.Error("expected S3 cluster")
+	}
+	if strings.Contains(output, `label="LAMBDA"`) {
+		t.Error("expected no cluster for a single resource")
 	}
 }
 
 func TestGenerator_Generate_MermaidFormat(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"MyBucket": {
-			Name: "MyBucket",
-			Type: "s3.Bucket",
-		},
-		"MyFunction": {
-			Name:         "MyFunction",
-			Type:         "lambda.Function",
-			Dependencies: []string{"MyBucket"},
-		},
-	}
-
-	gen := &Generator{Format: FormatMermaid}
+	gen := &Generator{IncludeParameters: true, IncludeConditions: true, IncludeOutputs: true, Format: FormatMermaid}
 	var sb strings.Builder
-	err := gen.Generate(resources, nil, &sb)
+	err := gen.Generate(New(testTemplate(), nil), &sb)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	output := sb.String()
 
-	// Should be mermaid format (flowchart or graph)
-	if !strings.Contains(output, "graph") && !strings.Contains(output, "flowchart") {
-		t.Errorf("expected mermaid graph/flowchart, got:\n%s", output)
+	if !strings.HasPrefix(output, "flowchart TB\n") {
+		t.Errorf("expected mermaid flowchart, got:\n%s", output)
 	}
 
 	// Should NOT be DOT format
 	if strings.Contains(output, "digraph") {
 		t.Error("expected mermaid format, not DOT")
 	}
+
+	for _, want := range []string{
+		`MyBucket["MyBucket<br/>AWS::S3::Bucket"]:::resource`,
+		`Environment(["Environment"]):::parameter`,
+		`IsProd{"IsProd"}:::condition`,
+		`FunctionArn[/"FunctionArn"/]:::output`,
+		`[["import<br/>shared-vpc-PrivateSubnet"]]:::import`,
+		"MyFunction -->|Arn| MyRole",
+		"MyFunction -.-> MyBucket",
+		"MyFunction --> MyBucket",
+		"MyFunction ==> import",
+		"linkStyle",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+func TestGenerator_Generate_MermaidClusters(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Bucket1": {Type: "AWS::S3::Bucket"},
+			"Bucket2": {Type: "AWS::S3::Bucket"},
+		},
+	}
+
+	output, err := (&Generator{ClusterByType: true, Format: FormatMermaid}).GenerateString(New(tmpl, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "subgraph cluster_S3 [S3]") {
+		t.Errorf("expected S3 subgraph, got:\n%s", output)
+	}
 }
 
 func TestGenerator_GenerateString(t *testing.T) {
-	resources := map[string]wetwire.DiscoveredResource{
-		"MyBucket": {
-			Name: "MyBucket",
-			Type: "s3.Bucket",
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"MyBucket": {Type: "AWS::S3::Bucket"},
 		},
 	}
 
 	gen := &Generator{}
-	output, err := gen.GenerateString(resources, nil)
+	output, err := gen.GenerateString(New(tmpl, nil))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -196,3 +307,50 @@ func TestGenerator_GenerateString(t *testing.T) {
 		t.Error("expected MyBucket in output")
 	}
 }
+
+func TestExtractService(t *testing.T) {
+	tests := map[string]string{
+		"AWS::S3::Bucket":   "S3",
+		"AWS::EC2::VPC":     "EC2",
+		"lambda.Function":   "LAMBDA",
+		"Custom::Something": "Other",
+	}
+	for in, want := range tests {
+		if got := extractService(in); got != want {
+			t.Errorf("extractService(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerator_Generate_HTML(t *testing.T) {
+	gen := &Generator{IncludeParameters: true, IncludeConditions: true, IncludeOutputs: true, Format: FormatHTML, Title: "graph <infra>"}
+	output, err := gen.GenerateString(New(testTemplate(), &discover.Result{
+		Resources: map[string]wetwire.DiscoveredResource{
+			"MyBucket": {Name: "MyBucket", File: "storage.go", Line: 12},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(output, "<!DOCTYPE html>") {
+		t.Error("expected an HTML document")
+	}
+	if !strings.Contains(output, "<title>graph &lt;infra&gt;</title>") {
+		t.Error("expected escaped title")
+	}
+
+	// Self-contained: no external scripts or styles
+	for _, external := range []string{"<script src", "<link", "@import"} {
+		if strings.Contains(output, external) {
+			t.Errorf("expected no %q in output", external)
+		}
+	}
+
+	// The graph is embedded as data, with definitions and locations
+	for _, want := range []string{`"id":"MyBucket"`, `"service":"S3"`, `"file":"storage.go"`, `"line":12`, `"kind":"ImportValue"`, `"BucketName"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %s in output", want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package graph
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+)
+
+// viewerHTML is the interactive viewer page. It has no external scripts or
+// styles, so the generated file works offline.
+//
+//go:embed viewer.html
+var viewerHTML string
+
+// viewer renders the viewer page; html/template encodes the graph as JSON in
+// the script context.
+var viewer = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// writeHTML writes the graph as a self-contained interactive HTML page.
+func writeHTML(w io.Writer, graph *Graph, title string) error {
+	if title == "" {
+		title = "Dependency graph"
+	}
+	return viewer.Execute(w, struct {
+		Title string
+		Graph *Graph
+	}{title, graph})
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// mermaidNodeShapes wraps node labels in the Mermaid shape for each kind.
+var mermaidNodeShapes = map[NodeKind][2]string{
+	NodeResource:  {"[", "]"},
+	NodeParameter: {"([", "])"},
+	NodeCondition: {"{", "}"},
+	NodeOutput:    {"[/", "/]"},
+	NodeImport:    {"[[", "]]"},
+}
+
+// mermaidEdgeStyles are the link styles for edge kinds drawn with the default
+// solid arrow.
+var mermaidEdgeStyles = map[EdgeKind]string{
+	EdgeGetAtt:      "stroke:blue",
+	EdgeImportValue: "stroke:darkgreen",
+	EdgeCondition:   "stroke:gray",
+}
+
+// mermaid renders the graph as a Mermaid flowchart.
+func (g *Generator) mermaid(graph *Graph) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart TB\n")
+
+	ids := make(map[string]string, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		ids[n.ID] = mermaidID(n, i)
+	}
+
+	writeNode := func(indent string, n Node) {
+		shape := mermaidNodeShapes[n.Kind]
+		fmt.Fprintf(&sb, "%s%s%s\"%s\"%s:::%s\n", indent, ids[n.ID], shape[0], mermaidLabel(n), shape[1], n.Kind)
+	}
+
+	// Clustered resources are written inside a subgraph per service
+	clustered := make(map[string][]Node)
+	if g.ClusterByType {
+		for _, n := range graph.Nodes {
+			if n.Kind == NodeResource {
+				service := extractService(n.Type)
+				clustered[service] = append(clustered[service], n)
+			}
+		}
+		for service, nodes := range clustered {
+			// Single resources need no cluster
+			if len(nodes) < 2 {
+				delete(clustered, service)
+			}
+		}
+	}
+	inCluster := make(map[string]bool)
+	services := make([]string, 0, len(clustered))
+	for service, nodes := range clustered {
+		services = append(services, service)
+		for _, n := range nodes {
+			inCluster[n.ID] = true
+		}
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		fmt.Fprintf(&sb, "  subgraph cluster_%s [%s]\n", service, service)
+		for _, n := range clustered[service] {
+			writeNode("    ", n)
+		}
+		sb.WriteString("  end\n")
+	}
+	for _, n := range graph.Nodes {
+		if !inCluster[n.ID] {
+			writeNode("  ", n)
+		}
+	}
+
+	for i, e := range graph.Edges {
+		arrow := "-->"
+		switch e.Kind {
+		case EdgeDependsOn:
+			arrow = "-.->"
+		case EdgeImportValue:
+			arrow = "==>"
+		case EdgeCondition:
+			arrow = "-.->"
+		}
+		if e.Kind == EdgeGetAtt && e.Attribute != "" {
+			arrow += "|" + mermaidEscape(e.Attribute) + "|"
+		}
+		fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		if style, ok := mermaidEdgeStyles[e.Kind]; ok {
+			fmt.Fprintf(&sb, "  linkStyle %d %s\n", i, style)
+		}
+	}
+
+	sb.WriteString("  classDef parameter stroke-dasharray: 5 5\n")
+	sb.WriteString("  classDef condition fill:#f5f5f5\n")
+	sb.WriteString("  classDef output fill:#eef6ee\n")
+	sb.WriteString("  classDef import stroke-dasharray: 5 5,fill:#eef6ee\n")
+	sb.WriteString("  classDef resource fill:#fff\n")
+
+	return sb.String()
+}
+
+// mermaidID returns a Mermaid-safe identifier for a node. Logical names are
+// alphanumeric and used as-is; import nodes are numbered.
+func mermaidID(n Node, index int) string {
+	if n.Kind == NodeImport {
+		return fmt.Sprintf("import%d", index)
+	}
+	return n.ID
+}
+
+// mermaidLabel returns the quoted label text of a node.
+func mermaidLabel(n Node) string {
+	switch n.Kind {
+	case NodeResource:
+		return mermaidEscape(n.Name) + "<br/>" + mermaidEscape(n.Type)
+	case NodeImport:
+		return "import<br/>" + mermaidEscape(n.Name)
+	}
+	return mermaidEscape(n.Name)
+}
+
+// mermaidEscape escapes text for a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "|", "#124;").Replace(s)
+}
@@ -0,0 +1,301 @@
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// NodeKind identifies what a node represents.
+type NodeKind string
+
+const (
+	// NodeResource is a template resource.
+	NodeResource NodeKind = "resource"
+	// NodeParameter is a template parameter.
+	NodeParameter NodeKind = "parameter"
+	// NodeCondition is a template condition.
+	NodeCondition NodeKind = "condition"
+	// NodeOutput is a template output.
+	NodeOutput NodeKind = "output"
+	// NodeImport is a value imported from another stack's export with
+	// Fn::ImportValue.
+	NodeImport NodeKind = "import"
+)
+
+// EdgeKind identifies how one node refers to another.
+type EdgeKind string
+
+const (
+	// EdgeRef is a Ref, or a ${Name} placeholder in Fn::Sub.
+	EdgeRef EdgeKind = "Ref"
+	// EdgeGetAtt is a Fn::GetAtt, or a ${Name.Attr} placeholder in Fn::Sub.
+	EdgeGetAtt EdgeKind = "GetAtt"
+	// EdgeDependsOn is an explicit DependsOn attribute.
+	EdgeDependsOn EdgeKind = "DependsOn"
+	// EdgeImportValue is a Fn::ImportValue of another stack's export.
+	EdgeImportValue EdgeKind = "ImportValue"
+	// EdgeCondition is a Condition attribute, a Fn::If or a condition
+	// referring to another condition.
+	EdgeCondition EdgeKind = "Condition"
+)
+
+// Node is a template entry in the graph.
+type Node struct {
+	// ID is unique within the graph: the logical name, or "Import:<export>"
+	// for imports.
+	ID string `json:"id"`
+	// Name is the logical name, or the export name for imports.
+	Name string   `json:"name"`
+	Kind NodeKind `json:"kind"`
+	// Type is the CloudFormation type of a resource or parameter.
+	Type string `json:"type,omitempty"`
+	// Service is the AWS service of a resource, e.g. "S3".
+	Service string `json:"service,omitempty"`
+	// File and Line locate the Go declaration, when known.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Definition is the template entry: the resource properties, the
+	// parameter, the condition expression or the output.
+	Definition any `json:"definition,omitempty"`
+}
+
+// Edge points from a node to a node it refers to.
+type Edge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	// Attribute is the attribute of a GetAtt edge.
+	Attribute string `json:"attribute,omitempty"`
+}
+
+// Graph is the dependency graph of a template.
+type Graph struct {
+	// Nodes are sorted by kind, then ID.
+	Nodes []Node `json:"nodes"`
+	// Edges are sorted by source, target and kind, without duplicates.
+	Edges []Edge `json:"edges"`
+}
+
+// Node returns the node with the given ID.
+func (g *Graph) Node(id string) (Node, bool) {
+	for _, n := range g.Nodes {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	return Node{}, false
+}
+
+// kindOrder orders node kinds in the graph.
+var kindOrder = map[NodeKind]int{
+	NodeParameter: 0,
+	NodeCondition: 1,
+	NodeResource:  2,
+	NodeOutput:    3,
+	NodeImport:    4,
+}
+
+// New builds the graph of a template. When result is non-nil, nodes carry the
+// Go file and line of their declarations.
+func New(tmpl *wetwire.Template, result *discover.Result) *Graph {
+	b := &builder{
+		nodes: make(map[string]Node),
+		edges: make(map[Edge]bool),
+	}
+
+	for name, param := range tmpl.Parameters {
+		n := Node{ID: name, Name: name, Kind: NodeParameter, Type: param.Type, Definition: param}
+		if result != nil {
+			if p, ok := result.Parameters[name]; ok {
+				n.File, n.Line = p.File, p.Line
+			}
+		}
+		b.nodes[name] = n
+	}
+	for name, cond := range tmpl.Conditions {
+		n := Node{ID: name, Name: name, Kind: NodeCondition, Definition: cond}
+		if result != nil {
+			if c, ok := result.Conditions[name]; ok {
+				n.File, n.Line = c.File, c.Line
+			}
+		}
+		b.nodes[name] = n
+		b.walk(name, cond)
+	}
+	for name, res := range tmpl.Resources {
+		if res.IsLoop() {
+			continue
+		}
+		n := Node{ID: name, Name: name, Kind: NodeResource, Type: res.Type, Service: extractService(res.Type), Definition: res.Properties}
+		if result != nil {
+			if r, ok := result.Resources[name]; ok {
+				n.File, n.Line = r.File, r.Line
+			}
+		}
+		b.nodes[name] = n
+		b.walk(name, res.Properties)
+		b.walk(name, res.Metadata)
+		for _, dep := range res.DependsOn {
+			b.addEdge(Edge{From: name, To: dep, Kind: EdgeDependsOn})
+		}
+		if res.Condition != "" {
+			b.addEdge(Edge{From: name, To: res.Condition, Kind: EdgeCondition})
+		}
+	}
+	for name, out := range tmpl.Outputs {
+		n := Node{ID: name, Name: name, Kind: NodeOutput, Definition: out}
+		if result != nil {
+			if o, ok := result.Outputs[name]; ok {
+				n.File, n.Line = o.File, o.Line
+			}
+		}
+		b.nodes[name] = n
+		b.walk(name, out.Value)
+	}
+
+	return b.graph()
+}
+
+// builder collects the nodes and edges of a graph.
+type builder struct {
+	nodes map[string]Node
+	edges map[Edge]bool
+}
+
+// walk adds the edges for the references in value, made from node from.
+func (b *builder) walk(from string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			if target, ok := v["Ref"].(string); ok {
+				b.addEdge(Edge{From: from, To: target, Kind: EdgeRef})
+				return
+			}
+			if getAtt, ok := v["Fn::GetAtt"]; ok {
+				if target, attr, ok := template.GetAttArgs(getAtt); ok {
+					b.addEdge(Edge{From: from, To: target, Kind: EdgeGetAtt, Attribute: attr})
+				}
+				return
+			}
+			if sub, ok := v["Fn::Sub"]; ok {
+				b.walkSub(from, sub)
+				return
+			}
+			if imp, ok := v["Fn::ImportValue"]; ok {
+				b.addImport(from, imp)
+				return
+			}
+			if cond, ok := v["Condition"].(string); ok {
+				b.addEdge(Edge{From: from, To: cond, Kind: EdgeCondition})
+				return
+			}
+			if args, ok := v["Fn::If"].([]any); ok && len(args) == 3 {
+				if cond, ok := args[0].(string); ok {
+					b.addEdge(Edge{From: from, To: cond, Kind: EdgeCondition})
+				}
+				b.walk(from, args[1])
+				b.walk(from, args[2])
+				return
+			}
+		}
+		for _, child := range v {
+			b.walk(from, child)
+		}
+	case []any:
+		for _, child := range v {
+			b.walk(from, child)
+		}
+	}
+}
+
+// walkSub adds the edges for the placeholders in a Fn::Sub value.
+func (b *builder) walkSub(from string, sub any) {
+	str, vars := template.SubArgs(sub)
+	for _, value := range vars {
+		b.walk(from, value)
+	}
+
+	for _, ref := range template.SubReferences(str, vars) {
+		if ref.Attr != "" {
+			b.addEdge(Edge{From: from, To: ref.Name, Kind: EdgeGetAtt, Attribute: ref.Attr})
+			continue
+		}
+		b.addEdge(Edge{From: from, To: ref.Name, Kind: EdgeRef})
+	}
+}
+
+// addImport adds an import node for the export named by a Fn::ImportValue
+// argument. Export names computed with Fn::Sub keep their placeholders, and
+// the references inside them become edges of the importing node.
+func (b *builder) addImport(from string, value any) {
+	var export string
+	switch v := value.(type) {
+	case string:
+		export = v
+	case map[string]any:
+		if sub, ok := v["Fn::Sub"]; ok {
+			export, _ = template.SubArgs(sub)
+		}
+		b.walk(from, v)
+	}
+	if export == "" {
+		return
+	}
+
+	id := "Import:" + export
+	b.nodes[id] = Node{ID: id, Name: export, Kind: NodeImport}
+	b.addEdge(Edge{From: from, To: id, Kind: EdgeImportValue})
+}
+
+// addEdge records an edge; references to pseudo parameters are skipped.
+func (b *builder) addEdge(e Edge) {
+	if strings.HasPrefix(e.To, "AWS::") || e.From == e.To {
+		return
+	}
+	b.edges[e] = true
+}
+
+// graph returns the collected nodes and the edges between them, sorted.
+func (b *builder) graph() *Graph {
+	g := &Graph{
+		Nodes: make([]Node, 0, len(b.nodes)),
+		Edges: make([]Edge, 0, len(b.edges)),
+	}
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		a, c := g.Nodes[i], g.Nodes[j]
+		if a.Kind != c.Kind {
+			return kindOrder[a.Kind] < kindOrder[c.Kind]
+		}
+		return a.ID < c.ID
+	})
+
+	// Edges to undefined names are left to validation
+	for e := range b.edges {
+		to, ok := b.nodes[e.To]
+		if !ok || (e.Kind == EdgeCondition) != (to.Kind == NodeCondition) {
+			continue
+		}
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, c := g.Edges[i], g.Edges[j]
+		if a.From != c.From {
+			return a.From < c.From
+		}
+		if a.To != c.To {
+			return a.To < c.To
+		}
+		if a.Kind != c.Kind {
+			return a.Kind < c.Kind
+		}
+		return a.Attribute < c.Attribute
+	})
+	return g
+}
@@ -0,0 +1,307 @@
+// Package impact finds everything that would be affected by changing a
+// resource: the resources that reference it, directly or through other
+// resources, and the outputs and exports built from any of them.
+//
+// The analysis works on discovery results alone, so it needs no build. A
+// reference is found through a resource's Dependencies and AttrRefUsages,
+// through property-type variables it embeds (VarAttrRefs), and through the
+// DependsOn of its ResourceAttributes.
+package impact
+
+import (
+	"fmt"
+	"sort"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+)
+
+// Reference is one way a resource or output refers to another.
+type Reference struct {
+	// To is the referenced resource or parameter.
+	To string `json:"to"`
+	// FieldPath is where the reference appears, e.g. "VpcConfig.SubnetIds".
+	// It is empty when discovery could not tell.
+	FieldPath string `json:"field_path,omitempty"`
+	// Attribute is the attribute of a GetAtt reference; empty for Ref.
+	Attribute string `json:"attribute,omitempty"`
+	// Via lists the property-type variables the reference passes through.
+	Via []string `json:"via,omitempty"`
+}
+
+// Dependent is a resource affected by the change.
+type Dependent struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Depth is 1 for resources that reference the target directly, 2 for
+	// resources that reference those, and so on.
+	Depth int `json:"depth"`
+	// References are the references to the target or to other dependents.
+	References []Reference `json:"references"`
+}
+
+// AffectedOutput is an output built from the target or a dependent.
+type AffectedOutput struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Exported reports whether the output is exported to other stacks.
+	Exported bool `json:"exported"`
+	// ExportName is the export name, when known.
+	ExportName string `json:"export_name,omitempty"`
+	// References are the references to the target or to dependents.
+	References []Reference `json:"references"`
+}
+
+// Result is the impact of changing a resource.
+type Result struct {
+	Target     string `json:"target"`
+	TargetType string `json:"target_type"`
+	File       string `json:"file,omitempty"`
+	Line       int    `json:"line,omitempty"`
+	// Dependents are sorted by depth, then name.
+	Dependents []Dependent `json:"dependents"`
+	// Outputs are sorted by name.
+	Outputs []AffectedOutput `json:"outputs"`
+}
+
+// Analyze returns the impact of changing target, a resource or parameter
+// in the discovery result.
+func Analyze(result *discover.Result, target string) (*Result, error) {
+	impact := &Result{
+		Target:     target,
+		Dependents: []Dependent{},
+		Outputs:    []AffectedOutput{},
+	}
+	if res, ok := result.Resources[target]; ok {
+		impact.TargetType, impact.File, impact.Line = res.Type, res.File, res.Line
+	} else if param, ok := result.Parameters[target]; ok {
+		impact.TargetType, impact.File, impact.Line = "Parameter", param.File, param.Line
+	} else {
+		return nil, fmt.Errorf("%s is not a resource or parameter", target)
+	}
+
+	a := &analyzer{result: result, refs: make(map[string][]Reference)}
+	for name := range result.Resources {
+		a.refs[name] = a.resourceReferences(name)
+	}
+
+	// Walk the reverse references breadth-first so each dependent gets the
+	// depth of its shortest path to the target
+	affected := map[string]int{target: 0}
+	frontier := []string{target}
+	for depth := 1; len(frontier) > 0; depth++ {
+		var next []string
+		for _, name := range sortedKeys(result.Resources) {
+			if _, done := affected[name]; done {
+				continue
+			}
+			for _, ref := range a.refs[name] {
+				if contains(frontier, ref.To) {
+					affected[name] = depth
+					next = append(next, name)
+					break
+				}
+			}
+		}
+		frontier = next
+	}
+
+	for name, depth := range affected {
+		if name == target {
+			continue
+		}
+		res := result.Resources[name]
+		impact.Dependents = append(impact.Dependents, Dependent{
+			Name:       name,
+			Type:       res.Type,
+			File:       res.File,
+			Line:       res.Line,
+			Depth:      depth,
+			References: filterReferences(a.refs[name], affected),
+		})
+	}
+	sort.Slice(impact.Dependents, func(i, j int) bool {
+		a, b := impact.Dependents[i], impact.Dependents[j]
+		if a.Depth != b.Depth {
+			return a.Depth < b.Depth
+		}
+		return a.Name < b.Name
+	})
+
+	for _, name := range sortedKeys(result.Outputs) {
+		out := result.Outputs[name]
+		refs := filterReferences(outputReferences(out), affected)
+		if len(refs) == 0 {
+			continue
+		}
+		impact.Outputs = append(impact.Outputs, AffectedOutput{
+			Name:       name,
+			File:       out.File,
+			Line:       out.Line,
+			Exported:   out.Exported,
+			ExportName: out.ExportName,
+			References: refs,
+		})
+	}
+
+	return impact, nil
+}
+
+// analyzer collects the references of each resource.
+type analyzer struct {
+	result *discover.Result
+	refs   map[string][]Reference
+}
+
+// isNode reports whether name is a resource or parameter, as opposed to a
+// property-type variable.
+func (a *analyzer) isNode(name string) bool {
+	_, isResource := a.result.Resources[name]
+	_, isParam := a.result.Parameters[name]
+	return isResource || isParam
+}
+
+// resourceReferences returns the references a resource makes.
+func (a *analyzer) resourceReferences(name string) []Reference {
+	refs := a.varReferences(name, "", nil, map[string]bool{name: true})
+
+	// DependsOn from ResourceAttributes declarations
+	for _, attrs := range a.result.Attributes {
+		if attrs.Resource != name {
+			continue
+		}
+		for _, dep := range attrs.DependsOn {
+			refs = append(refs, Reference{To: dep, FieldPath: "DependsOn"})
+		}
+	}
+
+	// Dependencies whose field path discovery did not keep, e.g. the second
+	// element of a list
+	found := make(map[string]bool)
+	for _, ref := range refs {
+		found[ref.To] = true
+	}
+	for _, dep := range a.result.Resources[name].Dependencies {
+		if !found[dep] && a.isNode(dep) && dep != name {
+			refs = append(refs, Reference{To: dep})
+			found[dep] = true
+		}
+	}
+
+	return dedupe(refs)
+}
+
+// varReferences returns the references made in a variable's fields,
+// following property-type variables it embeds.
+func (a *analyzer) varReferences(varName, prefix string, via []string, visited map[string]bool) []Reference {
+	info, ok := a.result.VarAttrRefs[varName]
+	if !ok {
+		return nil
+	}
+
+	var refs []Reference
+	for _, usage := range info.AttrRefs {
+		refs = append(refs, Reference{
+			To:        usage.ResourceName,
+			FieldPath: joinPath(prefix, usage.FieldPath),
+			Attribute: usage.Attribute,
+			Via:       via,
+		})
+	}
+	for _, fieldPath := range sortedKeys(info.VarRefs) {
+		ref := info.VarRefs[fieldPath]
+		path := joinPath(prefix, fieldPath)
+		if a.isNode(ref) {
+			refs = append(refs, Reference{To: ref, FieldPath: path, Via: via})
+			continue
+		}
+		if visited[ref] {
+			continue
+		}
+		visited[ref] = true
+		nextVia := append(append([]string{}, via...), ref)
+		refs = append(refs, a.varReferences(ref, path, nextVia, visited)...)
+	}
+	return refs
+}
+
+// outputReferences returns the references an output makes.
+func outputReferences(out wetwire.DiscoveredOutput) []Reference {
+	var refs []Reference
+	found := make(map[string]bool)
+	for _, usage := range out.AttrRefUsages {
+		refs = append(refs, Reference{To: usage.ResourceName, FieldPath: usage.FieldPath, Attribute: usage.Attribute})
+		found[usage.ResourceName] = true
+	}
+	for _, dep := range out.Dependencies {
+		if !found[dep] {
+			refs = append(refs, Reference{To: dep, FieldPath: "Value"})
+		}
+	}
+	return dedupe(refs)
+}
+
+// filterReferences keeps the references to affected names.
+func filterReferences(refs []Reference, affected map[string]int) []Reference {
+	var kept []Reference
+	for _, ref := range refs {
+		if _, ok := affected[ref.To]; ok {
+			kept = append(kept, ref)
+		}
+	}
+	return kept
+}
+
+// dedupe removes repeated references and sorts them by target and path.
+func dedupe(refs []Reference) []Reference {
+	seen := make(map[string]bool)
+	var unique []Reference
+	for _, ref := range refs {
+		key := fmt.Sprintf("%s|%s|%s|%v", ref.To, ref.FieldPath, ref.Attribute, ref.Via)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, ref)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].To != unique[j].To {
+			return unique[i].To < unique[j].To
+		}
+		return unique[i].FieldPath < unique[j].FieldPath
+	})
+	return unique
+}
+
+// joinPath joins field path segments with dots.
+func joinPath(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	}
+	return prefix + "." + path
+}
+
+// contains reports whether names includes name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+package impact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lex00/wetwire-aws-go/internal/discover"
+)
+
+const infra = `package infra
+
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/ec2"
+	"github.com/lex00/wetwire-aws-go/resources/kms"
+	"github.com/lex00/wetwire-aws-go/resources/lambda"
+	"github.com/lex00/wetwire-aws-go/resources/s3"
+)
+
+var Environment = Parameter{Type: "String"}
+
+var SharedVpc = ec2.VPC{
+	CidrBlock: "10.0.0.0/16",
+}
+
+var AppSubnet = ec2.Subnet{
+	VpcId: SharedVpc,
+}
+
+var AppSecurityGroup = ec2.SecurityGroup{
+	VpcId: SharedVpc.VpcId,
+}
+
+var AppVpcConfig = lambda.Function_VpcConfig{
+	SubnetIds:        []any{AppSubnet},
+	SecurityGroupIds: []any{AppSecurityGroup.GroupId},
+}
+
+var AppFunction = lambda.Function{
+	VpcConfig: AppVpcConfig,
+}
+
+var AppFunctionAttributes = wetwire.ResourceAttributes{
+	Resource:  AppFunction,
+	DependsOn: []any{Logs},
+}
+
+var Logs = s3.Bucket{
+	BucketName: Environment,
+}
+
+var Unrelated = kms.Key{}
+
+var VpcIdOutput = Output{
+	Value:      SharedVpc,
+	ExportName: "shared-vpc-id",
+}
+
+var FunctionArnOutput = Output{
+	Value: AppFunction.Arn,
+}
+
+var KeyOutput = Output{
+	Value: Unrelated.Arn,
+}
+`
+
+func discoverInfra(t *testing.T) *discover.Result {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "infra.go"), []byte(infra), 0644))
+
+	result, err := discover.Discover(discover.Options{Packages: []string{dir}})
+	require.NoError(t, err)
+	return result
+}
+
+func dependentNames(r *Result) []string {
+	var names []string
+	for _, d := range r.Dependents {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestAnalyze_Closure(t *testing.T) {
+	r, err := Analyze(discoverInfra(t), "SharedVpc")
+	require.NoError(t, err)
+
+	assert.Equal(t, "ec2.VPC", r.TargetType)
+	assert.Equal(t, []string{"AppSecurityGroup", "AppSubnet", "AppFunction"}, dependentNames(r))
+
+	subnet := r.Dependents[1]
+	assert.Equal(t, 1, subnet.Depth)
+	assert.Equal(t, []Reference{{To: "SharedVpc", FieldPath: "VpcId"}}, subnet.References)
+
+	sg := r.Dependents[0]
+	assert.Equal(t, []Reference{{To: "SharedVpc", FieldPath: "VpcId", Attribute: "VpcId"}}, sg.References)
+
+	// The function reaches the VPC through the property-type variable
+	fn := r.Dependents[2]
+	assert.Equal(t, 2, fn.Depth)
+	assert.Contains(t, fn.References, Reference{To: "AppSubnet", FieldPath: "VpcConfig.SubnetIds", Via: []string{"AppVpcConfig"}})
+	assert.Contains(t, fn.References, Reference{To: "AppSecurityGroup", FieldPath: "VpcConfig.SecurityGroupIds", Attribute: "GroupId", Via: []string{"AppVpcConfig"}})
+}
+
+func TestAnalyze_Outputs(t *testing.T) {
+	r, err := Analyze(discoverInfra(t), "SharedVpc")
+	require.NoError(t, err)
+
+	require.Len(t, r.Outputs, 2)
+	fnOut := r.Outputs[0]
+	assert.Equal(t, "FunctionArnOutput", fnOut.Name)
+	assert.False(t, fnOut.Exported)
+	assert.Equal(t, []Reference{{To: "AppFunction", FieldPath: "Value", Attribute: "Arn"}}, fnOut.References)
+
+	vpcOut := r.Outputs[1]
+	assert.Equal(t, "VpcIdOutput", vpcOut.Name)
+	assert.True(t, vpcOut.Exported)
+	assert.Equal(t, "shared-vpc-id", vpcOut.ExportName)
+}
+
+func TestAnalyze_DependsOnAndParameters(t *testing.T) {
+	r, err := Analyze(discoverInfra(t), "Environment")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Parameter", r.TargetType)
+	assert.Equal(t, []string{"Logs", "AppFunction"}, dependentNames(r))
+	assert.Equal(t, []Reference{{To: "Logs", FieldPath: "DependsOn"}}, r.Dependents[1].References)
+}
+
+func TestAnalyze_NoDependents(t *testing.T) {
+	r, err := Analyze(discoverInfra(t), "AppFunction")
+	require.NoError(t, err)
+
+	assert.Empty(t, r.Dependents)
+	require.Len(t, r.Outputs, 1)
+	assert.Equal(t, "FunctionArnOutput", r.Outputs[0].Name)
+}
+
+func TestAnalyze_UnknownTarget(t *testing.T) {
+	_, err := Analyze(discoverInfra(t), "AppVpcConfig")
+	assert.ErrorContains(t, err, "not a resource or parameter")
+}
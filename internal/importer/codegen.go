@@ -286,6 +286,9 @@ func GenerateCode(template *IRTemplate, packageName string) map[string]string {
 		categoryImports[category] = imports
 	}
 
+	// Generate loops before params so parameters used only by loops are included
+	loopsCode, loopsImports := generateLoops(ctx)
+
 	// Pre-scan all expressions for parameter references before generating params
 	// This ensures parameters used in conditions, resources, and Sub strings are included
 	prescanAllForParams(ctx)
@@ -304,6 +307,11 @@ func GenerateCode(template *IRTemplate, packageName string) map[string]string {
 		files["params.go"] = buildFile(ctx.packageName, "Parameters and Conditions", paramsImports, combined)
 	}
 
+	// Generate loops.go if there are Fn::ForEach resource loops
+	if loopsCode != "" {
+		files["loops.go"] = buildFile(ctx.packageName, "Fn::ForEach resource loops", loopsImports, loopsCode)
+	}
+
 	// Generate outputs.go if there are outputs
 	if outputsCode, outputsImports := generateOutputs(ctx); outputsCode != "" {
 		files["outputs.go"] = buildFile(ctx.packageName, "Outputs", outputsImports, outputsCode)
@@ -377,6 +385,7 @@ func codeUsesIntrinsics(code string) bool {
 		"AWS_PARTITION", "AWS_URL_SUFFIX", "AWS_NO_VALUE", "AWS_NOTIFICATION_ARNS",
 		"PolicyDocument{", "PolicyStatement{", "DenyStatement{", "AllowStatement{",
 		"ServicePrincipal{", "AWSPrincipal{", "FederatedPrincipal{", "Tag{",
+		"Length{", "ToJsonString{", "ForEach{",
 	}
 	for _, t := range intrinsicTypes {
 		if strings.Contains(code, t) {
@@ -406,6 +415,8 @@ func buildFile(packageName, description string, imports map[string]bool, code st
 		for _, imp := range sortedImports {
 			if imp == "github.com/lex00/wetwire-aws-go/intrinsics" {
 				importLines = append(importLines, fmt.Sprintf("\t. %q", imp))
+			} else if imp == "github.com/lex00/wetwire-aws-go" {
+				importLines = append(importLines, fmt.Sprintf("\twetwire %q", imp))
 			} else {
 				importLines = append(importLines, fmt.Sprintf("\t%q", imp))
 			}
@@ -444,6 +455,9 @@ type codegenContext struct {
 	// Track which GetAtt references would create initialization cycles
 	// Key is "sourceLogicalID:targetLogicalID", value is true if this reference is cyclic
 	cyclicGetAttRefs map[string]bool
+
+	// Identifiers of the Fn::ForEach loop being generated
+	loopIdentifiers map[string]bool
 }
 
 // propertyBlock represents a top-level var declaration for a property type instance.
@@ -464,6 +478,7 @@ func newCodegenContext(template *IRTemplate, packageName string) *codegenContext
 		usedParameters:   make(map[string]bool),
 		unknownResources: make(map[string]bool),
 		cyclicGetAttRefs: make(map[string]bool),
+		loopIdentifiers:  make(map[string]bool),
 	}
 
 	// Topologically sort resources
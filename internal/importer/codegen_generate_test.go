@@ -137,6 +137,48 @@ Resources:
 	assert.Contains(t, code, `var IsProdCondition = Equals{Environment, "prod"}`)
 }
 
+func TestGenerateCode_WithResourceAttributes(t *testing.T) {
+	content := []byte(`
+Parameters:
+  Environment:
+    Type: String
+
+Conditions:
+  IsProd: !Equals [!Ref Environment, "prod"]
+
+Resources:
+  LogBucket:
+    Type: AWS::S3::Bucket
+
+  DataBucket:
+    Type: AWS::S3::Bucket
+    Condition: IsProd
+    DependsOn: LogBucket
+    DeletionPolicy: Retain
+    UpdateReplacePolicy: Retain
+    Metadata:
+      Owner: data-team
+`)
+
+	ir, err := ParseTemplateContent(content, "test.yaml")
+	require.NoError(t, err)
+
+	files := GenerateCode(ir, "attrs")
+	code := files["storage.go"]
+
+	assert.Contains(t, code, `wetwire "github.com/lex00/wetwire-aws-go"`)
+	assert.Contains(t, code, "var DataBucketAttributes = wetwire.ResourceAttributes{")
+	assert.Contains(t, code, "Resource: DataBucket,")
+	assert.Contains(t, code, `Condition: "IsProdCondition",`)
+	assert.Contains(t, code, "DependsOn: []wetwire.Resource{LogBucket},")
+	assert.Contains(t, code, `DeletionPolicy: "Retain",`)
+	assert.Contains(t, code, `UpdateReplacePolicy: "Retain",`)
+	assert.Contains(t, code, `"Owner": "data-team"`)
+
+	// Resources without attributes get no companion declaration
+	assert.NotContains(t, code, "LogBucketAttributes")
+}
+
 func TestGenerateCode_WithMappings(t *testing.T) {
 	content := []byte(`
 Mappings:
@@ -627,3 +669,52 @@ Resources:
 	// If{} used in list fields should be wrapped in []any{}
 	assert.Contains(t, databaseCode, "[]any{If{", "If{} should be wrapped in []any{}")
 }
+
+func TestGenerateCode_LanguageExtensions(t *testing.T) {
+	content := []byte(`
+Transform: AWS::LanguageExtensions
+
+Parameters:
+  TopicNames:
+    Type: CommaDelimitedList
+
+Conditions:
+  IsProd: !Equals [!Ref "AWS::Region", "us-east-1"]
+
+Resources:
+  Fn::ForEach::Topics:
+    - TopicName
+    - !Ref TopicNames
+    - Topic${TopicName}:
+        Type: AWS::SNS::Topic
+        Properties:
+          TopicName: !Sub "${TopicName}"
+
+  Queue:
+    Type: AWS::SQS::Queue
+    DeletionPolicy: !If [IsProd, Retain, Delete]
+    Properties:
+      DelaySeconds: !Length [a, b]
+`)
+
+	ir, err := ParseTemplateContent(content, "test.yaml")
+	require.NoError(t, err)
+	require.Contains(t, ir.Loops, "Topics")
+	assert.Equal(t, "TopicName", ir.Loops["Topics"].Identifier)
+	assert.NotContains(t, ir.Resources, "Fn::ForEach::Topics")
+
+	files := GenerateCode(ir, "loops")
+	loopsCode := files["loops.go"]
+
+	assert.Contains(t, loopsCode, "var Topics = ForEach{")
+	assert.Contains(t, loopsCode, `Identifier: "TopicName",`)
+	assert.Contains(t, loopsCode, "Collection: TopicNames,")
+	assert.Contains(t, loopsCode, `"Topic${TopicName}": sns.Topic{`)
+	// The loop identifier is not a variable, so the Sub is kept
+	assert.Contains(t, loopsCode, `TopicName: Sub{String: "${TopicName}"},`)
+	assert.Contains(t, files["params.go"], "TopicNames")
+
+	messagingCode := files["messaging.go"]
+	assert.Contains(t, messagingCode, "DelaySeconds: Length{")
+	assert.Contains(t, messagingCode, `DeletionPolicy: If{"IsProd", `)
+}
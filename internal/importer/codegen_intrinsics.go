@@ -83,6 +83,10 @@ func simplifySubString(ctx *codegenContext, s string) string {
 					return fmt.Sprintf("%s.%s", sanitizeVarName(logicalID), attr)
 				}
 			}
+			// Loop identifiers are replaced by CloudFormation, so keep the Sub
+			if ctx.loopIdentifiers[inner] {
+				return fmt.Sprintf("Sub{String: %q}", s)
+			}
 			// Regular variable reference
 			// Check if it's a known resource or parameter
 			if _, ok := ctx.template.Resources[inner]; ok {
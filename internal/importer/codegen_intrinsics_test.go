@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -180,3 +181,21 @@ func TestPseudoParameterToGo(t *testing.T) {
 		})
 	}
 }
+
+func TestLanguageExtensionToGo(t *testing.T) {
+	ctx := &codegenContext{
+		template: NewIRTemplate(),
+		imports:  make(map[string]bool),
+	}
+
+	code, ok := languageExtensionToGo(ctx, map[string]any{"Fn::Length": []any{"a", "b"}})
+	require.True(t, ok)
+	assert.True(t, strings.HasPrefix(code, "Length{[]any{"))
+
+	code, ok = languageExtensionToGo(ctx, map[string]any{"Fn::ToJsonString": map[string]any{}})
+	require.True(t, ok)
+	assert.Equal(t, "ToJsonString{Json{}}", code)
+
+	_, ok = languageExtensionToGo(ctx, map[string]any{"Fn::Sub": "x"})
+	assert.False(t, ok)
+}
@@ -58,6 +58,12 @@ func prescanAllForParams(ctx *codegenContext) {
 		}
 	}
 
+	// Scan loops
+	for _, loop := range ctx.template.Loops {
+		scanExprForParams(ctx, loop.Collection)
+		scanExprForParams(ctx, loop.Outputs)
+	}
+
 	// Scan outputs
 	for _, output := range ctx.template.Outputs {
 		scanExprForParams(ctx, output.Value)
@@ -244,7 +250,12 @@ func generateMapping(ctx *codegenContext, mapping *IRMapping) string {
 }
 
 func generateCondition(ctx *codegenContext, condition *IRCondition) string {
-	varName := SanitizeGoName(condition.LogicalID) + "Condition"
+	varName := conditionVarName(condition.LogicalID)
 	value := valueToGo(ctx, condition.Expression, 0)
 	return fmt.Sprintf("var %s = %s", varName, value)
 }
+
+// conditionVarName returns the Go variable name generated for a condition.
+func conditionVarName(logicalID string) string {
+	return SanitizeGoName(logicalID) + "Condition"
+}
@@ -117,9 +117,80 @@ func generateResource(ctx *codegenContext, resource *IRResource) string {
 
 	lines = append(lines, "}")
 
+	if attrs := generateResourceAttributes(ctx, resource); attrs != "" {
+		lines = append(lines, "", attrs)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// generateResourceAttributes generates the companion ResourceAttributes
+// declaration for a resource's DependsOn, Condition, DeletionPolicy,
+// UpdateReplacePolicy and Metadata. Returns "" if the resource has none.
+func generateResourceAttributes(ctx *codegenContext, resource *IRResource) string {
+	var fields []string
+
+	if resource.Condition != "" {
+		fields = append(fields, fmt.Sprintf("\tCondition: %q,", conditionVarName(resource.Condition)))
+	}
+
+	var deps []string
+	for _, dep := range resource.DependsOn {
+		if _, exists := ctx.template.Resources[dep]; !exists || ctx.unknownResources[dep] {
+			continue
+		}
+		deps = append(deps, sanitizeVarName(dep))
+	}
+	if len(deps) > 0 {
+		fields = append(fields, fmt.Sprintf("\tDependsOn: []wetwire.Resource{%s},", strings.Join(deps, ", ")))
+	}
+
+	if policy := policyToGo(ctx, resource.DeletionPolicy); policy != "" {
+		fields = append(fields, fmt.Sprintf("\tDeletionPolicy: %s,", policy))
+	}
+	if policy := policyToGo(ctx, resource.UpdateReplacePolicy); policy != "" {
+		fields = append(fields, fmt.Sprintf("\tUpdateReplacePolicy: %s,", policy))
+	}
+	if len(resource.Metadata) > 0 {
+		// Metadata is free-form, so no property type or enum context applies
+		ctx.currentProperty = ""
+		fields = append(fields, fmt.Sprintf("\tMetadata: %s,", valueToGo(ctx, resource.Metadata, 1)))
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	ctx.imports["github.com/lex00/wetwire-aws-go"] = true
+
+	varName := sanitizeVarName(resource.LogicalID)
+	lines := []string{
+		fmt.Sprintf("var %sAttributes = wetwire.ResourceAttributes{", varName),
+		fmt.Sprintf("\tResource: %s,", varName),
+	}
+	lines = append(lines, fields...)
+	lines = append(lines, "}")
 	return strings.Join(lines, "\n")
 }
 
+// policyToGo converts a DeletionPolicy or UpdateReplacePolicy to Go source
+// code: a quoted policy name, or an intrinsic function under
+// AWS::LanguageExtensions. Returns "" if the policy is not set.
+func policyToGo(ctx *codegenContext, policy any) string {
+	switch v := policy.(type) {
+	case nil:
+		return ""
+	case string:
+		if v == "" {
+			return ""
+		}
+		return fmt.Sprintf("%q", v)
+	default:
+		ctx.currentProperty = ""
+		return valueToGo(ctx, v, 1)
+	}
+}
+
 // detectSAMImplicitResources identifies resources that SAM auto-generates
 // (like IAM roles for Lambda functions) and adds them to unknownResources.
 // This allows the importer to generate valid code for outputs that reference
@@ -88,6 +88,9 @@ func valueToBlockStyleProperty(ctx *codegenContext, value any, propName string,
 					if intrinsic != nil {
 						return intrinsicToGo(ctx, intrinsic)
 					}
+					if code, ok := languageExtensionToGo(ctx, v); ok {
+						return code
+					}
 				}
 			}
 		}
@@ -265,6 +268,9 @@ func valueToGoForBlock(ctx *codegenContext, value any, propName string, parentVa
 						}
 						return intrinsicToGo(ctx, intrinsic)
 					}
+					if code, ok := languageExtensionToGo(ctx, v); ok {
+						return code
+					}
 				}
 			}
 		}
@@ -431,6 +437,9 @@ func valueToGoWithProperty(ctx *codegenContext, value any, indent int, propName
 					if intrinsic != nil {
 						return intrinsicToGo(ctx, intrinsic)
 					}
+					if code, ok := languageExtensionToGo(ctx, v); ok {
+						return code
+					}
 				}
 			}
 		}
@@ -70,8 +70,8 @@ type IRResource struct {
 	Properties          map[string]*IRProperty
 	DependsOn           []string
 	Condition           string
-	DeletionPolicy      string
-	UpdateReplacePolicy string
+	DeletionPolicy      any // Usually a string; an intrinsic under AWS::LanguageExtensions
+	UpdateReplacePolicy any
 	Metadata            map[string]any
 }
 
@@ -130,6 +130,15 @@ type IRCondition struct {
 	Expression any // Usually an *IRIntrinsic
 }
 
+// IRLoop represents an Fn::ForEach resource loop of the
+// AWS::LanguageExtensions transform.
+type IRLoop struct {
+	Name       string         // Loop name, the suffix of Fn::ForEach::Name
+	Identifier string         // Placeholder for the current element
+	Collection any            // List of strings, or a Ref to a list parameter
+	Outputs    map[string]any // Logical ID template -> resource definition
+}
+
 // IRTemplate represents a complete parsed CloudFormation template.
 type IRTemplate struct {
 	Description              string
@@ -139,6 +148,7 @@ type IRTemplate struct {
 	Conditions               map[string]*IRCondition
 	Resources                map[string]*IRResource
 	Outputs                  map[string]*IROutput
+	Loops                    map[string]*IRLoop
 	SourceFile               string
 	ReferenceGraph           map[string][]string // resource -> list of resources it references
 }
@@ -152,6 +162,7 @@ func NewIRTemplate() *IRTemplate {
 		Conditions:               make(map[string]*IRCondition),
 		Resources:                make(map[string]*IRResource),
 		Outputs:                  make(map[string]*IROutput),
+		Loops:                    make(map[string]*IRLoop),
 		ReferenceGraph:           make(map[string][]string),
 	}
 }
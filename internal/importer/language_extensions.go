@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/differ"
+)
+
+// languageExtensions holds the parts of an AWS::LanguageExtensions template
+// that the shared parser does not understand: Fn::ForEach resource loops and
+// intrinsic functions as DeletionPolicy or UpdateReplacePolicy.
+type languageExtensions struct {
+	loops    map[string]*IRLoop
+	policies map[string]map[string]any // logical ID -> attribute -> value
+}
+
+// mayUseLanguageExtensions is a quick check for templates that could
+// declare the AWS::LanguageExtensions transform.
+func mayUseLanguageExtensions(content []byte) bool {
+	return bytes.Contains(content, []byte(wetwire.TransformLanguageExtensions))
+}
+
+// extractLanguageExtensions removes the loops and intrinsic policies from a
+// template that declares the AWS::LanguageExtensions transform. It returns
+// the rest of the template as JSON, with short-form intrinsics expanded, or
+// nil if the template does not declare the transform.
+func extractLanguageExtensions(content []byte) ([]byte, *languageExtensions, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		// Leave reporting the error to the shared parser
+		return nil, nil, nil
+	}
+	root := doc.Content[0]
+
+	var transforms wetwire.Transforms
+	transform := mappingValue(root, "Transform")
+	if transform == nil || transform.Decode(&transforms) != nil || !transforms.Has(wetwire.TransformLanguageExtensions) {
+		return nil, nil, nil
+	}
+
+	differ.ExpandShortForms(root)
+	ext := &languageExtensions{
+		loops:    make(map[string]*IRLoop),
+		policies: make(map[string]map[string]any),
+	}
+
+	if resources := mappingValue(root, "Resources"); resources != nil && resources.Kind == yaml.MappingNode {
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(resources.Content); i += 2 {
+			key, value := resources.Content[i], resources.Content[i+1]
+			if strings.HasPrefix(key.Value, wetwire.ForEachPrefix) {
+				loop, err := decodeLoop(key.Value, value)
+				if err != nil {
+					return nil, nil, err
+				}
+				ext.loops[loop.Name] = loop
+				continue
+			}
+			if err := ext.extractPolicies(key.Value, value); err != nil {
+				return nil, nil, err
+			}
+			kept = append(kept, key, value)
+		}
+		resources.Content = kept
+	}
+
+	keepTimestampStrings(root)
+	var rest any
+	if err := root.Decode(&rest); err != nil {
+		return nil, nil, err
+	}
+	data, err := json.Marshal(rest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, ext, nil
+}
+
+// decodeLoop decodes the [identifier, collection, outputs] arguments of the
+// Fn::ForEach loop key.
+func decodeLoop(key string, node *yaml.Node) (*IRLoop, error) {
+	if node.Kind != yaml.SequenceNode || len(node.Content) != 3 || node.Content[0].Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("Resources.%s: Fn::ForEach takes an identifier, a collection and an output template", key)
+	}
+
+	loop := &IRLoop{
+		Name:       strings.TrimPrefix(key, wetwire.ForEachPrefix),
+		Identifier: node.Content[0].Value,
+	}
+	if err := node.Content[1].Decode(&loop.Collection); err != nil {
+		return nil, fmt.Errorf("Resources.%s: %w", key, err)
+	}
+	if err := node.Content[2].Decode(&loop.Outputs); err != nil {
+		return nil, fmt.Errorf("Resources.%s: Fn::ForEach output template must be an object", key)
+	}
+	return loop, nil
+}
+
+// extractPolicies removes the DeletionPolicy and UpdateReplacePolicy of a
+// resource when they are intrinsic functions rather than policy names.
+func (ext *languageExtensions) extractPolicies(logicalID string, resource *yaml.Node) error {
+	if resource.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(resource.Content); i += 2 {
+		key, value := resource.Content[i], resource.Content[i+1]
+		if (key.Value == "DeletionPolicy" || key.Value == "UpdateReplacePolicy") && value.Kind != yaml.ScalarNode {
+			var policy any
+			if err := value.Decode(&policy); err != nil {
+				return fmt.Errorf("Resources.%s.%s: %w", logicalID, key.Value, err)
+			}
+			if ext.policies[logicalID] == nil {
+				ext.policies[logicalID] = make(map[string]any)
+			}
+			ext.policies[logicalID][key.Value] = policy
+			continue
+		}
+		kept = append(kept, key, value)
+	}
+	resource.Content = kept
+	return nil
+}
+
+// apply adds the extracted loops and policies to the parsed template.
+func (ext *languageExtensions) apply(ir *IRTemplate) {
+	if ext == nil {
+		return
+	}
+	for name, loop := range ext.loops {
+		ir.Loops[name] = loop
+	}
+	for logicalID, policies := range ext.policies {
+		resource, ok := ir.Resources[logicalID]
+		if !ok {
+			continue
+		}
+		if policy, ok := policies["DeletionPolicy"]; ok {
+			resource.DeletionPolicy = policy
+		}
+		if policy, ok := policies["UpdateReplacePolicy"]; ok {
+			resource.UpdateReplacePolicy = policy
+		}
+	}
+}
+
+// keepTimestampStrings tags unquoted dates, such as the 2010-09-09 of
+// AWSTemplateFormatVersion, as strings so they are not decoded as times.
+func keepTimestampStrings(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		keepTimestampStrings(child)
+	}
+}
+
+// mappingValue returns the value of a key of a YAML mapping, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// languageExtensionToGo converts an Fn::Length or Fn::ToJsonString map to
+// Go source code. The shared parser leaves these functions as plain maps.
+func languageExtensionToGo(ctx *codegenContext, m map[string]any) (string, bool) {
+	if len(m) != 1 {
+		return "", false
+	}
+	for k, v := range m {
+		switch k {
+		case "Fn::Length":
+			ctx.imports["github.com/lex00/wetwire-aws-go/intrinsics"] = true
+			return fmt.Sprintf("Length{%s}", valueToGo(ctx, v, 0)), true
+		case "Fn::ToJsonString":
+			ctx.imports["github.com/lex00/wetwire-aws-go/intrinsics"] = true
+			return fmt.Sprintf("ToJsonString{%s}", valueToGo(ctx, v, 0)), true
+		}
+	}
+	return "", false
+}
+
+// generateLoops generates ForEach declarations for the resource loops and
+// returns code + imports.
+func generateLoops(ctx *codegenContext) (string, map[string]bool) {
+	if len(ctx.template.Loops) == 0 {
+		return "", nil
+	}
+
+	savedImports := ctx.imports
+	ctx.imports = map[string]bool{"github.com/lex00/wetwire-aws-go/intrinsics": true}
+
+	var sections []string
+	for _, name := range sortedKeys(ctx.template.Loops) {
+		sections = append(sections, generateLoop(ctx, ctx.template.Loops[name]))
+	}
+
+	loopImports := ctx.imports
+	for imp := range loopImports {
+		savedImports[imp] = true
+	}
+	ctx.imports = savedImports
+
+	return strings.Join(sections, "\n\n"), loopImports
+}
+
+// generateLoop generates the ForEach declaration of a resource loop. Loop
+// outputs that are plain resources become typed resource literals; others
+// are kept as Json.
+func generateLoop(ctx *codegenContext, loop *IRLoop) string {
+	ctx.loopIdentifiers[loop.Identifier] = true
+	defer delete(ctx.loopIdentifiers, loop.Identifier)
+
+	varName := sanitizeVarName(loop.Name)
+	lines := []string{fmt.Sprintf("var %s = ForEach{", varName)}
+	if varName != loop.Name {
+		lines = append(lines, fmt.Sprintf("\tName: %q,", loop.Name))
+	}
+	lines = append(lines,
+		fmt.Sprintf("\tIdentifier: %q,", loop.Identifier),
+		fmt.Sprintf("\tCollection: %s,", valueToGo(ctx, loop.Collection, 1)),
+		"\tOutputs: map[string]any{",
+	)
+	for _, key := range sortedKeys(loop.Outputs) {
+		lines = append(lines, fmt.Sprintf("\t\t%q: %s,", key, loopOutputToGo(ctx, loop.Outputs[key])))
+	}
+	lines = append(lines, "\t},", "}")
+	return strings.Join(lines, "\n")
+}
+
+// loopOutputToGo converts a loop output to Go source code.
+func loopOutputToGo(ctx *codegenContext, output any) string {
+	def, _ := output.(map[string]any)
+	resourceType, _ := def["Type"].(string)
+	props, propsOK := def["Properties"].(map[string]any)
+	module, typeName := resolveResourceType(resourceType)
+	if module == "" || len(def) > 2 || (len(def) == 2 && !propsOK) {
+		// Not a plain resource, for example one with a DependsOn
+		ctx.currentResource, ctx.currentTypeName, ctx.currentProperty = "", "", ""
+		return valueToGo(ctx, output, 2)
+	}
+
+	ctx.imports[fmt.Sprintf("github.com/lex00/wetwire-aws-go/resources/%s", module)] = true
+	ctx.currentResource = module
+	ctx.currentTypeName = typeName
+
+	lines := []string{fmt.Sprintf("%s.%s{", module, typeName)}
+	for _, propName := range sortedKeys(props) {
+		ctx.currentProperty = propName
+		value := valueToGoWithProperty(ctx, props[propName], 3, propName)
+		if m, ok := props[propName].(map[string]any); ok && isListTypeProperty(propName) {
+			if intrinsic := mapToIntrinsic(m); intrinsic != nil && intrinsicNeedsArrayWrapping(intrinsic) {
+				value = fmt.Sprintf("[]any{%s}", value)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("\t\t\t%s: %s,", transformGoFieldNameForType(propName, typeName), value))
+	}
+	lines = append(lines, "\t\t}")
+	return strings.Join(lines, "\n")
+}
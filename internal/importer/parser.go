@@ -1,6 +1,7 @@
 package importer
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -10,6 +11,9 @@ import (
 // ParseTemplate parses a CloudFormation template file into IR.
 // Supports both YAML and JSON formats.
 func ParseTemplate(path string) (*IRTemplate, error) {
+	if content, err := os.ReadFile(path); err == nil && mayUseLanguageExtensions(content) {
+		return ParseTemplateContent(content, path)
+	}
 	tmpl, err := template.ParseTemplate(path)
 	if err != nil {
 		return nil, err
@@ -18,12 +22,27 @@ func ParseTemplate(path string) (*IRTemplate, error) {
 }
 
 // ParseTemplateContent parses CloudFormation template content into IR.
+// Fn::ForEach loops and intrinsic policies of AWS::LanguageExtensions
+// templates are extracted before the shared parser sees the template.
 func ParseTemplateContent(content []byte, sourceName string) (*IRTemplate, error) {
+	var ext *languageExtensions
+	if mayUseLanguageExtensions(content) {
+		rest, extracted, err := extractLanguageExtensions(content)
+		if err != nil {
+			return nil, err
+		}
+		if extracted != nil {
+			content, ext = rest, extracted
+		}
+	}
+
 	tmpl, err := template.ParseTemplateContent(content, sourceName)
 	if err != nil {
 		return nil, err
 	}
-	return convertTemplate(tmpl), nil
+	ir := convertTemplate(tmpl)
+	ext.apply(ir)
+	return ir, nil
 }
 
 // convertTemplate converts a shared template.Template to the local IRTemplate.
@@ -0,0 +1,393 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lex00/wetwire-aws-go/internal/suppress"
+)
+
+// Fixer is implemented by rules that can rewrite the issues they report.
+//
+// Fix returns text edits against src, the source that file was parsed from.
+// Edits are applied, the result is gofmt'ed and the file is re-parsed before
+// the next rule runs, so a fix only needs to handle one level of nesting per
+// call. Fixes must be idempotent: once applied, the rule no longer reports
+// the issue and Fix returns no edits.
+type Fixer interface {
+	Rule
+	Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit
+}
+
+// Edit replaces src[Start:End] with Text. Start == End inserts Text.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+	// Line is the line of the issue the edit fixes; edits for issues
+	// silenced by //wetwire:ignore are skipped. Zero marks supporting edits,
+	// such as adding an import, which are kept only with other edits.
+	Line int
+}
+
+// maxFixPasses bounds how many times the fixers are re-run on a file.
+// Each pass flattens one more level of nesting.
+const maxFixPasses = 10
+
+// FixFile applies all fixable rules to a single file and writes the result
+// back. Use LintFile with Options.Fix to also learn what was fixed.
+func FixFile(path string, opts Options) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	ctx := &PackageContext{
+		AllDefinedVars: make(map[string]bool),
+		VarRefs:        make(map[string]map[string]bool),
+		sources:        map[string][]byte{path: src},
+	}
+	collectDefinedVars(file, ctx.AllDefinedVars)
+	collectVarRefs(file, ctx.AllDefinedVars, ctx.VarRefs)
+
+	return writeFixed(path, src, getRules(opts.forFile(path)), ctx)
+}
+
+// FixPackage applies all fixable rules to the Go files in a package directory.
+// Like LintPackage, it accepts "dir/..." to fix packages recursively.
+// Test files are not modified.
+func FixPackage(pkgPath string, opts Options) error {
+	if strings.HasSuffix(pkgPath, "...") {
+		root := strings.TrimSuffix(strings.TrimSuffix(pkgPath, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if path != root && (info.Name() == "vendor" || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			// Directories that fail to parse are skipped, matching lintRecursive
+			_ = fixDir(path, opts)
+			return nil
+		})
+	}
+	return fixDir(pkgPath, opts)
+}
+
+// fixDir fixes the non-test Go files of the packages in dir.
+func fixDir(dir string, opts Options) error {
+	fset := token.NewFileSet()
+	notTest := func(info os.FileInfo) bool { return !strings.HasSuffix(info.Name(), "_test.go") }
+
+	pkgs, err := parser.ParseDir(fset, dir, notTest, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range pkgs {
+		ctx := buildPackageContext(pkg)
+		ctx.sources = make(map[string][]byte, len(pkg.Files))
+
+		paths := make([]string, 0, len(pkg.Files))
+		for path := range pkg.Files {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			ctx.sources[path] = src
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			if err := writeFixed(path, ctx.sources[path], getRules(opts.forFile(path)), ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeFixed fixes src and writes it to path if anything changed.
+func writeFixed(path string, src []byte, rules []Rule, ctx *PackageContext) error {
+	fixed := fixSource(path, src, rules, ctx)
+	if bytes.Equal(fixed, src) {
+		return nil
+	}
+	if ctx.sources != nil {
+		ctx.sources[path] = fixed
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, fixed, info.Mode().Perm())
+}
+
+// fixSource runs the fixable rules over src until no rule makes further changes.
+// Edits that would leave the file unparseable, or that introduce type errors
+// such as an initialization cycle, are discarded.
+func fixSource(filename string, src []byte, rules []Rule, ctx *PackageContext) []byte {
+	for pass := 0; pass < maxFixPasses; pass++ {
+		changed := false
+
+		for _, rule := range rules {
+			fixer, ok := rule.(Fixer)
+			if !ok {
+				continue
+			}
+
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+			if err != nil {
+				return src
+			}
+			collectDefinedVars(file, ctx.AllDefinedVars)
+			if ctx.VarRefs == nil {
+				ctx.VarRefs = make(map[string]map[string]bool)
+			}
+			collectVarRefs(file, ctx.AllDefinedVars, ctx.VarRefs)
+
+			edits := dropSuppressed(fixer.Fix(file, fset, src, ctx), rule.ID(), suppress.Parse(file, fset))
+			if len(edits) == 0 {
+				continue
+			}
+
+			out, err := format.Source(applyEdits(src, edits))
+			if err != nil || bytes.Equal(out, src) || addsTypeErrors(filename, src, out, ctx) {
+				continue
+			}
+			src = out
+			changed = true
+		}
+
+		if !changed {
+			break
+		}
+	}
+	return src
+}
+
+// addsTypeErrors reports whether replacing before with after in the package
+// produces type errors that before did not have. Imports are not resolved,
+// so only errors within the package, such as initialization cycles, count;
+// see typeErrors.
+func addsTypeErrors(filename string, before, after []byte, ctx *PackageContext) bool {
+	existing := typeErrors(filename, before, ctx)
+	for msg, n := range typeErrors(filename, after, ctx) {
+		if n > existing[msg] {
+			return true
+		}
+	}
+	return false
+}
+
+// typeErrors type-checks the package with src as the contents of filename
+// and returns the error messages, counted by message. Errors that follow
+// from not resolving imports (failed and unused imports, and names that a
+// dot import would provide) are left out; fixes only add imports the
+// module provides and the unused intrinsics import is cleaned up by its
+// own fix.
+func typeErrors(filename string, src []byte, ctx *PackageContext) map[string]int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return map[string]int{err.Error(): 1}
+	}
+	files := []*ast.File{file}
+	for path, other := range ctx.sources {
+		if path == filename {
+			continue
+		}
+		if f, err := parser.ParseFile(fset, path, other, 0); err == nil {
+			files = append(files, f)
+		}
+	}
+
+	errs := make(map[string]int)
+	conf := types.Config{
+		Importer: noImporter{},
+		Error: func(err error) {
+			terr, ok := err.(types.Error)
+			if !ok || importError(terr.Msg) {
+				return
+			}
+			errs[terr.Msg]++
+		},
+	}
+	_, _ = conf.Check(file.Name.Name, fset, files, nil)
+	return errs
+}
+
+// importError reports whether msg is a type error caused by unresolved imports.
+func importError(msg string) bool {
+	return strings.HasPrefix(msg, "could not import ") ||
+		strings.HasPrefix(msg, "undefined: ") ||
+		(strings.Contains(msg, " imported ") && strings.HasSuffix(msg, "and not used"))
+}
+
+// noImporter fails every import. The type checker then treats the imported
+// packages as opaque, which is enough to check the package's own variables.
+type noImporter struct{}
+
+func (noImporter) Import(path string) (*types.Package, error) {
+	return nil, fmt.Errorf("imports are not resolved: %s", path)
+}
+
+// dropSuppressed removes the edits for issues silenced by //wetwire:ignore,
+// along with the supporting edits when nothing else is left.
+func dropSuppressed(edits []Edit, rule string, suppressions *suppress.Set) []Edit {
+	var kept []Edit
+	fixes := 0
+	for _, e := range edits {
+		if e.Line != 0 {
+			if suppressions.Match(rule, e.Line) {
+				continue
+			}
+			fixes++
+		}
+		kept = append(kept, e)
+	}
+	if fixes == 0 {
+		return nil
+	}
+	return kept
+}
+
+// applyEdits applies non-overlapping edits to src. When edits overlap, the
+// one that starts first (or is larger) wins; the others are picked up by the
+// next pass.
+func applyEdits(src []byte, edits []Edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].Start != edits[j].Start {
+			return edits[i].Start < edits[j].Start
+		}
+		return edits[i].End > edits[j].End
+	})
+
+	var accepted []Edit
+	end := -1
+	for _, e := range edits {
+		if e.Start < end {
+			continue
+		}
+		accepted = append(accepted, e)
+		if e.End > end {
+			end = e.End
+		}
+	}
+
+	out := append([]byte(nil), src...)
+	for i := len(accepted) - 1; i >= 0; i-- {
+		e := accepted[i]
+		out = append(out[:e.Start], append([]byte(e.Text), out[e.End:]...)...)
+	}
+	return out
+}
+
+// fixedIssues returns the issues in before that no longer appear in after.
+// Issues are matched by rule and message, since fixes move code around.
+func fixedIssues(before, after []Issue) []Issue {
+	remaining := make(map[string]int)
+	for _, issue := range after {
+		remaining[issue.File+"\x00"+issue.Rule+"\x00"+issue.Message]++
+	}
+
+	var fixed []Issue
+	for _, issue := range before {
+		key := issue.File + "\x00" + issue.Rule + "\x00" + issue.Message
+		if remaining[key] > 0 {
+			remaining[key]--
+			continue
+		}
+		fixed = append(fixed, issue)
+	}
+	return fixed
+}
+
+// collectDefinedVars adds the package-level variables declared in file to vars.
+func collectDefinedVars(file *ast.File, vars map[string]bool) {
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
+			for _, spec := range genDecl.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range valueSpec.Names {
+						vars[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// collectVarRefs records in refs the package-level variables, among vars,
+// that the value of each variable declared in file refers to. Struct field
+// keys and selected names (x.Name) are not references.
+func collectVarRefs(file *ast.File, vars map[string]bool, refs map[string]map[string]bool) {
+	forEachTopLevelVar(file, func(name string, value ast.Expr, _ *ast.GenDecl) {
+		used := make(map[string]bool)
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.Ident:
+				if vars[node.Name] {
+					used[node.Name] = true
+				}
+			case *ast.SelectorExpr:
+				ast.Inspect(node.X, visit)
+				return false
+			case *ast.KeyValueExpr:
+				if _, ok := node.Key.(*ast.Ident); !ok {
+					ast.Inspect(node.Key, visit)
+				}
+				ast.Inspect(node.Value, visit)
+				return false
+			}
+			return true
+		}
+		ast.Inspect(value, visit)
+		refs[name] = used
+	})
+}
+
+// offset returns the byte offset of pos in its file.
+func offset(fset *token.FileSet, pos token.Pos) int {
+	return fset.Position(pos).Offset
+}
+
+// replaceNode returns an edit replacing node with text, fixing the issue on
+// the line where node starts.
+func replaceNode(fset *token.FileSet, node ast.Node, text string) Edit {
+	return Edit{
+		Start: offset(fset, node.Pos()),
+		End:   offset(fset, node.End()),
+		Text:  text,
+		Line:  fset.Position(node.Pos()).Line,
+	}
+}
+
+// nodeText returns the source text of node.
+func nodeText(fset *token.FileSet, src []byte, node ast.Node) string {
+	return string(src[offset(fset, node.Pos()):offset(fset, node.End())])
+}
@@ -0,0 +1,369 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixSourceFile writes src to a temp file, runs LintFile with Fix and returns
+// the rewritten source and the lint result.
+func fixSourceFile(t *testing.T, src string) (string, Result) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stack.go")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	result, err := LintFile(path, Options{Fix: true})
+	require.NoError(t, err)
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(out), result
+}
+
+func TestLintFile_Fix(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		contains []string
+		absent   []string
+		fixed    []string
+	}{
+		{
+			name: "WAW001 pseudo-parameter",
+			src: `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var Bucket = s3.Bucket{
+	BucketName: "AWS::StackName",
+}
+`,
+			contains: []string{"BucketName: AWS_STACK_NAME", `. "github.com/lex00/wetwire-aws-go/intrinsics"`},
+			fixed:    []string{"WAW001"},
+		},
+		{
+			name: "WAW002 map to intrinsic then direct reference",
+			src: `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var Logs = s3.Bucket{}
+
+var Bucket = s3.Bucket{
+	LoggingConfiguration: map[string]any{"DestinationBucketName": map[string]any{"Ref": "Logs"}},
+}
+`,
+			contains: []string{"DestinationBucketName: Logs"},
+			absent:   []string{`"Ref"`, "intrinsics"},
+			fixed:    []string{"WAW002"},
+		},
+		{
+			name: "WAW016 GetAtt to attribute field",
+			src: `package p
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/iam"
+	"github.com/lex00/wetwire-aws-go/resources/lambda"
+)
+
+var Role = iam.Role{}
+
+var Fn = lambda.Function{
+	Role: GetAtt{"Role", "Arn"},
+}
+`,
+			contains: []string{"Role: Role.Arn"},
+			absent:   []string{"GetAtt", "intrinsics"},
+			fixed:    []string{"WAW016"},
+		},
+		{
+			name: "WAW010 extract inline property types",
+			src: `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+// Bucket stores data.
+var Bucket = s3.Bucket{
+	VersioningConfiguration: &s3.Bucket_VersioningConfiguration{
+		Status: "Enabled",
+	},
+}
+`,
+			contains: []string{
+				"var BucketVersioningConfiguration = s3.Bucket_VersioningConfiguration{",
+				"// Bucket stores data.\nvar Bucket = s3.Bucket{",
+				"VersioningConfiguration: &BucketVersioningConfiguration,",
+			},
+			fixed: []string{"WAW010"},
+		},
+		{
+			name: "WAW012 enum constant",
+			src: `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/lambda"
+
+var Fn = lambda.Function{
+	Runtime: "python3.12",
+}
+`,
+			contains: []string{"Runtime: enums.LambdaRuntimePython312", `"github.com/lex00/cloudformation-schema-go/enums"`},
+			fixed:    []string{"WAW012"},
+		},
+		{
+			name: "WAW014 unused intrinsics import",
+			src: `package p
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/s3"
+)
+
+var Bucket = s3.Bucket{}
+`,
+			absent: []string{"intrinsics"},
+			fixed:  []string{"WAW014"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, result := fixSourceFile(t, tt.src)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.absent {
+				assert.NotContains(t, out, s)
+			}
+
+			var fixedRules []string
+			for _, issue := range result.Fixed {
+				fixedRules = append(fixedRules, issue.Rule)
+			}
+			for _, rule := range tt.fixed {
+				assert.Contains(t, fixedRules, rule)
+				for _, issue := range result.Issues {
+					assert.NotEqual(t, rule, issue.Rule, "issue should have been fixed: %s", issue.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestLintFile_FixIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack.go")
+	src := `package p
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/s3"
+)
+
+var Bucket = s3.Bucket{
+	BucketName: Sub{String: "${AWS::StackName}-data"},
+	BucketEncryption: &s3.Bucket_BucketEncryption{
+		ServerSideEncryptionConfiguration: []s3.Bucket_ServerSideEncryptionRule{
+			{ServerSideEncryptionByDefault: &s3.Bucket_ServerSideEncryptionByDefault{SSEAlgorithm: "AES256"}},
+		},
+	},
+}
+`
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	first, err := LintFile(path, Options{Fix: true})
+	require.NoError(t, err)
+	assert.NotEmpty(t, first.Fixed)
+	once, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	second, err := LintFile(path, Options{Fix: true})
+	require.NoError(t, err)
+	assert.Empty(t, second.Fixed)
+	twice, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(once), string(twice))
+	assert.Equal(t, first.Issues, second.Issues)
+}
+
+func TestLintFile_FixReportsRemaining(t *testing.T) {
+	out, result := fixSourceFile(t, `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var Bucket = s3.Bucket{
+	BucketName: "AWS::Region",
+}
+
+var region = "AWS::Region"
+
+var names = []string{"AWS::Region"}
+`)
+
+	// Values are rewritten; string collections are left alone
+	assert.Contains(t, out, "BucketName: AWS_REGION")
+	assert.Contains(t, out, "var region = AWS_REGION")
+	assert.Contains(t, out, `var names = []string{"AWS::Region"}`)
+
+	require.Len(t, result.Fixed, 2)
+	require.Len(t, result.Issues, 1)
+	assert.Equal(t, "WAW001", result.Issues[0].Rule)
+	assert.False(t, result.Issues[0].Fixable)
+}
+
+func TestLintFile_FixRespectsSuppressions(t *testing.T) {
+	out, result := fixSourceFile(t, `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+//wetwire:ignore WAW001 name is a literal
+var Kept = s3.Bucket{
+	BucketName: "AWS::Region",
+}
+
+var Fixed = s3.Bucket{
+	BucketName: "AWS::Region",
+}
+`)
+
+	assert.Contains(t, out, "//wetwire:ignore WAW001 name is a literal\nvar Kept = s3.Bucket{\n\tBucketName: \"AWS::Region\",")
+	assert.Contains(t, out, "BucketName: AWS_REGION")
+	require.Len(t, result.Fixed, 1)
+	assert.Empty(t, result.Issues)
+
+	// A file whose only fixable issue is suppressed is left untouched
+	src := `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+var Kept = s3.Bucket{
+	BucketName: "AWS::Region", //wetwire:ignore WAW001
+}
+`
+	out, result = fixSourceFile(t, src)
+	assert.Equal(t, src, out)
+	assert.Empty(t, result.Fixed)
+}
+
+func TestLintFile_FixAvoidsInitializationCycle(t *testing.T) {
+	out, _ := fixSourceFile(t, `package p
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/ec2"
+)
+
+var A = ec2.SecurityGroup{
+	GroupDescription: Ref{"B"},
+}
+
+var B = ec2.SecurityGroupIngress{
+	GroupId: GetAtt{"A", "GroupId"},
+}
+`)
+
+	// Only one of the two explicit references becomes a direct one
+	assert.Contains(t, out, "GroupDescription: B,")
+	assert.Contains(t, out, `GroupId: GetAtt{"A", "GroupId"},`)
+
+	errs := typeErrors("stack.go", []byte(out), &PackageContext{})
+	for msg := range errs {
+		assert.NotContains(t, msg, "initialization cycle")
+	}
+}
+
+func TestAddsTypeErrors(t *testing.T) {
+	before := []byte(`package p
+
+var A = []any{"B"}
+
+var B = []any{"A"}
+`)
+	cycle := []byte(`package p
+
+var A = []any{B}
+
+var B = []any{A}
+`)
+	direct := []byte(`package p
+
+var A = []any{B}
+
+var B = []any{"A"}
+`)
+
+	ctx := &PackageContext{}
+	assert.True(t, addsTypeErrors("stack.go", before, cycle, ctx))
+	assert.False(t, addsTypeErrors("stack.go", before, direct, ctx))
+
+	// Other files of the package are checked too
+	ctx.sources = map[string][]byte{"other.go": []byte("package p\n\nvar C = []any{D}\n")}
+	assert.True(t, addsTypeErrors("stack.go", []byte("package p\n\nvar D = []any{\"C\"}\n"), []byte("package p\n\nvar D = []any{C}\n"), ctx))
+}
+
+func TestLintPackage_Fix(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "role.go"), []byte(`package p
+
+import "github.com/lex00/wetwire-aws-go/resources/iam"
+
+var Role = iam.Role{}
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fn.go"), []byte(`package p
+
+import (
+	. "github.com/lex00/wetwire-aws-go/intrinsics"
+	"github.com/lex00/wetwire-aws-go/resources/lambda"
+)
+
+var Fn = lambda.Function{
+	Handler: Ref{"Role"},
+}
+`), 0o644))
+	testSrc := []byte(`package p
+
+var testRef = "AWS::Region"
+`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fn_test.go"), testSrc, 0o644))
+
+	result, err := LintPackage(dir, Options{Fix: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Fixed)
+
+	out, err := os.ReadFile(filepath.Join(dir, "fn.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(out), "Handler: Role,")
+
+	// Test files are never rewritten
+	got, err := os.ReadFile(filepath.Join(dir, "fn_test.go"))
+	require.NoError(t, err)
+	assert.Equal(t, testSrc, got)
+}
+
+func TestApplyEdits(t *testing.T) {
+	src := []byte("abcdef")
+
+	out := applyEdits(src, []Edit{
+		{Start: 4, End: 6, Text: "XY"},
+		{Start: 0, End: 0, Text: ">"},
+		{Start: 1, End: 3, Text: "B"},
+		{Start: 2, End: 3, Text: "overlap"},
+	})
+
+	assert.Equal(t, ">aBdXY", string(out))
+	assert.Equal(t, "abcdef", string(src))
+}
+
+func TestFixedIssues(t *testing.T) {
+	a := Issue{File: "a.go", Rule: "WAW001", Message: "m"}
+	b := Issue{File: "a.go", Rule: "WAW012", Message: "n"}
+
+	fixed := fixedIssues([]Issue{a, a, b}, []Issue{a})
+
+	assert.Equal(t, []Issue{a, b}, fixed)
+}
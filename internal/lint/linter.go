@@ -2,13 +2,16 @@
 package lint
 
 import (
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/lex00/wetwire-aws-go/internal/suppress"
 	corelint "github.com/lex00/wetwire-core-go/lint"
 )
 
@@ -32,7 +35,10 @@ const (
 // Result contains the outcome of linting.
 type Result struct {
 	Success bool
-	Issues  []Issue
+	// Issues are the issues that remain after any fixes
+	Issues []Issue
+	// Fixed are the issues rewritten by Options.Fix, as reported before fixing
+	Fixed []Issue
 }
 
 // Options configures the linter.
@@ -40,16 +46,121 @@ type Options struct {
 	// Rules to enable. If empty, all rules are enabled.
 	EnabledRules []string
 	// DisabledRules specifies rules to disable by ID (e.g., "WAW001", "WAW002").
-	// Takes precedence over EnabledRules.
+	// Takes precedence over EnabledRules and Overrides.
 	DisabledRules []string
 	// MaxResources for the FileTooLarge rule.
 	MaxResources int
-	// Fix automatically fixes fixable issues (reserved for future use).
+	// Severity overrides the reported severity of issues by rule ID.
+	Severity map[string]Severity
+	// Overrides adjust rules and severities for files under a directory.
+	// When several match a file, deeper directories win.
+	Overrides []Override
+	// Fix rewrites source files to fix issues from rules that implement Fixer.
 	Fix bool
 }
 
+// Override adjusts Options for the files under a directory.
+type Override struct {
+	// Dir is the directory the override applies to, including subdirectories.
+	// An empty Dir matches every file.
+	Dir string
+	// Rules enables (true) or disables (false) rules by ID.
+	Rules map[string]bool
+	// Severity overrides the reported severity of issues by rule ID.
+	Severity map[string]Severity
+}
+
+// ParseSeverity parses "error", "warning" or "info".
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "error":
+		return SeverityError, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "info":
+		return SeverityInfo, nil
+	default:
+		return 0, fmt.Errorf("invalid severity %q (valid: error, warning, info)", s)
+	}
+}
+
+// forFile returns the options that apply to the file at path, with the
+// matching Overrides folded into DisabledRules and Severity.
+func (o Options) forFile(path string) Options {
+	if len(o.Overrides) == 0 {
+		return o
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+
+	var matching []Override
+	for _, ov := range o.Overrides {
+		dir := filepath.Clean(ov.Dir)
+		if ov.Dir == "" || abs == dir || strings.HasPrefix(abs, dir+string(filepath.Separator)) {
+			matching = append(matching, ov)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		return len(matching[i].Dir) < len(matching[j].Dir)
+	})
+
+	disabled := make(map[string]bool)
+	severity := make(map[string]Severity)
+	for id, sev := range o.Severity {
+		severity[id] = sev
+	}
+	for _, ov := range matching {
+		for id, on := range ov.Rules {
+			disabled[id] = !on
+		}
+		for id, sev := range ov.Severity {
+			severity[id] = sev
+		}
+	}
+	for _, id := range o.DisabledRules {
+		disabled[id] = true
+	}
+
+	out := o
+	out.Overrides = nil
+	out.Severity = severity
+	out.DisabledRules = nil
+	for id, off := range disabled {
+		if off {
+			out.DisabledRules = append(out.DisabledRules, id)
+		}
+	}
+	sort.Strings(out.DisabledRules)
+	return out
+}
+
+// applySeverity rewrites the severity of issues whose rule has an override.
+func applySeverity(issues []Issue, severity map[string]Severity) []Issue {
+	if len(severity) == 0 {
+		return issues
+	}
+	for i := range issues {
+		if sev, ok := severity[issues[i].Rule]; ok {
+			issues[i].Severity = sev
+		}
+	}
+	return issues
+}
+
 // LintFile lints a single Go file.
+// With opts.Fix, fixable issues are rewritten in place first.
 func LintFile(path string, opts Options) (Result, error) {
+	if opts.Fix {
+		return lintAndFix(path, opts, lintFile, FixFile)
+	}
+	return lintFile(path, opts)
+}
+
+func lintFile(path string, opts Options) (Result, error) {
+	opts = opts.forFile(path)
 	fset := token.NewFileSet()
 
 	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
@@ -57,13 +168,7 @@ func LintFile(path string, opts Options) (Result, error) {
 		return Result{}, err
 	}
 
-	rules := getRules(opts)
-	var issues []Issue
-
-	for _, rule := range rules {
-		ruleIssues := rule.Check(file, fset)
-		issues = append(issues, ruleIssues...)
-	}
+	issues := checkFile(file, fset, getRules(opts), nil, opts.Severity)
 
 	return Result{
 		Success: len(issues) == 0,
@@ -72,7 +177,36 @@ func LintFile(path string, opts Options) (Result, error) {
 }
 
 // LintPackage lints all Go files in a package directory.
+// With opts.Fix, fixable issues are rewritten in place first.
 func LintPackage(pkgPath string, opts Options) (Result, error) {
+	if opts.Fix {
+		return lintAndFix(pkgPath, opts, lintPackage, FixPackage)
+	}
+	return lintPackage(pkgPath, opts)
+}
+
+// lintAndFix lints path, applies fixes and lints again, reporting the
+// issues that were fixed and those that remain.
+func lintAndFix(path string, opts Options, lintFn func(string, Options) (Result, error), fixFn func(string, Options) error) (Result, error) {
+	opts.Fix = false
+
+	before, err := lintFn(path, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := fixFn(path, opts); err != nil {
+		return Result{}, err
+	}
+	after, err := lintFn(path, opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	after.Fixed = fixedIssues(before.Issues, after.Issues)
+	return after, nil
+}
+
+func lintPackage(pkgPath string, opts Options) (Result, error) {
 	// Handle ... pattern
 	if strings.HasSuffix(pkgPath, "/...") {
 		return lintRecursive(strings.TrimSuffix(pkgPath, "/..."), opts)
@@ -90,24 +224,15 @@ func LintPackage(pkgPath string, opts Options) (Result, error) {
 		return Result{}, err
 	}
 
-	rules := getRules(opts)
 	var allIssues []Issue
 
 	for _, pkg := range pkgs {
 		// Build package context with all defined variables across files
 		ctx := buildPackageContext(pkg)
 
-		for _, file := range pkg.Files {
-			for _, rule := range rules {
-				var issues []Issue
-				// Use CheckWithContext for package-aware rules
-				if par, ok := rule.(PackageAwareRule); ok {
-					issues = par.CheckWithContext(file, fset, ctx)
-				} else {
-					issues = rule.Check(file, fset)
-				}
-				allIssues = append(allIssues, issues...)
-			}
+		for path, file := range pkg.Files {
+			fileOpts := opts.forFile(path)
+			allIssues = append(allIssues, checkFile(file, fset, getRules(fileOpts), ctx, fileOpts.Severity)...)
 		}
 	}
 
@@ -117,25 +242,47 @@ func LintPackage(pkgPath string, opts Options) (Result, error) {
 	}, nil
 }
 
+// checkFile runs rules over file, drops the issues silenced by
+// //wetwire:ignore comments and applies severity overrides. Package-aware
+// rules receive ctx when it is non-nil.
+func checkFile(file *ast.File, fset *token.FileSet, rules []Rule, ctx *PackageContext, severity map[string]Severity) []Issue {
+	var issues []Issue
+	for _, rule := range rules {
+		issues = append(issues, runRule(rule, file, fset, ctx)...)
+	}
+
+	suppressions := suppress.Parse(file, fset)
+	kept := issues[:0]
+	for _, issue := range issues {
+		if !suppressions.Match(issue.Rule, issue.Line) {
+			kept = append(kept, issue)
+		}
+	}
+
+	return applySeverity(kept, severity)
+}
+
+// runRule checks file with rule, using CheckWithContext for package-aware rules.
+func runRule(rule Rule, file *ast.File, fset *token.FileSet, ctx *PackageContext) []Issue {
+	if par, ok := rule.(PackageAwareRule); ok && ctx != nil {
+		return par.CheckWithContext(file, fset, ctx)
+	}
+	return rule.Check(file, fset)
+}
+
 // buildPackageContext collects all package-level variable definitions across all files.
 // Uses ast.Package which is returned by parser.ParseDir - no replacement exists for this use case.
 func buildPackageContext(pkg *ast.Package) *PackageContext { //nolint:staticcheck
 	ctx := &PackageContext{
 		AllDefinedVars: make(map[string]bool),
+		VarRefs:        make(map[string]map[string]bool),
 	}
 
 	for _, file := range pkg.Files {
-		for _, decl := range file.Decls {
-			if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
-				for _, spec := range genDecl.Specs {
-					if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-						for _, name := range valueSpec.Names {
-							ctx.AllDefinedVars[name.Name] = true
-						}
-					}
-				}
-			}
-		}
+		collectDefinedVars(file, ctx.AllDefinedVars)
+	}
+	for _, file := range pkg.Files {
+		collectVarRefs(file, ctx.AllDefinedVars, ctx.VarRefs)
 	}
 
 	return ctx
@@ -172,7 +319,7 @@ func lintRecursive(root string, opts Options) (Result, error) {
 				return nil
 			}
 
-			result, err := LintFile(path, opts)
+			result, err := lintFile(path, opts)
 			if err != nil {
 				// Log but don't fail on parse errors
 				return nil
@@ -208,6 +355,15 @@ func getRules(opts Options) []Rule {
 		}
 	}
 
+	// Stale suppressions are judged against every rule, including disabled
+	// ones, so turning a rule off does not flag its suppressions
+	for i, r := range all {
+		if stale, ok := r.(StaleSuppression); ok {
+			stale.Rules = append(append([]Rule(nil), all[:i]...), all[i+1:]...)
+			all[i] = stale
+		}
+	}
+
 	// Build disabled rules set (takes precedence)
 	disabled := make(map[string]bool)
 	for _, id := range opts.DisabledRules {
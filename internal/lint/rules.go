@@ -37,6 +37,48 @@ import (
 type PackageContext struct {
 	// AllDefinedVars contains all package-level variable names across all files
 	AllDefinedVars map[string]bool
+
+	// VarRefs maps each package-level variable to the package-level
+	// variables its value refers to
+	VarRefs map[string]map[string]bool
+
+	// sources holds the current source of each file being fixed, keyed by
+	// path, so fixes can be type-checked against the whole package
+	sources map[string][]byte
+}
+
+// refersTo reports whether the value of from refers to to, directly or
+// through other package-level variables.
+func (ctx *PackageContext) refersTo(from, to string) bool {
+	seen := make(map[string]bool)
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		if name == to {
+			return true
+		}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		for ref := range ctx.VarRefs[name] {
+			if visit(ref) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+// addRef records that the value of from now refers to to.
+func (ctx *PackageContext) addRef(from, to string) {
+	if ctx.VarRefs == nil {
+		ctx.VarRefs = make(map[string]map[string]bool)
+	}
+	if ctx.VarRefs[from] == nil {
+		ctx.VarRefs[from] = make(map[string]bool)
+	}
+	ctx.VarRefs[from][to] = true
 }
 
 // PackageAwareRule is an optional interface for rules that need package-level context.
@@ -70,6 +112,7 @@ var pseudoParams = map[string]string{
 
 func (r HardcodedPseudoParameter) Check(file *ast.File, fset *token.FileSet) []Issue {
 	var issues []Issue
+	unfixable := unfixableStrings(file)
 
 	ast.Inspect(file, func(n ast.Node) bool {
 		lit, ok := n.(*ast.BasicLit)
@@ -90,6 +133,7 @@ func (r HardcodedPseudoParameter) Check(file *ast.File, fset *token.FileSet) []I
 				Line:       pos.Line,
 				Column:     pos.Column,
 				Severity:   SeverityWarning,
+				Fixable:    !unfixable[lit],
 			})
 		}
 
@@ -111,22 +155,24 @@ func (r MapShouldBeIntrinsic) Description() string {
 }
 
 var intrinsicKeys = map[string]string{
-	"Ref":             "Ref",
-	"Fn::Sub":         "Sub",
-	"Fn::Join":        "Join",
-	"Fn::Select":      "Select",
-	"Fn::GetAZs":      "GetAZs",
-	"Fn::GetAtt":      "GetAtt",
-	"Fn::If":          "If",
-	"Fn::Equals":      "Equals",
-	"Fn::And":         "And",
-	"Fn::Or":          "Or",
-	"Fn::Not":         "Not",
-	"Fn::Base64":      "Base64",
-	"Fn::Split":       "Split",
-	"Fn::ImportValue": "ImportValue",
-	"Fn::FindInMap":   "FindInMap",
-	"Fn::Cidr":        "Cidr",
+	"Ref":              "Ref",
+	"Fn::Sub":          "Sub",
+	"Fn::Join":         "Join",
+	"Fn::Select":       "Select",
+	"Fn::GetAZs":       "GetAZs",
+	"Fn::GetAtt":       "GetAtt",
+	"Fn::If":           "If",
+	"Fn::Equals":       "Equals",
+	"Fn::And":          "And",
+	"Fn::Or":           "Or",
+	"Fn::Not":          "Not",
+	"Fn::Base64":       "Base64",
+	"Fn::Split":        "Split",
+	"Fn::ImportValue":  "ImportValue",
+	"Fn::FindInMap":    "FindInMap",
+	"Fn::Cidr":         "Cidr",
+	"Fn::Length":       "Length",
+	"Fn::ToJsonString": "ToJsonString",
 }
 
 func (r MapShouldBeIntrinsic) Check(file *ast.File, fset *token.FileSet) []Issue {
@@ -161,6 +207,7 @@ func (r MapShouldBeIntrinsic) Check(file *ast.File, fset *token.FileSet) []Issue
 		keyValue := strings.Trim(keyLit.Value, `"`)
 		if typeName, found := intrinsicKeys[keyValue]; found {
 			pos := fset.Position(comp.Pos())
+			_, fixable := mapIntrinsic(comp, func(ast.Expr) string { return "" })
 			issues = append(issues, Issue{
 				Rule:       r.ID(),
 				Message:    "Use intrinsics." + typeName + "{...} instead of map[string]any{\"" + keyValue + "\": ...}",
@@ -169,6 +216,7 @@ func (r MapShouldBeIntrinsic) Check(file *ast.File, fset *token.FileSet) []Issue
 				Line:       pos.Line,
 				Column:     pos.Column,
 				Severity:   SeverityWarning,
+				Fixable:    fixable,
 			})
 		}
 
@@ -434,18 +482,8 @@ func (r InlinePropertyType) Check(file *ast.File, fset *token.FileSet) []Issue {
 			return true
 		}
 
-		fieldName := strings.ToLower(keyIdent.Name)
-
 		// Check if field name suggests a property type
-		isPropertyType := false
-		for _, suffix := range propertyTypeSuffixes {
-			if strings.HasSuffix(fieldName, suffix) {
-				isPropertyType = true
-				break
-			}
-		}
-
-		if !isPropertyType {
+		if !hasPropertyTypeSuffix(keyIdent.Name) {
 			return true
 		}
 
@@ -469,6 +507,7 @@ func (r InlinePropertyType) Check(file *ast.File, fset *token.FileSet) []Issue {
 			Line:       pos.Line,
 			Column:     pos.Column,
 			Severity:   SeverityWarning,
+			Fixable:    inTopLevelVar(file, kv.Pos()),
 		})
 
 		return true
@@ -476,3 +515,14 @@ func (r InlinePropertyType) Check(file *ast.File, fset *token.FileSet) []Issue {
 
 	return issues
 }
+
+// hasPropertyTypeSuffix reports whether a field name suggests a property type.
+func hasPropertyTypeSuffix(field string) bool {
+	field = strings.ToLower(field)
+	for _, suffix := range propertyTypeSuffixes {
+		if strings.HasSuffix(field, suffix) {
+			return true
+		}
+	}
+	return false
+}
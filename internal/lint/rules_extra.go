@@ -5,6 +5,8 @@ import (
 	"go/ast"
 	"go/token"
 	"strings"
+
+	"github.com/lex00/wetwire-aws-go/internal/suppress"
 )
 
 type InvalidEnumValue struct{}
@@ -379,6 +381,7 @@ func (r PreferEnumConstant) Check(file *ast.File, fset *token.FileSet) []Issue {
 						Line:       pos.Line,
 						Column:     pos.Column,
 						Severity:   SeverityWarning,
+						Fixable:    true,
 					})
 				}
 			}
@@ -410,6 +413,7 @@ var knownIdentifiers = map[string]bool{
 	"If": true, "Equals": true, "And": true, "Or": true, "Not": true,
 	"Base64": true, "Split": true, "FindInMap": true, "Cidr": true,
 	"GetAZs": true, "ImportValue": true, "Condition": true, "Transform": true,
+	"Length": true, "ToJsonString": true, "ForEach": true,
 	// Pseudo-parameters
 	"AWS_REGION": true, "AWS_ACCOUNT_ID": true, "AWS_STACK_NAME": true,
 	"AWS_STACK_ID": true, "AWS_PARTITION": true, "AWS_URL_SUFFIX": true,
@@ -515,6 +519,7 @@ var intrinsicTypeNames = map[string]bool{
 	"And": true, "Or": true, "Not": true, "Base64": true,
 	"Split": true, "FindInMap": true, "Cidr": true, "GetAZs": true,
 	"ImportValue": true, "Condition": true, "Transform": true,
+	"Length": true, "ToJsonString": true, "ForEach": true,
 	"List": true, "Param": true, "Output": true,
 	"PolicyDocument": true, "PolicyStatement": true, "DenyStatement": true,
 	"ServicePrincipal": true, "AWSPrincipal": true, "AllPrincipal": true,
@@ -529,16 +534,7 @@ func (r UnusedIntrinsicsImport) Check(file *ast.File, fset *token.FileSet) []Iss
 	var issues []Issue
 
 	// Check if intrinsics is imported as dot import
-	var intrinsicsImport *ast.ImportSpec
-	for _, imp := range file.Imports {
-		if imp.Path != nil && strings.Contains(imp.Path.Value, "intrinsics") {
-			if imp.Name != nil && imp.Name.Name == "." {
-				intrinsicsImport = imp
-				break
-			}
-		}
-	}
-
+	intrinsicsImport := dotIntrinsicsImport(file)
 	if intrinsicsImport == nil {
 		return issues // No dot import of intrinsics
 	}
@@ -578,8 +574,82 @@ func (r UnusedIntrinsicsImport) Check(file *ast.File, fset *token.FileSet) []Iss
 			Line:       pos.Line,
 			Column:     pos.Column,
 			Severity:   SeverityError,
+			Fixable:    true,
+		})
+	}
+
+	return issues
+}
+
+// StaleSuppression reports //wetwire:ignore comments that no longer silence
+// any issue, so that suppressions are cleaned up when the code they excused
+// changes. Optimizer rule IDs (OPT-*) are left to the optimizer.
+type StaleSuppression struct {
+	// Rules are checked to decide whether a suppression is still needed.
+	// Defaults to AllRules.
+	Rules []Rule
+}
+
+func (r StaleSuppression) ID() string { return "WAW020" }
+func (r StaleSuppression) Description() string {
+	return "Suppression comment does not match any issue"
+}
+
+func (r StaleSuppression) Check(file *ast.File, fset *token.FileSet) []Issue {
+	return r.CheckWithContext(file, fset, nil)
+}
+
+// CheckWithContext implements PackageAwareRule so package-aware rules are
+// judged the same way the linter runs them.
+func (r StaleSuppression) CheckWithContext(file *ast.File, fset *token.FileSet, ctx *PackageContext) []Issue {
+	suppressions := suppress.Parse(file, fset)
+	if len(suppressions.Suppressions) == 0 {
+		return nil
+	}
+
+	rules := r.Rules
+	if rules == nil {
+		rules = AllRules()
+	}
+	known := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.ID() == r.ID() {
+			continue
+		}
+		known[rule.ID()] = true
+		for _, issue := range runRule(rule, file, fset, ctx) {
+			suppressions.Match(issue.Rule, issue.Line)
+		}
+	}
+
+	var issues []Issue
+	report := func(sup *suppress.Suppression, message string) {
+		issues = append(issues, Issue{
+			Rule:     r.ID(),
+			Message:  message,
+			File:     sup.File,
+			Line:     sup.Line,
+			Column:   sup.Column,
+			Severity: SeverityWarning,
 		})
 	}
 
+	for _, sup := range suppressions.Suppressions {
+		if len(sup.Rules) == 0 {
+			report(sup, "Suppression comment names no rule ID (e.g. //wetwire:ignore WAW001 reason)")
+		}
+	}
+
+	judge := func(id string) bool {
+		return id != r.ID() && !suppress.IsOptimizerRule(id)
+	}
+	for _, unused := range suppressions.Unused(judge) {
+		if !known[unused.Rule] {
+			report(unused.Suppression, fmt.Sprintf("Suppression of unknown rule %s", unused.Rule))
+			continue
+		}
+		report(unused.Suppression, fmt.Sprintf("Suppression of %s does not match any issue; remove it", unused.Rule))
+	}
+
 	return issues
 }
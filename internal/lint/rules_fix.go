@@ -0,0 +1,808 @@
+package lint
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/lex00/wetwire-aws-go/resources"
+)
+
+// Import paths used by fixes.
+const (
+	intrinsicsImportPath = "github.com/lex00/wetwire-aws-go/intrinsics"
+	enumsImportPath      = "github.com/lex00/cloudformation-schema-go/enums"
+	resourcesImportPath  = "github.com/lex00/wetwire-aws-go/resources/"
+)
+
+// Fix replaces hardcoded pseudo-parameter strings and Ref{"AWS::..."} with
+// the intrinsics constants.
+func (r HardcodedPseudoParameter) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	prefix, importEdit := qualifier(file, fset, src, intrinsicsImportPath, ".")
+	skip := unfixableStrings(file)
+
+	var edits []Edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.CompositeLit:
+			if name, ok := refTarget(node); ok {
+				if constant, found := pseudoParams[name]; found {
+					edits = append(edits, replaceNode(fset, node, prefix+constant))
+					return false
+				}
+			}
+		case *ast.BasicLit:
+			if node.Kind != token.STRING || skip[node] {
+				return true
+			}
+			if constant, found := pseudoParams[strings.Trim(node.Value, `"`)]; found {
+				edits = append(edits, replaceNode(fset, node, prefix+constant))
+			}
+		}
+		return true
+	})
+
+	return withImport(edits, importEdit)
+}
+
+// Fix converts map[string]any{"Ref": ...} and map[string]any{"Fn::...": ...}
+// to the equivalent intrinsic type.
+func (r MapShouldBeIntrinsic) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	prefix, importEdit := qualifier(file, fset, src, intrinsicsImportPath, ".")
+	text := func(e ast.Expr) string { return nodeText(fset, src, e) }
+
+	var edits []Edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		comp, ok := n.(*ast.CompositeLit)
+		if !ok || !isMapStringAny(comp.Type) {
+			return true
+		}
+		if intrinsic, ok := mapIntrinsic(comp, text); ok {
+			edits = append(edits, replaceNode(fset, comp, prefix+intrinsic))
+		}
+		return true
+	})
+
+	return withImport(edits, importEdit)
+}
+
+// Fix extracts inline map[string]any property values to named vars.
+func (r InlinePropertyType) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	return extractInline(file, fset, src, ctx, func(field string, lit *ast.CompositeLit, inSlice bool) bool {
+		return !inSlice && isMapStringAny(lit.Type) && len(lit.Elts) > 1 && hasPropertyTypeSuffix(field)
+	})
+}
+
+// Fix extracts map[string]any items of known property arrays to named vars.
+func (r InlineMapInSlice) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	return extractInline(file, fset, src, ctx, func(field string, lit *ast.CompositeLit, inSlice bool) bool {
+		_, known := knownPropertyArrays[field]
+		return inSlice && known && field != "Tags" && isMapStringAny(lit.Type)
+	})
+}
+
+// Fix extracts inline struct literals in block-style slices to named vars.
+func (r InlineStructLiteral) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	return extractInline(file, fset, src, ctx, func(field string, lit *ast.CompositeLit, inSlice bool) bool {
+		return inSlice && knownTypedSlices[field]
+	})
+}
+
+// Fix converts map[string]any to intrinsic types or, when the resource
+// registry knows the property type, to the typed property struct.
+func (r UnflattenedMap) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	var edits []Edit
+	for comp, typeName := range typedMapConversions(file) {
+		edits = append(edits, replaceNode(fset, comp.Type, typeName))
+		for _, elt := range comp.Elts {
+			key := elt.(*ast.KeyValueExpr).Key
+			edit := replaceNode(fset, key, strings.Trim(key.(*ast.BasicLit).Value, `"`))
+			// Keys are converted together with the map they belong to
+			edit.Line = fset.Position(comp.Pos()).Line
+			edits = append(edits, edit)
+		}
+	}
+	return edits
+}
+
+// Fix extracts nested property type literals to named vars.
+func (r InlineTypedStruct) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	return extractInline(file, fset, src, ctx, func(field string, lit *ast.CompositeLit, inSlice bool) bool {
+		return isPropertyTypeLit(lit)
+	})
+}
+
+// Fix replaces raw enum strings with enum constants.
+func (r PreferEnumConstant) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	prefix, importEdit := qualifier(file, fset, src, enumsImportPath, "")
+
+	var edits []Edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		kv, ok := n.(*ast.KeyValueExpr)
+		if !ok {
+			return true
+		}
+		if lit, constName, ok := enumConstantFor(kv); ok {
+			edits = append(edits, replaceNode(fset, lit, prefix+constName))
+		}
+		return true
+	})
+
+	return withImport(edits, importEdit)
+}
+
+// Fix removes the unused intrinsics import.
+func (r UnusedIntrinsicsImport) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	if len(r.Check(file, fset)) == 0 {
+		return nil
+	}
+	return []Edit{removeImport(file, fset, src, dotIntrinsicsImport(file))}
+}
+
+// Fix replaces Ref{"Name"} with a direct reference when Name is a
+// package-level var whose value does not refer back to the current var,
+// which would be an initialization cycle.
+func (r AvoidExplicitRef) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	var edits []Edit
+	forEachTopLevelVar(file, func(varName string, value ast.Expr, _ *ast.GenDecl) {
+		ast.Inspect(value, func(n ast.Node) bool {
+			comp, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if name, ok := refTarget(comp); ok && name != varName && ctx.AllDefinedVars[name] && !ctx.refersTo(name, varName) {
+				ctx.addRef(varName, name)
+				edits = append(edits, replaceNode(fset, comp, name))
+				return false
+			}
+			return true
+		})
+	})
+	return edits
+}
+
+// Fix replaces GetAtt{"Name", "Attr"} with Name.Attr when Name is a
+// package-level var whose value does not refer back to the current var.
+func (r AvoidExplicitGetAtt) Fix(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext) []Edit {
+	var edits []Edit
+	forEachTopLevelVar(file, func(varName string, value ast.Expr, _ *ast.GenDecl) {
+		ast.Inspect(value, func(n ast.Node) bool {
+			comp, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			if resource, field, ok := getAttTarget(comp); ok && resource != varName && ctx.AllDefinedVars[resource] && !ctx.refersTo(resource, varName) {
+				ctx.addRef(varName, resource)
+				edits = append(edits, replaceNode(fset, comp, resource+"."+field))
+				return false
+			}
+			return true
+		})
+	})
+	return edits
+}
+
+// refTarget returns the name in a Ref{"Name"} literal.
+func refTarget(comp *ast.CompositeLit) (string, bool) {
+	ident, ok := comp.Type.(*ast.Ident)
+	if !ok || ident.Name != "Ref" || len(comp.Elts) != 1 {
+		return "", false
+	}
+	lit, ok := comp.Elts[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	return strings.Trim(lit.Value, `"`), true
+}
+
+// getAttTarget returns the resource var and attribute field for a
+// GetAtt{"Name", "Attr"} literal. Dotted attributes use underscores
+// ("Endpoint.Address" -> Endpoint_Address).
+func getAttTarget(comp *ast.CompositeLit) (string, string, bool) {
+	ident, ok := comp.Type.(*ast.Ident)
+	if !ok || ident.Name != "GetAtt" || len(comp.Elts) != 2 {
+		return "", "", false
+	}
+	resLit, ok1 := comp.Elts[0].(*ast.BasicLit)
+	attrLit, ok2 := comp.Elts[1].(*ast.BasicLit)
+	if !ok1 || !ok2 || resLit.Kind != token.STRING || attrLit.Kind != token.STRING {
+		return "", "", false
+	}
+	resource := strings.Trim(resLit.Value, `"`)
+	field := strings.ReplaceAll(strings.Trim(attrLit.Value, `"`), ".", "_")
+	if !token.IsIdentifier(resource) || !token.IsIdentifier(field) {
+		return "", "", false
+	}
+	return resource, field, true
+}
+
+// mapIntrinsic returns the intrinsic type literal equivalent to a
+// single-key intrinsic map, using text to render sub-expressions.
+func mapIntrinsic(comp *ast.CompositeLit, text func(ast.Expr) string) (string, bool) {
+	if len(comp.Elts) != 1 {
+		return "", false
+	}
+	kv, ok := comp.Elts[0].(*ast.KeyValueExpr)
+	if !ok {
+		return "", false
+	}
+	keyLit, ok := kv.Key.(*ast.BasicLit)
+	if !ok || keyLit.Kind != token.STRING {
+		return "", false
+	}
+
+	key := strings.Trim(keyLit.Value, `"`)
+	typeName, found := intrinsicKeys[key]
+	if !found {
+		return "", false
+	}
+
+	value := kv.Value
+	args, isList := listElements(value)
+	positional := func(n int) (string, bool) {
+		if !isList || len(args) != n {
+			return "", false
+		}
+		parts := make([]string, n)
+		for i, arg := range args {
+			parts[i] = text(arg)
+		}
+		return typeName + "{" + strings.Join(parts, ", ") + "}", true
+	}
+
+	switch key {
+	case "Ref", "Fn::Base64", "Fn::ImportValue", "Fn::And", "Fn::Or", "Fn::Length", "Fn::ToJsonString":
+		return typeName + "{" + text(value) + "}", true
+	case "Fn::Sub":
+		if isList && len(args) == 2 {
+			return "SubWithMap{String: " + text(args[0]) + ", Variables: " + text(args[1]) + "}", true
+		}
+		if !isList {
+			return "Sub{String: " + text(value) + "}", true
+		}
+	case "Fn::GetAtt":
+		if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			resource, attr, ok := strings.Cut(strings.Trim(lit.Value, `"`), ".")
+			if ok {
+				return fmt.Sprintf("GetAtt{%q, %q}", resource, attr), true
+			}
+			return "", false
+		}
+		return positional(2)
+	case "Fn::Join":
+		if isList && len(args) == 2 {
+			return "Join{Delimiter: " + text(args[0]) + ", Values: " + text(args[1]) + "}", true
+		}
+	case "Fn::Select":
+		if isList && len(args) == 2 {
+			if index, ok := intLiteral(args[0]); ok {
+				return fmt.Sprintf("Select{Index: %d, List: %s}", index, text(args[1])), true
+			}
+		}
+	case "Fn::GetAZs":
+		if lit, ok := value.(*ast.BasicLit); ok && lit.Value == `""` {
+			return "GetAZs{}", true
+		}
+		return "GetAZs{Region: " + text(value) + "}", true
+	case "Fn::If", "Fn::FindInMap", "Fn::Cidr":
+		return positional(3)
+	case "Fn::Equals", "Fn::Split":
+		return positional(2)
+	case "Fn::Not":
+		return positional(1)
+	}
+	return "", false
+}
+
+// listElements returns the elements of a slice literal.
+func listElements(expr ast.Expr) ([]ast.Expr, bool) {
+	comp, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := comp.Type.(*ast.ArrayType); !ok {
+		return nil, false
+	}
+	for _, elt := range comp.Elts {
+		if _, ok := elt.(*ast.KeyValueExpr); ok {
+			return nil, false
+		}
+	}
+	return comp.Elts, true
+}
+
+// intLiteral parses an integer literal or a string containing an integer.
+func intLiteral(expr ast.Expr) (int, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || (lit.Kind != token.INT && lit.Kind != token.STRING) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.Trim(lit.Value, `"`))
+	return n, err == nil
+}
+
+// unfixableStrings returns string literals that cannot be replaced by a
+// pseudo-parameter constant: map keys, constants and elements of string
+// slices or maps.
+func unfixableStrings(file *ast.File) map[*ast.BasicLit]bool {
+	skip := make(map[*ast.BasicLit]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.KeyValueExpr:
+			if lit, ok := node.Key.(*ast.BasicLit); ok {
+				skip[lit] = true
+			}
+		case *ast.GenDecl:
+			if node.Tok == token.CONST {
+				ast.Inspect(node, func(c ast.Node) bool {
+					if lit, ok := c.(*ast.BasicLit); ok {
+						skip[lit] = true
+					}
+					return true
+				})
+				return false
+			}
+		case *ast.CompositeLit:
+			if isStringCollection(node.Type) {
+				for _, elt := range node.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						elt = kv.Value
+					}
+					if lit, ok := elt.(*ast.BasicLit); ok {
+						skip[lit] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+	return skip
+}
+
+// isStringCollection reports whether expr is a []string or map[K]string type.
+func isStringCollection(expr ast.Expr) bool {
+	var elem ast.Expr
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		elem = t.Elt
+	case *ast.MapType:
+		elem = t.Value
+	default:
+		return false
+	}
+	ident, ok := elem.(*ast.Ident)
+	return ok && ident.Name == "string"
+}
+
+// enumConstantFor returns the string literal and enum constant name for a
+// field assignment that should use an enum constant.
+func enumConstantFor(kv *ast.KeyValueExpr) (*ast.BasicLit, string, bool) {
+	fieldIdent, ok := kv.Key.(*ast.Ident)
+	if !ok {
+		return nil, "", false
+	}
+	service, ok := enumFieldToService[fieldIdent.Name]
+	if !ok {
+		return nil, "", false
+	}
+	enumName := fieldIdent.Name
+	if mapped, ok := enumFieldToEnumName[fieldIdent.Name]; ok {
+		enumName = mapped
+	}
+
+	lit, ok := kv.Value.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, "", false
+	}
+
+	constName, ok := enumConstantMap[service][enumName][strings.Trim(lit.Value, `"`)]
+	return lit, constName, ok
+}
+
+// dotIntrinsicsImport returns the dot import of the intrinsics package, if any.
+func dotIntrinsicsImport(file *ast.File) *ast.ImportSpec {
+	for _, imp := range file.Imports {
+		if imp.Path != nil && strings.Contains(imp.Path.Value, "intrinsics") {
+			if imp.Name != nil && imp.Name.Name == "." {
+				return imp
+			}
+		}
+	}
+	return nil
+}
+
+// forEachTopLevelVar calls fn for each single-value package-level var.
+func forEachTopLevelVar(file *ast.File, fn func(name string, value ast.Expr, decl *ast.GenDecl)) {
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok || len(valueSpec.Names) != 1 || len(valueSpec.Values) != 1 {
+				continue
+			}
+			fn(valueSpec.Names[0].Name, valueSpec.Values[0], genDecl)
+		}
+	}
+}
+
+// inTopLevelVar reports whether pos is inside the value of a package-level var.
+func inTopLevelVar(file *ast.File, pos token.Pos) bool {
+	found := false
+	forEachTopLevelVar(file, func(_ string, value ast.Expr, _ *ast.GenDecl) {
+		if value.Pos() <= pos && pos < value.End() {
+			found = true
+		}
+	})
+	return found
+}
+
+// isPropertyTypeLit reports whether lit is a property type literal such as
+// s3.Bucket_BucketEncryption{...}.
+func isPropertyTypeLit(lit *ast.CompositeLit) bool {
+	sel, ok := lit.Type.(*ast.SelectorExpr)
+	return ok && strings.Contains(sel.Sel.Name, "_")
+}
+
+// unwrapLit returns the composite literal in expr, looking through &.
+func unwrapLit(expr ast.Expr) (*ast.CompositeLit, bool) {
+	addr := false
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		expr = unary.X
+		addr = true
+	}
+	comp, _ := expr.(*ast.CompositeLit)
+	return comp, addr
+}
+
+// extractInline moves composite literals selected by match out of
+// package-level var declarations into their own vars, declared before the
+// var that used them. Only the outermost match is extracted per call;
+// nested matches are extracted by the next pass.
+func extractInline(file *ast.File, fset *token.FileSet, src []byte, ctx *PackageContext, match func(field string, lit *ast.CompositeLit, inSlice bool) bool) []Edit {
+	var edits []Edit
+
+	forEachTopLevelVar(file, func(varName string, value ast.Expr, decl *ast.GenDecl) {
+		root, _ := unwrapLit(value)
+		if root == nil {
+			return
+		}
+
+		insertAt := offset(fset, decl.Pos())
+		if decl.Doc != nil {
+			insertAt = offset(fset, decl.Doc.Pos())
+		}
+
+		extract := func(expr ast.Expr, lit *ast.CompositeLit, addr bool, typeExpr ast.Expr, baseName string) {
+			name := allocVarName(ctx, baseName)
+			body := string(src[offset(fset, lit.Lbrace):offset(fset, lit.End())])
+			edits = append(edits, Edit{
+				Start: insertAt,
+				End:   insertAt,
+				Text:  "var " + name + " = " + nodeText(fset, src, typeExpr) + body + "\n\n",
+				Line:  fset.Position(expr.Pos()).Line,
+			})
+			if addr {
+				name = "&" + name
+			}
+			edits = append(edits, replaceNode(fset, expr, name))
+		}
+
+		var visit func(comp *ast.CompositeLit, prefix string)
+		visit = func(comp *ast.CompositeLit, prefix string) {
+			for _, elt := range comp.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				field := fieldName(kv.Key)
+				lit, addr := unwrapLit(kv.Value)
+				if lit == nil || field == "" {
+					continue
+				}
+
+				if lit.Type != nil && match(field, lit, false) {
+					extract(kv.Value, lit, addr, lit.Type, prefix+field)
+					continue
+				}
+
+				arr, isSlice := lit.Type.(*ast.ArrayType)
+				if !isSlice {
+					visit(lit, prefix+field)
+					continue
+				}
+				for i, item := range lit.Elts {
+					itemLit, itemAddr := unwrapLit(item)
+					if itemLit == nil {
+						continue
+					}
+					typeExpr := itemLit.Type
+					if typeExpr == nil {
+						typeExpr = arr.Elt
+					}
+					if _, isPtr := typeExpr.(*ast.StarExpr); !isPtr && match(field, itemLit, true) {
+						extract(item, itemLit, itemAddr, typeExpr, prefix+singular(field)+itemSuffix(itemLit, i))
+						continue
+					}
+					visit(itemLit, prefix+singular(field)+itemSuffix(itemLit, i))
+				}
+			}
+		}
+		visit(root, varName)
+	})
+
+	return edits
+}
+
+// fieldName returns the name of a struct field or string map key.
+func fieldName(key ast.Expr) string {
+	switch k := key.(type) {
+	case *ast.Ident:
+		return k.Name
+	case *ast.BasicLit:
+		if k.Kind == token.STRING {
+			return cleanVarName(strings.Trim(k.Value, `"`))
+		}
+	}
+	return ""
+}
+
+// itemSuffix distinguishes extracted slice items, preferring an identifying
+// field value (Key, Name, ...) over the item's position.
+func itemSuffix(lit *ast.CompositeLit, index int) string {
+	values := make(map[string]string)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if v, ok := kv.Value.(*ast.BasicLit); ok {
+			values[fieldName(kv.Key)] = strings.Trim(v.Value, `"`)
+		}
+	}
+
+	for _, key := range []string{"Id", "Name", "Key", "Type", "DeviceName", "PolicyName", "Status"} {
+		if s := cleanVarName(values[key]); s != "" {
+			return s
+		}
+	}
+	if port := cleanVarName(values["FromPort"]); port != "" {
+		return "Port" + port
+	}
+	return strconv.Itoa(index + 1)
+}
+
+// cleanVarName converts a value to a Go identifier fragment.
+func cleanVarName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
+			if upper {
+				b.WriteString(strings.ToUpper(string(r)))
+			} else {
+				b.WriteRune(r)
+			}
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	out := b.String()
+	if len(out) > 20 {
+		out = out[:20]
+	}
+	return out
+}
+
+// singular returns a simple singular form of a property name.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ss"):
+		return name
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// allocVarName returns an unused package-level var name based on base and
+// reserves it in ctx.
+func allocVarName(ctx *PackageContext, base string) string {
+	name := base
+	for i := 2; ctx.AllDefinedVars[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	ctx.AllDefinedVars[name] = true
+	return name
+}
+
+// typedMapConversions finds map[string]any literals in resource and property
+// type declarations whose property type is known from the resource registry.
+// It returns the qualified type each literal should use (e.g.
+// "s3.Bucket_VersioningConfiguration"). Only maps whose keys are all
+// exported identifiers are converted.
+func typedMapConversions(file *ast.File) map[*ast.CompositeLit]string {
+	services := resourceImports(file)
+	conversions := make(map[*ast.CompositeLit]string)
+
+	var visit func(comp *ast.CompositeLit, pkg, typeName string)
+	visit = func(comp *ast.CompositeLit, pkg, typeName string) {
+		for _, elt := range comp.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok || ignoreFields[key.Name] {
+				continue
+			}
+			target := resources.PropertyTypeMap[services[pkg]+"."+typeName+"."+key.Name]
+
+			lit, _ := unwrapLit(kv.Value)
+			if lit == nil {
+				continue
+			}
+			items := []*ast.CompositeLit{lit}
+			if _, isSlice := lit.Type.(*ast.ArrayType); isSlice {
+				items = nil
+				for _, item := range lit.Elts {
+					if itemLit, _ := unwrapLit(item); itemLit != nil {
+						items = append(items, itemLit)
+					}
+				}
+			}
+
+			for _, item := range items {
+				if target != "" && isMapStringAny(item.Type) && keysAreFields(item) {
+					conversions[item] = pkg + "." + target
+				} else if sel, ok := item.Type.(*ast.SelectorExpr); ok && isPkg(sel.X, pkg) {
+					visit(item, pkg, sel.Sel.Name)
+				}
+			}
+		}
+	}
+
+	forEachTopLevelVar(file, func(_ string, value ast.Expr, _ *ast.GenDecl) {
+		root, _ := unwrapLit(value)
+		if root == nil {
+			return
+		}
+		if sel, ok := root.Type.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok && services[pkg.Name] != "" {
+				visit(root, pkg.Name, sel.Sel.Name)
+			}
+		}
+	})
+
+	return conversions
+}
+
+// keysAreFields reports whether every key of a map literal is an exported
+// Go identifier, so the map can become a struct literal.
+func keysAreFields(comp *ast.CompositeLit) bool {
+	for _, elt := range comp.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return false
+		}
+		lit, ok := kv.Key.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return false
+		}
+		name := strings.Trim(lit.Value, `"`)
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPkg(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// resourceImports maps the local names of imported resource packages to
+// their service names.
+func resourceImports(file *ast.File) map[string]string {
+	services := make(map[string]string)
+	for _, imp := range file.Imports {
+		path := strings.Trim(imp.Path.Value, `"`)
+		if !strings.HasPrefix(path, resourcesImportPath) {
+			continue
+		}
+		service := strings.TrimPrefix(path, resourcesImportPath)
+		local := service
+		if imp.Name != nil {
+			local = imp.Name.Name
+		}
+		services[local] = service
+	}
+	return services
+}
+
+// qualifier returns the prefix for identifiers from the package at path
+// ("" for dot imports, "name." otherwise) and, when the file does not import
+// it yet, an edit adding the import under alias ("." or "" for the default name).
+func qualifier(file *ast.File, fset *token.FileSet, src []byte, path, alias string) (string, *Edit) {
+	for _, imp := range file.Imports {
+		if strings.Trim(imp.Path.Value, `"`) != path {
+			continue
+		}
+		if imp.Name == nil {
+			return path[strings.LastIndex(path, "/")+1:] + ".", nil
+		}
+		if imp.Name.Name == "." {
+			return "", nil
+		}
+		return imp.Name.Name + ".", nil
+	}
+
+	spec := strconv.Quote(path)
+	prefix := path[strings.LastIndex(path, "/")+1:] + "."
+	if alias != "" {
+		spec = alias + " " + spec
+		prefix = alias + "."
+	}
+	if alias == "." {
+		prefix = ""
+	}
+	edit := addImport(file, fset, src, spec)
+	edit.Line = 0
+	return prefix, &edit
+}
+
+// addImport returns an edit adding an import spec to file.
+func addImport(file *ast.File, fset *token.FileSet, src []byte, spec string) Edit {
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.IMPORT {
+			continue
+		}
+		if genDecl.Lparen.IsValid() {
+			pos := offset(fset, genDecl.Rparen)
+			return Edit{Start: pos, End: pos, Text: "\t" + spec + "\n"}
+		}
+		existing := nodeText(fset, src, genDecl.Specs[0])
+		return replaceNode(fset, genDecl, "import (\n\t"+existing+"\n\t"+spec+"\n)")
+	}
+
+	pos := offset(fset, file.Name.End())
+	return Edit{Start: pos, End: pos, Text: "\n\nimport " + spec + "\n"}
+}
+
+// removeImport returns an edit deleting an import spec, and its declaration
+// when it is the only spec.
+func removeImport(file *ast.File, fset *token.FileSet, src []byte, imp *ast.ImportSpec) Edit {
+	var node ast.Node = imp
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT && len(genDecl.Specs) == 1 && genDecl.Specs[0] == imp {
+			node = genDecl
+		}
+	}
+
+	start, end := offset(fset, node.Pos()), offset(fset, node.End())
+	for start > 0 && (src[start-1] == ' ' || src[start-1] == '\t') {
+		start--
+	}
+	if end < len(src) && src[end] == '\n' {
+		end++
+	}
+	return Edit{Start: start, End: end, Line: fset.Position(imp.Pos()).Line}
+}
+
+// withImport appends importEdit to edits when there is something to fix.
+func withImport(edits []Edit, importEdit *Edit) []Edit {
+	if len(edits) > 0 && importEdit != nil {
+		edits = append(edits, *importEdit)
+	}
+	return edits
+}
@@ -186,7 +186,7 @@ func TestLintFile_WithDisabledRules(t *testing.T) {
 }
 
 func TestOptions_FixField(t *testing.T) {
-	// Test that Fix field is respected (currently no-op but should not error)
+	// Fix does not change which rules run
 	opts := Options{
 		Fix: true,
 	}
@@ -194,3 +194,123 @@ func TestOptions_FixField(t *testing.T) {
 	rules := getRules(opts)
 	assert.NotEmpty(t, rules)
 }
+
+func TestOptions_SeverityAndOverrides(t *testing.T) {
+	dir, err := filepath.Abs("testdata/simple")
+	require.NoError(t, err)
+
+	result, err := LintPackage("testdata/simple", Options{
+		Severity: map[string]Severity{"WAW001": SeverityInfo},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Issues)
+	for _, issue := range result.Issues {
+		if issue.Rule == "WAW001" {
+			assert.Equal(t, SeverityInfo, issue.Severity)
+		}
+	}
+
+	// A directory override disables the rule, a deeper one re-enables it
+	opts := Options{Overrides: []Override{
+		{Dir: filepath.Dir(dir), Rules: map[string]bool{"WAW001": false}},
+	}}
+	result, err = LintPackage("testdata/simple", opts)
+	require.NoError(t, err)
+	for _, issue := range result.Issues {
+		assert.NotEqual(t, "WAW001", issue.Rule)
+	}
+
+	opts.Overrides = append(opts.Overrides, Override{Dir: dir, Rules: map[string]bool{"WAW001": true}})
+	result, err = LintFile("testdata/simple/with_issues.go", opts)
+	require.NoError(t, err)
+	found := false
+	for _, issue := range result.Issues {
+		found = found || issue.Rule == "WAW001"
+	}
+	assert.True(t, found)
+}
+
+func TestParseSeverity(t *testing.T) {
+	sev, err := ParseSeverity("Warning")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityWarning, sev)
+
+	_, err = ParseSeverity("fatal")
+	assert.Error(t, err)
+}
+
+func TestLintFile_Suppressions(t *testing.T) {
+	src := `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+//wetwire:ignore WAW001 bucket name is a literal
+var Named = s3.Bucket{
+	BucketName: "AWS::Region",
+}
+
+var Trailing = s3.Bucket{
+	BucketName: "AWS::Region", //wetwire:ignore WAW001
+	Tags:       "AWS::StackName",
+}
+
+//wetwire:ignore WAW002 nothing to silence
+var Clean = s3.Bucket{}
+
+//wetwire:ignore WAW999
+var Unknown = s3.Bucket{}
+
+//wetwire:ignore
+var Bare = s3.Bucket{}
+`
+	path := filepath.Join(t.TempDir(), "stack.go")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	result, err := LintFile(path, Options{})
+	require.NoError(t, err)
+
+	var waw001 []int
+	var stale []string
+	for _, issue := range result.Issues {
+		switch issue.Rule {
+		case "WAW001":
+			waw001 = append(waw001, issue.Line)
+		case "WAW020":
+			assert.Equal(t, SeverityWarning, issue.Severity)
+			stale = append(stale, issue.Message)
+		}
+	}
+
+	// Only the line not covered by a directive is reported
+	assert.Equal(t, []int{12}, waw001)
+	require.Len(t, stale, 3)
+	assert.Contains(t, stale[0], "names no rule ID")
+	assert.Equal(t, "Suppression of WAW002 does not match any issue; remove it", stale[1])
+	assert.Equal(t, "Suppression of unknown rule WAW999", stale[2])
+
+	// Disabling WAW020 silences the stale reports
+	result, err = LintFile(path, Options{DisabledRules: []string{"WAW020"}})
+	require.NoError(t, err)
+	for _, issue := range result.Issues {
+		assert.NotEqual(t, "WAW020", issue.Rule)
+	}
+}
+
+func TestStaleSuppression_DisabledRule(t *testing.T) {
+	src := `package p
+
+import "github.com/lex00/wetwire-aws-go/resources/s3"
+
+//wetwire:ignore WAW001 still needed
+var Named = s3.Bucket{
+	BucketName: "AWS::Region",
+}
+`
+	path := filepath.Join(t.TempDir(), "stack.go")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+
+	// Disabling a rule does not make its suppressions stale
+	result, err := LintFile(path, Options{DisabledRules: []string{"WAW001"}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Issues)
+}
@@ -149,6 +149,7 @@ func (r InlineMapInSlice) Check(file *ast.File, fset *token.FileSet) []Issue {
 				Line:       pos.Line,
 				Column:     pos.Column,
 				Severity:   SeverityWarning,
+				Fixable:    inTopLevelVar(file, kv.Pos()),
 			})
 		}
 
@@ -234,6 +235,7 @@ func (r InlineStructLiteral) Check(file *ast.File, fset *token.FileSet) []Issue
 					Line:       pos.Line,
 					Column:     pos.Column,
 					Severity:   SeverityWarning,
+					Fixable:    inTopLevelVar(file, innerComp.Pos()),
 				})
 			}
 		}
@@ -278,6 +280,14 @@ var ignoreFields = map[string]bool{
 
 func (r UnflattenedMap) Check(file *ast.File, fset *token.FileSet) []Issue {
 	var issues []Issue
+	conversions := typedMapConversions(file)
+	fixable := func(comp *ast.CompositeLit) bool {
+		if _, ok := conversions[comp]; ok {
+			return true
+		}
+		_, ok := mapIntrinsic(comp, func(ast.Expr) string { return "" })
+		return ok
+	}
 
 	ast.Inspect(file, func(n ast.Node) bool {
 		// Look for field assignments in struct literals
@@ -302,7 +312,7 @@ func (r UnflattenedMap) Check(file *ast.File, fset *token.FileSet) []Issue {
 		}
 
 		// Recursively find all map[string]any in the value
-		foundIssues := findUnflattenedMaps(kv.Value, fieldName, fset, r.ID())
+		foundIssues := findUnflattenedMaps(kv.Value, fieldName, fset, r.ID(), fixable)
 		issues = append(issues, foundIssues...)
 
 		// Return false to prevent double-processing of nested KeyValueExpr
@@ -315,7 +325,8 @@ func (r UnflattenedMap) Check(file *ast.File, fset *token.FileSet) []Issue {
 
 // findUnflattenedMaps recursively searches for map[string]any patterns in an expression.
 // It tracks the path through the structure for meaningful error messages.
-func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID string) []Issue {
+// fixable reports whether lint --fix can convert a map.
+func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID string, fixable func(*ast.CompositeLit) bool) []Issue {
 	var issues []Issue
 
 	comp, ok := expr.(*ast.CompositeLit)
@@ -334,6 +345,7 @@ func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID
 			Line:       pos.Line,
 			Column:     pos.Column,
 			Severity:   SeverityWarning,
+			Fixable:    fixable(comp),
 		})
 
 		// Recursively check map values
@@ -345,7 +357,7 @@ func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID
 					keyName = strings.Trim(lit.Value, `"`)
 				}
 				newPath := path + "." + keyName
-				issues = append(issues, findUnflattenedMaps(kv.Value, newPath, fset, ruleID)...)
+				issues = append(issues, findUnflattenedMaps(kv.Value, newPath, fset, ruleID, fixable)...)
 			}
 		}
 		return issues
@@ -357,7 +369,7 @@ func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID
 			// Check each element recursively
 			for i, elt := range comp.Elts {
 				newPath := fmt.Sprintf("%s[%d]", path, i)
-				issues = append(issues, findUnflattenedMaps(elt, newPath, fset, ruleID)...)
+				issues = append(issues, findUnflattenedMaps(elt, newPath, fset, ruleID, fixable)...)
 			}
 			return issues
 		}
@@ -372,7 +384,7 @@ func findUnflattenedMaps(expr ast.Expr, path string, fset *token.FileSet, ruleID
 			}
 			if fieldName != "" && !ignoreFields[fieldName] {
 				newPath := path + "." + fieldName
-				issues = append(issues, findUnflattenedMaps(kv.Value, newPath, fset, ruleID)...)
+				issues = append(issues, findUnflattenedMaps(kv.Value, newPath, fset, ruleID, fixable)...)
 			}
 		}
 	}
@@ -433,7 +445,7 @@ func (r InlineTypedStruct) Check(file *ast.File, fset *token.FileSet) []Issue {
 
 			for _, value := range valueSpec.Values {
 				// Find inline typed structs within this top-level var
-				foundIssues := findInlineTypedStructs(value, fset, r.ID(), 0)
+				foundIssues := findInlineTypedStructs(value, fset, r.ID(), 0, true)
 				issues = append(issues, foundIssues...)
 			}
 		}
@@ -445,8 +457,9 @@ func (r InlineTypedStruct) Check(file *ast.File, fset *token.FileSet) []Issue {
 }
 
 // findInlineTypedStructs recursively finds typed property type struct literals
-// that are nested (depth > 0) and should be flattened.
-func findInlineTypedStructs(expr ast.Expr, fset *token.FileSet, ruleID string, depth int) []Issue {
+// that are nested (depth > 0) and should be flattened. Literals inside call
+// arguments are not fixable.
+func findInlineTypedStructs(expr ast.Expr, fset *token.FileSet, ruleID string, depth int, fixable bool) []Issue {
 	var issues []Issue
 
 	switch e := expr.(type) {
@@ -480,6 +493,7 @@ func findInlineTypedStructs(expr ast.Expr, fset *token.FileSet, ruleID string, d
 				Line:       pos.Line,
 				Column:     pos.Column,
 				Severity:   SeverityWarning,
+				Fixable:    fixable,
 			})
 		}
 
@@ -487,23 +501,23 @@ func findInlineTypedStructs(expr ast.Expr, fset *token.FileSet, ruleID string, d
 		for _, elt := range e.Elts {
 			if kv, ok := elt.(*ast.KeyValueExpr); ok {
 				// Struct field: key-value pair
-				issues = append(issues, findInlineTypedStructs(kv.Value, fset, ruleID, depth+1)...)
+				issues = append(issues, findInlineTypedStructs(kv.Value, fset, ruleID, depth+1, fixable)...)
 			} else if isSlice {
 				// Array element: direct element (not key-value)
-				issues = append(issues, findInlineTypedStructs(elt, fset, ruleID, depth+1)...)
+				issues = append(issues, findInlineTypedStructs(elt, fset, ruleID, depth+1, fixable)...)
 			}
 		}
 
 	case *ast.UnaryExpr:
 		// Handle pointer expressions like &s3.Bucket_BucketEncryption{...}
 		if e.Op == token.AND {
-			issues = append(issues, findInlineTypedStructs(e.X, fset, ruleID, depth)...)
+			issues = append(issues, findInlineTypedStructs(e.X, fset, ruleID, depth, fixable)...)
 		}
 
 	case *ast.CallExpr:
 		// Recurse into function call arguments
 		for _, arg := range e.Args {
-			issues = append(issues, findInlineTypedStructs(arg, fset, ruleID, depth+1)...)
+			issues = append(issues, findInlineTypedStructs(arg, fset, ruleID, depth+1, false)...)
 		}
 	}
 
@@ -59,6 +59,7 @@ func (r AvoidExplicitRef) Check(file *ast.File, fset *token.FileSet) []Issue {
 			suggestion = fmt.Sprintf("For resources: use %s directly. For parameters: var %s = Param(\"%s\")", refName, refName, refName)
 		}
 
+		_, pseudo := pseudoParams[refName]
 		issues = append(issues, Issue{
 			Rule:       r.ID(),
 			Message:    msg,
@@ -67,6 +68,7 @@ func (r AvoidExplicitRef) Check(file *ast.File, fset *token.FileSet) []Issue {
 			Line:       pos.Line,
 			Column:     pos.Column,
 			Severity:   SeverityWarning,
+			Fixable:    pseudo || token.IsIdentifier(refName),
 		})
 
 		return true
@@ -126,6 +128,7 @@ func (r AvoidExplicitGetAtt) Check(file *ast.File, fset *token.FileSet) []Issue
 			suggestion = fmt.Sprintf("Use %s.%s instead", resourceName, attrName)
 		}
 
+		_, _, fixable := getAttTarget(comp)
 		issues = append(issues, Issue{
 			Rule:       r.ID(),
 			Message:    msg,
@@ -134,6 +137,7 @@ func (r AvoidExplicitGetAtt) Check(file *ast.File, fset *token.FileSet) []Issue
 			Line:       pos.Line,
 			Column:     pos.Column,
 			Severity:   SeverityWarning,
+			Fixable:    fixable,
 		})
 
 		return true
@@ -589,5 +593,6 @@ func AllRules() []Rule {
 		AvoidPointerAssignment{},
 		PreferJsonType{},
 		SecretPattern{},
+		StaleSuppression{},
 	}
 }
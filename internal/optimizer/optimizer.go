@@ -1,55 +1,152 @@
 // Package optimizer provides CloudFormation optimization suggestions.
-// It analyzes resources for security, cost, performance, and reliability improvements.
+// It analyzes the resources of a built template for security, cost,
+// performance, and reliability improvements, inspecting their property values
+// so that only genuine gaps are reported.
 package optimizer
 
 import (
+	"fmt"
+	"sort"
+
 	wetwire "github.com/lex00/wetwire-aws-go"
-	"github.com/lex00/wetwire-aws-go/internal/discover"
+	"github.com/lex00/wetwire-aws-go/internal/suppress"
 )
 
 // Options configures the optimizer.
 type Options struct {
 	// Category filters suggestions: "all", "security", "cost", "performance", "reliability"
 	Category string
+	// Categories filters suggestions to several categories when Category is "all" or empty.
+	Categories []string
+	// Resources maps logical names to their Go declarations so suggestions
+	// report the file and line of the resource
+	Resources map[string]wetwire.DiscoveredResource
+}
+
+// includes reports whether suggestions in category pass the filter.
+func (o Options) includes(category string) bool {
+	if o.Category != "" && o.Category != "all" {
+		return category == o.Category
+	}
+	if len(o.Categories) == 0 {
+		return true
+	}
+	for _, c := range o.Categories {
+		if c == category || c == "all" {
+			return true
+		}
+	}
+	return false
 }
 
 // Result contains optimization suggestions.
 type Result struct {
 	Suggestions []wetwire.OptimizeSuggestion
 	Summary     wetwire.OptimizeSummary
+	// StaleSuppressions are //wetwire:ignore comments naming optimizer rules
+	// that did not match any suggestion.
+	StaleSuppressions []wetwire.StaleSuppression
 }
 
-// Optimize analyzes discovered resources and returns optimization suggestions.
-func Optimize(discoverResult *discover.Result, opts Options) (*Result, error) {
+// Optimize analyzes the resources of a built template and returns
+// optimization suggestions.
+func Optimize(template *wetwire.Template, opts Options) (*Result, error) {
 	result := &Result{}
 
+	// Suppression comments, by source file
+	suppressions := make(map[string]*suppress.Set)
+
+	names := make([]string, 0, len(template.Resources))
+	for name, def := range template.Resources {
+		// Fn::ForEach loops are templates, not resources
+		if !def.IsLoop() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
 	// Apply all rules to each resource
-	for name, res := range discoverResult.Resources {
-		// Ensure the resource name is set
-		if res.Name == "" {
-			res.Name = name
+	for _, name := range names {
+		decl := opts.Resources[name]
+		res := Resource{
+			Name:     name,
+			Def:      template.Resources[name],
+			File:     decl.File,
+			Line:     decl.Line,
+			Template: template,
+		}
+
+		set, seen := suppressions[res.File]
+		if !seen && res.File != "" {
+			// Unreadable files simply have no suppressions
+			set, _ = suppress.ParseFile(res.File)
+			suppressions[res.File] = set
+		}
+
+		for _, suggestion := range analyzeResource(res, opts) {
+			if set.Match(suggestion.Rule, suggestion.Line) {
+				continue
+			}
+			result.Suggestions = append(result.Suggestions, suggestion)
 		}
-		suggestions := analyzeResource(res, opts.Category)
-		result.Suggestions = append(result.Suggestions, suggestions...)
 	}
 
+	result.StaleSuppressions = staleSuppressions(suppressions, opts)
+
 	// Calculate summary
 	result.Summary = calculateSummary(result.Suggestions)
 
 	return result, nil
 }
 
+// staleSuppressions reports optimizer rule IDs in suppression comments that
+// matched no suggestion. Rules excluded by the category filter are not judged.
+func staleSuppressions(suppressions map[string]*suppress.Set, opts Options) []wetwire.StaleSuppression {
+	categories := make(map[string]string)
+	for _, rule := range AllRules() {
+		categories[rule.ID] = rule.Category
+	}
+
+	files := make([]string, 0, len(suppressions))
+	for file := range suppressions {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var stale []wetwire.StaleSuppression
+	for _, file := range files {
+		judge := func(id string) bool {
+			category, known := categories[id]
+			return suppress.IsOptimizerRule(id) && (!known || opts.includes(category))
+		}
+		for _, unused := range suppressions[file].Unused(judge) {
+			message := fmt.Sprintf("Suppression of %s does not match any suggestion; remove it", unused.Rule)
+			if _, known := categories[unused.Rule]; !known {
+				message = fmt.Sprintf("Suppression of unknown optimizer rule %s", unused.Rule)
+			}
+			stale = append(stale, wetwire.StaleSuppression{
+				Rule:    unused.Rule,
+				File:    unused.Suppression.File,
+				Line:    unused.Suppression.Line,
+				Message: message,
+			})
+		}
+	}
+	return stale
+}
+
 // analyzeResource applies optimization rules to a single resource.
-func analyzeResource(res wetwire.DiscoveredResource, category string) []wetwire.OptimizeSuggestion {
+func analyzeResource(res Resource, opts Options) []wetwire.OptimizeSuggestion {
 	var suggestions []wetwire.OptimizeSuggestion
 
 	// Run rules based on resource type
-	rules := getRulesForType(res.Type)
+	rules := getRulesForType(res.Def.Type)
 	for _, rule := range rules {
-		if category != "all" && rule.Category != category {
+		if !opts.includes(rule.Category) {
 			continue
 		}
 		if suggestion := rule.Check(res); suggestion != nil {
+			suggestion.Rule = rule.ID
 			suggestions = append(suggestions, *suggestion)
 		}
 	}
@@ -82,26 +179,45 @@ type Rule struct {
 	Category    string
 	Title       string
 	Description string
-	Check       func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion
+	// Check returns a suggestion when the resource has the gap the rule
+	// looks for, and nil otherwise.
+	Check func(res Resource) *wetwire.OptimizeSuggestion
+}
+
+// AllRules returns every optimization rule.
+func AllRules() []Rule {
+	var rules []Rule
+	for _, group := range [][]Rule{
+		s3BucketRules,
+		lambdaFunctionRules,
+		iamRules,
+		ec2InstanceRules,
+		rdsInstanceRules,
+		dynamoDBTableRules,
+		genericRules,
+	} {
+		rules = append(rules, group...)
+	}
+	return rules
 }
 
-// getRulesForType returns applicable rules for a resource type.
+// getRulesForType returns applicable rules for a CloudFormation resource type.
 func getRulesForType(resourceType string) []Rule {
 	var rules []Rule
 
 	// Add type-specific rules
 	switch resourceType {
-	case "s3.Bucket":
+	case "AWS::S3::Bucket":
 		rules = append(rules, s3BucketRules...)
-	case "lambda.Function":
+	case "AWS::Lambda::Function":
 		rules = append(rules, lambdaFunctionRules...)
-	case "iam.Role", "iam.Policy":
+	case "AWS::IAM::Role", "AWS::IAM::Policy", "AWS::IAM::ManagedPolicy", "AWS::IAM::User", "AWS::IAM::Group":
 		rules = append(rules, iamRules...)
-	case "ec2.Instance":
+	case "AWS::EC2::Instance":
 		rules = append(rules, ec2InstanceRules...)
-	case "rds.DBInstance":
+	case "AWS::RDS::DBInstance":
 		rules = append(rules, rdsInstanceRules...)
-	case "dynamodb.Table":
+	case "AWS::DynamoDB::Table":
 		rules = append(rules, dynamoDBTableRules...)
 	}
 
@@ -1,69 +1,284 @@
 package optimizer
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
-	"github.com/lex00/wetwire-aws-go/internal/discover"
 )
 
+// newTemplate returns a template with the given resources.
+func newTemplate(resources map[string]wetwire.ResourceDef) *wetwire.Template {
+	return &wetwire.Template{
+		AWSTemplateFormatVersion: "2010-09-09",
+		Resources:                resources,
+	}
+}
+
+// rulesFor returns the rule IDs of the suggestions for a resource.
+func rulesFor(result *Result, resource string) map[string]bool {
+	rules := map[string]bool{}
+	for _, s := range result.Suggestions {
+		if s.Resource == resource {
+			rules[s.Rule] = true
+		}
+	}
+	return rules
+}
+
 func TestOptimize(t *testing.T) {
-	discoverResult := &discover.Result{
-		Resources: map[string]wetwire.DiscoveredResource{
-			"DataBucket": {
-				Name:    "DataBucket",
-				Type:    "s3.Bucket",
-				Package: "myproject",
-				File:    "storage.go",
-				Line:    10,
-			},
-			"ProcessorRole": {
-				Name:    "ProcessorRole",
-				Type:    "iam.Role",
-				Package: "myproject",
-				File:    "security.go",
-				Line:    5,
+	tmpl := newTemplate(map[string]wetwire.ResourceDef{
+		"DataBucket": {Type: "AWS::S3::Bucket"},
+		"ProcessorRole": {
+			Type: "AWS::IAM::Role",
+			Properties: map[string]any{
+				"Policies": []any{map[string]any{
+					"PolicyName": "all",
+					"PolicyDocument": map[string]any{
+						"Statement": []any{map[string]any{
+							"Effect":   "Allow",
+							"Action":   "s3:*",
+							"Resource": "*",
+						}},
+					},
+				}},
 			},
 		},
-	}
+	})
 
-	result, err := Optimize(discoverResult, Options{Category: "all"})
+	result, err := Optimize(tmpl, Options{
+		Category: "all",
+		Resources: map[string]wetwire.DiscoveredResource{
+			"DataBucket":    {Name: "DataBucket", Type: "s3.Bucket", File: "storage.go", Line: 10},
+			"ProcessorRole": {Name: "ProcessorRole", Type: "iam.Role", File: "security.go", Line: 5},
+		},
+	})
 	if err != nil {
 		t.Fatalf("Optimize() error = %v", err)
 	}
 
 	if len(result.Suggestions) == 0 {
-		t.Error("expected suggestions for S3 bucket and IAM role")
+		t.Fatal("expected suggestions for S3 bucket and IAM role")
 	}
 
-	// Verify we have different categories
-	categories := map[string]bool{}
+	// Suggestions point at the Go declaration
 	for _, s := range result.Suggestions {
-		categories[s.Category] = true
+		switch s.Resource {
+		case "DataBucket":
+			if s.File != "storage.go" || s.Line != 10 {
+				t.Errorf("%s: location = %s:%d, want storage.go:10", s.Rule, s.File, s.Line)
+			}
+		case "ProcessorRole":
+			if s.File != "security.go" || s.Line != 5 {
+				t.Errorf("%s: location = %s:%d, want security.go:5", s.Rule, s.File, s.Line)
+			}
+		}
 	}
 
-	if !categories["security"] {
-		t.Error("expected security suggestions")
+	role := rulesFor(result, "ProcessorRole")
+	if !role["OPT-IAM-001"] {
+		t.Error("expected OPT-IAM-001 for a policy allowing s3:* on *")
 	}
 }
 
-func TestOptimizeWithCategoryFilter(t *testing.T) {
-	discoverResult := &discover.Result{
-		Resources: map[string]wetwire.DiscoveredResource{
-			"DataBucket": {
-				Name: "DataBucket",
-				Type: "s3.Bucket",
-				File: "storage.go",
-				Line: 10,
-			},
+func TestOptimizePropertyAware(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		gap   wetwire.ResourceDef
+		fixed wetwire.ResourceDef
+	}{
+		{
+			name:  "S3 encryption",
+			rule:  "OPT-S3-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::S3::Bucket"},
+			fixed: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketEncryption": map[string]any{}}},
+		},
+		{
+			name: "S3 public access block",
+			rule: "OPT-S3-002",
+			gap: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"PublicAccessBlockConfiguration": map[string]any{"BlockPublicAcls": true},
+			}},
+			fixed: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"PublicAccessBlockConfiguration": map[string]any{
+					"BlockPublicAcls":       true,
+					"BlockPublicPolicy":     true,
+					"IgnorePublicAcls":      "true",
+					"RestrictPublicBuckets": map[string]any{"Ref": "Restrict"},
+				},
+			}},
+		},
+		{
+			name: "S3 versioning",
+			rule: "OPT-S3-003",
+			gap: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"VersioningConfiguration": map[string]any{"Status": "Suspended"},
+			}},
+			fixed: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"VersioningConfiguration": map[string]any{"Status": "Enabled"},
+			}},
+		},
+		{
+			name: "S3 lifecycle",
+			rule: "OPT-S3-004",
+			gap: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"LifecycleConfiguration": map[string]any{"Rules": []any{}},
+			}},
+			fixed: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{
+				"LifecycleConfiguration": map[string]any{"Rules": []any{map[string]any{"Status": "Enabled"}}},
+			}},
+		},
+		{
+			name:  "Lambda memory",
+			rule:  "OPT-LAM-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::Lambda::Function"},
+			fixed: wetwire.ResourceDef{Type: "AWS::Lambda::Function", Properties: map[string]any{"MemorySize": float64(512)}},
+		},
+		{
+			name:  "Lambda dead letter queue",
+			rule:  "OPT-LAM-002",
+			gap:   wetwire.ResourceDef{Type: "AWS::Lambda::Function"},
+			fixed: wetwire.ResourceDef{Type: "AWS::Lambda::Function", Properties: map[string]any{"DeadLetterConfig": map[string]any{"TargetArn": "arn"}}},
+		},
+		{
+			name:  "Lambda timeout",
+			rule:  "OPT-LAM-003",
+			gap:   wetwire.ResourceDef{Type: "AWS::Lambda::Function", Properties: map[string]any{"Timeout": float64(900)}},
+			fixed: wetwire.ResourceDef{Type: "AWS::Lambda::Function", Properties: map[string]any{"Timeout": float64(30)}},
+		},
+		{
+			name: "IAM wildcard",
+			rule: "OPT-IAM-001",
+			gap: wetwire.ResourceDef{Type: "AWS::IAM::Policy", Properties: map[string]any{
+				"PolicyDocument": map[string]any{"Statement": map[string]any{"Effect": "Allow", "Action": []any{"*"}, "Resource": "arn:aws:s3:::bucket"}},
+			}},
+			fixed: wetwire.ResourceDef{Type: "AWS::IAM::Policy", Properties: map[string]any{
+				"PolicyDocument": map[string]any{"Statement": []any{
+					map[string]any{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"},
+					map[string]any{"Effect": "Deny", "Action": "*", "Resource": "*"},
+				}},
+			}},
+		},
+		{
+			name:  "EC2 previous generation",
+			rule:  "OPT-EC2-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::EC2::Instance", Properties: map[string]any{"InstanceType": "t2.micro"}},
+			fixed: wetwire.ResourceDef{Type: "AWS::EC2::Instance", Properties: map[string]any{"InstanceType": "t3.micro"}},
+		},
+		{
+			name: "EC2 IMDSv2",
+			rule: "OPT-EC2-003",
+			gap: wetwire.ResourceDef{Type: "AWS::EC2::Instance", Properties: map[string]any{
+				"MetadataOptions": map[string]any{"HttpTokens": "optional"},
+			}},
+			fixed: wetwire.ResourceDef{Type: "AWS::EC2::Instance", Properties: map[string]any{
+				"MetadataOptions": map[string]any{"HttpTokens": "required"},
+			}},
+		},
+		{
+			name:  "RDS Multi-AZ",
+			rule:  "OPT-RDS-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::RDS::DBInstance", Properties: map[string]any{"MultiAZ": false}},
+			fixed: wetwire.ResourceDef{Type: "AWS::RDS::DBInstance", Properties: map[string]any{"MultiAZ": true}},
+		},
+		{
+			name:  "RDS backups",
+			rule:  "OPT-RDS-002",
+			gap:   wetwire.ResourceDef{Type: "AWS::RDS::DBInstance"},
+			fixed: wetwire.ResourceDef{Type: "AWS::RDS::DBInstance", Properties: map[string]any{"BackupRetentionPeriod": "14"}},
+		},
+		{
+			name:  "RDS encryption in a cluster",
+			rule:  "OPT-RDS-003",
+			gap:   wetwire.ResourceDef{Type: "AWS::RDS::DBInstance"},
+			fixed: wetwire.ResourceDef{Type: "AWS::RDS::DBInstance", Properties: map[string]any{"DBClusterIdentifier": map[string]any{"Ref": "Cluster"}}},
+		},
+		{
+			name:  "DynamoDB capacity",
+			rule:  "OPT-DDB-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::DynamoDB::Table", Properties: map[string]any{"BillingMode": "PROVISIONED"}},
+			fixed: wetwire.ResourceDef{Type: "AWS::DynamoDB::Table", Properties: map[string]any{"BillingMode": "PAY_PER_REQUEST"}},
+		},
+		{
+			name: "DynamoDB PITR",
+			rule: "OPT-DDB-002",
+			gap:  wetwire.ResourceDef{Type: "AWS::DynamoDB::Table"},
+			fixed: wetwire.ResourceDef{Type: "AWS::DynamoDB::Table", Properties: map[string]any{
+				"PointInTimeRecoverySpecification": map[string]any{"PointInTimeRecoveryEnabled": true},
+			}},
+		},
+		{
+			name:  "DeletionPolicy",
+			rule:  "OPT-GEN-001",
+			gap:   wetwire.ResourceDef{Type: "AWS::DynamoDB::Table"},
+			fixed: wetwire.ResourceDef{Type: "AWS::DynamoDB::Table", DeletionPolicy: "Retain"},
+		},
+		{
+			name:  "UpdateReplacePolicy",
+			rule:  "OPT-GEN-002",
+			gap:   wetwire.ResourceDef{Type: "AWS::S3::Bucket"},
+			fixed: wetwire.ResourceDef{Type: "AWS::S3::Bucket", UpdateReplacePolicy: "Retain"},
 		},
 	}
 
-	result, err := Optimize(discoverResult, Options{Category: "security"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Optimize(newTemplate(map[string]wetwire.ResourceDef{"Gap": tt.gap, "Fixed": tt.fixed}), Options{})
+			if err != nil {
+				t.Fatalf("Optimize() error = %v", err)
+			}
+			if !rulesFor(result, "Gap")[tt.rule] {
+				t.Errorf("expected %s for the resource with the gap", tt.rule)
+			}
+			if rulesFor(result, "Fixed")[tt.rule] {
+				t.Errorf("expected no %s for the resource without the gap", tt.rule)
+			}
+		})
+	}
+}
+
+func TestOptimizeRelatedResources(t *testing.T) {
+	tmpl := newTemplate(map[string]wetwire.ResourceDef{
+		"Table": {Type: "AWS::DynamoDB::Table"},
+		"TableScaling": {Type: "AWS::ApplicationAutoScaling::ScalableTarget", Properties: map[string]any{
+			"ServiceNamespace": "dynamodb",
+			"ResourceId":       map[string]any{"Fn::Sub": "table/${Table}"},
+		}},
+		"Fn": {Type: "AWS::Lambda::Function"},
+		"FnInvokeConfig": {Type: "AWS::Lambda::EventInvokeConfig", Properties: map[string]any{
+			"FunctionName":      map[string]any{"Ref": "Fn"},
+			"DestinationConfig": map[string]any{"OnFailure": map[string]any{"Destination": "arn"}},
+		}},
+	})
+
+	result, err := Optimize(tmpl, Options{})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	if rulesFor(result, "Table")["OPT-DDB-001"] {
+		t.Error("expected no OPT-DDB-001 for a table with auto scaling")
+	}
+	if rulesFor(result, "Fn")["OPT-LAM-002"] {
+		t.Error("expected no OPT-LAM-002 for a function with an OnFailure destination")
+	}
+}
+
+func TestOptimizeWithCategoryFilter(t *testing.T) {
+	tmpl := newTemplate(map[string]wetwire.ResourceDef{
+		"DataBucket": {Type: "AWS::S3::Bucket"},
+	})
+
+	result, err := Optimize(tmpl, Options{Category: "security"})
 	if err != nil {
 		t.Fatalf("Optimize() error = %v", err)
 	}
 
+	if len(result.Suggestions) == 0 {
+		t.Error("expected security suggestions for an unencrypted bucket")
+	}
+
 	// All suggestions should be security category
 	for _, s := range result.Suggestions {
 		if s.Category != "security" {
@@ -72,12 +287,107 @@ func TestOptimizeWithCategoryFilter(t *testing.T) {
 	}
 }
 
-func TestOptimizeEmptyResources(t *testing.T) {
-	discoverResult := &discover.Result{
-		Resources: map[string]wetwire.DiscoveredResource{},
+func TestOptimizeWithCategories(t *testing.T) {
+	tmpl := newTemplate(map[string]wetwire.ResourceDef{
+		"DataBucket": {Type: "AWS::S3::Bucket"},
+	})
+
+	all, err := Optimize(tmpl, Options{Category: "all"})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	result, err := Optimize(tmpl, Options{Category: "all", Categories: []string{"security", "cost"}})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	want := 0
+	for _, s := range all.Suggestions {
+		if s.Category == "security" || s.Category == "cost" {
+			want++
+		}
+	}
+	if len(result.Suggestions) != want {
+		t.Errorf("expected %d security and cost suggestions, got %d", want, len(result.Suggestions))
+	}
+	for _, s := range result.Suggestions {
+		if s.Category != "security" && s.Category != "cost" {
+			t.Errorf("expected only security or cost suggestions, got %s", s.Category)
+		}
+	}
+
+	// An explicit Category takes precedence over Categories
+	result, err = Optimize(tmpl, Options{Category: "reliability", Categories: []string{"security"}})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	for _, s := range result.Suggestions {
+		if s.Category != "reliability" {
+			t.Errorf("expected only reliability suggestions, got %s", s.Category)
+		}
+	}
+}
+
+func TestOptimizeWithSuppressions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "storage.go")
+	src := `package p
+
+//wetwire:ignore OPT-S3-001 OPT-S3-004 encrypted by bucket policy
+var DataBucket = s3.Bucket{}
+
+//wetwire:ignore OPT-LAM-001 OPT-XYZ-001
+var LogsBucket = s3.Bucket{}
+`
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
 	}
 
-	result, err := Optimize(discoverResult, Options{Category: "all"})
+	tmpl := newTemplate(map[string]wetwire.ResourceDef{
+		"DataBucket": {Type: "AWS::S3::Bucket"},
+		"LogsBucket": {Type: "AWS::S3::Bucket"},
+	})
+	resources := map[string]wetwire.DiscoveredResource{
+		"DataBucket": {Name: "DataBucket", Type: "s3.Bucket", File: file, Line: 4},
+		"LogsBucket": {Name: "LogsBucket", Type: "s3.Bucket", File: file, Line: 7},
+	}
+
+	result, err := Optimize(tmpl, Options{Category: "all", Resources: resources})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+
+	for _, s := range result.Suggestions {
+		if s.Resource == "DataBucket" && (s.Rule == "OPT-S3-001" || s.Rule == "OPT-S3-004") {
+			t.Errorf("expected %s to be suppressed for DataBucket", s.Rule)
+		}
+	}
+
+	var stale []string
+	for _, s := range result.StaleSuppressions {
+		if s.File != file || s.Line != 6 {
+			t.Errorf("expected stale suppression at %s:6, got %s:%d", file, s.File, s.Line)
+		}
+		stale = append(stale, s.Rule)
+	}
+	if len(stale) != 2 || stale[0] != "OPT-LAM-001" || stale[1] != "OPT-XYZ-001" {
+		t.Errorf("expected OPT-LAM-001 and OPT-XYZ-001 to be stale, got %v", stale)
+	}
+
+	// Rules outside the category filter are not judged
+	result, err = Optimize(tmpl, Options{Category: "reliability", Resources: resources})
+	if err != nil {
+		t.Fatalf("Optimize() error = %v", err)
+	}
+	for _, s := range result.StaleSuppressions {
+		if s.Rule != "OPT-XYZ-001" {
+			t.Errorf("expected only the unknown rule to be stale, got %s", s.Rule)
+		}
+	}
+}
+
+func TestOptimizeEmptyResources(t *testing.T) {
+	result, err := Optimize(newTemplate(map[string]wetwire.ResourceDef{}), Options{Category: "all"})
 	if err != nil {
 		t.Fatalf("Optimize() error = %v", err)
 	}
@@ -123,24 +433,27 @@ func TestCalculateSummary(t *testing.T) {
 func TestGetRulesForType(t *testing.T) {
 	tests := []struct {
 		resourceType string
-		wantRules    bool
+		wantSpecific bool
 	}{
-		{"s3.Bucket", true},
-		{"lambda.Function", true},
-		{"iam.Role", true},
-		{"iam.Policy", true},
-		{"ec2.Instance", true},
-		{"rds.DBInstance", true},
-		{"dynamodb.Table", true},
-		{"unknown.Type", true}, // generic rules still apply
+		{"AWS::S3::Bucket", true},
+		{"AWS::Lambda::Function", true},
+		{"AWS::IAM::Role", true},
+		{"AWS::IAM::Policy", true},
+		{"AWS::EC2::Instance", true},
+		{"AWS::RDS::DBInstance", true},
+		{"AWS::DynamoDB::Table", true},
+		{"AWS::SNS::Topic", false}, // generic rules still apply
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.resourceType, func(t *testing.T) {
 			rules := getRulesForType(tt.resourceType)
-			if tt.wantRules && len(rules) == 0 {
+			if len(rules) == 0 {
 				t.Errorf("getRulesForType(%s) returned no rules", tt.resourceType)
 			}
+			if got := len(rules) > len(genericRules); got != tt.wantSpecific {
+				t.Errorf("getRulesForType(%s) type-specific rules = %v, want %v", tt.resourceType, got, tt.wantSpecific)
+			}
 		})
 	}
 }
@@ -0,0 +1,178 @@
+package optimizer
+
+import (
+	"strconv"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// Resource is a template resource being analyzed.
+type Resource struct {
+	// Name is the logical ID of the resource.
+	Name string
+	// Def is the resource as it appears in the built template.
+	Def wetwire.ResourceDef
+	// File and Line locate the Go declaration of the resource, when known.
+	File string
+	Line int
+	// Template is the template the resource belongs to, for rules that look
+	// at related resources.
+	Template *wetwire.Template
+}
+
+// prop returns the property at path, following nested objects.
+func (r Resource) prop(path ...string) (any, bool) {
+	var value any = r.Def.Properties
+	for _, key := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// isTrue reports whether the property at path is known to be true. Unset
+// properties are false; intrinsic functions count as true, since the value is
+// only known at deploy time and the rule cannot prove a gap.
+func (r Resource) isTrue(path ...string) bool {
+	value, ok := r.prop(path...)
+	if !ok {
+		return false
+	}
+	if isIntrinsic(value) {
+		return true
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		return err == nil && b
+	}
+	return false
+}
+
+// stringProp returns the property at path as a string. known is false when
+// the property is unset or computed by an intrinsic function.
+func (r Resource) stringProp(path ...string) (value string, known bool) {
+	v, ok := r.prop(path...)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// numberProp returns the property at path as a number. known is false when
+// the property is unset or computed by an intrinsic function.
+func (r Resource) numberProp(path ...string) (value float64, known bool) {
+	v, ok := r.prop(path...)
+	if !ok {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		// CloudFormation accepts numbers as strings
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// isIntrinsic reports whether value is an intrinsic function call such as
+// {"Ref": "Name"} or {"Fn::If": [...]}.
+func isIntrinsic(value any) bool {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 1 {
+		return false
+	}
+	for key := range m {
+		return key == "Ref" || key == "Condition" || strings.HasPrefix(key, "Fn::")
+	}
+	return false
+}
+
+// references reports whether value refers to the resource name through Ref,
+// Fn::GetAtt or a ${Name} placeholder in Fn::Sub.
+func references(value any, name string) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		if ref, ok := v["Ref"].(string); ok && ref == name {
+			return true
+		}
+		if getAtt, ok := v["Fn::GetAtt"].([]any); ok && len(getAtt) > 0 && getAtt[0] == name {
+			return true
+		}
+		if sub, ok := v["Fn::Sub"]; ok {
+			if s, ok := sub.(string); ok && subReferences(s, name) {
+				return true
+			}
+			if parts, ok := sub.([]any); ok && len(parts) > 0 {
+				if s, ok := parts[0].(string); ok && subReferences(s, name) {
+					return true
+				}
+			}
+		}
+		for _, child := range v {
+			if references(child, name) {
+				return true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if references(child, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subReferences reports whether an Fn::Sub string refers to name.
+func subReferences(s, name string) bool {
+	return strings.Contains(s, "${"+name+"}") || strings.Contains(s, "${"+name+".")
+}
+
+// related returns the template resources of resourceType whose property
+// prop refers to the resource.
+func (r Resource) related(resourceType, prop string) []wetwire.ResourceDef {
+	if r.Template == nil {
+		return nil
+	}
+	var defs []wetwire.ResourceDef
+	for _, def := range r.Template.Resources {
+		if def.Type == resourceType && references(def.Properties[prop], r.Name) {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// asList returns value as a list, wrapping a single value.
+func asList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	default:
+		return []any{v}
+	}
+}
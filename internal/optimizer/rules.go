@@ -1,6 +1,8 @@
 package optimizer
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
@@ -13,9 +15,10 @@ var s3BucketRules = []Rule{
 		Category:    "security",
 		Title:       "S3 bucket should have encryption enabled",
 		Description: "Server-side encryption protects data at rest",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
-			// This is a static analysis suggestion - actual property checking
-			// would require runtime extraction
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if _, ok := res.prop("BucketEncryption"); ok {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "security",
@@ -33,13 +36,22 @@ var s3BucketRules = []Rule{
 		Category:    "security",
 		Title:       "S3 bucket should block public access",
 		Description: "Public access can lead to data exposure",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			var open []string
+			for _, setting := range []string{"BlockPublicAcls", "BlockPublicPolicy", "IgnorePublicAcls", "RestrictPublicBuckets"} {
+				if !res.isTrue("PublicAccessBlockConfiguration", setting) {
+					open = append(open, setting)
+				}
+			}
+			if len(open) == 0 {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "security",
 				Severity:    "high",
 				Title:       "Consider blocking public access",
-				Description: "S3 buckets should have PublicAccessBlockConfiguration to prevent accidental public exposure.",
+				Description: fmt.Sprintf("S3 buckets should have PublicAccessBlockConfiguration to prevent accidental public exposure; %s not enabled.", strings.Join(open, ", ")),
 				Suggestion:  "Add PublicAccessBlockConfiguration with BlockPublicAcls, BlockPublicPolicy, IgnorePublicAcls, and RestrictPublicBuckets set to true.",
 				File:        res.File,
 				Line:        res.Line,
@@ -51,7 +63,11 @@ var s3BucketRules = []Rule{
 		Category:    "reliability",
 		Title:       "S3 bucket should have versioning enabled",
 		Description: "Versioning protects against accidental deletion",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			status, known := res.stringProp("VersioningConfiguration", "Status")
+			if status == "Enabled" || (!known && hasProp(res, "VersioningConfiguration", "Status")) {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
@@ -69,7 +85,10 @@ var s3BucketRules = []Rule{
 		Category:    "cost",
 		Title:       "S3 bucket should have lifecycle rules",
 		Description: "Lifecycle rules help manage storage costs",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if rules, ok := res.prop("LifecycleConfiguration", "Rules"); ok && (isIntrinsic(rules) || len(asList(rules)) > 0) {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "cost",
@@ -91,14 +110,17 @@ var lambdaFunctionRules = []Rule{
 		Category:    "performance",
 		Title:       "Lambda function memory should be optimized",
 		Description: "Memory allocation affects both performance and cost",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if hasProp(res, "MemorySize") {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "performance",
 				Severity:    "medium",
 				Title:       "Review Lambda memory configuration",
-				Description: "Lambda memory allocation affects CPU allocation and execution speed. Consider using AWS Lambda Power Tuning to find the optimal memory setting.",
-				Suggestion:  "Use AWS Lambda Power Tuning tool to find the optimal memory configuration for your workload.",
+				Description: "MemorySize is not set, so the function runs with the 128 MB default. Lambda memory allocation affects CPU allocation and execution speed.",
+				Suggestion:  "Use AWS Lambda Power Tuning tool to find the optimal memory configuration for your workload, and set MemorySize.",
 				File:        res.File,
 				Line:        res.Line,
 			}
@@ -109,14 +131,23 @@ var lambdaFunctionRules = []Rule{
 		Category:    "reliability",
 		Title:       "Lambda function should have error handling",
 		Description: "Dead letter queues help handle failed invocations",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if hasProp(res, "DeadLetterConfig", "TargetArn") {
+				return nil
+			}
+			// An on-failure destination handles failed async invocations too
+			for _, cfg := range res.related("AWS::Lambda::EventInvokeConfig", "FunctionName") {
+				if onFailure, ok := cfg.Properties["DestinationConfig"].(map[string]any); ok && onFailure["OnFailure"] != nil {
+					return nil
+				}
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
 				Severity:    "medium",
 				Title:       "Consider adding a dead letter queue",
 				Description: "A dead letter queue (DLQ) captures failed async invocations for later analysis or retry.",
-				Suggestion:  "Add DeadLetterConfig pointing to an SQS queue or SNS topic.",
+				Suggestion:  "Add DeadLetterConfig pointing to an SQS queue or SNS topic, or an EventInvokeConfig with an OnFailure destination.",
 				File:        res.File,
 				Line:        res.Line,
 			}
@@ -127,13 +158,17 @@ var lambdaFunctionRules = []Rule{
 		Category:    "cost",
 		Title:       "Lambda function timeout should be appropriate",
 		Description: "Excessive timeouts can increase costs",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			timeout, known := res.numberProp("Timeout")
+			if !known || timeout < longLambdaTimeout {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "cost",
 				Severity:    "low",
 				Title:       "Review Lambda timeout setting",
-				Description: "Ensure the timeout is set appropriately for your function's expected execution time. Long timeouts with errors can be costly.",
+				Description: fmt.Sprintf("Timeout is %g seconds. Ensure the timeout is set appropriately for your function's expected execution time. Long timeouts with errors can be costly.", timeout),
 				Suggestion:  "Set Timeout to match your function's expected execution time plus a reasonable buffer.",
 				File:        res.File,
 				Line:        res.Line,
@@ -142,6 +177,9 @@ var lambdaFunctionRules = []Rule{
 	},
 }
 
+// longLambdaTimeout is the Lambda timeout, in seconds, from which OPT-LAM-003 asks for a review.
+const longLambdaTimeout = 300
+
 // iamRules contains optimization rules for IAM resources.
 var iamRules = []Rule{
 	{
@@ -149,20 +187,21 @@ var iamRules = []Rule{
 		Category:    "security",
 		Title:       "IAM role should use least privilege",
 		Description: "Overly permissive policies increase security risk",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
-			if res.Type == "iam.Role" || res.Type == "iam.Policy" {
-				return &wetwire.OptimizeSuggestion{
-					Resource:    res.Name,
-					Category:    "security",
-					Severity:    "high",
-					Title:       "Review IAM permissions for least privilege",
-					Description: "Ensure IAM policies grant only the minimum permissions required. Avoid using * in Resource or Action.",
-					Suggestion:  "Replace wildcard (*) permissions with specific resource ARNs and actions.",
-					File:        res.File,
-					Line:        res.Line,
-				}
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			wildcards := policyWildcards(res)
+			if len(wildcards) == 0 {
+				return nil
+			}
+			return &wetwire.OptimizeSuggestion{
+				Resource:    res.Name,
+				Category:    "security",
+				Severity:    "high",
+				Title:       "Review IAM permissions for least privilege",
+				Description: fmt.Sprintf("Ensure IAM policies grant only the minimum permissions required. Allowed wildcards: %s.", strings.Join(wildcards, ", ")),
+				Suggestion:  "Replace wildcard (*) permissions with specific resource ARNs and actions.",
+				File:        res.File,
+				Line:        res.Line,
 			}
-			return nil
 		},
 	},
 }
@@ -174,14 +213,26 @@ var ec2InstanceRules = []Rule{
 		Category:    "cost",
 		Title:       "EC2 instance type should be appropriate",
 		Description: "Right-sizing instances reduces costs",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			instanceType, known := res.stringProp("InstanceType")
+			if !known {
+				// Unset defaults to m1.small
+				if hasProp(res, "InstanceType") || hasProp(res, "LaunchTemplate") {
+					return nil
+				}
+				instanceType = "m1.small"
+			}
+			family, _, _ := strings.Cut(instanceType, ".")
+			if !previousGenerationFamilies[family] {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "cost",
 				Severity:    "medium",
 				Title:       "Review EC2 instance type sizing",
-				Description: "Ensure the instance type matches your workload requirements. Consider using AWS Compute Optimizer for recommendations.",
-				Suggestion:  "Use AWS Compute Optimizer to analyze utilization and get right-sizing recommendations.",
+				Description: fmt.Sprintf("%s is a previous-generation instance type. Current generations offer better price-performance.", instanceType),
+				Suggestion:  "Move to a current-generation instance type, and use AWS Compute Optimizer to analyze utilization and get right-sizing recommendations.",
 				File:        res.File,
 				Line:        res.Line,
 			}
@@ -192,7 +243,7 @@ var ec2InstanceRules = []Rule{
 		Category:    "reliability",
 		Title:       "EC2 instance should use auto scaling",
 		Description: "Auto scaling improves availability and cost efficiency",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
@@ -210,7 +261,15 @@ var ec2InstanceRules = []Rule{
 		Category:    "security",
 		Title:       "EC2 instance should have IMDSv2 enabled",
 		Description: "IMDSv2 provides better metadata security",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			tokens, known := res.stringProp("MetadataOptions", "HttpTokens")
+			if tokens == "required" || (!known && hasProp(res, "MetadataOptions", "HttpTokens")) {
+				return nil
+			}
+			// Metadata options may come from the launch template
+			if !known && hasProp(res, "LaunchTemplate") {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "security",
@@ -225,14 +284,27 @@ var ec2InstanceRules = []Rule{
 	},
 }
 
+// previousGenerationFamilies lists EC2 instance families superseded by current generations.
+var previousGenerationFamilies = map[string]bool{
+	"t1": true, "t2": true,
+	"m1": true, "m2": true, "m3": true, "m4": true,
+	"c1": true, "c3": true, "c4": true,
+	"r3": true, "r4": true,
+	"i2": true, "d2": true, "g2": true, "g3": true, "p2": true, "x1": true,
+}
+
 // rdsInstanceRules contains optimization rules for RDS instances.
+// Instances in an Aurora cluster inherit these settings from the cluster.
 var rdsInstanceRules = []Rule{
 	{
 		ID:          "OPT-RDS-001",
 		Category:    "reliability",
 		Title:       "RDS instance should have Multi-AZ enabled",
 		Description: "Multi-AZ provides high availability",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if inCluster(res) || res.isTrue("MultiAZ") {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
@@ -250,13 +322,27 @@ var rdsInstanceRules = []Rule{
 		Category:    "reliability",
 		Title:       "RDS instance should have automated backups",
 		Description: "Automated backups enable point-in-time recovery",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if inCluster(res) {
+				return nil
+			}
+			// Unset defaults to one day
+			retention, known := res.numberProp("BackupRetentionPeriod")
+			if !known {
+				if hasProp(res, "BackupRetentionPeriod") {
+					return nil
+				}
+				retention = 1
+			}
+			if retention >= minBackupRetentionDays {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
 				Severity:    "high",
 				Title:       "Enable automated backups",
-				Description: "Automated backups with point-in-time recovery protect against data loss.",
+				Description: fmt.Sprintf("Backups are retained for %g days. Automated backups with point-in-time recovery protect against data loss.", retention),
 				Suggestion:  "Set BackupRetentionPeriod to at least 7 days.",
 				File:        res.File,
 				Line:        res.Line,
@@ -268,7 +354,10 @@ var rdsInstanceRules = []Rule{
 		Category:    "security",
 		Title:       "RDS instance should have encryption enabled",
 		Description: "Storage encryption protects data at rest",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if inCluster(res) || res.isTrue("StorageEncrypted") {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "security",
@@ -283,6 +372,9 @@ var rdsInstanceRules = []Rule{
 	},
 }
 
+// minBackupRetentionDays is the backup retention OPT-RDS-002 expects.
+const minBackupRetentionDays = 7
+
 // dynamoDBTableRules contains optimization rules for DynamoDB tables.
 var dynamoDBTableRules = []Rule{
 	{
@@ -290,13 +382,23 @@ var dynamoDBTableRules = []Rule{
 		Category:    "cost",
 		Title:       "DynamoDB table should use appropriate capacity mode",
 		Description: "On-demand vs provisioned capacity affects costs",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			mode, known := res.stringProp("BillingMode")
+			if mode == "PAY_PER_REQUEST" || (!known && hasProp(res, "BillingMode")) {
+				return nil
+			}
+			// Provisioned capacity with auto scaling is fine
+			for _, target := range res.related("AWS::ApplicationAutoScaling::ScalableTarget", "ResourceId") {
+				if target.Properties["ServiceNamespace"] == "dynamodb" {
+					return nil
+				}
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "cost",
 				Severity:    "medium",
 				Title:       "Review DynamoDB capacity mode",
-				Description: "On-demand mode is cost-effective for unpredictable workloads. Provisioned with auto-scaling is better for predictable traffic.",
+				Description: "The table uses provisioned capacity without auto scaling. On-demand mode is cost-effective for unpredictable workloads. Provisioned with auto-scaling is better for predictable traffic.",
 				Suggestion:  "Use on-demand for variable workloads, or provisioned with auto-scaling for steady traffic patterns.",
 				File:        res.File,
 				Line:        res.Line,
@@ -308,7 +410,10 @@ var dynamoDBTableRules = []Rule{
 		Category:    "reliability",
 		Title:       "DynamoDB table should have point-in-time recovery",
 		Description: "PITR enables recovery from accidental data loss",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			if res.isTrue("PointInTimeRecoverySpecification", "PointInTimeRecoveryEnabled") {
+				return nil
+			}
 			return &wetwire.OptimizeSuggestion{
 				Resource:    res.Name,
 				Category:    "reliability",
@@ -330,27 +435,21 @@ var genericRules = []Rule{
 		Category:    "reliability",
 		Title:       "Resource should have DeletionPolicy",
 		Description: "DeletionPolicy controls what happens when resource is deleted",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
 			// Only suggest for stateful resources
-			statefulTypes := []string{
-				"s3.Bucket", "rds.DBInstance", "dynamodb.Table",
-				"ec2.Volume", "efs.FileSystem", "elasticache.CacheCluster",
-			}
-			for _, t := range statefulTypes {
-				if strings.HasPrefix(res.Type, strings.Split(t, ".")[0]) {
-					return &wetwire.OptimizeSuggestion{
-						Resource:    res.Name,
-						Category:    "reliability",
-						Severity:    "low",
-						Title:       "Consider adding DeletionPolicy",
-						Description: "DeletionPolicy controls behavior when the resource is deleted from the stack. Use 'Retain' or 'Snapshot' for stateful resources.",
-						Suggestion:  "Add DeletionPolicy: Retain or DeletionPolicy: Snapshot to protect data.",
-						File:        res.File,
-						Line:        res.Line,
-					}
-				}
+			if !statefulTypes[res.Def.Type] || res.Def.DeletionPolicy != nil {
+				return nil
+			}
+			return &wetwire.OptimizeSuggestion{
+				Resource:    res.Name,
+				Category:    "reliability",
+				Severity:    "low",
+				Title:       "Consider adding DeletionPolicy",
+				Description: "DeletionPolicy controls behavior when the resource is deleted from the stack. Use 'Retain' or 'Snapshot' for stateful resources.",
+				Suggestion:  "Add DeletionPolicy: Retain or DeletionPolicy: Snapshot to protect data.",
+				File:        res.File,
+				Line:        res.Line,
 			}
-			return nil
 		},
 	},
 	{
@@ -358,26 +457,100 @@ var genericRules = []Rule{
 		Category:    "reliability",
 		Title:       "Resource should have UpdateReplacePolicy",
 		Description: "UpdateReplacePolicy controls behavior during replacements",
-		Check: func(res wetwire.DiscoveredResource) *wetwire.OptimizeSuggestion {
-			// Only suggest for stateful resources
-			statefulTypes := []string{
-				"s3.Bucket", "rds.DBInstance", "dynamodb.Table",
-			}
-			for _, t := range statefulTypes {
-				if strings.HasPrefix(res.Type, strings.Split(t, ".")[0]) {
-					return &wetwire.OptimizeSuggestion{
-						Resource:    res.Name,
-						Category:    "reliability",
-						Severity:    "low",
-						Title:       "Consider adding UpdateReplacePolicy",
-						Description: "UpdateReplacePolicy controls behavior when updates require resource replacement.",
-						Suggestion:  "Add UpdateReplacePolicy: Retain or UpdateReplacePolicy: Snapshot.",
-						File:        res.File,
-						Line:        res.Line,
-					}
-				}
+		Check: func(res Resource) *wetwire.OptimizeSuggestion {
+			// Only suggest for stateful resources that hold data
+			if !replaceProtectedTypes[res.Def.Type] || res.Def.UpdateReplacePolicy != nil {
+				return nil
+			}
+			return &wetwire.OptimizeSuggestion{
+				Resource:    res.Name,
+				Category:    "reliability",
+				Severity:    "low",
+				Title:       "Consider adding UpdateReplacePolicy",
+				Description: "UpdateReplacePolicy controls behavior when updates require resource replacement.",
+				Suggestion:  "Add UpdateReplacePolicy: Retain or UpdateReplacePolicy: Snapshot.",
+				File:        res.File,
+				Line:        res.Line,
 			}
-			return nil
 		},
 	},
 }
+
+// statefulTypes are the resource types OPT-GEN-001 expects a DeletionPolicy on.
+var statefulTypes = map[string]bool{
+	"AWS::S3::Bucket":                true,
+	"AWS::RDS::DBInstance":           true,
+	"AWS::RDS::DBCluster":            true,
+	"AWS::DynamoDB::Table":           true,
+	"AWS::EC2::Volume":               true,
+	"AWS::EFS::FileSystem":           true,
+	"AWS::ElastiCache::CacheCluster": true,
+}
+
+// replaceProtectedTypes are the resource types OPT-GEN-002 expects an UpdateReplacePolicy on.
+var replaceProtectedTypes = map[string]bool{
+	"AWS::S3::Bucket":      true,
+	"AWS::RDS::DBInstance": true,
+	"AWS::RDS::DBCluster":  true,
+	"AWS::DynamoDB::Table": true,
+}
+
+// hasProp reports whether the property at path is set.
+func hasProp(res Resource, path ...string) bool {
+	_, ok := res.prop(path...)
+	return ok
+}
+
+// inCluster reports whether an RDS instance belongs to an Aurora cluster.
+func inCluster(res Resource) bool {
+	return hasProp(res, "DBClusterIdentifier")
+}
+
+// policyWildcards returns the wildcard actions and resources allowed by the
+// inline policies of an IAM role or policy, sorted.
+func policyWildcards(res Resource) []string {
+	var documents []any
+	switch res.Def.Type {
+	case "AWS::IAM::Role", "AWS::IAM::User", "AWS::IAM::Group":
+		policies, _ := res.prop("Policies")
+		for _, policy := range asList(policies) {
+			if m, ok := policy.(map[string]any); ok {
+				documents = append(documents, m["PolicyDocument"])
+			}
+		}
+	default:
+		doc, _ := res.prop("PolicyDocument")
+		documents = append(documents, doc)
+	}
+
+	found := make(map[string]bool)
+	for _, doc := range documents {
+		m, ok := doc.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, stmt := range asList(m["Statement"]) {
+			s, ok := stmt.(map[string]any)
+			if !ok || s["Effect"] == "Deny" {
+				continue
+			}
+			for _, action := range asList(s["Action"]) {
+				if a, ok := action.(string); ok && (a == "*" || strings.HasSuffix(a, ":*")) {
+					found["Action "+a] = true
+				}
+			}
+			for _, resource := range asList(s["Resource"]) {
+				if r, ok := resource.(string); ok && r == "*" {
+					found["Resource *"] = true
+				}
+			}
+		}
+	}
+
+	wildcards := make([]string, 0, len(found))
+	for w := range found {
+		wildcards = append(wildcards, w)
+	}
+	sort.Strings(wildcards)
+	return wildcards
+}
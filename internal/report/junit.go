@@ -0,0 +1,148 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the report as JUnit XML with one suite per command.
+// Each rule without findings is a passing test case; each finding is a
+// failing test case named after its rule and location.
+func (r *Report) writeJUnit(w io.Writer) error {
+	suiteName := strings.TrimSpace(r.Tool.Name + " " + r.Command)
+	suite := junitTestSuite{Name: suiteName}
+
+	byRule := make(map[string][]Finding)
+	var unknown []Finding
+	index := r.ruleIndex()
+	for _, f := range r.Findings {
+		if _, ok := index[f.Rule]; ok {
+			byRule[f.Rule] = append(byRule[f.Rule], f)
+		} else {
+			unknown = append(unknown, f)
+		}
+	}
+
+	for _, rule := range r.Rules {
+		findings := byRule[rule.ID]
+		if len(findings) == 0 {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      junitRuleName(rule),
+				ClassName: suiteName,
+			})
+			continue
+		}
+		for _, f := range findings {
+			suite.TestCases = append(suite.TestCases, r.junitFailureCase(f, rule))
+		}
+	}
+	for _, f := range unknown {
+		suite.TestCases = append(suite.TestCases, r.junitFailureCase(f, Rule{ID: f.Rule}))
+	}
+
+	// An empty suite is reported as a single passing check
+	if len(suite.TestCases) == 0 {
+		suite.TestCases = append(suite.TestCases, junitTestCase{Name: suiteName, ClassName: suiteName})
+	}
+
+	suite.Tests = len(suite.TestCases)
+	for _, tc := range suite.TestCases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	out := junitTestSuites{
+		Name:     suiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitFailureCase returns a failing test case for a finding.
+func (r *Report) junitFailureCase(f Finding, rule Rule) junitTestCase {
+	location := f.Resource
+	if f.File != "" {
+		location = relPath(f.File)
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, f.Line)
+		}
+	}
+
+	name := f.Rule
+	if location != "" {
+		name = fmt.Sprintf("%s %s", f.Rule, location)
+	}
+
+	text := f.Message
+	if location != "" {
+		text = fmt.Sprintf("%s: %s", location, f.Message)
+	}
+	if rule.Help != "" {
+		text += "\n" + rule.Help
+	}
+
+	className := relPath(f.File)
+	if f.File == "" {
+		className = strings.TrimSpace(r.Tool.Name + " " + r.Command)
+	}
+
+	return junitTestCase{
+		Name:      name,
+		ClassName: className,
+		Failure: &junitFailure{
+			Message: f.Message,
+			Type:    f.Level,
+			Text:    text,
+		},
+	}
+}
+
+// junitRuleName names the passing test case of a rule.
+func junitRuleName(rule Rule) string {
+	if rule.Description == "" {
+		return rule.ID
+	}
+	return fmt.Sprintf("%s: %s", rule.ID, rule.Description)
+}
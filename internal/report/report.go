@@ -0,0 +1,136 @@
+// Package report renders lint, validation and optimizer findings in the
+// SARIF and JUnit XML formats, so CI systems can show them inline on pull
+// requests and in test reports.
+//
+// A Report pairs the findings of one command with the metadata of the rules
+// that produced them:
+//
+//	r := report.Lint(tool, issues)
+//	err := r.Write(os.Stdout, report.FormatSARIF)
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output formats.
+const (
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+)
+
+// IsFormat reports whether format is rendered by this package.
+func IsFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatSARIF, FormatJUnit:
+		return true
+	}
+	return false
+}
+
+// Finding levels, as used by SARIF.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Tool identifies the program that produced a report.
+type Tool struct {
+	Name           string
+	Version        string
+	InformationURI string
+}
+
+// Rule describes a check that can produce findings.
+type Rule struct {
+	ID string
+	// Name is an identifier-style name, e.g. HardcodedPseudoParameter.
+	Name string
+	// Description is a one-line summary of what the rule checks.
+	Description string
+	// Help explains how to resolve a finding.
+	Help    string
+	HelpURI string
+	// Level is the default level of the rule's findings.
+	Level string
+	// Category groups rules, e.g. "security" for optimizer rules.
+	Category string
+}
+
+// Finding is a single result reported by a rule.
+type Finding struct {
+	Rule    string
+	Level   string
+	Message string
+	// File, Line and Column locate the finding in the Go source.
+	File   string
+	Line   int
+	Column int
+	// Resource is the logical name of the resource the finding is about.
+	Resource string
+}
+
+// Report is the set of findings from one command run.
+type Report struct {
+	Tool Tool
+	// Command is the command that ran, e.g. "lint".
+	Command  string
+	Rules    []Rule
+	Findings []Finding
+}
+
+// Write renders the report in format to w.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case FormatSARIF:
+		return r.writeSARIF(w)
+	case FormatJUnit:
+		return r.writeJUnit(w)
+	default:
+		return fmt.Errorf("unsupported report format: %s (supported: sarif, junit)", format)
+	}
+}
+
+// SeverityLevel maps a lint severity (error, warning, info) or an optimizer
+// severity (high, medium, low) to a finding level.
+func SeverityLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "error", "high":
+		return LevelError
+	case "warning", "medium":
+		return LevelWarning
+	default:
+		return LevelNote
+	}
+}
+
+// ruleIndex returns the position of each rule ID in r.Rules.
+func (r *Report) ruleIndex() map[string]int {
+	index := make(map[string]int, len(r.Rules))
+	for i, rule := range r.Rules {
+		index[rule.ID] = i
+	}
+	return index
+}
+
+// relPath returns file relative to the working directory with forward
+// slashes, or file unchanged when it lies outside it.
+func relPath(file string) string {
+	if !filepath.IsAbs(file) {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(wd, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,210 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+var testTool = Tool{Name: "wetwire-aws", Version: "v1.0.0", InformationURI: "https://github.com/lex00/wetwire-aws-go"}
+
+func writeSARIF(t *testing.T, r *Report) sarifLog {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, FormatSARIF))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	return log
+}
+
+func writeJUnit(t *testing.T, r *Report) junitTestSuites {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, FormatJUnit))
+	assert.Contains(t, buf.String(), xml.Header)
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	return suites
+}
+
+func TestLint_SARIF(t *testing.T) {
+	r := Lint(testTool, []wetwire.LintIssue{{
+		File:     "infra/storage.go",
+		Line:     12,
+		Column:   14,
+		Severity: "error",
+		Message:  "Use AWS_REGION instead of \"AWS::Region\"",
+		Rule:     "WAW001",
+	}})
+
+	log := writeSARIF(t, r)
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, sarifSchema, log.Schema)
+	require.Len(t, log.Runs, 1)
+
+	driver := log.Runs[0].Tool.Driver
+	assert.Equal(t, "wetwire-aws", driver.Name)
+	assert.Equal(t, "v1.0.0", driver.Version)
+	require.NotEmpty(t, driver.Rules)
+	rule := driver.Rules[0]
+	assert.Equal(t, "WAW001", rule.ID)
+	assert.Equal(t, "HardcodedPseudoParameter", rule.Name)
+	assert.NotEmpty(t, rule.ShortDescription.Text)
+	require.NotNil(t, rule.Help)
+	assert.Contains(t, rule.Help.Text, "lint --fix")
+	assert.Contains(t, rule.Help.Text, "//wetwire:ignore WAW001")
+	assert.Equal(t, lintRulesURL, rule.HelpURI)
+
+	require.Len(t, log.Runs[0].Results, 1)
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, "WAW001", result.RuleID)
+	require.NotNil(t, result.RuleIndex)
+	assert.Equal(t, 0, *result.RuleIndex)
+	assert.Equal(t, LevelError, result.Level)
+	require.Len(t, result.Locations, 1)
+	physical := result.Locations[0].PhysicalLocation
+	require.NotNil(t, physical)
+	assert.Equal(t, "infra/storage.go", physical.ArtifactLocation.URI)
+	assert.Equal(t, sarifSrcRoot, physical.ArtifactLocation.URIBaseID)
+	assert.Equal(t, &sarifRegion{StartLine: 12, StartColumn: 14}, physical.Region)
+}
+
+func TestLint_SARIFNoIssues(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Lint(testTool, nil).Write(&buf, FormatSARIF))
+
+	// Code scanning expects an empty results array, not null
+	assert.Contains(t, buf.String(), `"results": []`)
+}
+
+func TestValidate_SARIF(t *testing.T) {
+	r := Validate(testTool, wetwire.SchemaResult{
+		Errors: []wetwire.SchemaError{
+			{Resource: "DataBucket", Property: "BucketName", Message: "expected type String", File: "/elsewhere/storage.go", Line: 5},
+		},
+		Warnings: []wetwire.SchemaError{
+			{Resource: "ApiUrl", Property: "Value", Message: "unknown attribute"},
+		},
+	})
+
+	results := writeSARIF(t, r).Runs[0].Results
+	require.Len(t, results, 2)
+
+	assert.Equal(t, LevelError, results[0].Level)
+	assert.Equal(t, "DataBucket.BucketName: expected type String", results[0].Message.Text)
+	physical := results[0].Locations[0].PhysicalLocation
+	assert.Equal(t, "file:///elsewhere/storage.go", physical.ArtifactLocation.URI)
+	assert.Empty(t, physical.ArtifactLocation.URIBaseID)
+
+	// Without a file, the resource is the only location
+	assert.Equal(t, LevelWarning, results[1].Level)
+	assert.Nil(t, results[1].Locations[0].PhysicalLocation)
+	assert.Equal(t, []sarifLogicalLocation{{Name: "ApiUrl", Kind: "resource"}}, results[1].Locations[0].LogicalLocations)
+}
+
+func TestOptimize_SARIF(t *testing.T) {
+	r := Optimize(testTool, wetwire.OptimizeResult{
+		Suggestions: []wetwire.OptimizeSuggestion{{
+			Rule:        "OPT-S3-001",
+			Resource:    "DataBucket",
+			Category:    "security",
+			Severity:    "high",
+			Description: "S3 buckets should have server-side encryption enabled.",
+			Suggestion:  "Add BucketEncryption.",
+			File:        "storage.go",
+			Line:        4,
+		}},
+		StaleSuppressions: []wetwire.StaleSuppression{
+			{Rule: "OPT-LAM-001", File: "storage.go", Line: 9, Message: "Suppression of OPT-LAM-001 does not match any suggestion; remove it"},
+		},
+	})
+
+	log := writeSARIF(t, r)
+	rules := make(map[string]sarifRule)
+	for _, rule := range log.Runs[0].Tool.Driver.Rules {
+		rules[rule.ID] = rule
+	}
+	require.Contains(t, rules, "OPT-S3-001")
+	assert.Equal(t, &sarifRuleProperty{Tags: []string{"security"}}, rules["OPT-S3-001"].Properties)
+	require.Contains(t, rules, "WAW020")
+
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, LevelError, results[0].Level)
+	assert.Contains(t, results[0].Message.Text, "Add BucketEncryption.")
+	assert.Equal(t, "WAW020", results[1].RuleID)
+	assert.Equal(t, LevelWarning, results[1].Level)
+}
+
+func TestReport_JUnit(t *testing.T) {
+	r := &Report{
+		Tool:    testTool,
+		Command: "lint",
+		Rules: []Rule{
+			{ID: "WAW001", Description: "Use pseudo-parameter constants", Help: "Use AWS_REGION."},
+			{ID: "WAW002", Description: "Use intrinsic types"},
+		},
+		Findings: []Finding{
+			{Rule: "WAW001", Level: LevelError, Message: "first", File: "a.go", Line: 3},
+			{Rule: "WAW001", Level: LevelWarning, Message: "second", File: "b.go", Line: 7},
+			{Rule: "WAW099", Level: LevelNote, Message: "unknown rule"},
+		},
+	}
+
+	suites := writeJUnit(t, r)
+	assert.Equal(t, "wetwire-aws lint", suites.Name)
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 3, suites.Failures)
+	require.Len(t, suites.Suites, 1)
+
+	cases := suites.Suites[0].TestCases
+	require.Len(t, cases, 4)
+
+	assert.Equal(t, "WAW001 a.go:3", cases[0].Name)
+	assert.Equal(t, "a.go", cases[0].ClassName)
+	require.NotNil(t, cases[0].Failure)
+	assert.Equal(t, "first", cases[0].Failure.Message)
+	assert.Equal(t, LevelError, cases[0].Failure.Type)
+	assert.Equal(t, "a.go:3: first\nUse AWS_REGION.", cases[0].Failure.Text)
+
+	// Rules without findings pass
+	assert.Equal(t, "WAW002: Use intrinsic types", cases[2].Name)
+	assert.Nil(t, cases[2].Failure)
+
+	assert.Equal(t, "WAW099", cases[3].Name)
+	assert.Equal(t, "wetwire-aws lint", cases[3].ClassName)
+}
+
+func TestReport_JUnitEmpty(t *testing.T) {
+	suites := writeJUnit(t, &Report{Tool: testTool, Command: "validate"})
+	assert.Equal(t, 1, suites.Tests)
+	assert.Equal(t, 0, suites.Failures)
+}
+
+func TestReport_WriteUnknownFormat(t *testing.T) {
+	err := Lint(testTool, nil).Write(&bytes.Buffer{}, "html")
+	assert.Error(t, err)
+}
+
+func TestIsFormat(t *testing.T) {
+	assert.True(t, IsFormat("sarif"))
+	assert.True(t, IsFormat("JUnit"))
+	assert.False(t, IsFormat("json"))
+}
+
+func TestSeverityLevel(t *testing.T) {
+	assert.Equal(t, LevelError, SeverityLevel("error"))
+	assert.Equal(t, LevelError, SeverityLevel("high"))
+	assert.Equal(t, LevelWarning, SeverityLevel("warning"))
+	assert.Equal(t, LevelWarning, SeverityLevel("medium"))
+	assert.Equal(t, LevelNote, SeverityLevel("info"))
+	assert.Equal(t, LevelNote, SeverityLevel("low"))
+}
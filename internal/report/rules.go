@@ -0,0 +1,137 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/lint"
+	"github.com/lex00/wetwire-aws-go/internal/optimizer"
+)
+
+// Documentation pages linked from rule help.
+const (
+	docsURL         = "https://lex00.github.io/wetwire-aws-go/"
+	lintRulesURL    = docsURL + "lint-rules/"
+	cliURL          = docsURL + "cli/"
+	schemaRuleID    = "schema"
+	staleSuppressID = "WAW020"
+)
+
+// Lint returns a report of lint issues, with the metadata of every lint rule.
+func Lint(tool Tool, issues []wetwire.LintIssue) *Report {
+	r := &Report{Tool: tool, Command: "lint", Rules: lintRules()}
+	for _, issue := range issues {
+		r.Findings = append(r.Findings, Finding{
+			Rule:    issue.Rule,
+			Level:   SeverityLevel(issue.Severity),
+			Message: issue.Message,
+			File:    issue.File,
+			Line:    issue.Line,
+			Column:  issue.Column,
+		})
+	}
+	return r
+}
+
+// Validate returns a report of schema validation errors and warnings.
+func Validate(tool Tool, result wetwire.SchemaResult) *Report {
+	r := &Report{
+		Tool:    tool,
+		Command: "validate",
+		Rules: []Rule{{
+			ID:          schemaRuleID,
+			Name:        "ResourceSpecification",
+			Description: "Resources match the CloudFormation resource specification",
+			Help:        "Check the resource's properties, attributes and Ref/GetAtt targets against the CloudFormation resource specification for its type.",
+			HelpURI:     cliURL,
+			Level:       LevelError,
+		}},
+	}
+
+	add := func(errs []wetwire.SchemaError, level string) {
+		for _, e := range errs {
+			r.Findings = append(r.Findings, Finding{
+				Rule:     schemaRuleID,
+				Level:    level,
+				Message:  fmt.Sprintf("%s.%s: %s", e.Resource, e.Property, e.Message),
+				File:     e.File,
+				Line:     e.Line,
+				Resource: e.Resource,
+			})
+		}
+	}
+	add(result.Errors, LevelError)
+	add(result.Warnings, LevelWarning)
+
+	return r
+}
+
+// Optimize returns a report of optimizer suggestions and stale optimizer
+// suppressions, with the metadata of every optimizer rule.
+func Optimize(tool Tool, result wetwire.OptimizeResult) *Report {
+	r := &Report{Tool: tool, Command: "optimize"}
+	for _, rule := range optimizer.AllRules() {
+		r.Rules = append(r.Rules, Rule{
+			ID:          rule.ID,
+			Description: rule.Title,
+			Help:        rule.Description,
+			HelpURI:     cliURL,
+			Level:       LevelNote,
+			Category:    rule.Category,
+		})
+	}
+
+	for _, s := range result.Suggestions {
+		r.Findings = append(r.Findings, Finding{
+			Rule:     s.Rule,
+			Level:    SeverityLevel(s.Severity),
+			Message:  fmt.Sprintf("%s: %s %s", s.Resource, s.Description, s.Suggestion),
+			File:     s.File,
+			Line:     s.Line,
+			Resource: s.Resource,
+		})
+	}
+
+	if len(result.StaleSuppressions) > 0 {
+		for _, rule := range lintRules() {
+			if rule.ID == staleSuppressID {
+				r.Rules = append(r.Rules, rule)
+			}
+		}
+		for _, stale := range result.StaleSuppressions {
+			r.Findings = append(r.Findings, Finding{
+				Rule:    staleSuppressID,
+				Level:   LevelWarning,
+				Message: stale.Message,
+				File:    stale.File,
+				Line:    stale.Line,
+			})
+		}
+	}
+
+	return r
+}
+
+// lintRules returns the metadata of the lint rules.
+func lintRules() []Rule {
+	var rules []Rule
+	for _, rule := range lint.AllRules() {
+		help := rule.Description() + "."
+		if _, ok := rule.(lint.Fixer); ok {
+			help += " Fixable with `wetwire-aws lint --fix`."
+		}
+		if rule.ID() != staleSuppressID {
+			help += fmt.Sprintf(" Silence a deliberate use with `//wetwire:ignore %s <reason>`.", rule.ID())
+		}
+
+		rules = append(rules, Rule{
+			ID:          rule.ID(),
+			Name:        reflect.TypeOf(rule).Name(),
+			Description: rule.Description(),
+			Help:        help,
+			HelpURI:     lintRulesURL,
+		})
+	}
+	return rules
+}
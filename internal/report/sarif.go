@@ -0,0 +1,170 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// SARIF 2.1.0 identifiers.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifSrcRoot is the base ID of locations relative to the checkout.
+	sarifSrcRoot = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name,omitempty"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	Help                 *sarifText         `json:"help,omitempty"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	DefaultConfiguration *sarifRuleConfig   `json:"defaultConfiguration,omitempty"`
+	Properties           *sarifRuleProperty `json:"properties,omitempty"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifRuleProperty struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex *int            `json:"ruleIndex,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+}
+
+// writeSARIF writes the report as a SARIF 2.1.0 log with a single run.
+func (r *Report) writeSARIF(w io.Writer) error {
+	driver := sarifDriver{
+		Name:           r.Tool.Name,
+		Version:        r.Tool.Version,
+		InformationURI: r.Tool.InformationURI,
+		Rules:          make([]sarifRule, 0, len(r.Rules)),
+	}
+	for _, rule := range r.Rules {
+		sr := sarifRule{
+			ID:               rule.ID,
+			Name:             rule.Name,
+			ShortDescription: sarifText{Text: rule.Description},
+			HelpURI:          rule.HelpURI,
+		}
+		if rule.Help != "" {
+			sr.Help = &sarifText{Text: rule.Help}
+		}
+		if rule.Level != "" {
+			sr.DefaultConfiguration = &sarifRuleConfig{Level: rule.Level}
+		}
+		if rule.Category != "" {
+			sr.Properties = &sarifRuleProperty{Tags: []string{rule.Category}}
+		}
+		driver.Rules = append(driver.Rules, sr)
+	}
+
+	index := r.ruleIndex()
+	results := make([]sarifResult, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		result := sarifResult{
+			RuleID:  f.Rule,
+			Level:   f.Level,
+			Message: sarifText{Text: f.Message},
+		}
+		if i, ok := index[f.Rule]; ok {
+			result.RuleIndex = &i
+		}
+		if loc := sarifLocationOf(f); loc != nil {
+			result.Locations = []sarifLocation{*loc}
+		}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// sarifLocationOf returns the location of a finding, or nil if it has none.
+func sarifLocationOf(f Finding) *sarifLocation {
+	if f.File == "" && f.Resource == "" {
+		return nil
+	}
+
+	loc := &sarifLocation{}
+	if f.File != "" {
+		artifact := sarifArtifactLocation{URI: relPath(f.File), URIBaseID: sarifSrcRoot}
+		if filepath.IsAbs(filepath.FromSlash(artifact.URI)) {
+			artifact = sarifArtifactLocation{URI: "file://" + artifact.URI}
+		}
+		loc.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: artifact}
+		if f.Line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+	}
+	if f.Resource != "" {
+		loc.LogicalLocations = []sarifLogicalLocation{{Name: f.Resource, Kind: "resource"}}
+	}
+	return loc
+}
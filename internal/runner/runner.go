@@ -56,19 +56,30 @@ import (
 
 	"github.com/lex00/wetwire-aws-go/intrinsics"
 	pkg "{{.ImportPath}}"
-)
+{{range .ExternalImports}}	{{.Alias}} "{{.Path}}"
+{{end}})
 
 // Resource interface for CloudFormation resources
 type Resource interface {
 	ResourceType() string
 }
 
+// cfValuer is implemented by wetwire.Value[T] typed property values and
+// by wetwire.ImageAsset
+type cfValuer interface {
+	CFValue() any
+}
+
 // parameterNames maps Parameter signature to logical name
 var parameterNames = make(map[string]string)
 
 // resourceSignatures maps resource JSON signature to logical name
 var resourceSignatures = make(map[string]string)
 
+// outputSignatures maps the JSON signature of an output of another package
+// to its qualified name
+var outputSignatures = make(map[string]string)
+
 func main() {
 	// Use reflection to find all exported variables in the package
 	pkgValue := reflect.ValueOf(pkg.{{.FirstVar}})
@@ -77,6 +88,17 @@ func main() {
 	// The resources are discovered via var names passed as arguments
 	varNames := os.Args[1:]
 
+	// Resources and outputs of other packages are referenced by import path
+	// and name, so they can be resolved across stacks
+	for name, value := range externalVars() {
+		if res, ok := value.(Resource); ok {
+			resourceSignatures[resourceSignature(res)] = name
+		}
+		if out, ok := value.(intrinsics.Output); ok {
+			outputSignatures[outputSignature(out)] = name
+		}
+	}
+
 	// First pass: collect all Parameter values and build name lookup
 	// Also collect resources for Ref generation
 	for _, name := range varNames {
@@ -168,6 +190,12 @@ func resourceSignature(r Resource) string {
 	return r.ResourceType() + ":" + string(data)
 }
 
+// outputSignature creates a unique signature for an Output
+func outputSignature(o intrinsics.Output) string {
+	data, _ := json.Marshal(o)
+	return string(data)
+}
+
 // serializeValue converts a value to JSON-compatible format, handling Parameters specially
 // When nested=true, Resources are converted to Refs (for use inside Outputs, etc.)
 func serializeValue(v reflect.Value) any {
@@ -195,6 +223,26 @@ func serializeValueNested(v reflect.Value, nested bool) any {
 		return serializeValueNested(v.Elem(), nested)
 	}
 
+	// Unwrap typed property values to their literal or intrinsic
+	if v.CanInterface() {
+		if cv, ok := v.Interface().(cfValuer); ok {
+			value := reflect.ValueOf(cv.CFValue())
+			if serialized := serializeValueNested(value, true); serialized != nil || !value.IsValid() {
+				return serialized
+			}
+			// A literal empty value was set explicitly, so it is kept
+			switch value.Kind() {
+			case reflect.String:
+				return ""
+			case reflect.Slice, reflect.Array:
+				return []any{}
+			case reflect.Struct, reflect.Map:
+				return map[string]any{}
+			}
+			return nil
+		}
+	}
+
 	// Check if this is a Parameter - convert to Ref with name lookup
 	if v.Type().String() == "intrinsics.Parameter" {
 		param := v.Interface().(intrinsics.Parameter)
@@ -206,6 +254,15 @@ func serializeValueNested(v reflect.Value, nested bool) any {
 		return map[string]any{"Ref": ""}
 	}
 
+	// Check if this is an output of another package - convert to Ref to its qualified name
+	if nested && v.CanInterface() {
+		if out, ok := v.Interface().(intrinsics.Output); ok {
+			if name, found := outputSignatures[outputSignature(out)]; found {
+				return map[string]any{"Ref": name}
+			}
+		}
+	}
+
 	// Check if this is a Resource - convert to Ref with name lookup (only when nested)
 	if nested && v.CanInterface() {
 		if res, ok := v.Interface().(Resource); ok {
@@ -313,6 +370,26 @@ func serializeValueNested(v reflect.Value, nested bool) any {
 				"Key":   val.Key,
 				"Value": serializeValueNested(reflect.ValueOf(val.Value), true),
 			}
+		case intrinsics.Length:
+			return map[string]any{
+				"Fn::Length": serializeValueNested(reflect.ValueOf(val.List), true),
+			}
+		case intrinsics.ToJsonString:
+			return map[string]any{
+				"Fn::ToJsonString": serializeValueNested(reflect.ValueOf(val.Value), true),
+			}
+		case intrinsics.ForEach:
+			outputs := make(map[string]any)
+			for k, v := range val.Outputs {
+				outputs[k] = serializeLoopOutput(reflect.ValueOf(v))
+			}
+			return map[string][]any{
+				val.Key(): {
+					val.Identifier,
+					serializeValueNested(reflect.ValueOf(val.Collection), true),
+					outputs,
+				},
+			}
 		case intrinsics.Transform:
 			params := make(map[string]any)
 			for k, v := range val.Parameters {
@@ -427,6 +504,21 @@ func serializeValueNested(v reflect.Value, nested bool) any {
 	}
 }
 
+// serializeLoopOutput converts an Fn::ForEach output. A resource becomes a
+// resource definition, with its properties, rather than a Ref.
+func serializeLoopOutput(v reflect.Value) any {
+	if v.IsValid() && v.CanInterface() {
+		if res, ok := v.Interface().(Resource); ok {
+			def := map[string]any{"Type": res.ResourceType()}
+			if props := serializeValueNested(v, false); props != nil {
+				def["Properties"] = props
+			}
+			return def
+		}
+	}
+	return serializeValueNested(v, true)
+}
+
 func splitFirst(s string, sep byte) string {
 	for i := 0; i < len(s); i++ {
 		if s[i] == sep {
@@ -437,6 +529,11 @@ func splitFirst(s string, sep byte) string {
 }
 
 func isZero(v reflect.Value) bool {
+	if v.Kind() == reflect.Struct && v.CanInterface() {
+		if cv, ok := v.Interface().(cfValuer); ok {
+			return cv.CFValue() == nil
+		}
+	}
 	switch v.Kind() {
 	case reflect.Ptr, reflect.Interface:
 		return v.IsNil()
@@ -464,8 +561,31 @@ func getVar(name string) any {
 {{end}}	}
 	return nil
 }
+
+func externalVars() map[string]any {
+	return map[string]any{
+{{range .ExternalVars}}		"{{.Key}}": {{.Alias}}.{{.Name}},
+{{end}}	}
+}
 `))
 
+// ExternalVar is a package-level variable of another package that extracted
+// values may reference, such as a resource of another stack or an output of
+// a nested stack. A reference to an external resource or output is
+// serialized as a Ref to its QualifiedName.
+type ExternalVar struct {
+	// ImportPath is the import path of the declaring package
+	ImportPath string
+	// Name is the variable name
+	Name string
+}
+
+// QualifiedName returns the import path and name of the variable,
+// e.g. "example.com/infra/network.Vpc".
+func (v ExternalVar) QualifiedName() string {
+	return v.ImportPath + "." + v.Name
+}
+
 // ExtractValues runs a generated Go program to extract resource values.
 // Deprecated: Use ExtractAll instead which supports Parameters, Outputs, etc.
 func ExtractValues(pkgPath string, resources map[string]wetwire.DiscoveredResource) (map[string]map[string]any, error) {
@@ -480,7 +600,7 @@ func ExtractValues(pkgPath string, resources map[string]wetwire.DiscoveredResour
 	}
 
 	// Delegate to the common extraction function
-	return extractVarValues(pkgPath, varNames)
+	return extractVarValues(pkgPath, varNames, nil)
 }
 
 // ExtractedValues contains all extracted values organized by type.
@@ -490,15 +610,22 @@ type ExtractedValues struct {
 	Outputs    map[string]map[string]any
 	Mappings   map[string]any
 	Conditions map[string]any
+	Attributes map[string]map[string]any
+	Loops      map[string]map[string]any
 }
 
-// ExtractAll extracts values for all discovered components.
+// ExtractAll extracts values for all discovered components. References to
+// the external variables, resources of other packages, are serialized as
+// Refs to their qualified names.
 func ExtractAll(pkgPath string,
 	resources map[string]wetwire.DiscoveredResource,
 	parameters map[string]wetwire.DiscoveredParameter,
 	outputs map[string]wetwire.DiscoveredOutput,
 	mappings map[string]wetwire.DiscoveredMapping,
 	conditions map[string]wetwire.DiscoveredCondition,
+	attributes map[string]wetwire.DiscoveredAttributes,
+	loops map[string]wetwire.DiscoveredLoop,
+	external []ExternalVar,
 ) (*ExtractedValues, error) {
 	// Collect all variable names
 	varNames := make([]string, 0)
@@ -517,6 +644,12 @@ func ExtractAll(pkgPath string,
 	for name := range conditions {
 		varNames = append(varNames, name)
 	}
+	for name := range attributes {
+		varNames = append(varNames, name)
+	}
+	for name := range loops {
+		varNames = append(varNames, name)
+	}
 
 	if len(varNames) == 0 {
 		return &ExtractedValues{
@@ -525,11 +658,13 @@ func ExtractAll(pkgPath string,
 			Outputs:    make(map[string]map[string]any),
 			Mappings:   make(map[string]any),
 			Conditions: make(map[string]any),
+			Attributes: make(map[string]map[string]any),
+			Loops:      make(map[string]map[string]any),
 		}, nil
 	}
 
 	// Extract all values using the generic extractor
-	allValues, err := extractVarValues(pkgPath, varNames)
+	allValues, err := extractVarValues(pkgPath, varNames, external)
 	if err != nil {
 		return nil, err
 	}
@@ -541,6 +676,8 @@ func ExtractAll(pkgPath string,
 		Outputs:    make(map[string]map[string]any),
 		Mappings:   make(map[string]any),
 		Conditions: make(map[string]any),
+		Attributes: make(map[string]map[string]any),
+		Loops:      make(map[string]map[string]any),
 	}
 
 	for name := range resources {
@@ -568,12 +705,22 @@ func ExtractAll(pkgPath string,
 			result.Conditions[name] = val
 		}
 	}
+	for name := range attributes {
+		if val, ok := allValues[name]; ok {
+			result.Attributes[name] = val
+		}
+	}
+	for name := range loops {
+		if val, ok := allValues[name]; ok {
+			result.Loops[name] = val
+		}
+	}
 
 	return result, nil
 }
 
 // extractVarValues extracts values for a list of variable names.
-func extractVarValues(pkgPath string, varNames []string) (map[string]map[string]any, error) {
+func extractVarValues(pkgPath string, varNames []string, external []ExternalVar) (map[string]map[string]any, error) {
 	if len(varNames) == 0 {
 		return nil, nil
 	}
@@ -714,14 +861,19 @@ require github.com/lex00/wetwire-aws-go v1.9.0
 		return nil, fmt.Errorf("creating runner file: %w", err)
 	}
 
+	imports, externalVars := externalTemplateData(importPath, external)
 	data := struct {
-		ImportPath string
-		FirstVar   string
-		VarNames   []string
+		ImportPath      string
+		FirstVar        string
+		VarNames        []string
+		ExternalImports []externalImport
+		ExternalVars    []externalVarData
 	}{
-		ImportPath: importPath,
-		FirstVar:   firstVar,
-		VarNames:   varNames,
+		ImportPath:      importPath,
+		FirstVar:        firstVar,
+		VarNames:        varNames,
+		ExternalImports: imports,
+		ExternalVars:    externalVars,
 	}
 
 	if err := runnerTemplate.Execute(f, data); err != nil {
@@ -788,3 +940,37 @@ require %s v0.0.0
 
 	return result, nil
 }
+
+// externalImport is an import of the runner program for external variables.
+type externalImport struct {
+	Alias string
+	Path  string
+}
+
+// externalVarData is an external variable of the runner program.
+type externalVarData struct {
+	Key   string
+	Alias string
+	Name  string
+}
+
+// externalTemplateData returns the imports and variables of the runner
+// program for the external variables, skipping those of the package itself.
+func externalTemplateData(importPath string, external []ExternalVar) ([]externalImport, []externalVarData) {
+	var imports []externalImport
+	var vars []externalVarData
+	aliases := make(map[string]string)
+	for _, v := range external {
+		if v.ImportPath == importPath {
+			continue
+		}
+		alias, ok := aliases[v.ImportPath]
+		if !ok {
+			alias = fmt.Sprintf("ext%d", len(imports))
+			aliases[v.ImportPath] = alias
+			imports = append(imports, externalImport{Alias: alias, Path: v.ImportPath})
+		}
+		vars = append(vars, externalVarData{Key: v.QualifiedName(), Alias: alias, Name: v.Name})
+	}
+	return imports, vars
+}
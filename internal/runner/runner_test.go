@@ -2,9 +2,11 @@ package runner
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
@@ -170,6 +172,35 @@ require github.com/lex00/wetwire-aws-go v1.0.0
 	}
 }
 
+func TestPackageDir(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	goMod := "module example.com/myproject\n\ngo 1.23.0\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte(goMod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appDir := filepath.Join(tmpDir, "infra", "app")
+	if err := os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{"example.com/myproject/infra/app/data", filepath.Join(appDir, "data")},
+		{"example.com/myproject", tmpDir},
+		{"example.com/myprojectx/infra", ""},
+		{"github.com/lex00/wetwire-aws-go/resources/s3", ""},
+	}
+	for _, tt := range tests {
+		got, ok := PackageDir(appDir, tt.importPath)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("PackageDir(%q) = %q, %v, want %q", tt.importPath, got, ok, tt.want)
+		}
+	}
+}
+
 func TestFindGoModInfo_WithReplaceDirectives(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -366,7 +397,7 @@ func TestExtractValues_EmptyResources(t *testing.T) {
 }
 
 func TestExtractAll_EmptyInputs(t *testing.T) {
-	result, err := ExtractAll("./testpkg", nil, nil, nil, nil, nil)
+	result, err := ExtractAll("./testpkg", nil, nil, nil, nil, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("ExtractAll failed: %v", err)
 	}
@@ -388,14 +419,21 @@ func TestRunnerTemplateGeneration(t *testing.T) {
 	// Test that the runner template generates valid Go code
 	var buf bytes.Buffer
 
+	imports, externalVars := externalTemplateData("example.com/test/infra", []ExternalVar{
+		{ImportPath: "example.com/test/network", Name: "Vpc"},
+	})
 	data := struct {
-		ImportPath string
-		FirstVar   string
-		VarNames   []string
+		ImportPath      string
+		FirstVar        string
+		VarNames        []string
+		ExternalImports []externalImport
+		ExternalVars    []externalVarData
 	}{
-		ImportPath: "example.com/test/infra",
-		FirstVar:   "MyBucket",
-		VarNames:   []string{"MyBucket", "MyFunction", "MyRole"},
+		ImportPath:      "example.com/test/infra",
+		FirstVar:        "MyBucket",
+		VarNames:        []string{"MyBucket", "MyFunction", "MyRole"},
+		ExternalImports: imports,
+		ExternalVars:    externalVars,
 	}
 
 	err := runnerTemplate.Execute(&buf, data)
@@ -414,6 +452,14 @@ func TestRunnerTemplateGeneration(t *testing.T) {
 		t.Error("generated code should import the target package")
 	}
 
+	if !contains(output, `ext0 "example.com/test/network"`) {
+		t.Error("generated code should import the packages of external variables")
+	}
+
+	if !contains(output, `"example.com/test/network.Vpc": ext0.Vpc,`) {
+		t.Error("generated code should map external variables by qualified name")
+	}
+
 	if !contains(output, "case \"MyBucket\":") {
 		t.Error("generated code should have case for MyBucket")
 	}
@@ -425,6 +471,10 @@ func TestRunnerTemplateGeneration(t *testing.T) {
 	if !contains(output, "case \"MyRole\":") {
 		t.Error("generated code should have case for MyRole")
 	}
+
+	if !contains(output, "CFValue() any") {
+		t.Error("generated code should unwrap typed property values")
+	}
 }
 
 func contains(s, substr string) bool {
@@ -495,7 +545,7 @@ func TestExtractValues_WithRealPackage(t *testing.T) {
 // extractTestValues is a helper that bypasses the DiscoveredResource type requirement
 func extractTestValues(pkgPath string, varNames []string) (map[string]map[string]any, error) {
 	// This calls the unexported extractVarValues directly
-	return extractVarValues(pkgPath, varNames)
+	return extractVarValues(pkgPath, varNames, nil)
 }
 
 func TestExtractAll_WithRealPackage(t *testing.T) {
@@ -522,7 +572,7 @@ func TestExtractAll_WithRealPackage(t *testing.T) {
 		}
 	}
 
-	result, err := ExtractAll(pkgPath, discoveredResources, nil, nil, nil, nil)
+	result, err := ExtractAll(pkgPath, discoveredResources, nil, nil, nil, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("ExtractAll failed: %v", err)
 	}
@@ -559,6 +609,50 @@ func TestExtractAll_WithRealPackage(t *testing.T) {
 	}
 }
 
+func TestExtractAll_PolicyZeroValues(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"WebGroup": {Name: "WebGroup", Type: "autoscaling.AutoScalingGroup", Package: "autoscaling"},
+	}
+	attributes := map[string]wetwire.DiscoveredAttributes{
+		"WebGroupAttributes": {Name: "WebGroupAttributes", Resource: "WebGroup"},
+	}
+
+	result, err := ExtractAll("./testdata/policies", resources, nil, nil, nil, nil, attributes, nil, nil)
+	if err != nil {
+		t.Fatalf("ExtractAll failed: %v", err)
+	}
+
+	attrs, ok := result.Attributes["WebGroupAttributes"]
+	if !ok {
+		t.Fatal("WebGroupAttributes not found in Attributes")
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	creation, _ := got["CreationPolicy"].(map[string]any)
+	asg, _ := creation["AutoScalingCreationPolicy"].(map[string]any)
+	if v, ok := asg["MinSuccessfulInstancesPercent"]; !ok || v != float64(0) {
+		t.Errorf("MinSuccessfulInstancesPercent = %v (set %v), want 0", v, ok)
+	}
+
+	update, _ := got["UpdatePolicy"].(map[string]any)
+	replacing, _ := update["AutoScalingReplacingUpdate"].(map[string]any)
+	if v, ok := replacing["WillReplace"]; !ok || v != false {
+		t.Errorf("WillReplace = %v (set %v), want false", v, ok)
+	}
+	rolling, _ := update["AutoScalingRollingUpdate"].(map[string]any)
+	want := map[string]any{"MaxBatchSize": float64(2)}
+	if !reflect.DeepEqual(rolling, want) {
+		t.Errorf("AutoScalingRollingUpdate = %v, want %v", rolling, want)
+	}
+}
+
 func TestFindGoModInfo_NoModuleDirective(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -579,7 +673,7 @@ func TestFindGoModInfo_NoModuleDirective(t *testing.T) {
 }
 
 func TestExtractVarValues_EmptyVarNames(t *testing.T) {
-	result, err := extractVarValues("./testdata/simple", nil)
+	result, err := extractVarValues("./testdata/simple", nil, nil)
 	if err != nil {
 		t.Fatalf("Expected no error for empty varNames, got: %v", err)
 	}
@@ -589,7 +683,7 @@ func TestExtractVarValues_EmptyVarNames(t *testing.T) {
 }
 
 func TestExtractVarValues_EmptySlice(t *testing.T) {
-	result, err := extractVarValues("./testdata/simple", []string{})
+	result, err := extractVarValues("./testdata/simple", []string{}, nil)
 	if err != nil {
 		t.Fatalf("Expected no error for empty slice, got: %v", err)
 	}
@@ -735,7 +829,7 @@ func TestExtractAll_WithParametersAndOutputs(t *testing.T) {
 		"RegionMapping": {Name: "RegionMapping"},
 	}
 
-	result, err := ExtractAll(pkgPath, resources, parameters, outputs, mappings, nil)
+	result, err := ExtractAll(pkgPath, resources, parameters, outputs, mappings, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("ExtractAll failed: %v", err)
 	}
@@ -787,7 +881,7 @@ func TestExtractAll_WithParametersAndOutputs(t *testing.T) {
 
 func TestExtractVarValues_NonExistentDir(t *testing.T) {
 	// Test with a path that doesn't exist
-	_, err := extractVarValues("/nonexistent/path/to/pkg", []string{"SomeVar"})
+	_, err := extractVarValues("/nonexistent/path/to/pkg", []string{"SomeVar"}, nil)
 	// Should return an error about the path
 	if err == nil {
 		t.Error("Expected error for non-existent path")
@@ -804,7 +898,7 @@ func TestExtractAll_WithEmptyConditions(t *testing.T) {
 	// Empty conditions map - function should handle gracefully
 	conditions := map[string]wetwire.DiscoveredCondition{}
 
-	result, err := ExtractAll(pkgPath, resources, nil, nil, nil, conditions)
+	result, err := ExtractAll(pkgPath, resources, nil, nil, nil, conditions, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("ExtractAll failed: %v", err)
 	}
@@ -824,7 +918,7 @@ func TestExtractAll_OnlyResources(t *testing.T) {
 		"TestBucket": {Name: "TestBucket", Type: "s3.Bucket", Package: "s3"},
 	}
 
-	result, err := ExtractAll(pkgPath, resources, nil, nil, nil, nil)
+	result, err := ExtractAll(pkgPath, resources, nil, nil, nil, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("ExtractAll failed: %v", err)
 	}
@@ -866,7 +960,7 @@ func TestCreateRunnerSubdir_WriteError(t *testing.T) {
 
 func TestExtractAll_AllEmpty(t *testing.T) {
 	// Test with all empty inputs
-	result, err := ExtractAll("./testdata/simple", nil, nil, nil, nil, nil)
+	result, err := ExtractAll("./testdata/simple", nil, nil, nil, nil, nil, nil, nil, nil)
 	require.NoError(t, err)
 
 	// Should return a result with all empty maps
@@ -143,6 +143,47 @@ func findGoModInfo(dir string) (*goModInfo, error) {
 	}
 }
 
+// ImportPath returns the import path of the package in dir, from the module
+// path of the enclosing go.mod.
+func ImportPath(dir string) (string, error) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path: %w", err)
+	}
+	info, err := findGoModInfo(absPath)
+	if err != nil {
+		return "", fmt.Errorf("finding module info: %w", err)
+	}
+
+	importPath := info.ModulePath
+	if relPath, err := filepath.Rel(info.GoModDir, absPath); err == nil && relPath != "." {
+		importPath = info.ModulePath + "/" + filepath.ToSlash(relPath)
+	}
+	return importPath, nil
+}
+
+// PackageDir returns the directory of the package with importPath if it is
+// in the module of dir.
+func PackageDir(dir, importPath string) (string, bool) {
+	absPath, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	info, err := findGoModInfo(absPath)
+	if err != nil || info.Synthetic {
+		return "", false
+	}
+
+	if importPath == info.ModulePath {
+		return info.GoModDir, true
+	}
+	rel, ok := strings.CutPrefix(importPath, info.ModulePath+"/")
+	if !ok {
+		return "", false
+	}
+	return filepath.Join(info.GoModDir, filepath.FromSlash(rel)), true
+}
+
 // createSyntheticGoModInfo generates module info when no go.mod exists.
 // Uses the directory name as the module path.
 func createSyntheticGoModInfo(dir string) (*goModInfo, error) {
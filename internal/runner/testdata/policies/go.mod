@@ -0,0 +1,9 @@
+module testdata/policies
+
+go 1.23.0
+
+require github.com/lex00/wetwire-aws-go v1.9.0
+
+require github.com/lex00/cloudformation-schema-go v1.0.0 // indirect
+
+replace github.com/lex00/wetwire-aws-go => ../../../..
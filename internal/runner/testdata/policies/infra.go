@@ -0,0 +1,28 @@
+package policies
+
+import (
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/resources/autoscaling"
+)
+
+var WebGroup = autoscaling.AutoScalingGroup{
+	MinSize: "0",
+	MaxSize: "4",
+}
+
+var WebGroupAttributes = wetwire.ResourceAttributes{
+	Resource: WebGroup,
+	CreationPolicy: &wetwire.CreationPolicy{
+		AutoScalingCreationPolicy: &wetwire.AutoScalingCreationPolicy{
+			MinSuccessfulInstancesPercent: wetwire.Ptr(0),
+		},
+	},
+	UpdatePolicy: &wetwire.UpdatePolicy{
+		AutoScalingReplacingUpdate: &wetwire.AutoScalingReplacingUpdate{
+			WillReplace: wetwire.Ptr(false),
+		},
+		AutoScalingRollingUpdate: &wetwire.AutoScalingRollingUpdate{
+			MaxBatchSize: wetwire.Ptr(2),
+		},
+	},
+}
@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// CloudFormation template quotas.
+const (
+	// MaxTemplateSize is the size of a template uploaded to S3, in bytes.
+	MaxTemplateSize = 1 << 20
+	// MaxTemplateBodySize is the size of a template passed inline with
+	// --template-body, in bytes.
+	MaxTemplateBodySize = 51200
+	// MaxResources is the number of resources of a template.
+	MaxResources = 500
+	// MaxParameters is the number of parameters of a template.
+	MaxParameters = 200
+	// MaxOutputs is the number of outputs of a template.
+	MaxOutputs = 200
+)
+
+// limitsResource is the Resource of limit errors, which concern the whole
+// template.
+const limitsResource = "Template"
+
+// splitHint suggests how to bring a template within the limits.
+const splitHint = "split it into nested stacks, e.g. with build --auto-split"
+
+// ResourceCount returns the number of resources CloudFormation creates for a
+// template. An Fn::ForEach loop over a literal list counts its expanded
+// resources; other loops, including malformed ones, count once.
+func ResourceCount(t *wetwire.Template) int {
+	count := 0
+	for _, def := range t.Resources {
+		if len(def.ForEach) != 3 {
+			count++
+			continue
+		}
+		collection, _ := def.ForEach[1].([]any)
+		outputs, _ := def.ForEach[2].(map[string]any)
+		if n := len(collection) * len(outputs); n > 0 {
+			count += n
+		} else {
+			count++
+		}
+	}
+	return count
+}
+
+// validateLimits checks the template against the CloudFormation quotas on
+// size and on the number of resources, parameters and outputs.
+func validateLimits(t *wetwire.Template) ([]wetwire.SchemaError, []wetwire.SchemaError) {
+	var errors, warnings []wetwire.SchemaError
+	over := func(property string, count, limit int, what string) {
+		if count > limit {
+			errors = append(errors, wetwire.SchemaError{
+				Resource: limitsResource,
+				Property: property,
+				Message:  fmt.Sprintf("template has %d %s, over the limit of %d; %s", count, what, limit, splitHint),
+			})
+		}
+	}
+	over("Resources", ResourceCount(t), MaxResources, "resources")
+	over("Parameters", len(t.Parameters), MaxParameters, "parameters")
+	over("Outputs", len(t.Outputs), MaxOutputs, "outputs")
+
+	// Measured without indentation, the smallest form of the template
+	data, err := json.Marshal(t)
+	if err != nil {
+		return errors, warnings
+	}
+	switch size := len(data); {
+	case size > MaxTemplateSize:
+		errors = append(errors, wetwire.SchemaError{
+			Resource: limitsResource,
+			Property: "Size",
+			Message:  fmt.Sprintf("template is %d bytes, over the limit of %d; %s", size, MaxTemplateSize, splitHint),
+		})
+	case size > MaxTemplateBodySize:
+		warnings = append(warnings, wetwire.SchemaError{
+			Resource: limitsResource,
+			Property: "Size",
+			Message:  fmt.Sprintf("template is %d bytes, over the %d bytes that can be passed inline; upload it to S3", size, MaxTemplateBodySize),
+		})
+	}
+	return errors, warnings
+}
@@ -0,0 +1,98 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func TestValidateTemplate_Limits(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources:  map[string]wetwire.ResourceDef{},
+		Parameters: map[string]wetwire.Parameter{},
+		Outputs:    map[string]wetwire.Output{},
+	}
+	for i := 0; i < MaxResources+1; i++ {
+		tmpl.Resources[fmt.Sprintf("Queue%d", i)] = wetwire.ResourceDef{Type: "AWS::SQS::Queue"}
+	}
+	for i := 0; i < MaxParameters+1; i++ {
+		tmpl.Parameters[fmt.Sprintf("Param%d", i)] = wetwire.Parameter{Type: "String"}
+	}
+	for i := 0; i < MaxOutputs; i++ {
+		tmpl.Outputs[fmt.Sprintf("Output%d", i)] = wetwire.Output{Value: "value"}
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: &Spec{}})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+
+	var limits []wetwire.SchemaError
+	for _, e := range result.Errors {
+		if e.Resource == limitsResource {
+			limits = append(limits, e)
+		}
+	}
+	require.Len(t, limits, 2)
+	assert.Equal(t, "Resources", limits[0].Property)
+	assert.Contains(t, limits[0].Message, "template has 501 resources, over the limit of 500")
+	assert.Contains(t, limits[0].Message, "build --auto-split")
+	assert.Equal(t, "Parameters", limits[1].Property)
+}
+
+func TestValidateLimits_Size(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Function": {
+				Type:       "AWS::Lambda::Function",
+				Properties: map[string]any{"Code": map[string]any{"ZipFile": strings.Repeat("x", MaxTemplateSize)}},
+			},
+		},
+	}
+
+	errs, warnings := validateLimits(tmpl)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "Size", errs[0].Property)
+	assert.Contains(t, errs[0].Message, "over the limit of 1048576")
+	assert.Empty(t, warnings)
+
+	// Over 51,200 bytes is a warning, as the template can still go through S3
+	tmpl.Resources["Function"].Properties["Code"] = map[string]any{"ZipFile": strings.Repeat("x", MaxTemplateBodySize)}
+	errs, warnings = validateLimits(tmpl)
+	assert.Empty(t, errs)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "Size", warnings[0].Property)
+	assert.Contains(t, warnings[0].Message, "upload it to S3")
+
+	errs, warnings = validateLimits(&wetwire.Template{})
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+}
+
+func TestResourceCount(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Queue": {Type: "AWS::SQS::Queue"},
+			"Fn::ForEach::Topics": {ForEach: []any{
+				"Name",
+				[]any{"A", "B", "C"},
+				map[string]any{"Topic${Name}": map[string]any{}, "Sub${Name}": map[string]any{}},
+			}},
+			"Fn::ForEach::Buckets": {ForEach: []any{
+				"Name",
+				map[string]any{"Ref": "BucketNames"},
+				map[string]any{"Bucket${Name}": map[string]any{}},
+			}},
+		},
+	}
+	assert.Equal(t, 8, ResourceCount(tmpl))
+
+	// Malformed loops count once instead of panicking
+	tmpl.Resources["Fn::ForEach::Short"] = wetwire.ResourceDef{ForEach: []any{"Name"}}
+	tmpl.Resources["List"] = wetwire.ResourceDef{ForEach: []any{}}
+	assert.Equal(t, 10, ResourceCount(tmpl))
+}
@@ -0,0 +1,419 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+	"github.com/lex00/wetwire-aws-go/internal/template"
+)
+
+// refReturns records what Ref returns for common resource types. Types not
+// listed here are not checked for Ref/ARN mismatches.
+var refReturns = map[string]string{
+	"AWS::ApiGateway::RestApi":                  "ID",
+	"AWS::CertificateManager::Certificate":      "ARN",
+	"AWS::CloudFront::Distribution":             "ID",
+	"AWS::Cognito::UserPool":                    "ID",
+	"AWS::DynamoDB::Table":                      "name",
+	"AWS::EC2::Instance":                        "ID",
+	"AWS::EC2::SecurityGroup":                   "ID",
+	"AWS::EC2::Subnet":                          "ID",
+	"AWS::EC2::VPC":                             "ID",
+	"AWS::ECR::Repository":                      "name",
+	"AWS::ECS::Cluster":                         "name",
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": "ARN",
+	"AWS::ElasticLoadBalancingV2::TargetGroup":  "ARN",
+	"AWS::IAM::InstanceProfile":                 "name",
+	"AWS::IAM::ManagedPolicy":                   "ARN",
+	"AWS::IAM::Role":                            "name",
+	"AWS::IAM::User":                            "name",
+	"AWS::Kinesis::Stream":                      "name",
+	"AWS::KMS::Key":                             "ID",
+	"AWS::Lambda::Function":                     "name",
+	"AWS::Lambda::LayerVersion":                 "ARN",
+	"AWS::Logs::LogGroup":                       "name",
+	"AWS::S3::Bucket":                           "name",
+	"AWS::SecretsManager::Secret":               "ARN",
+	"AWS::SNS::Topic":                           "ARN",
+	"AWS::SQS::Queue":                           "URL",
+	"AWS::SSM::Parameter":                       "name",
+	"AWS::StepFunctions::StateMachine":          "ARN",
+}
+
+// knownAttributes lists the GetAtt attributes of common resource types. It
+// is used for types missing from the bundled specification; other types are
+// not checked for attribute names.
+var knownAttributes = map[string][]string{
+	"AWS::ApiGateway::RestApi":                  {"RestApiId", "RootResourceId"},
+	"AWS::ApiGatewayV2::Api":                    {"ApiEndpoint", "ApiId"},
+	"AWS::CloudFront::Distribution":             {"DomainName", "Id"},
+	"AWS::Cognito::UserPool":                    {"Arn", "ProviderName", "ProviderURL", "UserPoolId"},
+	"AWS::DynamoDB::Table":                      {"Arn", "StreamArn"},
+	"AWS::EC2::Instance":                        {"AvailabilityZone", "InstanceId", "PrivateDnsName", "PrivateIp", "PublicDnsName", "PublicIp", "State", "State.Code", "State.Name", "VpcId"},
+	"AWS::EC2::SecurityGroup":                   {"GroupId", "Id", "VpcId"},
+	"AWS::EC2::Subnet":                          {"AvailabilityZone", "AvailabilityZoneId", "BlockPublicAccessStates", "BlockPublicAccessStates.InternetGatewayBlockMode", "CidrBlock", "Ipv6CidrBlocks", "NetworkAclAssociationId", "OutpostArn", "SubnetId", "VpcId"},
+	"AWS::EC2::VPC":                             {"CidrBlock", "CidrBlockAssociations", "DefaultNetworkAcl", "DefaultSecurityGroup", "Ipv6CidrBlocks", "VpcId"},
+	"AWS::ECR::Repository":                      {"Arn", "RepositoryUri"},
+	"AWS::ECS::Cluster":                         {"Arn"},
+	"AWS::ECS::Service":                         {"Name", "ServiceArn"},
+	"AWS::ECS::TaskDefinition":                  {"TaskDefinitionArn"},
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": {"CanonicalHostedZoneID", "DNSName", "LoadBalancerArn", "LoadBalancerFullName", "LoadBalancerName", "SecurityGroups"},
+	"AWS::ElasticLoadBalancingV2::TargetGroup":  {"LoadBalancerArns", "TargetGroupArn", "TargetGroupFullName", "TargetGroupName"},
+	"AWS::Events::Rule":                         {"Arn"},
+	"AWS::IAM::InstanceProfile":                 {"Arn"},
+	"AWS::IAM::ManagedPolicy":                   {"AttachmentCount", "CreateDate", "DefaultVersionId", "IsAttachable", "PermissionsBoundaryUsageCount", "PolicyArn", "PolicyId", "UpdateDate"},
+	"AWS::IAM::Role":                            {"Arn", "RoleId"},
+	"AWS::IAM::User":                            {"Arn"},
+	"AWS::Kinesis::Stream":                      {"Arn"},
+	"AWS::KMS::Key":                             {"Arn", "KeyId"},
+	"AWS::Lambda::Function":                     {"Arn", "SnapStartResponse", "SnapStartResponse.ApplyOn", "SnapStartResponse.OptimizationStatus"},
+	"AWS::Lambda::LayerVersion":                 {"LayerVersionArn"},
+	"AWS::Logs::LogGroup":                       {"Arn"},
+	"AWS::S3::Bucket":                           {"Arn", "DomainName", "DualStackDomainName", "MetadataConfiguration.Destination", "MetadataConfiguration.Destination.TableBucketArn", "MetadataConfiguration.Destination.TableBucketType", "MetadataConfiguration.Destination.TableNamespace", "MetadataConfiguration.InventoryTableConfiguration.TableArn", "MetadataConfiguration.InventoryTableConfiguration.TableName", "MetadataConfiguration.JournalTableConfiguration.TableArn", "MetadataConfiguration.JournalTableConfiguration.TableName", "MetadataTableConfiguration.S3TablesDestination.TableArn", "MetadataTableConfiguration.S3TablesDestination.TableNamespace", "RegionalDomainName", "WebsiteURL"},
+	"AWS::SecretsManager::Secret":               {"Id"},
+	"AWS::SNS::Topic":                           {"TopicArn", "TopicName"},
+	"AWS::SQS::Queue":                           {"Arn", "QueueName", "QueueUrl"},
+	"AWS::SSM::Parameter":                       {"Type", "Value"},
+	"AWS::StepFunctions::StateMachine":          {"Arn", "Name", "StateMachineRevisionId"},
+}
+
+// arnProperties lists properties that require an ARN but whose names do not
+// end in "Arn", keyed by resource type.
+var arnProperties = map[string]map[string]bool{
+	"AWS::Lambda::Function":     {"Role": true},
+	"AWS::Events::Rule":         {"Targets.Arn": true, "Targets.RoleArn": true},
+	"AWS::Lambda::Permission":   {"SourceArn": true},
+	"AWS::Serverless::Function": {"Role": true},
+}
+
+// conditionalAttribute describes a GetAtt attribute that only exists for
+// some configurations of a resource.
+type conditionalAttribute struct {
+	// attributePrefix matches the attribute name (e.g., "RedisEndpoint.")
+	attributePrefix string
+	// available reports whether the attribute exists for the given literal
+	// properties; it returns true when the configuration cannot be determined
+	available func(props map[string]any) bool
+	// requirement describes the configuration that provides the attribute
+	requirement string
+}
+
+// conditionalAttributes lists configuration-dependent attributes by resource type.
+var conditionalAttributes = map[string][]conditionalAttribute{
+	"AWS::ElastiCache::CacheCluster": {
+		{"RedisEndpoint.", literalEquals("Engine", "redis"), "Engine redis"},
+		{"ConfigurationEndpoint.", literalEquals("Engine", "memcached"), "Engine memcached"},
+	},
+	"AWS::ElastiCache::ReplicationGroup": {
+		{"ConfigurationEndPoint.", clusterModeEnabled(true), "cluster mode enabled"},
+		{"PrimaryEndPoint.", clusterModeEnabled(false), "cluster mode disabled"},
+		{"ReaderEndPoint.", clusterModeEnabled(false), "cluster mode disabled"},
+	},
+	"AWS::RDS::DBCluster": {
+		{"ReadEndpoint.", func(props map[string]any) bool {
+			mode, ok := props["EngineMode"].(string)
+			return !ok || mode != "serverless"
+		}, "an EngineMode other than serverless"},
+	},
+	"AWS::S3::Bucket": {
+		{"WebsiteURL", hasProperty("WebsiteConfiguration"), "a WebsiteConfiguration"},
+	},
+}
+
+// literalEquals returns an availability check that passes unless the
+// property is a literal string other than want (case-insensitive).
+func literalEquals(property, want string) func(map[string]any) bool {
+	return func(props map[string]any) bool {
+		s, ok := props[property].(string)
+		return !ok || strings.EqualFold(s, want)
+	}
+}
+
+// hasProperty returns an availability check that passes when property is set.
+func hasProperty(property string) func(map[string]any) bool {
+	return func(props map[string]any) bool {
+		_, ok := props[property]
+		return ok
+	}
+}
+
+// clusterModeEnabled returns an availability check for ElastiCache
+// replication groups in (or not in) cluster mode.
+func clusterModeEnabled(want bool) func(map[string]any) bool {
+	return func(props map[string]any) bool {
+		if mode, ok := props["ClusterMode"].(string); ok {
+			return strings.EqualFold(mode, "enabled") == want
+		}
+		if n, ok := primitiveNumber(props["NumNodeGroups"]); ok {
+			return (n > 1) == want
+		}
+		// Cluster mode is disabled by default
+		return !want
+	}
+}
+
+// referenceChecker validates Ref and Fn::GetAtt targets in a template.
+type referenceChecker struct {
+	template *wetwire.Template
+	spec     *Spec
+	errors   []wetwire.SchemaError
+	warnings []wetwire.SchemaError
+	// implicit holds the resources the SAM transform generates; nil until
+	// first needed
+	implicit map[string]bool
+}
+
+// validateReferences checks Ref and GetAtt usages in a resource's properties.
+func validateReferences(name string, resource wetwire.ResourceDef, template *wetwire.Template, spec *Spec) ([]wetwire.SchemaError, []wetwire.SchemaError) {
+	c := &referenceChecker{template: template, spec: spec}
+	for _, propName := range sortedKeys(resource.Properties) {
+		c.walk(name, resource.Type, propName, propName, resource.Properties[propName])
+	}
+	return c.errors, c.warnings
+}
+
+// validateOutputReferences checks Ref and GetAtt usages in template outputs.
+func validateOutputReferences(template *wetwire.Template, spec *Spec) ([]wetwire.SchemaError, []wetwire.SchemaError) {
+	c := &referenceChecker{template: template, spec: spec}
+	names := make([]string, 0, len(template.Outputs))
+	for name := range template.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.walk("Outputs."+name, "", "Value", "Value", template.Outputs[name].Value)
+	}
+	return c.errors, c.warnings
+}
+
+// hasLoops reports whether a template has Fn::ForEach loops, whose
+// resources are only known once CloudFormation expands them.
+func hasLoops(t *wetwire.Template) bool {
+	for _, def := range t.Resources {
+		if def.IsLoop() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *referenceChecker) errorf(resource, property, format string, args ...any) {
+	c.errors = append(c.errors, wetwire.SchemaError{
+		Resource: resource,
+		Property: property,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// walk visits a value. path is the full property path used in messages;
+// schemaPath drops list indices so it can be matched against arnProperties.
+func (c *referenceChecker) walk(resource, resourceType, path, schemaPath string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 1 {
+			if target, ok := v["Ref"].(string); ok {
+				c.checkRef(resource, resourceType, path, schemaPath, target)
+				return
+			}
+			if getAtt, ok := v["Fn::GetAtt"]; ok {
+				if target, attr, ok := template.GetAttArgs(getAtt); ok {
+					c.checkGetAtt(resource, path, target, attr)
+				}
+				return
+			}
+			if sub, ok := v["Fn::Sub"]; ok {
+				c.checkSub(resource, path, sub)
+				return
+			}
+		}
+		for _, key := range sortedKeys(v) {
+			nextSchemaPath := schemaPath
+			if !strings.HasPrefix(key, "Fn::") {
+				nextSchemaPath = schemaPath + "." + key
+			}
+			c.walk(resource, resourceType, path+"."+key, nextSchemaPath, v[key])
+		}
+	case []any:
+		for i, item := range v {
+			c.walk(resource, resourceType, fmt.Sprintf("%s[%d]", path, i), schemaPath, item)
+		}
+	}
+}
+
+// checkRef validates a Ref target and, for resources, what the Ref returns.
+func (c *referenceChecker) checkRef(resource, resourceType, path, schemaPath, target string) {
+	if strings.HasPrefix(target, "AWS::") {
+		return
+	}
+	if _, ok := c.template.Parameters[target]; ok {
+		return
+	}
+	res, ok := c.template.Resources[target]
+	if !ok {
+		if !hasLoops(c.template) && !c.isImplicit(target) {
+			c.errorf(resource, path, "Ref to undefined resource or parameter %q", target)
+		}
+		return
+	}
+
+	returns, known := refReturns[res.Type]
+	if !known || returns == "ARN" || !expectsARN(resourceType, schemaPath) {
+		return
+	}
+	msg := fmt.Sprintf("Ref to %s (%s) returns its %s, but %s expects an ARN", target, res.Type, returns, path)
+	if rs, ok := c.spec.ResourceTypes[res.Type]; ok {
+		if _, hasArn := rs.Attributes["Arn"]; hasArn {
+			msg += fmt.Sprintf("; use %s.Arn", target)
+		}
+	}
+	c.errorf(resource, path, "%s", msg)
+}
+
+// checkGetAtt validates a GetAtt target and attribute name.
+func (c *referenceChecker) checkGetAtt(resource, path, target, attr string) {
+	res, ok := c.template.Resources[target]
+	if !ok {
+		if !hasLoops(c.template) && !c.isImplicit(target) {
+			c.errorf(resource, path, "GetAtt on undefined resource %q", target)
+		}
+		return
+	}
+
+	// Custom resources and nested stacks expose arbitrary attributes
+	if strings.HasPrefix(res.Type, "Custom::") || res.Type == "AWS::CloudFormation::CustomResource" ||
+		(res.Type == "AWS::CloudFormation::Stack" && strings.HasPrefix(attr, "Outputs.")) {
+		return
+	}
+
+	if names, ok := c.attributeNames(res.Type); ok {
+		idx := sort.SearchStrings(names, attr)
+		if idx == len(names) || names[idx] != attr {
+			c.errorf(resource, path, "%s (%s) has no attribute %q; valid attributes: %s",
+				target, res.Type, attr, strings.Join(names, ", "))
+			return
+		}
+	}
+
+	for _, ca := range conditionalAttributes[res.Type] {
+		if strings.HasPrefix(attr, ca.attributePrefix) && !ca.available(res.Properties) {
+			c.warnings = append(c.warnings, wetwire.SchemaError{
+				Resource: resource,
+				Property: path,
+				Message:  fmt.Sprintf("%s.%s is only available with %s", target, attr, ca.requirement),
+			})
+		}
+	}
+}
+
+// isImplicit reports whether target is a resource the SAM transform
+// generates, which the template may reference without declaring it.
+func (c *referenceChecker) isImplicit(target string) bool {
+	if c.implicit == nil {
+		c.implicit = samImplicitResources(c.template)
+	}
+	return c.implicit[target]
+}
+
+// samImplicitResources returns the logical IDs of the resources the SAM
+// transform adds to a template: function and state machine roles, the
+// deployments and stages of APIs, and the ServerlessRestApi and
+// ServerlessHttpApi created for events without an explicit API. Templates
+// without the transform have none.
+func samImplicitResources(t *wetwire.Template) map[string]bool {
+	implicit := make(map[string]bool)
+	if !t.Transform.Has(wetwire.TransformServerless) {
+		return implicit
+	}
+	for name, res := range t.Resources {
+		switch res.Type {
+		case "AWS::Serverless::Function":
+			if _, hasRole := res.Properties["Role"]; !hasRole {
+				implicit[name+"Role"] = true
+			}
+			if alias, ok := res.Properties["AutoPublishAlias"].(string); ok {
+				implicit[name+"Alias"+alias] = true
+			}
+			events, _ := res.Properties["Events"].(map[string]any)
+			for _, event := range events {
+				e, _ := event.(map[string]any)
+				props, _ := e["Properties"].(map[string]any)
+				switch e["Type"] {
+				case "Api":
+					if _, explicit := props["RestApiId"]; !explicit {
+						implicit["ServerlessRestApi"] = true
+						implicit["ServerlessRestApiDeployment"] = true
+						implicit["ServerlessRestApiProdStage"] = true
+					}
+				case "HttpApi":
+					if _, explicit := props["ApiId"]; !explicit {
+						implicit["ServerlessHttpApi"] = true
+						implicit["ServerlessHttpApiApiGatewayDefaultStage"] = true
+					}
+				}
+			}
+		case "AWS::Serverless::StateMachine":
+			if _, hasRole := res.Properties["Role"]; !hasRole {
+				implicit[name+"Role"] = true
+			}
+		case "AWS::Serverless::Api":
+			implicit[name+"Deployment"] = true
+			implicit[name+"Stage"] = true
+			if stage, ok := res.Properties["StageName"].(string); ok {
+				implicit[name+stage+"Stage"] = true
+			}
+		case "AWS::Serverless::HttpApi":
+			implicit[name+"ApiGatewayDefaultStage"] = true
+		}
+	}
+	return implicit
+}
+
+// checkSub validates ${Name} and ${Name.Attr} references in a Fn::Sub string.
+func (c *referenceChecker) checkSub(resource, path string, sub any) {
+	str, vars := template.SubArgs(sub)
+	for _, key := range sortedKeys(vars) {
+		c.walk(resource, "", path+".Fn::Sub."+key, "", vars[key])
+	}
+
+	for _, ref := range template.SubReferences(str, vars) {
+		if ref.Attr != "" {
+			if _, isParam := c.template.Parameters[ref.Name]; !isParam {
+				c.checkGetAtt(resource, path, ref.Name, ref.Attr)
+			}
+			continue
+		}
+		c.checkRef(resource, "", path, "", ref.Name)
+	}
+}
+
+// expectsARN reports whether the property at schemaPath requires an ARN.
+func expectsARN(resourceType, schemaPath string) bool {
+	if schemaPath == "" {
+		return false
+	}
+	if arnProperties[resourceType][schemaPath] {
+		return true
+	}
+	last := schemaPath[strings.LastIndex(schemaPath, ".")+1:]
+	return strings.HasSuffix(last, "Arn") || strings.HasSuffix(last, "ARN") ||
+		strings.HasSuffix(last, "Arns") || strings.HasSuffix(last, "ARNs")
+}
+
+// attributeNames returns the sorted GetAtt attribute names of a resource
+// type from the specification, or from knownAttributes when the
+// specification does not describe the type.
+func (c *referenceChecker) attributeNames(resourceType string) ([]string, bool) {
+	rs, ok := c.spec.ResourceTypes[resourceType]
+	if !ok || rs.Attributes == nil {
+		names, known := knownAttributes[resourceType]
+		return names, known
+	}
+	names := make([]string, 0, len(rs.Attributes))
+	for name := range rs.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, true
+}
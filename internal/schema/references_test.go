@@ -0,0 +1,258 @@
+package schema
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func referenceSpec() *Spec {
+	return &Spec{
+		ResourceTypes: map[string]ResourceSpec{
+			"AWS::IAM::Role": {
+				Attributes: map[string]AttributeSpec{"Arn": {PrimitiveType: "String"}, "RoleId": {PrimitiveType: "String"}},
+			},
+			"AWS::Lambda::Function": {
+				Attributes: map[string]AttributeSpec{"Arn": {PrimitiveType: "String"}},
+			},
+			"AWS::ElastiCache::CacheCluster": {
+				Attributes: map[string]AttributeSpec{
+					"RedisEndpoint.Address":         {PrimitiveType: "String"},
+					"ConfigurationEndpoint.Address": {PrimitiveType: "String"},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateTemplate_References(t *testing.T) {
+	role := wetwire.ResourceDef{Type: "AWS::IAM::Role"}
+
+	tests := []struct {
+		name     string
+		props    map[string]any
+		extra    map[string]wetwire.ResourceDef
+		property string
+		message  string
+	}{
+		{
+			name:     "unknown attribute",
+			props:    map[string]any{"Role": map[string]any{"Fn::GetAtt": []string{"MyRole", "Name"}}},
+			property: "Role",
+			message:  `MyRole (AWS::IAM::Role) has no attribute "Name"; valid attributes: Arn, RoleId`,
+		},
+		{
+			name:     "undefined GetAtt target",
+			props:    map[string]any{"Role": map[string]any{"Fn::GetAtt": []any{"Missing", "Arn"}}},
+			property: "Role",
+			message:  `GetAtt on undefined resource "Missing"`,
+		},
+		{
+			name:     "undefined Ref target",
+			props:    map[string]any{"Role": map[string]any{"Fn::GetAtt": []string{"MyRole", "Arn"}}, "Handler": map[string]any{"Ref": "Missing"}},
+			property: "Handler",
+			message:  `Ref to undefined resource or parameter "Missing"`,
+		},
+		{
+			name:     "Ref where ARN expected",
+			props:    map[string]any{"Role": map[string]any{"Ref": "MyRole"}},
+			property: "Role",
+			message:  "returns its name, but Role expects an ARN; use MyRole.Arn",
+		},
+		{
+			name: "Ref where ARN expected in nested list",
+			props: map[string]any{
+				"Role":   map[string]any{"Fn::GetAtt": []string{"MyRole", "Arn"}},
+				"Layers": []any{map[string]any{"Ref": "Other"}},
+				"DeadLetterConfig": map[string]any{
+					"TargetArn": map[string]any{"Ref": "Other"},
+				},
+			},
+			extra:    map[string]wetwire.ResourceDef{"Other": {Type: "AWS::SQS::Queue"}},
+			property: "DeadLetterConfig.TargetArn",
+			message:  "returns its URL",
+		},
+		{
+			name:     "Fn::Sub attribute",
+			props:    map[string]any{"Role": map[string]any{"Fn::Sub": "${MyRole.Nope}"}},
+			property: "Role",
+			message:  `has no attribute "Nope"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := map[string]wetwire.ResourceDef{
+				"MyRole": role,
+				"Fn":     {Type: "AWS::Lambda::Function", Properties: tt.props},
+			}
+			for name, res := range tt.extra {
+				resources[name] = res
+			}
+
+			result, err := ValidateTemplate(&wetwire.Template{Resources: resources}, Options{Spec: referenceSpec()})
+			require.NoError(t, err)
+
+			assert.False(t, result.Valid)
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, "Fn", result.Errors[0].Resource)
+			assert.Equal(t, tt.property, result.Errors[0].Property)
+			assert.Contains(t, result.Errors[0].Message, tt.message)
+		})
+	}
+}
+
+func TestValidateTemplate_ReferencesValid(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{"Env": {Type: "String"}},
+		Resources: map[string]wetwire.ResourceDef{
+			"MyRole": {Type: "AWS::IAM::Role"},
+			"Fn": {
+				Type: "AWS::Lambda::Function",
+				Properties: map[string]any{
+					"Role":         map[string]any{"Fn::GetAtt": []string{"MyRole", "Arn"}},
+					"FunctionName": map[string]any{"Fn::Sub": []any{"${Env}-${Suffix}-${AWS::Region}-${!Literal}", map[string]any{"Suffix": map[string]any{"Ref": "MyRole"}}}},
+					"Description":  map[string]any{"Fn::If": []any{"IsProd", map[string]any{"Ref": "AWS::NoValue"}, "dev"}},
+				},
+			},
+			"Custom": {Type: "Custom::Thing"},
+			"Uses": {
+				Type:       "AWS::SQS::Queue",
+				Properties: map[string]any{"QueueName": map[string]any{"Fn::GetAtt": "Custom.Anything"}},
+			},
+		},
+		Outputs: map[string]wetwire.Output{
+			"FunctionArn": {Value: map[string]any{"Fn::GetAtt": []string{"Fn", "Arn"}}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: referenceSpec()})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+}
+
+func TestValidateTemplate_ReferencesOutputs(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{"MyRole": {Type: "AWS::IAM::Role"}},
+		Outputs: map[string]wetwire.Output{
+			"RoleName": {Value: map[string]any{"Fn::GetAtt": []string{"MyRole", "RoleName"}}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: referenceSpec()})
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Outputs.RoleName", result.Errors[0].Resource)
+	assert.Contains(t, result.Errors[0].Message, `has no attribute "RoleName"`)
+}
+
+func TestValidateTemplate_SAMImplicitResources(t *testing.T) {
+	data, err := os.ReadFile("testdata/sam.yaml")
+	require.NoError(t, err)
+	var tmpl wetwire.Template
+	require.NoError(t, yaml.Unmarshal(data, &tmpl))
+
+	// Resources generated by the transform are defined; others are not
+	result, err := ValidateTemplate(&tmpl, Options{Spec: referenceSpec()})
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Outputs.Missing", result.Errors[0].Resource)
+	assert.Contains(t, result.Errors[0].Message, `undefined resource or parameter "ApiRole"`)
+
+	// Without the transform nothing is generated
+	tmpl.Transform = nil
+	result, err = ValidateTemplate(&tmpl, Options{Spec: referenceSpec()})
+	require.NoError(t, err)
+	var undefined int
+	for _, e := range result.Errors {
+		if strings.Contains(e.Message, "undefined resource") {
+			undefined++
+		}
+	}
+	assert.Equal(t, 6, undefined)
+}
+
+func TestValidateTemplate_ConditionalAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		engine  any
+		attr    string
+		warning string
+	}{
+		{name: "memcached redis endpoint", engine: "memcached", attr: "RedisEndpoint.Address", warning: "only available with Engine redis"},
+		{name: "redis configuration endpoint", engine: "redis", attr: "ConfigurationEndpoint.Address", warning: "only available with Engine memcached"},
+		{name: "redis endpoint", engine: "redis", attr: "RedisEndpoint.Address"},
+		{name: "engine from parameter", engine: map[string]any{"Ref": "Engine"}, attr: "RedisEndpoint.Address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &wetwire.Template{
+				Parameters: map[string]wetwire.Parameter{"Engine": {Type: "String"}},
+				Resources: map[string]wetwire.ResourceDef{
+					"Cache": {Type: "AWS::ElastiCache::CacheCluster", Properties: map[string]any{"Engine": tt.engine}},
+				},
+			}
+			tmpl.Resources["Fn"] = wetwire.ResourceDef{
+				Type:       "AWS::Lambda::Function",
+				Properties: map[string]any{"Environment": map[string]any{"Variables": map[string]any{"HOST": map[string]any{"Fn::GetAtt": []string{"Cache", tt.attr}}}}},
+			}
+
+			result, err := ValidateTemplate(tmpl, Options{Spec: referenceSpec()})
+			require.NoError(t, err)
+			assert.True(t, result.Valid)
+
+			if tt.warning == "" {
+				assert.Empty(t, result.Warnings)
+				return
+			}
+			require.Len(t, result.Warnings, 1)
+			assert.Equal(t, "Environment.Variables.HOST", result.Warnings[0].Property)
+			assert.Contains(t, result.Warnings[0].Message, tt.warning)
+		})
+	}
+}
+
+func TestValidateTemplate_BundledSpecAttributes(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Data":  {Type: "AWS::S3::Bucket"},
+			"Queue": {Type: "AWS::SQS::Queue"},
+			"Uses": {
+				Type: "AWS::SNS::Topic",
+				Properties: map[string]any{
+					"DisplayName": map[string]any{"Fn::GetAtt": []string{"Data", "Arn"}},
+					"TopicName":   map[string]any{"Fn::Sub": "${Queue.QueueName}-${Data.BucketArn}"},
+				},
+			},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{})
+	require.NoError(t, err)
+
+	var attrErrors []wetwire.SchemaError
+	for _, e := range result.Errors {
+		if strings.Contains(e.Message, "has no attribute") {
+			attrErrors = append(attrErrors, e)
+		}
+	}
+	require.Len(t, attrErrors, 1)
+	assert.Equal(t, "TopicName", attrErrors[0].Property)
+	assert.Contains(t, attrErrors[0].Message, `Data (AWS::S3::Bucket) has no attribute "BucketArn"`)
+}
+
+func TestKnownAttributesSorted(t *testing.T) {
+	for resourceType, names := range knownAttributes {
+		assert.True(t, sort.StringsAreSorted(names), "%s attributes are not sorted", resourceType)
+	}
+}
@@ -1,9 +1,12 @@
 // Package schema provides offline CloudFormation schema validation.
-// It validates resources against known CloudFormation resource schemas.
+// It validates resources against the CloudFormation resource specification
+// bundled with the binary, falling back to a hand-written subset of schemas
+// for types the bundle does not cover.
 package schema
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	wetwire "github.com/lex00/wetwire-aws-go"
@@ -13,6 +16,11 @@ import (
 type Options struct {
 	// Strict enables strict validation mode
 	Strict bool
+	// Spec overrides the bundled specification
+	Spec *Spec
+	// Resources maps logical names to their Go declarations so errors
+	// report the file and line of the resource
+	Resources map[string]wetwire.DiscoveredResource
 }
 
 // Result contains schema validation results.
@@ -26,11 +34,42 @@ type Result struct {
 func ValidateTemplate(template *wetwire.Template, opts Options) (*Result, error) {
 	result := &Result{Valid: true}
 
-	for name, resource := range template.Resources {
-		errors, warnings := validateResource(name, resource, opts)
-		result.Errors = append(result.Errors, errors...)
-		result.Warnings = append(result.Warnings, warnings...)
+	spec := opts.Spec
+	if spec == nil {
+		var err error
+		if spec, err = BundledSpec(); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(template.Resources))
+	for name := range template.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// Fn::ForEach loops are expanded by CloudFormation; their resource
+		// templates are not validated
+		def := template.Resources[name]
+		if def.IsLoop() || strings.HasPrefix(name, wetwire.ForEachPrefix) {
+			if err, ok := loopError(name, def); ok {
+				result.Errors = append(result.Errors, withPosition([]wetwire.SchemaError{err}, opts.Resources[name])...)
+			}
+			continue
+		}
+		errors, warnings := validateResource(name, template.Resources[name], template.Transform, spec, opts)
+		refErrors, refWarnings := validateReferences(name, template.Resources[name], template, spec)
+		errors, warnings = append(errors, refErrors...), append(warnings, refWarnings...)
+		result.Errors = append(result.Errors, withPosition(errors, opts.Resources[name])...)
+		result.Warnings = append(result.Warnings, withPosition(warnings, opts.Resources[name])...)
 	}
+	outputErrors, outputWarnings := validateOutputReferences(template, spec)
+	result.Errors = append(result.Errors, outputErrors...)
+	result.Warnings = append(result.Warnings, outputWarnings...)
+	limitErrors, limitWarnings := validateLimits(template)
+	result.Errors = append(result.Errors, limitErrors...)
+	result.Warnings = append(result.Warnings, limitWarnings...)
 
 	if len(result.Errors) > 0 {
 		result.Valid = false
@@ -39,8 +78,36 @@ func ValidateTemplate(template *wetwire.Template, opts Options) (*Result, error)
 	return result, nil
 }
 
+// withPosition sets the Go source position of a resource on its errors.
+func withPosition(errs []wetwire.SchemaError, res wetwire.DiscoveredResource) []wetwire.SchemaError {
+	for i := range errs {
+		errs[i].File = res.File
+		errs[i].Line = res.Line
+	}
+	return errs
+}
+
+// loopError reports a malformed Fn::ForEach loop: a loop key holding a
+// resource or a list of other than three arguments, or a list value under a
+// key without the Fn::ForEach:: prefix.
+func loopError(name string, def wetwire.ResourceDef) (wetwire.SchemaError, bool) {
+	if !strings.HasPrefix(name, wetwire.ForEachPrefix) {
+		return wetwire.SchemaError{
+			Resource: name,
+			Message:  fmt.Sprintf("resource is a list; only keys starting with %s hold loops", wetwire.ForEachPrefix),
+		}, true
+	}
+	if len(def.ForEach) != 3 {
+		return wetwire.SchemaError{
+			Resource: name,
+			Message:  "Fn::ForEach takes an identifier, a collection and an output template",
+		}, true
+	}
+	return wetwire.SchemaError{}, false
+}
+
 // validateResource validates a single resource.
-func validateResource(name string, resource wetwire.ResourceDef, opts Options) ([]wetwire.SchemaError, []wetwire.SchemaError) {
+func validateResource(name string, resource wetwire.ResourceDef, transforms wetwire.Transforms, spec *Spec, opts Options) ([]wetwire.SchemaError, []wetwire.SchemaError) {
 	var errors, warnings []wetwire.SchemaError
 
 	// Validate resource type format
@@ -52,7 +119,17 @@ func validateResource(name string, resource wetwire.ResourceDef, opts Options) (
 		})
 	}
 
-	// Get schema for resource type
+	// Validate resource attributes
+	errors = append(errors, validateAttributes(name, resource, transforms)...)
+	errors = append(errors, validatePolicies(name, resource)...)
+
+	// Prefer the specification, which covers every resource type
+	if rs, ok := spec.ResourceTypes[resource.Type]; ok {
+		specErrors, specWarnings := validateAgainstSpec(name, resource, rs, spec, opts)
+		return append(errors, specErrors...), append(warnings, specWarnings...)
+	}
+
+	// Fall back to the hand-written schemas
 	schema, ok := resourceSchemas[resource.Type]
 	if !ok {
 		// Unknown resource type - this is a warning, not an error
@@ -97,6 +174,149 @@ func validateResource(name string, resource wetwire.ResourceDef, opts Options) (
 	return errors, warnings
 }
 
+// Allowed values for the DeletionPolicy and UpdateReplacePolicy attributes.
+var (
+	deletionPolicies      = []string{"Delete", "Retain", "RetainExceptOnCreate", "Snapshot"}
+	updateReplacePolicies = []string{"Delete", "Retain", "Snapshot"}
+)
+
+// snapshotResourceTypes lists the resource types that support the Snapshot policy.
+var snapshotResourceTypes = map[string]bool{
+	"AWS::DocDB::DBCluster":              true,
+	"AWS::EC2::Volume":                   true,
+	"AWS::ElastiCache::CacheCluster":     true,
+	"AWS::ElastiCache::ReplicationGroup": true,
+	"AWS::Neptune::DBCluster":            true,
+	"AWS::RDS::DBCluster":                true,
+	"AWS::RDS::DBInstance":               true,
+	"AWS::Redshift::Cluster":             true,
+}
+
+// validateAttributes validates the DeletionPolicy and UpdateReplacePolicy
+// attributes. Intrinsic functions are allowed under AWS::LanguageExtensions.
+func validateAttributes(name string, resource wetwire.ResourceDef, transforms wetwire.Transforms) []wetwire.SchemaError {
+	var errors []wetwire.SchemaError
+
+	policies := []struct {
+		attribute string
+		value     any
+		allowed   []string
+	}{
+		{"DeletionPolicy", resource.DeletionPolicy, deletionPolicies},
+		{"UpdateReplacePolicy", resource.UpdateReplacePolicy, updateReplacePolicies},
+	}
+	for _, p := range policies {
+		if p.value == nil || p.value == "" {
+			continue
+		}
+		value, isName := p.value.(string)
+		if !isName {
+			if !transforms.Has(wetwire.TransformLanguageExtensions) {
+				errors = append(errors, wetwire.SchemaError{
+					Resource: name,
+					Property: p.attribute,
+					Message:  fmt.Sprintf("intrinsic functions in %s need the %s transform", p.attribute, wetwire.TransformLanguageExtensions),
+				})
+			}
+			continue
+		}
+		if !contains(p.allowed, value) {
+			errors = append(errors, wetwire.SchemaError{
+				Resource: name,
+				Property: p.attribute,
+				Message:  fmt.Sprintf("value %q not in allowed values: %v", value, p.allowed),
+			})
+			continue
+		}
+		if value == "Snapshot" && !snapshotResourceTypes[resource.Type] {
+			errors = append(errors, wetwire.SchemaError{
+				Resource: name,
+				Property: p.attribute,
+				Message:  fmt.Sprintf("%s does not support the Snapshot policy", resource.Type),
+			})
+		}
+	}
+
+	return errors
+}
+
+// creationPolicySupport maps each CreationPolicy key to the resource types that accept it.
+var creationPolicySupport = map[string][]string{
+	"AutoScalingCreationPolicy": {"AWS::AutoScaling::AutoScalingGroup"},
+	"ResourceSignal": {
+		"AWS::AutoScaling::AutoScalingGroup",
+		"AWS::CloudFormation::WaitCondition",
+		"AWS::EC2::Instance",
+	},
+	"StartFleet": {"AWS::AppStream::Fleet", "AWS::AppStream::ImageBuilder"},
+}
+
+// updatePolicySupport maps each UpdatePolicy key to the resource types that accept it.
+var updatePolicySupport = map[string][]string{
+	"AutoScalingReplacingUpdate":  {"AWS::AutoScaling::AutoScalingGroup"},
+	"AutoScalingRollingUpdate":    {"AWS::AutoScaling::AutoScalingGroup"},
+	"AutoScalingScheduledAction":  {"AWS::AutoScaling::AutoScalingGroup"},
+	"CodeDeployLambdaAliasUpdate": {"AWS::Lambda::Alias"},
+	"EnableVersionUpgrade":        {"AWS::Elasticsearch::Domain", "AWS::OpenSearchService::Domain"},
+	"UseOnlineResharding":         {"AWS::ElastiCache::ReplicationGroup"},
+}
+
+// validatePolicies checks that CreationPolicy and UpdatePolicy are only used
+// on resource types that support them.
+func validatePolicies(name string, resource wetwire.ResourceDef) []wetwire.SchemaError {
+	var errors []wetwire.SchemaError
+
+	policies := []struct {
+		attribute string
+		value     map[string]any
+		support   map[string][]string
+	}{
+		{"CreationPolicy", resource.CreationPolicy, creationPolicySupport},
+		{"UpdatePolicy", resource.UpdatePolicy, updatePolicySupport},
+	}
+	for _, p := range policies {
+		for _, key := range sortedKeys(p.value) {
+			types, known := p.support[key]
+			switch {
+			case !known:
+				errors = append(errors, wetwire.SchemaError{
+					Resource: name,
+					Property: p.attribute,
+					Message:  fmt.Sprintf("unknown %s setting: %s", p.attribute, key),
+				})
+			case !contains(types, resource.Type):
+				errors = append(errors, wetwire.SchemaError{
+					Resource: name,
+					Property: p.attribute,
+					Message:  fmt.Sprintf("%s.%s is not supported on %s (supported: %s)", p.attribute, key, resource.Type, strings.Join(types, ", ")),
+				})
+			}
+		}
+	}
+
+	return errors
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contains reports whether values contains s.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 // isValidResourceType checks if a resource type has valid format.
 func isValidResourceType(resourceType string) bool {
 	// CloudFormation resource types follow pattern: AWS::Service::Resource or Custom::*
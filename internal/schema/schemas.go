@@ -1,8 +1,7 @@
 package schema
 
-// resourceSchemas contains known CloudFormation resource schemas.
-// This is a subset of common resources - a complete implementation
-// would fetch schemas from AWS CloudFormation Registry.
+// resourceSchemas contains hand-written schemas for common resources.
+// They are used only for types missing from the bundled specification.
 var resourceSchemas = map[string]ResourceSchema{
 	"AWS::S3::Bucket": {
 		Type:     "AWS::S3::Bucket",
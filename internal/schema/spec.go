@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// bundledSpecData is the CloudFormation resource specification bundled at
+// build time. It is written by codegen (go run ./codegen) from the same
+// spec used to generate resources/, so validation works offline.
+//
+// The checked-in bundle was derived from the generated resources/ package:
+// it lists every resource type with its properties, nested property types,
+// required property types and GetAtt attributes. Codegen drops attributes
+// named like a property, so attribute lists also include the property names,
+// except for the types in knownAttributes. Primitive types, allowed values
+// and required primitives come from the hand-written schemas, and update
+// types from the fallback table, until codegen is run against the full
+// specification.
+//
+//go:embed spec.json.gz
+var bundledSpecData []byte
+
+// Spec is a compact form of the CloudFormation resource specification.
+type Spec struct {
+	// Version is the ResourceSpecificationVersion the bundle was built from
+	Version string `json:"Version,omitempty"`
+	// ResourceTypes maps "AWS::S3::Bucket" to its definition
+	ResourceTypes map[string]ResourceSpec `json:"ResourceTypes"`
+	// PropertyTypes maps "AWS::S3::Bucket.VersioningConfiguration" to its definition
+	PropertyTypes map[string]PropertyTypeSpec `json:"PropertyTypes"`
+}
+
+// ResourceSpec describes a resource type.
+type ResourceSpec struct {
+	Properties map[string]PropertySpec  `json:"Properties,omitempty"`
+	Attributes map[string]AttributeSpec `json:"Attributes,omitempty"`
+}
+
+// PropertyTypeSpec describes a nested property type.
+type PropertyTypeSpec struct {
+	Properties map[string]PropertySpec `json:"Properties,omitempty"`
+}
+
+// PropertySpec describes a single property and its constraints.
+type PropertySpec struct {
+	Required bool `json:"Required,omitempty"`
+	// PrimitiveType is String, Integer, Long, Double, Boolean, Timestamp or Json
+	PrimitiveType string `json:"PrimitiveType,omitempty"`
+	// Type is List, Map or the name of a property type
+	Type string `json:"Type,omitempty"`
+	// ItemType is the property type of List or Map items
+	ItemType string `json:"ItemType,omitempty"`
+	// PrimitiveItemType is the primitive type of List or Map items
+	PrimitiveItemType string `json:"PrimitiveItemType,omitempty"`
+	// UpdateType is Mutable, Conditional or Immutable: whether changing the
+	// property updates the resource in place, may replace it or replaces it
+	UpdateType string `json:"UpdateType,omitempty"`
+
+	AllowedValues []string `json:"AllowedValues,omitempty"`
+	Pattern       string   `json:"Pattern,omitempty"`
+	MinLength     *int     `json:"MinLength,omitempty"`
+	MaxLength     *int     `json:"MaxLength,omitempty"`
+	Minimum       *float64 `json:"Minimum,omitempty"`
+	Maximum       *float64 `json:"Maximum,omitempty"`
+}
+
+// AttributeSpec describes a Fn::GetAtt attribute.
+type AttributeSpec struct {
+	PrimitiveType     string `json:"PrimitiveType,omitempty"`
+	Type              string `json:"Type,omitempty"`
+	PrimitiveItemType string `json:"PrimitiveItemType,omitempty"`
+}
+
+var (
+	bundledSpec     *Spec
+	bundledSpecErr  error
+	bundledSpecOnce sync.Once
+)
+
+// BundledSpec returns the specification embedded in the binary.
+// The result is decoded once and shared; callers must not modify it.
+func BundledSpec() (*Spec, error) {
+	bundledSpecOnce.Do(func() {
+		bundledSpec, bundledSpecErr = LoadSpec(bytes.NewReader(bundledSpecData))
+	})
+	return bundledSpec, bundledSpecErr
+}
+
+// LoadSpec reads a gzip-compressed JSON specification bundle.
+func LoadSpec(r io.Reader) (*Spec, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	var spec Spec
+	if err := json.NewDecoder(gz).Decode(&spec); err != nil {
+		return nil, fmt.Errorf("decoding spec bundle: %w", err)
+	}
+	return &spec, nil
+}
+
+// WriteSpec writes a specification bundle in the format read by LoadSpec.
+func WriteSpec(w io.Writer, spec *Spec) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(spec); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// propertyType returns the nested property type definition for a resource
+// or property type, following CloudFormation naming ("AWS::S3::Bucket.Rule").
+// The shared "Tag" type is looked up without a parent prefix.
+func (s *Spec) propertyType(parent, name string) (PropertyTypeSpec, bool) {
+	if pt, ok := s.PropertyTypes[resourceTypeOf(parent)+"."+name]; ok {
+		return pt, true
+	}
+	pt, ok := s.PropertyTypes[name]
+	return pt, ok
+}
+
+// resourceTypeOf strips a property type suffix ("AWS::S3::Bucket.Rule" -> "AWS::S3::Bucket").
+func resourceTypeOf(typeName string) string {
+	if idx := strings.LastIndex(typeName, "."); idx >= 0 {
+		return typeName[:idx]
+	}
+	return typeName
+}
@@ -0,0 +1,332 @@
+package schema
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func intPtr(n int) *int { return &n }
+
+func testSpec() *Spec {
+	return &Spec{
+		ResourceTypes: map[string]ResourceSpec{
+			"AWS::S3::Bucket": {
+				Properties: map[string]PropertySpec{
+					"BucketName":              {PrimitiveType: "String", Pattern: "^[a-z0-9.-]+$", MinLength: intPtr(3), MaxLength: intPtr(63)},
+					"AccessControl":           {PrimitiveType: "String", AllowedValues: []string{"Private", "PublicRead"}},
+					"VersioningConfiguration": {Type: "VersioningConfiguration"},
+					"Tags":                    {Type: "List", ItemType: "Tag"},
+				},
+				Attributes: map[string]AttributeSpec{"Arn": {PrimitiveType: "String"}},
+			},
+			"AWS::SQS::Queue": {
+				Properties: map[string]PropertySpec{
+					"DelaySeconds": {PrimitiveType: "Integer"},
+					"FifoQueue":    {PrimitiveType: "Boolean"},
+				},
+			},
+		},
+		PropertyTypes: map[string]PropertyTypeSpec{
+			"AWS::S3::Bucket.VersioningConfiguration": {
+				Properties: map[string]PropertySpec{
+					"Status": {PrimitiveType: "String", Required: true},
+				},
+			},
+			"Tag": {
+				Properties: map[string]PropertySpec{
+					"Key":   {PrimitiveType: "String", Required: true},
+					"Value": {PrimitiveType: "String", Required: true},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateTemplate_Spec(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource wetwire.ResourceDef
+		property string
+		message  string
+	}{
+		{
+			name:     "pattern",
+			resource: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": "My_Bucket"}},
+			property: "BucketName",
+			message:  "does not match pattern",
+		},
+		{
+			name:     "max length",
+			resource: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"BucketName": string(bytes.Repeat([]byte("a"), 64))}},
+			property: "BucketName",
+			message:  "exceeds maximum 63",
+		},
+		{
+			name:     "enum",
+			resource: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"AccessControl": "Open"}},
+			property: "AccessControl",
+			message:  "not in allowed values",
+		},
+		{
+			name:     "nested required",
+			resource: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"VersioningConfiguration": map[string]any{}}},
+			property: "VersioningConfiguration.Status",
+			message:  "missing required property: Status",
+		},
+		{
+			name:     "list item type",
+			resource: wetwire.ResourceDef{Type: "AWS::S3::Bucket", Properties: map[string]any{"Tags": []any{map[string]any{"Key": "env"}}}},
+			property: "Tags[0].Value",
+			message:  "missing required property: Value",
+		},
+		{
+			name:     "integer",
+			resource: wetwire.ResourceDef{Type: "AWS::SQS::Queue", Properties: map[string]any{"DelaySeconds": 1.5}},
+			property: "DelaySeconds",
+			message:  "expected type Integer",
+		},
+		{
+			name:     "boolean",
+			resource: wetwire.ResourceDef{Type: "AWS::SQS::Queue", Properties: map[string]any{"FifoQueue": "yes"}},
+			property: "FifoQueue",
+			message:  "expected type Boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{"Res": tt.resource}}
+
+			result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+			require.NoError(t, err)
+
+			assert.False(t, result.Valid)
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, tt.property, result.Errors[0].Property)
+			assert.Contains(t, result.Errors[0].Message, tt.message)
+		})
+	}
+}
+
+func TestValidateTemplate_SpecValid(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"DataBucket": {
+				Type: "AWS::S3::Bucket",
+				Properties: map[string]any{
+					"BucketName":              map[string]any{"Fn::Sub": "${AWS::StackName}-data"},
+					"AccessControl":           "Private",
+					"VersioningConfiguration": map[string]any{"Status": "Enabled"},
+					"Tags":                    []any{map[string]any{"Key": "env", "Value": "prod"}},
+				},
+			},
+			"JobQueue": {
+				Type:       "AWS::SQS::Queue",
+				Properties: map[string]any{"DelaySeconds": float64(5), "FifoQueue": "true"},
+			},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, result.Warnings)
+}
+
+func TestValidateTemplate_ErrorPosition(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"DataBucket": {Type: "AWS::S3::Bucket", Properties: map[string]any{"AccessControl": "Open"}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{
+		Spec: testSpec(),
+		Resources: map[string]wetwire.DiscoveredResource{
+			"DataBucket": {Name: "DataBucket", File: "storage.go", Line: 12},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "storage.go", result.Errors[0].File)
+	assert.Equal(t, 12, result.Errors[0].Line)
+}
+
+func TestValidateTemplate_LanguageExtensions(t *testing.T) {
+	retainInProd := map[string]any{"Fn::If": []any{"IsProd", "Retain", "Delete"}}
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"DataBucket": {Type: "AWS::S3::Bucket", DeletionPolicy: retainInProd},
+			"Fn::ForEach::Topics": {ForEach: []any{
+				"TopicName",
+				[]any{"Success"},
+				map[string]any{"Topic${TopicName}": map[string]any{"Type": "AWS::SNS::Topic"}},
+			}},
+		},
+		Outputs: map[string]wetwire.Output{
+			"SuccessTopic": {Value: map[string]any{"Ref": "TopicSuccess"}},
+		},
+	}
+
+	// Intrinsic policies need the transform
+	result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "DeletionPolicy", result.Errors[0].Property)
+	assert.Contains(t, result.Errors[0].Message, "need the AWS::LanguageExtensions transform")
+
+	// Loops are not validated as resources, and references to their
+	// resources are allowed
+	tmpl.Transform = wetwire.Transforms{wetwire.TransformLanguageExtensions}
+	result, err = ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+}
+
+func TestValidateTemplate_LoopKeys(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Transform: wetwire.Transforms{wetwire.TransformLanguageExtensions},
+		Resources: map[string]wetwire.ResourceDef{
+			"Fn::ForEach::Buckets": {Type: "AWS::S3::Bucket"},
+			"Topics": {ForEach: []any{
+				"TopicName",
+				[]any{"Success"},
+				map[string]any{"Topic${TopicName}": map[string]any{"Type": "AWS::SNS::Topic"}},
+			}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "Fn::ForEach::Buckets", result.Errors[0].Resource)
+	assert.Contains(t, result.Errors[0].Message, "Fn::ForEach takes an identifier")
+	assert.Equal(t, "Topics", result.Errors[1].Resource)
+	assert.Contains(t, result.Errors[1].Message, "only keys starting with Fn::ForEach:: hold loops")
+}
+
+func TestValidateTemplate_MalformedLoop(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Transform: wetwire.Transforms{wetwire.TransformLanguageExtensions},
+		Resources: map[string]wetwire.ResourceDef{
+			"Fn::ForEach::Topics": {ForEach: []any{"Name"}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Fn::ForEach::Topics", result.Errors[0].Resource)
+	assert.Contains(t, result.Errors[0].Message, "Fn::ForEach takes an identifier")
+}
+
+func TestValidateTemplate_CreationPolicy(t *testing.T) {
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Fleet":   {Type: "AWS::AppStream::Fleet", CreationPolicy: map[string]any{"StartFleet": true}},
+			"Builder": {Type: "AWS::AppStream::ImageBuilder", CreationPolicy: map[string]any{"StartFleet": true}},
+			"SignaledFleet": {Type: "AWS::AppStream::Fleet", CreationPolicy: map[string]any{
+				"ResourceSignal": map[string]any{"Count": 1},
+			}},
+			"Server": {Type: "AWS::EC2::Instance", CreationPolicy: map[string]any{"StartFleet": true}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: testSpec()})
+	require.NoError(t, err)
+
+	var messages []string
+	for _, e := range result.Errors {
+		if e.Property == "CreationPolicy" {
+			messages = append(messages, e.Resource+": "+e.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		"Server: CreationPolicy.StartFleet is not supported on AWS::EC2::Instance (supported: AWS::AppStream::Fleet, AWS::AppStream::ImageBuilder)",
+		"SignaledFleet: CreationPolicy.ResourceSignal is not supported on AWS::AppStream::Fleet (supported: AWS::AutoScaling::AutoScalingGroup, AWS::CloudFormation::WaitCondition, AWS::EC2::Instance)",
+	}, messages)
+}
+
+func TestValidateTemplate_FallbackSchemas(t *testing.T) {
+	// Types missing from the spec use the hand-written schemas
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Fn": {Type: "AWS::Lambda::Function", Properties: map[string]any{}},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Spec: &Spec{}})
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "Role", result.Errors[0].Property)
+}
+
+func TestSpec_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteSpec(&buf, testSpec()))
+
+	spec, err := LoadSpec(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, testSpec(), spec)
+}
+
+func TestBundledSpec(t *testing.T) {
+	spec, err := BundledSpec()
+	require.NoError(t, err)
+	require.NotNil(t, spec)
+
+	bucket, ok := spec.ResourceTypes["AWS::S3::Bucket"]
+	require.True(t, ok, "bundle has no AWS::S3::Bucket")
+	assert.Contains(t, bucket.Properties, "BucketName")
+	assert.Contains(t, bucket.Attributes, "Arn")
+	assert.Contains(t, spec.PropertyTypes, "AWS::S3::Bucket.VersioningConfiguration")
+
+	tmpl := &wetwire.Template{
+		Resources: map[string]wetwire.ResourceDef{
+			"Bucket": {
+				Type: "AWS::S3::Bucket",
+				Properties: map[string]any{
+					"BucketName":  "data",
+					"BucketColor": "blue",
+				},
+			},
+		},
+	}
+
+	result, err := ValidateTemplate(tmpl, Options{Strict: true})
+	require.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	require.Len(t, result.Warnings, 1)
+	assert.Equal(t, "BucketColor", result.Warnings[0].Property)
+	assert.Contains(t, result.Warnings[0].Message, "unknown property")
+}
+
+func TestSpec_UpdateType(t *testing.T) {
+	spec := testSpec()
+	spec.ResourceTypes["AWS::SQS::Queue"].Properties["DelaySeconds"] = PropertySpec{PrimitiveType: "Integer", UpdateType: UpdateMutable}
+
+	tests := []struct {
+		resourceType, property, want string
+	}{
+		{"AWS::SQS::Queue", "DelaySeconds", UpdateMutable},
+		// The fallback table covers properties the bundle has no update type for
+		{"AWS::SQS::Queue", "FifoQueue", UpdateImmutable},
+		{"AWS::RDS::DBInstance", "DBInstanceIdentifier", UpdateImmutable},
+		{"AWS::RDS::DBInstance", "Engine", UpdateConditional},
+		{"AWS::RDS::DBInstance", "AllocatedStorage", ""},
+		{"AWS::Custom::Thing", "Name", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, spec.UpdateType(tt.resourceType, tt.property), "%s.%s", tt.resourceType, tt.property)
+	}
+}
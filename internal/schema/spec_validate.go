@@ -0,0 +1,267 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// specValidator validates resource properties against a Spec.
+type specValidator struct {
+	spec     *Spec
+	resource string
+	opts     Options
+	errors   []wetwire.SchemaError
+	warnings []wetwire.SchemaError
+}
+
+// validateAgainstSpec validates a resource's properties, recursing into
+// nested property types, lists and maps.
+func validateAgainstSpec(name string, resource wetwire.ResourceDef, rs ResourceSpec, spec *Spec, opts Options) ([]wetwire.SchemaError, []wetwire.SchemaError) {
+	v := &specValidator{spec: spec, resource: name, opts: opts}
+	v.validateProperties(resource.Type, resource.Properties, rs.Properties, "")
+	return v.errors, v.warnings
+}
+
+func (v *specValidator) errorf(property, format string, args ...any) {
+	v.errors = append(v.errors, wetwire.SchemaError{
+		Resource: v.resource,
+		Property: property,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// validateProperties validates an object against the property definitions of
+// typeName, which is a resource type or a qualified property type name.
+func (v *specValidator) validateProperties(typeName string, values map[string]any, props map[string]PropertySpec, path string) {
+	names := make([]string, 0, len(props))
+	for propName := range props {
+		names = append(names, propName)
+	}
+	sort.Strings(names)
+
+	for _, propName := range names {
+		if props[propName].Required {
+			if _, exists := values[propName]; !exists {
+				v.errorf(joinPath(path, propName), "missing required property: %s", propName)
+			}
+		}
+	}
+
+	for _, propName := range sortedKeys(values) {
+		propSpec, ok := props[propName]
+		if !ok {
+			if v.opts.Strict {
+				v.warnings = append(v.warnings, wetwire.SchemaError{
+					Resource: v.resource,
+					Property: joinPath(path, propName),
+					Message:  fmt.Sprintf("unknown property: %s", propName),
+				})
+			}
+			continue
+		}
+		v.validateValue(typeName, joinPath(path, propName), values[propName], propSpec)
+	}
+}
+
+// validateValue validates a single value against its property definition.
+func (v *specValidator) validateValue(typeName, path string, value any, prop PropertySpec) {
+	if isIntrinsic(value) {
+		return
+	}
+
+	switch {
+	case prop.PrimitiveType != "":
+		v.validatePrimitive(path, value, prop.PrimitiveType, prop)
+
+	case prop.Type == "List":
+		items, ok := value.([]any)
+		if !ok {
+			v.errorf(path, "expected type List")
+			return
+		}
+		for i, item := range items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if isIntrinsic(item) {
+				continue
+			}
+			if prop.ItemType != "" {
+				v.validateNested(typeName, itemPath, item, prop.ItemType)
+			} else if prop.PrimitiveItemType != "" {
+				v.validatePrimitive(itemPath, item, prop.PrimitiveItemType, PropertySpec{})
+			}
+		}
+
+	case prop.Type == "Map":
+		entries, ok := value.(map[string]any)
+		if !ok {
+			v.errorf(path, "expected type Map")
+			return
+		}
+		for _, key := range sortedKeys(entries) {
+			entryPath := path + "." + key
+			if isIntrinsic(entries[key]) {
+				continue
+			}
+			if prop.ItemType != "" {
+				v.validateNested(typeName, entryPath, entries[key], prop.ItemType)
+			} else if prop.PrimitiveItemType != "" {
+				v.validatePrimitive(entryPath, entries[key], prop.PrimitiveItemType, PropertySpec{})
+			}
+		}
+
+	case prop.Type != "":
+		v.validateNested(typeName, path, value, prop.Type)
+	}
+}
+
+// validateNested validates an object against a named property type.
+func (v *specValidator) validateNested(typeName, path string, value any, propType string) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		v.errorf(path, "expected %s object", propType)
+		return
+	}
+	pt, ok := v.spec.propertyType(typeName, propType)
+	if !ok {
+		return
+	}
+	v.validateProperties(resourceTypeOf(typeName)+"."+propType, obj, pt.Properties, path)
+}
+
+// validatePrimitive checks a primitive value's type and constraints.
+func (v *specValidator) validatePrimitive(path string, value any, primitive string, prop PropertySpec) {
+	switch primitive {
+	case "String", "Timestamp":
+		s, ok := primitiveString(value)
+		if !ok {
+			v.errorf(path, "expected type %s", primitive)
+			return
+		}
+		v.validateString(path, s, prop)
+	case "Integer", "Long":
+		n, ok := primitiveNumber(value)
+		if !ok || n != float64(int64(n)) {
+			v.errorf(path, "expected type %s", primitive)
+			return
+		}
+		v.validateNumber(path, n, prop)
+	case "Double":
+		n, ok := primitiveNumber(value)
+		if !ok {
+			v.errorf(path, "expected type %s", primitive)
+			return
+		}
+		v.validateNumber(path, n, prop)
+	case "Boolean":
+		switch b := value.(type) {
+		case bool:
+		case string:
+			if b != "true" && b != "false" {
+				v.errorf(path, "expected type Boolean")
+			}
+		default:
+			v.errorf(path, "expected type Boolean")
+		}
+	}
+}
+
+func (v *specValidator) validateString(path, s string, prop PropertySpec) {
+	if len(prop.AllowedValues) > 0 && !contains(prop.AllowedValues, s) {
+		v.errorf(path, "value %q not in allowed values: %v", s, prop.AllowedValues)
+	}
+	if prop.MinLength != nil && len(s) < *prop.MinLength {
+		v.errorf(path, "length %d is less than minimum %d", len(s), *prop.MinLength)
+	}
+	if prop.MaxLength != nil && len(s) > *prop.MaxLength {
+		v.errorf(path, "length %d exceeds maximum %d", len(s), *prop.MaxLength)
+	}
+	if prop.Pattern != "" {
+		if re := compilePattern(prop.Pattern); re != nil && !re.MatchString(s) {
+			v.errorf(path, "value %q does not match pattern %s", s, prop.Pattern)
+		}
+	}
+}
+
+func (v *specValidator) validateNumber(path string, n float64, prop PropertySpec) {
+	if prop.Minimum != nil && n < *prop.Minimum {
+		v.errorf(path, "value %v is less than minimum %v", n, *prop.Minimum)
+	}
+	if prop.Maximum != nil && n > *prop.Maximum {
+		v.errorf(path, "value %v exceeds maximum %v", n, *prop.Maximum)
+	}
+}
+
+// primitiveString accepts strings and the scalars CloudFormation coerces to strings.
+func primitiveString(value any) (string, bool) {
+	switch s := value.(type) {
+	case string:
+		return s, true
+	case bool, int, int64, float64:
+		return fmt.Sprintf("%v", s), true
+	}
+	return "", false
+}
+
+// primitiveNumber accepts numbers and numeric strings.
+func primitiveNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// isIntrinsic reports whether value is a Ref or Fn:: intrinsic, which can
+// only be checked once the stack is deployed.
+func isIntrinsic(value any) bool {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 1 {
+		return false
+	}
+	for key := range m {
+		return key == "Ref" || strings.HasPrefix(key, "Fn::")
+	}
+	return false
+}
+
+// joinPath appends a property name to a dotted property path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+var (
+	patternCache   = make(map[string]*regexp.Regexp)
+	patternCacheMu sync.Mutex
+)
+
+// compilePattern compiles and caches a spec pattern. Patterns Go's regexp
+// cannot compile (e.g. lookaheads) are skipped and return nil.
+func compilePattern(pattern string) *regexp.Regexp {
+	patternCacheMu.Lock()
+	defer patternCacheMu.Unlock()
+	if re, ok := patternCache[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	patternCache[pattern] = re
+	return re
+}
@@ -0,0 +1,131 @@
+package schema
+
+// Update types of the CloudFormation resource specification.
+const (
+	// UpdateMutable properties are updated in place.
+	UpdateMutable = "Mutable"
+	// UpdateConditional properties may replace the resource, depending on
+	// the old and new values.
+	UpdateConditional = "Conditional"
+	// UpdateImmutable properties replace the resource when changed.
+	UpdateImmutable = "Immutable"
+)
+
+// UpdateType returns the update type of a top-level resource property, or ""
+// when neither the specification nor the hand-written table knows it.
+func (s *Spec) UpdateType(resourceType, property string) string {
+	if res, ok := s.ResourceTypes[resourceType]; ok {
+		if prop, ok := res.Properties[property]; ok && prop.UpdateType != "" {
+			return prop.UpdateType
+		}
+	}
+	return updateTypes[resourceType][property]
+}
+
+// updateTypes lists the properties of common resource types that replace or
+// may replace the resource. It is used only when the bundled specification
+// does not cover a type; properties missing here have no known update type.
+var updateTypes = map[string]map[string]string{
+	"AWS::S3::Bucket": {
+		"BucketName": UpdateImmutable,
+	},
+	"AWS::RDS::DBInstance": {
+		"AvailabilityZone":     UpdateConditional,
+		"CharacterSetName":     UpdateImmutable,
+		"DBClusterIdentifier":  UpdateImmutable,
+		"DBInstanceIdentifier": UpdateImmutable,
+		"DBName":               UpdateImmutable,
+		"DBSnapshotIdentifier": UpdateImmutable,
+		"Engine":               UpdateConditional,
+		"KmsKeyId":             UpdateImmutable,
+		"MasterUsername":       UpdateImmutable,
+		"StorageEncrypted":     UpdateConditional,
+	},
+	"AWS::RDS::DBCluster": {
+		"AvailabilityZones":   UpdateImmutable,
+		"DBClusterIdentifier": UpdateImmutable,
+		"DatabaseName":        UpdateImmutable,
+		"Engine":              UpdateImmutable,
+		"EngineMode":          UpdateImmutable,
+		"KmsKeyId":            UpdateImmutable,
+		"MasterUsername":      UpdateImmutable,
+		"SnapshotIdentifier":  UpdateImmutable,
+		"StorageEncrypted":    UpdateImmutable,
+	},
+	"AWS::DynamoDB::Table": {
+		"KeySchema":             UpdateImmutable,
+		"LocalSecondaryIndexes": UpdateImmutable,
+		"TableName":             UpdateImmutable,
+	},
+	"AWS::DynamoDB::GlobalTable": {
+		"KeySchema":             UpdateImmutable,
+		"LocalSecondaryIndexes": UpdateImmutable,
+		"TableName":             UpdateImmutable,
+	},
+	"AWS::EFS::FileSystem": {
+		"AvailabilityZoneName": UpdateImmutable,
+		"Encrypted":            UpdateImmutable,
+		"KmsKeyId":             UpdateImmutable,
+		"PerformanceMode":      UpdateImmutable,
+	},
+	"AWS::ElastiCache::ReplicationGroup": {
+		"AtRestEncryptionEnabled": UpdateImmutable,
+		"KmsKeyId":                UpdateImmutable,
+		"ReplicationGroupId":      UpdateImmutable,
+	},
+	"AWS::OpenSearchService::Domain": {
+		"DomainName": UpdateImmutable,
+	},
+	"AWS::Kinesis::Stream": {
+		"Name": UpdateImmutable,
+	},
+	"AWS::SQS::Queue": {
+		"FifoQueue": UpdateImmutable,
+		"QueueName": UpdateImmutable,
+	},
+	"AWS::SNS::Topic": {
+		"FifoTopic": UpdateImmutable,
+		"TopicName": UpdateImmutable,
+	},
+	"AWS::Logs::LogGroup": {
+		"LogGroupName": UpdateImmutable,
+	},
+	"AWS::ECR::Repository": {
+		"EncryptionConfiguration": UpdateImmutable,
+		"RepositoryName":          UpdateImmutable,
+	},
+	"AWS::SecretsManager::Secret": {
+		"Name": UpdateImmutable,
+	},
+	"AWS::Lambda::Function": {
+		"FunctionName": UpdateImmutable,
+		"PackageType":  UpdateImmutable,
+	},
+	"AWS::IAM::Role": {
+		"Path":     UpdateImmutable,
+		"RoleName": UpdateImmutable,
+	},
+	"AWS::EC2::VPC": {
+		"CidrBlock":       UpdateImmutable,
+		"InstanceTenancy": UpdateConditional,
+	},
+	"AWS::EC2::Subnet": {
+		"AvailabilityZone": UpdateImmutable,
+		"CidrBlock":        UpdateImmutable,
+		"VpcId":            UpdateImmutable,
+	},
+	"AWS::EC2::SecurityGroup": {
+		"GroupDescription": UpdateImmutable,
+		"GroupName":        UpdateImmutable,
+		"VpcId":            UpdateImmutable,
+	},
+	"AWS::EC2::Instance": {
+		"AvailabilityZone": UpdateImmutable,
+		"ImageId":          UpdateImmutable,
+		"InstanceType":     UpdateConditional,
+		"KeyName":          UpdateImmutable,
+		"SecurityGroups":   UpdateImmutable,
+		"SubnetId":         UpdateImmutable,
+		"UserData":         UpdateConditional,
+	},
+}
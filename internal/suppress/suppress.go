@@ -0,0 +1,233 @@
+// Package suppress parses //wetwire:ignore comments that silence lint rules
+// and optimizer suggestions.
+//
+// A line directive names one or more rule IDs followed by an optional reason:
+//
+//	//wetwire:ignore WAW019 test fixture, not a real key
+//	var Secret = secretsmanager.Secret{...}
+//
+//	var Bucket = s3.Bucket{
+//		BucketName: "AWS::Region", //wetwire:ignore WAW001 literal name
+//	}
+//
+// A directive on its own line applies to the declaration or field that
+// follows it; a trailing directive applies to the declaration or field that
+// starts on its line, or to that line alone. A file directive applies to the
+// whole file:
+//
+//	//wetwire:ignore-file WAW004
+//
+// IDs may be separated by spaces or commas; the reason starts at the first
+// word that is not a rule ID.
+package suppress
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// Directive prefixes.
+const (
+	LineDirective = "//wetwire:ignore"
+	FileDirective = "//wetwire:ignore-file"
+)
+
+// ruleID matches lint and optimizer rule IDs such as WAW019 or OPT-S3-001.
+var ruleID = regexp.MustCompile(`^(WAW\d+|OPT(-[A-Z0-9]+)+)$`)
+
+// Suppression is a single //wetwire:ignore directive.
+type Suppression struct {
+	// Rules are the rule IDs the directive silences.
+	Rules []string
+	// Reason is the free text after the rule IDs.
+	Reason string
+	// File and Line locate the comment.
+	File string
+	Line int
+	// Column is the column of the comment.
+	Column int
+	// FileLevel is true for //wetwire:ignore-file.
+	FileLevel bool
+	// StartLine and EndLine are the lines covered by a line directive.
+	StartLine int
+	EndLine   int
+
+	used map[string]bool
+}
+
+// covers reports whether the suppression applies to line.
+func (s *Suppression) covers(line int) bool {
+	return s.FileLevel || (line >= s.StartLine && line <= s.EndLine)
+}
+
+// Set holds the suppressions of one file.
+type Set struct {
+	Suppressions []*Suppression
+}
+
+// Unused is a rule ID in a suppression that silenced nothing.
+type Unused struct {
+	Suppression *Suppression
+	Rule        string
+}
+
+// Parse collects the suppressions in file, which must have been parsed
+// with parser.ParseComments.
+func Parse(file *ast.File, fset *token.FileSet) *Set {
+	set := &Set{}
+	var lines *nodeLines
+
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			s := parseDirective(c.Text)
+			if s == nil {
+				continue
+			}
+
+			pos := fset.Position(c.Pos())
+			s.File = pos.Filename
+			s.Line = pos.Line
+			s.Column = pos.Column
+
+			if !s.FileLevel {
+				if lines == nil {
+					lines = collectNodeLines(file, fset)
+				}
+				s.StartLine, s.EndLine = lines.target(pos.Line, fset.Position(group.End()).Line)
+			}
+			set.Suppressions = append(set.Suppressions, s)
+		}
+	}
+
+	return set
+}
+
+// ParseFile parses the Go file at path and collects its suppressions.
+func ParseFile(path string) (*Set, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(file, fset), nil
+}
+
+// parseDirective parses a directive comment, returning nil for other comments.
+func parseDirective(text string) *Suppression {
+	s := &Suppression{used: make(map[string]bool)}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(text, FileDirective):
+		s.FileLevel = true
+		rest = strings.TrimPrefix(text, FileDirective)
+	case strings.HasPrefix(text, LineDirective):
+		rest = strings.TrimPrefix(text, LineDirective)
+	default:
+		return nil
+	}
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		// e.g. //wetwire:ignored
+		return nil
+	}
+
+	fields := strings.Fields(strings.ReplaceAll(rest, ",", " "))
+	i := 0
+	for ; i < len(fields) && ruleID.MatchString(fields[i]); i++ {
+		s.Rules = append(s.Rules, fields[i])
+	}
+	s.Reason = strings.Join(fields[i:], " ")
+
+	return s
+}
+
+// Match reports whether an issue for rule on line is suppressed, and records
+// the suppression as used.
+func (s *Set) Match(rule string, line int) bool {
+	if s == nil {
+		return false
+	}
+
+	matched := false
+	for _, sup := range s.Suppressions {
+		if !sup.covers(line) {
+			continue
+		}
+		for _, id := range sup.Rules {
+			if id == rule {
+				sup.used[id] = true
+				matched = true
+			}
+		}
+	}
+	return matched
+}
+
+// Unused returns the rule IDs of suppressions that matched nothing and for
+// which judge returns true. judge limits the report to rules that were
+// actually checked.
+func (s *Set) Unused(judge func(rule string) bool) []Unused {
+	if s == nil {
+		return nil
+	}
+
+	var unused []Unused
+	for _, sup := range s.Suppressions {
+		for _, id := range sup.Rules {
+			if !sup.used[id] && judge(id) {
+				unused = append(unused, Unused{Suppression: sup, Rule: id})
+			}
+		}
+	}
+	return unused
+}
+
+// IsOptimizerRule reports whether id names an optimizer rule.
+func IsOptimizerRule(id string) bool {
+	return strings.HasPrefix(id, "OPT-")
+}
+
+// nodeLines records, by line, the extent of the declarations and fields
+// starting on that line and which lines contain code.
+type nodeLines struct {
+	extent map[int]int
+	code   map[int]bool
+}
+
+func collectNodeLines(file *ast.File, fset *token.FileSet) *nodeLines {
+	lines := &nodeLines{extent: make(map[int]int), code: make(map[int]bool)}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.Comment, *ast.CommentGroup:
+			return n != nil
+		case *ast.GenDecl, *ast.ValueSpec, *ast.KeyValueExpr, *ast.Field, *ast.FuncDecl:
+			start := fset.Position(n.Pos()).Line
+			end := fset.Position(n.End()).Line
+			if end > lines.extent[start] {
+				lines.extent[start] = end
+			}
+		}
+		lines.code[fset.Position(n.Pos()).Line] = true
+		lines.code[fset.Position(n.End()).Line] = true
+		return true
+	})
+
+	return lines
+}
+
+// target returns the lines covered by a directive on line that belongs to a
+// comment group ending on groupEnd.
+func (l *nodeLines) target(line, groupEnd int) (int, int) {
+	if !l.code[line] {
+		// On its own line: applies to what follows the comment group
+		line = groupEnd + 1
+	}
+	if end, ok := l.extent[line]; ok {
+		return line, end
+	}
+	return line, line
+}
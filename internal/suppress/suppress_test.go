@@ -0,0 +1,108 @@
+package suppress
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const source = `//wetwire:ignore-file WAW004
+package p
+
+//wetwire:ignore WAW019, WAW001 test fixture
+var Secret = secrets.Secret{
+	Value: "x",
+}
+
+var Bucket = s3.Bucket{
+	BucketName: "AWS::Region", //wetwire:ignore WAW001 literal name
+	//wetwire:ignore OPT-S3-001
+	Versioning: &s3.Bucket_Versioning{
+		Status: "Enabled",
+	},
+	Tags: nil, //wetwire:ignored not a directive
+}
+
+var Plain = "x" //wetwire:ignore
+`
+
+func parseSource(t *testing.T) *Set {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", source, parser.ParseComments)
+	require.NoError(t, err)
+	return Parse(file, fset)
+}
+
+func TestParse(t *testing.T) {
+	set := parseSource(t)
+	require.Len(t, set.Suppressions, 5)
+
+	file := set.Suppressions[0]
+	assert.True(t, file.FileLevel)
+	assert.Equal(t, []string{"WAW004"}, file.Rules)
+
+	decl := set.Suppressions[1]
+	assert.Equal(t, []string{"WAW019", "WAW001"}, decl.Rules)
+	assert.Equal(t, "test fixture", decl.Reason)
+	assert.Equal(t, 4, decl.Line)
+	assert.Equal(t, 5, decl.StartLine)
+	assert.Equal(t, 7, decl.EndLine)
+
+	trailing := set.Suppressions[2]
+	assert.Equal(t, "literal name", trailing.Reason)
+	assert.Equal(t, 10, trailing.StartLine)
+	assert.Equal(t, 10, trailing.EndLine)
+
+	field := set.Suppressions[3]
+	assert.Equal(t, []string{"OPT-S3-001"}, field.Rules)
+	assert.Equal(t, 12, field.StartLine)
+	assert.Equal(t, 14, field.EndLine)
+
+	bare := set.Suppressions[4]
+	assert.Empty(t, bare.Rules)
+	assert.Equal(t, 18, bare.StartLine)
+}
+
+func TestSet_Match(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		line int
+		want bool
+	}{
+		{name: "file level", rule: "WAW004", line: 9, want: true},
+		{name: "declaration", rule: "WAW019", line: 6, want: true},
+		{name: "second id", rule: "WAW001", line: 5, want: true},
+		{name: "other rule on declaration", rule: "WAW002", line: 6, want: false},
+		{name: "trailing", rule: "WAW001", line: 10, want: true},
+		{name: "trailing does not cover next line", rule: "WAW001", line: 11, want: false},
+		{name: "field below directive", rule: "OPT-S3-001", line: 13, want: true},
+		{name: "outside field", rule: "OPT-S3-001", line: 15, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseSource(t).Match(tt.rule, tt.line))
+		})
+	}
+}
+
+func TestSet_Unused(t *testing.T) {
+	set := parseSource(t)
+	set.Match("WAW019", 5)
+	set.Match("WAW001", 10)
+
+	var unused []string
+	for _, u := range set.Unused(func(id string) bool { return !IsOptimizerRule(id) }) {
+		unused = append(unused, u.Rule)
+	}
+	assert.Equal(t, []string{"WAW004", "WAW001"}, unused)
+
+	var nilSet *Set
+	assert.False(t, nilSet.Match("WAW001", 1))
+	assert.Empty(t, nilSet.Unused(func(string) bool { return true }))
+}
@@ -0,0 +1,174 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// ApplyAliases gives resources the logical IDs in aliases, which maps a
+// resource's name (its Go variable) to the logical ID to keep, and rewrites
+// every Ref, Fn::GetAtt, Fn::Sub and DependsOn that refers to them. A Go
+// variable can then be renamed without CloudFormation replacing the resource.
+func ApplyAliases(t *wetwire.Template, aliases map[string]string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	taken := make(map[string]string)
+	for _, name := range names {
+		id := aliases[name]
+		if _, ok := t.Resources[name]; !ok {
+			return fmt.Errorf("alias %s: no resource named %s", id, name)
+		}
+		if other, ok := taken[id]; ok {
+			return fmt.Errorf("alias %s: used for both %s and %s", id, other, name)
+		}
+		taken[id] = name
+		if _, isResource := t.Resources[id]; isResource && aliases[id] == "" {
+			return fmt.Errorf("alias %s for %s: a resource already has that logical ID", id, name)
+		}
+		if _, isParam := t.Parameters[id]; isParam {
+			return fmt.Errorf("alias %s for %s: a parameter already has that logical ID", id, name)
+		}
+	}
+
+	resources := make(map[string]wetwire.ResourceDef, len(t.Resources))
+	for name, def := range t.Resources {
+		def.Properties = renameMap(def.Properties, aliases)
+		def.Metadata = renameMap(def.Metadata, aliases)
+		def.CreationPolicy = renameMap(def.CreationPolicy, aliases)
+		def.UpdatePolicy = renameMap(def.UpdatePolicy, aliases)
+		if len(def.DependsOn) > 0 {
+			dependsOn := make([]string, len(def.DependsOn))
+			for i, dep := range def.DependsOn {
+				dependsOn[i] = aliased(dep, aliases)
+			}
+			def.DependsOn = dependsOn
+		}
+		resources[aliased(name, aliases)] = def
+	}
+	t.Resources = resources
+
+	for name, out := range t.Outputs {
+		out.Value = renameReferences(out.Value, aliases)
+		t.Outputs[name] = out
+	}
+	for name, cond := range t.Conditions {
+		t.Conditions[name] = renameReferences(cond, aliases)
+	}
+	return nil
+}
+
+// aliased returns the logical ID for name.
+func aliased(name string, aliases map[string]string) string {
+	if id, ok := aliases[name]; ok {
+		return id
+	}
+	return name
+}
+
+// renameMap applies renameReferences to a map, keeping nil maps nil.
+func renameMap(m map[string]any, aliases map[string]string) map[string]any {
+	if m == nil {
+		return nil
+	}
+	return renameReferences(m, aliases).(map[string]any)
+}
+
+// renameReferences returns v with the resource references in Ref,
+// Fn::GetAtt and Fn::Sub renamed.
+func renameReferences(v any, aliases map[string]string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 1 {
+			if ref, ok := val["Ref"].(string); ok {
+				return map[string]any{"Ref": aliased(ref, aliases)}
+			}
+			if getAtt, ok := val["Fn::GetAtt"]; ok {
+				return map[string]any{"Fn::GetAtt": renameGetAtt(getAtt, aliases)}
+			}
+			if sub, ok := val["Fn::Sub"]; ok {
+				return map[string]any{"Fn::Sub": renameSub(sub, aliases)}
+			}
+		}
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			result[k] = renameReferences(item, aliases)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = renameReferences(item, aliases)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// renameGetAtt renames the resource of a Fn::GetAtt in list or "Name.Attr" form.
+func renameGetAtt(getAtt any, aliases map[string]string) any {
+	switch val := getAtt.(type) {
+	case []string:
+		if len(val) > 0 {
+			return append([]string{aliased(val[0], aliases)}, val[1:]...)
+		}
+	case []any:
+		if len(val) > 0 {
+			if name, ok := val[0].(string); ok {
+				return append([]any{aliased(name, aliases)}, val[1:]...)
+			}
+		}
+	case string:
+		name, attr, found := strings.Cut(val, ".")
+		if found {
+			return aliased(name, aliases) + "." + attr
+		}
+		return aliased(val, aliases)
+	}
+	return getAtt
+}
+
+// renameSub renames ${Name} references in a Fn::Sub string, or in the string
+// of a [string, variables] pair except for names bound by the variables.
+func renameSub(sub any, aliases map[string]string) any {
+	switch val := sub.(type) {
+	case string:
+		return renameSubString(val, aliases, nil)
+	case []any:
+		if len(val) != 2 {
+			return sub
+		}
+		str, ok := val[0].(string)
+		if !ok {
+			return sub
+		}
+		vars, _ := val[1].(map[string]any)
+		return []any{renameSubString(str, aliases, vars), renameReferences(val[1], aliases)}
+	}
+	return sub
+}
+
+// renameSubString renames the references in a Fn::Sub template string.
+func renameSubString(s string, aliases map[string]string, vars map[string]any) string {
+	return ReplaceSubReferences(s, vars, func(ref SubReference) (string, bool) {
+		id, ok := aliases[ref.Name]
+		if !ok {
+			return "", false
+		}
+		if ref.Attr != "" {
+			id += "." + ref.Attr
+		}
+		return "${" + id + "}", true
+	})
+}
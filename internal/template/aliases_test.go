@@ -0,0 +1,92 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func aliasTemplate() *wetwire.Template {
+	return &wetwire.Template{
+		Parameters: map[string]wetwire.Parameter{"Environment": {Type: "String"}},
+		Resources: map[string]wetwire.ResourceDef{
+			"DataBucket": {Type: "AWS::S3::Bucket"},
+			"Reader": {
+				Type: "AWS::Lambda::Function",
+				Properties: map[string]any{
+					"Environment": map[string]any{"Variables": map[string]any{
+						"BUCKET": map[string]any{"Ref": "DataBucket"},
+						"ARN":    map[string]any{"Fn::GetAtt": []string{"DataBucket", "Arn"}},
+						"DOMAIN": map[string]any{"Fn::GetAtt": "DataBucket.DomainName"},
+						"URL":    map[string]any{"Fn::Sub": "s3://${DataBucket}/${Environment}/${DataBucket.Arn}"},
+						"BOUND": map[string]any{"Fn::Sub": []any{
+							"${DataBucket}-${Other}",
+							map[string]any{"DataBucket": "literal", "Other": map[string]any{"Ref": "DataBucket"}},
+						}},
+					}},
+				},
+				DependsOn: []string{"DataBucket"},
+			},
+		},
+		Outputs: map[string]wetwire.Output{
+			"BucketArn": {Value: map[string]any{"Fn::GetAtt": []any{"DataBucket", "Arn"}}},
+		},
+	}
+}
+
+func TestApplyAliases(t *testing.T) {
+	tmpl := aliasTemplate()
+	require.NoError(t, ApplyAliases(tmpl, map[string]string{"DataBucket": "LogsBucket"}))
+
+	assert.Contains(t, tmpl.Resources, "LogsBucket")
+	assert.NotContains(t, tmpl.Resources, "DataBucket")
+
+	reader := tmpl.Resources["Reader"]
+	assert.Equal(t, []string{"LogsBucket"}, reader.DependsOn)
+
+	vars := reader.Properties["Environment"].(map[string]any)["Variables"].(map[string]any)
+	assert.Equal(t, map[string]any{"Ref": "LogsBucket"}, vars["BUCKET"])
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []string{"LogsBucket", "Arn"}}, vars["ARN"])
+	assert.Equal(t, map[string]any{"Fn::GetAtt": "LogsBucket.DomainName"}, vars["DOMAIN"])
+	assert.Equal(t, map[string]any{"Fn::Sub": "s3://${LogsBucket}/${Environment}/${LogsBucket.Arn}"}, vars["URL"])
+	// Names bound by the Fn::Sub variables are left alone
+	assert.Equal(t, map[string]any{"Fn::Sub": []any{
+		"${DataBucket}-${Other}",
+		map[string]any{"DataBucket": "literal", "Other": map[string]any{"Ref": "LogsBucket"}},
+	}}, vars["BOUND"])
+
+	assert.Equal(t, map[string]any{"Fn::GetAtt": []any{"LogsBucket", "Arn"}}, tmpl.Outputs["BucketArn"].Value)
+}
+
+func TestApplyAliases_Swap(t *testing.T) {
+	tmpl := &wetwire.Template{Resources: map[string]wetwire.ResourceDef{
+		"A": {Type: "AWS::S3::Bucket"},
+		"B": {Type: "AWS::SQS::Queue"},
+	}}
+	require.NoError(t, ApplyAliases(tmpl, map[string]string{"A": "B", "B": "A"}))
+
+	assert.Equal(t, "AWS::SQS::Queue", tmpl.Resources["A"].Type)
+	assert.Equal(t, "AWS::S3::Bucket", tmpl.Resources["B"].Type)
+}
+
+func TestApplyAliases_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		wantErr string
+	}{
+		{"unknown resource", map[string]string{"Missing": "Old"}, "no resource named Missing"},
+		{"resource collision", map[string]string{"DataBucket": "Reader"}, "a resource already has that logical ID"},
+		{"parameter collision", map[string]string{"DataBucket": "Environment"}, "a parameter already has that logical ID"},
+		{"duplicate", map[string]string{"DataBucket": "Old", "Reader": "Old"}, "used for both DataBucket and Reader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ApplyAliases(aliasTemplate(), tt.aliases)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// serializeLoop returns the Fn::ForEach::Name key and the arguments of a
+// ForEach resource loop. An unnamed loop takes the variable name, and the
+// GetAtts in the properties of its resources are resolved like those of a
+// resource.
+func (b *Builder) serializeLoop(name string) (string, []any, error) {
+	value, _ := b.values[name].(map[string]any)
+	if len(value) != 1 {
+		return "", nil, fmt.Errorf("%s is not a ForEach loop", name)
+	}
+
+	var key string
+	var args []any
+	for k, v := range value {
+		key = k
+		args, _ = v.([]any)
+	}
+	if !strings.HasPrefix(key, wetwire.ForEachPrefix) || len(args) != 3 {
+		return "", nil, fmt.Errorf("%s is not a ForEach loop", name)
+	}
+	if key == wetwire.ForEachPrefix {
+		key += name
+	}
+	outputs, ok := args[2].(map[string]any)
+	if !ok || len(outputs) == 0 {
+		return "", nil, fmt.Errorf("%s has no Outputs", name)
+	}
+
+	attrRefsByPath := make(map[string]wetwire.AttrRefUsage)
+	for _, usage := range b.resolveAllAttrRefs(name) {
+		attrRefsByPath[usage.FieldPath] = usage
+	}
+	for logicalID, output := range outputs {
+		def, ok := output.(map[string]any)
+		if !ok {
+			continue
+		}
+		if props, ok := def["Properties"].(map[string]any); ok {
+			def["Properties"] = b.transformValueWithPath(props, "Outputs", attrRefsByPath)
+		}
+		outputs[logicalID] = def
+	}
+
+	return key, args, nil
+}
+
+// languageExtensionFunctions are the intrinsic functions that need the
+// AWS::LanguageExtensions transform; Fn::ForEach is matched by prefix.
+var languageExtensionFunctions = map[string]bool{
+	"Fn::Length":       true,
+	"Fn::ToJsonString": true,
+}
+
+// UsesLanguageExtensions reports whether the template uses Fn::ForEach,
+// Fn::Length, Fn::ToJsonString or an intrinsic function as a
+// DeletionPolicy or UpdateReplacePolicy.
+func UsesLanguageExtensions(t *wetwire.Template) bool {
+	for _, def := range t.Resources {
+		if def.IsLoop() || isIntrinsicPolicy(def.DeletionPolicy) || isIntrinsicPolicy(def.UpdateReplacePolicy) {
+			return true
+		}
+		if containsLanguageExtension(def.Properties) || containsLanguageExtension(def.Metadata) {
+			return true
+		}
+	}
+	for _, output := range t.Outputs {
+		if containsLanguageExtension(output.Value) {
+			return true
+		}
+	}
+	for _, condition := range t.Conditions {
+		if containsLanguageExtension(condition) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIntrinsicPolicy reports whether a policy is set to something other
+// than a policy name.
+func isIntrinsicPolicy(policy any) bool {
+	if policy == nil {
+		return false
+	}
+	_, isName := policy.(string)
+	return !isName
+}
+
+// containsLanguageExtension reports whether a value contains a
+// LanguageExtensions function.
+func containsLanguageExtension(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if languageExtensionFunctions[key] || strings.HasPrefix(key, wetwire.ForEachPrefix) {
+				return true
+			}
+			if containsLanguageExtension(val) {
+				return true
+			}
+		}
+	case []any:
+		for _, val := range v {
+			if containsLanguageExtension(val) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+func TestBuilder_Build_ForEachLoop(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"KeyRing": {Name: "KeyRing", Type: "kms.Key"},
+	}
+
+	builder := NewBuilder(resources)
+	builder.SetLoops(map[string]wetwire.DiscoveredLoop{
+		"Topics": {Name: "Topics", File: "topics.go", Line: 3},
+	})
+	builder.SetVarAttrRefs(map[string]VarAttrRefInfo{
+		"Topics": {AttrRefs: []wetwire.AttrRefUsage{
+			{ResourceName: "KeyRing", Attribute: "Arn", FieldPath: "Outputs.KmsMasterKeyId"},
+		}},
+	})
+	builder.SetValue("KeyRing", map[string]any{})
+	builder.SetValue("Topics", map[string]any{
+		"Fn::ForEach::": []any{
+			"TopicName",
+			[]any{"Success", "Failure"},
+			map[string]any{
+				"Topic${TopicName}": map[string]any{
+					"Type": "AWS::SNS::Topic",
+					"Properties": map[string]any{
+						"KmsMasterKeyId": map[string]any{"Fn::GetAtt": []any{"", "Arn"}},
+					},
+				},
+			},
+		},
+	})
+
+	tmpl, err := builder.Build()
+	require.NoError(t, err)
+
+	// An unnamed loop takes the variable name
+	loop, ok := tmpl.Resources["Fn::ForEach::Topics"]
+	require.True(t, ok)
+	require.True(t, loop.IsLoop())
+	assert.Equal(t, "TopicName", loop.ForEach[0])
+
+	topic := loop.ForEach[2].(map[string]any)["Topic${TopicName}"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"KmsMasterKeyId": map[string]any{"Fn::GetAtt": []string{"KeyRing", "Arn"}},
+	}, topic["Properties"])
+
+	assert.Equal(t, wetwire.Transforms{wetwire.TransformLanguageExtensions}, tmpl.Transform)
+}
+
+func TestBuilder_Build_InvalidLoop(t *testing.T) {
+	builder := NewBuilder(map[string]wetwire.DiscoveredResource{})
+	builder.SetLoops(map[string]wetwire.DiscoveredLoop{
+		"Topics": {Name: "Topics", File: "topics.go", Line: 3},
+	})
+	builder.SetValue("Topics", map[string]any{
+		"Fn::ForEach::Topics": []any{"TopicName", []any{"Success"}, map[string]any{}},
+	})
+
+	_, err := builder.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "topics.go:3: Topics has no Outputs")
+}
+
+func TestBuilder_Build_LanguageExtensionsTransform(t *testing.T) {
+	tests := []struct {
+		name     string
+		function string
+		policy   any
+		sam      bool
+		want     wetwire.Transforms
+	}{
+		{name: "none", policy: "Retain"},
+		{name: "Fn::Length", function: "Fn::Length", want: wetwire.Transforms{wetwire.TransformLanguageExtensions}},
+		{name: "Fn::ToJsonString", function: "Fn::ToJsonString", want: wetwire.Transforms{wetwire.TransformLanguageExtensions}},
+		{
+			name:   "intrinsic policy",
+			policy: map[string]any{"Fn::If": []any{"IsProd", "Retain", "Delete"}},
+			want:   wetwire.Transforms{wetwire.TransformLanguageExtensions},
+		},
+		{
+			name:     "before SAM",
+			function: "Fn::Length",
+			sam:      true,
+			want:     wetwire.Transforms{wetwire.TransformLanguageExtensions, wetwire.TransformServerless},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := map[string]wetwire.DiscoveredResource{
+				"Queue": {Name: "Queue", Type: "sqs.Queue"},
+			}
+			if tt.sam {
+				resources["Handler"] = wetwire.DiscoveredResource{Name: "Handler", Type: "serverless.Function"}
+			}
+
+			builder := NewBuilder(resources)
+			props := map[string]any{}
+			if tt.function != "" {
+				props["DelaySeconds"] = map[string]any{tt.function: map[string]any{"Ref": "Subnets"}}
+			}
+			builder.SetValue("Queue", props)
+			builder.SetValue("Handler", map[string]any{})
+			if tt.policy != nil {
+				builder.SetAttributes(map[string]wetwire.DiscoveredAttributes{
+					"QueueAttributes": {Name: "QueueAttributes", Resource: "Queue"},
+				})
+				builder.SetValue("QueueAttributes", map[string]any{"DeletionPolicy": tt.policy})
+			}
+
+			tmpl, err := builder.Build()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tmpl.Transform)
+			if tt.policy != nil {
+				assert.Equal(t, tt.policy, tmpl.Resources["Queue"].DeletionPolicy)
+			}
+		})
+	}
+}
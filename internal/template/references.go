@@ -0,0 +1,101 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+)
+
+// subVariable matches ${Name} and ${Name.Attr} in Fn::Sub strings, skipping
+// ${!Literal} escapes.
+var subVariable = regexp.MustCompile(`\$\{([^!}][^}]*)\}`)
+
+// GetAttArgs returns the resource and attribute of a Fn::GetAtt, written as
+// a list or as "Resource.Attr". A list of more than two names, such as
+// ["Stack", "Outputs", "Name"], joins the attribute with dots.
+func GetAttArgs(arg any) (resource, attr string, ok bool) {
+	var parts []string
+	switch v := arg.(type) {
+	case []string:
+		parts = v
+	case []any:
+		parts = make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", "", false
+			}
+			parts[i] = s
+		}
+	case string:
+		parts = strings.SplitN(v, ".", 2)
+	}
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", false
+	}
+	return parts[0], strings.Join(parts[1:], "."), true
+}
+
+// SubArgs returns the string and the variable map of a Fn::Sub, written as
+// a string or as a [string, variables] list.
+func SubArgs(arg any) (string, map[string]any) {
+	switch v := arg.(type) {
+	case string:
+		return v, nil
+	case []any:
+		if len(v) == 2 {
+			str, _ := v[0].(string)
+			vars, _ := v[1].(map[string]any)
+			return str, vars
+		}
+	}
+	return "", nil
+}
+
+// SubReference is a ${Name} or ${Name.Attr} variable of a Fn::Sub string
+// that is not set by its variable map: a Ref, or a GetAtt when Attr is set.
+type SubReference struct {
+	Name string
+	Attr string
+}
+
+// SubReferences returns the variables of a Fn::Sub string that vars does
+// not set, in the order they appear.
+func SubReferences(str string, vars map[string]any) []SubReference {
+	var refs []SubReference
+	for _, match := range subVariable.FindAllStringSubmatch(str, -1) {
+		if ref, ok := subReference(match[1], vars); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ReplaceSubReferences rewrites the variables of a Fn::Sub string that
+// vars does not set. replace returns the text of the rewritten variable,
+// or false to keep it.
+func ReplaceSubReferences(str string, vars map[string]any, replace func(ref SubReference) (string, bool)) string {
+	return subVariable.ReplaceAllStringFunc(str, func(match string) string {
+		ref, ok := subReference(match[2:len(match)-1], vars)
+		if !ok {
+			return match
+		}
+		if text, ok := replace(ref); ok {
+			return text
+		}
+		return match
+	})
+}
+
+// subReference parses the text of a Fn::Sub variable, reporting false when
+// vars sets it.
+func subReference(variable string, vars map[string]any) (SubReference, bool) {
+	variable = strings.TrimSpace(variable)
+	if _, isVar := vars[variable]; isVar {
+		return SubReference{}, false
+	}
+	name, attr, _ := strings.Cut(variable, ".")
+	if _, isVar := vars[name]; isVar && attr != "" {
+		return SubReference{}, false
+	}
+	return SubReference{Name: name, Attr: attr}, true
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAttArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg      any
+		resource string
+		attr     string
+		ok       bool
+	}{
+		{name: "string list", arg: []string{"Role", "Arn"}, resource: "Role", attr: "Arn", ok: true},
+		{name: "list", arg: []any{"Db", "Endpoint.Address"}, resource: "Db", attr: "Endpoint.Address", ok: true},
+		{name: "nested stack output", arg: []any{"Stack", "Outputs", "Name"}, resource: "Stack", attr: "Outputs.Name", ok: true},
+		{name: "dotted string", arg: "Db.Endpoint.Address", resource: "Db", attr: "Endpoint.Address", ok: true},
+		{name: "attribute from Ref", arg: []any{"Role", map[string]any{"Ref": "Attr"}}},
+		{name: "missing attribute", arg: []any{"Role"}},
+		{name: "empty resource", arg: []string{"", "Arn"}},
+		{name: "string without attribute", arg: "Role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, attr, ok := GetAttArgs(tt.arg)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.resource, resource)
+			assert.Equal(t, tt.attr, attr)
+		})
+	}
+}
+
+func TestSubReferences(t *testing.T) {
+	str, vars := SubArgs([]any{
+		"${Bucket}/${Role.Arn}/${!Literal}/${ Prefix }/${Local.Attr}/${AWS::Region}",
+		map[string]any{"Prefix": "p", "Local": "l"},
+	})
+
+	assert.Equal(t, []SubReference{
+		{Name: "Bucket"},
+		{Name: "Role", Attr: "Arn"},
+		{Name: "AWS::Region"},
+	}, SubReferences(str, vars))
+
+	rewritten := ReplaceSubReferences(str, vars, func(ref SubReference) (string, bool) {
+		return "${New" + ref.Name + "}", ref.Name == "Role"
+	})
+	assert.Equal(t, "${Bucket}/${NewRole}/${!Literal}/${ Prefix }/${Local.Attr}/${AWS::Region}", rewritten)
+}
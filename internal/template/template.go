@@ -26,6 +26,8 @@ type Builder struct {
 	outputs     map[string]wetwire.DiscoveredOutput
 	mappings    map[string]wetwire.DiscoveredMapping
 	conditions  map[string]wetwire.DiscoveredCondition
+	attributes  map[string]wetwire.DiscoveredAttributes
+	loops       map[string]wetwire.DiscoveredLoop
 	values      map[string]any            // Actual struct values for serialization
 	varAttrRefs map[string]VarAttrRefInfo // For recursive AttrRef resolution
 }
@@ -38,6 +40,8 @@ func NewBuilder(resources map[string]wetwire.DiscoveredResource) *Builder {
 		outputs:     make(map[string]wetwire.DiscoveredOutput),
 		mappings:    make(map[string]wetwire.DiscoveredMapping),
 		conditions:  make(map[string]wetwire.DiscoveredCondition),
+		attributes:  make(map[string]wetwire.DiscoveredAttributes),
+		loops:       make(map[string]wetwire.DiscoveredLoop),
 		values:      make(map[string]any),
 		varAttrRefs: make(map[string]VarAttrRefInfo),
 	}
@@ -57,6 +61,8 @@ func NewBuilderFull(
 		outputs:     outputs,
 		mappings:    mappings,
 		conditions:  conditions,
+		attributes:  make(map[string]wetwire.DiscoveredAttributes),
+		loops:       make(map[string]wetwire.DiscoveredLoop),
 		values:      make(map[string]any),
 		varAttrRefs: make(map[string]VarAttrRefInfo),
 	}
@@ -67,6 +73,36 @@ func (b *Builder) SetVarAttrRefs(varAttrRefs map[string]VarAttrRefInfo) {
 	b.varAttrRefs = varAttrRefs
 }
 
+// SetAttributes sets the discovered ResourceAttributes declarations.
+// Their extracted values are supplied through SetValue like any other variable.
+func (b *Builder) SetAttributes(attributes map[string]wetwire.DiscoveredAttributes) {
+	b.attributes = attributes
+}
+
+// SetLoops sets the discovered ForEach resource loops.
+// Their extracted values are supplied through SetValue like any other variable.
+func (b *Builder) SetLoops(loops map[string]wetwire.DiscoveredLoop) {
+	b.loops = loops
+}
+
+// attributesByResource indexes the attribute declarations by target resource.
+func (b *Builder) attributesByResource() map[string]wetwire.DiscoveredAttributes {
+	result := make(map[string]wetwire.DiscoveredAttributes, len(b.attributes))
+	for _, attrs := range b.attributes {
+		result[attrs.Resource] = attrs
+	}
+	return result
+}
+
+// dependencies returns the implicit and explicit dependencies of a resource.
+func (b *Builder) dependencies(name string, attrsByResource map[string]wetwire.DiscoveredAttributes) []string {
+	deps := b.resources[name].Dependencies
+	if attrs, ok := attrsByResource[name]; ok && len(attrs.DependsOn) > 0 {
+		deps = append(append([]string(nil), deps...), attrs.DependsOn...)
+	}
+	return deps
+}
+
 // resolveAllAttrRefs collects all AttrRefUsages reachable from a variable
 // by following all dependencies transitively.
 func (b *Builder) resolveAllAttrRefs(varName string) []wetwire.AttrRefUsage {
@@ -118,6 +154,8 @@ func (b *Builder) Build() (*wetwire.Template, error) {
 		return nil, err
 	}
 
+	attrsByResource := b.attributesByResource()
+
 	template := &wetwire.Template{
 		AWSTemplateFormatVersion: "2010-09-09",
 		Resources:                make(map[string]wetwire.ResourceDef),
@@ -177,10 +215,28 @@ func (b *Builder) Build() (*wetwire.Template, error) {
 			return nil, fmt.Errorf("serializing %s: %w", name, err)
 		}
 
-		template.Resources[name] = wetwire.ResourceDef{
+		def := wetwire.ResourceDef{
 			Type:       resourceType,
 			Properties: props,
 		}
+		if attrs, ok := attrsByResource[name]; ok {
+			if err := b.applyAttributes(&def, attrs); err != nil {
+				return nil, err
+			}
+		}
+		template.Resources[name] = def
+	}
+
+	// Add the ForEach resource loops, keyed Fn::ForEach::Name
+	for name, loop := range b.loops {
+		key, args, err := b.serializeLoop(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", loop.File, loop.Line, err)
+		}
+		if _, exists := template.Resources[key]; exists {
+			return nil, fmt.Errorf("%s:%d: %s: duplicate loop name %q", loop.File, loop.Line, name, key)
+		}
+		template.Resources[key] = wetwire.ResourceDef{ForEach: args}
 	}
 
 	// Build Outputs section
@@ -194,14 +250,53 @@ func (b *Builder) Build() (*wetwire.Template, error) {
 		}
 	}
 
-	// Set SAM Transform header if any SAM resources are present
+	// Set the Transform header: LanguageExtensions must run before SAM
+	if UsesLanguageExtensions(template) {
+		template.Transform = append(template.Transform, wetwire.TransformLanguageExtensions)
+	}
 	if hasSAMResources {
-		template.Transform = "AWS::Serverless-2016-10-31"
+		template.Transform = append(template.Transform, wetwire.TransformServerless)
 	}
 
 	return template, nil
 }
 
+// applyAttributes copies the values of a ResourceAttributes declaration onto
+// the resource definition.
+func (b *Builder) applyAttributes(def *wetwire.ResourceDef, attrs wetwire.DiscoveredAttributes) error {
+	def.DependsOn = attrs.DependsOn
+
+	values, _ := b.values[attrs.Name].(map[string]any)
+	if cond, ok := values["Condition"].(string); ok && cond != "" {
+		if _, exists := b.conditions[cond]; !exists {
+			return fmt.Errorf("%s:%d: %s references undefined condition %q", attrs.File, attrs.Line, attrs.Name, cond)
+		}
+		def.Condition = cond
+	}
+
+	attrRefsByPath := make(map[string]wetwire.AttrRefUsage)
+	for _, usage := range b.resolveAllAttrRefs(attrs.Name) {
+		attrRefsByPath[usage.FieldPath] = usage
+	}
+	// Policies are a name, or an intrinsic under AWS::LanguageExtensions
+	if policy, ok := values["DeletionPolicy"]; ok && policy != "" {
+		def.DeletionPolicy = b.transformValueWithPath(policy, "DeletionPolicy", attrRefsByPath)
+	}
+	if policy, ok := values["UpdateReplacePolicy"]; ok && policy != "" {
+		def.UpdateReplacePolicy = b.transformValueWithPath(policy, "UpdateReplacePolicy", attrRefsByPath)
+	}
+	if metadata, ok := values["Metadata"].(map[string]any); ok {
+		def.Metadata, _ = b.transformValueWithPath(metadata, "Metadata", attrRefsByPath).(map[string]any)
+	}
+	if policy, ok := values["CreationPolicy"].(map[string]any); ok {
+		def.CreationPolicy, _ = b.transformValueWithPath(policy, "CreationPolicy", attrRefsByPath).(map[string]any)
+	}
+	if policy, ok := values["UpdatePolicy"].(map[string]any); ok {
+		def.UpdatePolicy, _ = b.transformValueWithPath(policy, "UpdatePolicy", attrRefsByPath).(map[string]any)
+	}
+	return nil
+}
+
 // serializeParameter converts a Parameter value to the template format.
 func (b *Builder) serializeParameter(name string, value any) wetwire.Parameter {
 	// The value is already serialized as a map from the runner
@@ -278,14 +373,14 @@ func (b *Builder) serializeOutput(name string, value any, discovered wetwire.Dis
 	if exp, ok := valMap["Export"].(map[string]any); ok {
 		if expName, ok := exp["Name"].(string); ok {
 			output.Export = &struct {
-				Name string `json:"Name"`
+				Name string `json:"Name" yaml:"Name"`
 			}{Name: expName}
 		}
 	}
 	// Handle ExportName field (alternative format)
 	if expName, ok := valMap["ExportName"]; ok {
 		output.Export = &struct {
-			Name string `json:"Name"`
+			Name string `json:"Name" yaml:"Name"`
 		}{Name: fmt.Sprintf("%v", expName)}
 	}
 
@@ -472,8 +567,9 @@ func (b *Builder) topologicalSort() ([]string, error) {
 		inDegree[name] = 0
 	}
 
-	for name, res := range b.resources {
-		for _, dep := range res.Dependencies {
+	attrsByResource := b.attributesByResource()
+	for name := range b.resources {
+		for _, dep := range b.dependencies(name, attrsByResource) {
 			if _, exists := b.resources[dep]; exists {
 				graph[dep] = append(graph[dep], name)
 				inDegree[name]++
@@ -521,13 +617,15 @@ func (b *Builder) detectCycle() error {
 	visited := make(map[string]bool)
 	path := make(map[string]bool)
 
+	attrsByResource := b.attributesByResource()
+
 	var cycle []string
 	var findCycle func(node string) bool
 	findCycle = func(node string) bool {
 		visited[node] = true
 		path[node] = true
 
-		for _, dep := range b.resources[node].Dependencies {
+		for _, dep := range b.dependencies(node, attrsByResource) {
 			if _, exists := b.resources[dep]; !exists {
 				continue
 			}
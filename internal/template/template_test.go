@@ -207,7 +207,7 @@ func TestBuilder_Build_SAMFunction(t *testing.T) {
 	require.NoError(t, err)
 
 	// SAM templates must have Transform header
-	assert.Equal(t, "AWS::Serverless-2016-10-31", template.Transform)
+	assert.Equal(t, wetwire.Transforms{wetwire.TransformServerless}, template.Transform)
 	assert.Equal(t, "2010-09-09", template.AWSTemplateFormatVersion)
 
 	// Verify resource type
@@ -235,7 +235,7 @@ func TestBuilder_Build_SAMApi(t *testing.T) {
 	template, err := builder.Build()
 	require.NoError(t, err)
 
-	assert.Equal(t, "AWS::Serverless-2016-10-31", template.Transform)
+	assert.Equal(t, wetwire.Transforms{wetwire.TransformServerless}, template.Transform)
 	assert.Equal(t, "AWS::Serverless::Api", template.Resources["MyApi"].Type)
 }
 
@@ -272,7 +272,7 @@ func TestBuilder_Build_MixedSAMAndCFN(t *testing.T) {
 	require.NoError(t, err)
 
 	// Transform should be set because SAM resources are present
-	assert.Equal(t, "AWS::Serverless-2016-10-31", template.Transform)
+	assert.Equal(t, wetwire.Transforms{wetwire.TransformServerless}, template.Transform)
 
 	// Both resources should be present with correct types
 	assert.Equal(t, "AWS::S3::Bucket", template.Resources["DataBucket"].Type)
@@ -649,6 +649,109 @@ func TestBuilder_Build_WithConditions(t *testing.T) {
 	assert.Contains(t, tmpl.Conditions, "IsProd")
 }
 
+func TestBuilder_Build_WithResourceAttributes(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"LogBucket":  {Name: "LogBucket", Type: "s3.Bucket"},
+		"DataBucket": {Name: "DataBucket", Type: "s3.Bucket"},
+	}
+	conditions := map[string]wetwire.DiscoveredCondition{
+		"IsProd": {Name: "IsProd"},
+	}
+
+	builder := NewBuilderFull(resources, nil, nil, nil, conditions)
+	builder.SetAttributes(map[string]wetwire.DiscoveredAttributes{
+		"DataBucketAttributes": {
+			Name:      "DataBucketAttributes",
+			Resource:  "DataBucket",
+			DependsOn: []string{"LogBucket"},
+		},
+	})
+	builder.SetValue("LogBucket", map[string]any{})
+	builder.SetValue("DataBucket", map[string]any{})
+	builder.SetValue("IsProd", map[string]any{"Fn::Equals": []any{"a", "b"}})
+	builder.SetValue("DataBucketAttributes", map[string]any{
+		"Condition":           "IsProd",
+		"DeletionPolicy":      "Retain",
+		"UpdateReplacePolicy": "Snapshot",
+		"Metadata":            map[string]any{"Owner": "data-team"},
+	})
+
+	tmpl, err := builder.Build()
+	require.NoError(t, err)
+
+	bucket := tmpl.Resources["DataBucket"]
+	assert.Equal(t, []string{"LogBucket"}, bucket.DependsOn)
+	assert.Equal(t, "IsProd", bucket.Condition)
+	assert.Equal(t, "Retain", bucket.DeletionPolicy)
+	assert.Equal(t, "Snapshot", bucket.UpdateReplacePolicy)
+	assert.Equal(t, map[string]any{"Owner": "data-team"}, bucket.Metadata)
+
+	logBucket := tmpl.Resources["LogBucket"]
+	assert.Empty(t, logBucket.DependsOn)
+	assert.Empty(t, logBucket.DeletionPolicy)
+}
+
+func TestBuilder_Build_WithPolicies(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"WebGroup": {Name: "WebGroup", Type: "autoscaling.AutoScalingGroup"},
+	}
+
+	builder := NewBuilder(resources)
+	builder.SetAttributes(map[string]wetwire.DiscoveredAttributes{
+		"WebGroupAttributes": {Name: "WebGroupAttributes", Resource: "WebGroup"},
+	})
+	builder.SetValue("WebGroup", map[string]any{"MinSize": "1", "MaxSize": "3"})
+	builder.SetValue("WebGroupAttributes", map[string]any{
+		"CreationPolicy": map[string]any{
+			"ResourceSignal": map[string]any{"Count": 2, "Timeout": "PT15M"},
+		},
+		"UpdatePolicy": map[string]any{
+			"AutoScalingRollingUpdate": map[string]any{"MaxBatchSize": 1, "PauseTime": "PT5M"},
+		},
+	})
+
+	tmpl, err := builder.Build()
+	require.NoError(t, err)
+
+	group := tmpl.Resources["WebGroup"]
+	assert.Equal(t, map[string]any{"Count": 2, "Timeout": "PT15M"}, group.CreationPolicy["ResourceSignal"])
+	assert.Equal(t, map[string]any{"MaxBatchSize": 1, "PauseTime": "PT5M"}, group.UpdatePolicy["AutoScalingRollingUpdate"])
+}
+
+func TestBuilder_Build_ResourceAttributesUndefinedCondition(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"DataBucket": {Name: "DataBucket", Type: "s3.Bucket"},
+	}
+
+	builder := NewBuilder(resources)
+	builder.SetAttributes(map[string]wetwire.DiscoveredAttributes{
+		"DataBucketAttributes": {Name: "DataBucketAttributes", Resource: "DataBucket", File: "storage.go", Line: 9},
+	})
+	builder.SetValue("DataBucket", map[string]any{})
+	builder.SetValue("DataBucketAttributes", map[string]any{"Condition": "IsProd"})
+
+	_, err := builder.Build()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage.go:9")
+	assert.Contains(t, err.Error(), `undefined condition "IsProd"`)
+}
+
+func TestBuilder_TopologicalSort_ExplicitDependsOn(t *testing.T) {
+	resources := map[string]wetwire.DiscoveredResource{
+		"A": {Name: "A", Type: "s3.Bucket"},
+		"B": {Name: "B", Type: "s3.Bucket"},
+	}
+
+	builder := NewBuilder(resources)
+	builder.SetAttributes(map[string]wetwire.DiscoveredAttributes{
+		"AAttributes": {Name: "AAttributes", Resource: "A", DependsOn: []string{"B"}},
+	})
+
+	order, err := builder.topologicalSort()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"B", "A"}, order)
+}
+
 func TestBuilder_Build_WithOutputExport(t *testing.T) {
 	resources := map[string]wetwire.DiscoveredResource{
 		"MyBucket": {Name: "MyBucket", Type: "s3.Bucket"},
@@ -0,0 +1,96 @@
+// Package worktree checks out git revisions in temporary worktrees, so a
+// package can be built as it was at another revision without touching the
+// working tree.
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree is a temporary checkout of a revision.
+type Worktree struct {
+	// Dir is the root of the checkout.
+	Dir string
+	// Ref is the checked out revision.
+	Ref string
+
+	repo string
+	temp string
+}
+
+// Root returns the top-level directory of the git repository containing dir.
+func Root(dir string) (string, error) {
+	out, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(out), nil
+}
+
+// Add checks out ref of the repository at repo in a new temporary worktree.
+// The worktree is detached, so no branch is created; call Remove when done.
+func Add(repo, ref string) (*Worktree, error) {
+	temp, err := os.MkdirTemp("", "wetwire-worktree-")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(temp, "checkout")
+	if _, err := git(repo, "worktree", "add", "--detach", dir, ref); err != nil {
+		_ = os.RemoveAll(temp)
+		return nil, err
+	}
+	return &Worktree{Dir: dir, Ref: ref, repo: repo, temp: temp}, nil
+}
+
+// Remove deletes the worktree and its temporary directory.
+func (w *Worktree) Remove() error {
+	_, err := git(w.repo, "worktree", "remove", "--force", w.Dir)
+	if rmErr := os.RemoveAll(w.temp); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// Path maps a path in the repository to the same path in the worktree. A
+// trailing "/..." package pattern is kept.
+func (w *Worktree) Path(path string) (string, error) {
+	suffix := ""
+	if strings.HasSuffix(path, "/...") {
+		path, suffix = strings.TrimSuffix(path, "/..."), "/..."
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// Resolve symlinks on both sides, e.g. /tmp -> /private/tmp on macOS
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	repo := w.repo
+	if resolved, err := filepath.EvalSymlinks(repo); err == nil {
+		repo = resolved
+	}
+
+	rel, err := filepath.Rel(repo, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the repository %s", path, w.repo)
+	}
+	return filepath.Join(w.Dir, rel) + suffix, nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
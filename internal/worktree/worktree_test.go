@@ -0,0 +1,100 @@
+package worktree
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initRepo creates a repository with two commits of infra/main.go.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	infra := filepath.Join(repo, "infra")
+	require.NoError(t, os.MkdirAll(infra, 0755))
+	run("init", "-q")
+	for _, version := range []string{"v1", "v2"} {
+		require.NoError(t, os.WriteFile(filepath.Join(infra, "main.go"), []byte(version), 0644))
+		run("add", "-A")
+		run("commit", "-q", "-m", version)
+	}
+	return repo
+}
+
+func TestRoot(t *testing.T) {
+	repo := initRepo(t)
+
+	root, err := Root(filepath.Join(repo, "infra"))
+	require.NoError(t, err)
+
+	want, err := filepath.EvalSymlinks(repo)
+	require.NoError(t, err)
+	assert.Equal(t, want, root)
+}
+
+func TestRoot_NotARepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	_, err := Root(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestAdd(t *testing.T) {
+	repo := initRepo(t)
+
+	wt, err := Add(repo, "HEAD~1")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(wt.Dir, "infra", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	// The working tree is untouched
+	data, err = os.ReadFile(filepath.Join(repo, "infra", "main.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(data))
+
+	require.NoError(t, wt.Remove())
+	assert.NoDirExists(t, wt.Dir)
+}
+
+func TestAdd_UnknownRef(t *testing.T) {
+	repo := initRepo(t)
+
+	_, err := Add(repo, "no-such-ref")
+	assert.ErrorContains(t, err, "git worktree add")
+}
+
+func TestPath(t *testing.T) {
+	repo := initRepo(t)
+	wt, err := Add(repo, "HEAD")
+	require.NoError(t, err)
+	defer func() { _ = wt.Remove() }()
+
+	path, err := wt.Path(filepath.Join(repo, "infra") + "/...")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(wt.Dir, "infra")+"/...", path)
+
+	_, err = wt.Path(t.TempDir())
+	assert.ErrorContains(t, err, "outside the repository")
+}
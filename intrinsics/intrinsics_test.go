@@ -146,6 +146,38 @@ func TestCidr_MarshalJSON(t *testing.T) {
 	assert.JSONEq(t, `{"Fn::Cidr": ["10.0.0.0/16", 6, 8]}`, string(data))
 }
 
+func TestLength_MarshalJSON(t *testing.T) {
+	length := Length{List: Ref{LogicalName: "Subnets"}}
+	data, err := json.Marshal(length)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Fn::Length": {"Ref": "Subnets"}}`, string(data))
+}
+
+func TestToJsonString_MarshalJSON(t *testing.T) {
+	toJSON := ToJsonString{Value: map[string]any{"Env": Ref{LogicalName: "Environment"}}}
+	data, err := json.Marshal(toJSON)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Fn::ToJsonString": {"Env": {"Ref": "Environment"}}}`, string(data))
+}
+
+func TestForEach_MarshalJSON(t *testing.T) {
+	forEach := ForEach{
+		Name:       "Topics",
+		Identifier: "TopicName",
+		Collection: []any{"Success", "Failure"},
+		Outputs: map[string]any{
+			"Topic${TopicName}": map[string]any{"Type": "AWS::SNS::Topic"},
+		},
+	}
+	data, err := json.Marshal(forEach)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Fn::ForEach::Topics": [
+		"TopicName",
+		["Success", "Failure"],
+		{"Topic${TopicName}": {"Type": "AWS::SNS::Topic"}}
+	]}`, string(data))
+}
+
 func TestPseudoParameters(t *testing.T) {
 	// Test that pseudo-parameters serialize correctly
 	tests := []struct {
@@ -0,0 +1,82 @@
+package intrinsics
+
+import (
+	"encoding/json"
+
+	wetwire "github.com/lex00/wetwire-aws-go"
+)
+
+// The functions in this file need the AWS::LanguageExtensions transform,
+// which the template builder adds when a template uses them.
+
+// Length represents the Fn::Length intrinsic function, the number of
+// elements of a list.
+//
+// Example:
+//
+//	Length{Subnets} → {"Fn::Length": {"Ref": "Subnets"}}
+type Length struct {
+	List any
+}
+
+// MarshalJSON serializes Length to CloudFormation syntax.
+func (l Length) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"Fn::Length": l.List})
+}
+
+// ToJsonString represents the Fn::ToJsonString intrinsic function, which
+// converts an object or list to a JSON string.
+//
+// Example:
+//
+//	ToJsonString{Json{"Env": Environment}} → {"Fn::ToJsonString": {"Env": {"Ref": "Environment"}}}
+type ToJsonString struct {
+	Value any
+}
+
+// MarshalJSON serializes ToJsonString to CloudFormation syntax.
+func (t ToJsonString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"Fn::ToJsonString": t.Value})
+}
+
+// ForEach represents the Fn::ForEach intrinsic function, which replicates
+// its outputs once per element of a collection. The keys of Outputs are
+// templates that reference the loop identifier, as ${Identifier} or
+// &{Identifier} (which drops non-alphanumeric characters).
+//
+// Declared as a package-level variable, a ForEach is a resource loop: each
+// output key is a logical ID and each value a resource, so
+//
+//	var Topics = ForEach{
+//	    Name:       "Topics",
+//	    Identifier: "TopicName",
+//	    Collection: []any{"Success", "Failure"},
+//	    Outputs: map[string]any{
+//	        "Topic${TopicName}": sns.Topic{TopicName: Sub{"${TopicName}"}},
+//	    },
+//	}
+//
+// creates the resources TopicSuccess and TopicFailure. Name defaults to the
+// variable name.
+type ForEach struct {
+	// Name is the unique name of the loop, the suffix of Fn::ForEach::Name
+	Name string
+	// Identifier is the placeholder for the current element
+	Identifier string
+	// Collection is a list of strings, or a Ref to a list parameter
+	Collection any
+	// Outputs maps key templates to the values to replicate
+	Outputs map[string]any
+}
+
+// Key returns the Fn::ForEach::Name key of the loop.
+func (f ForEach) Key() string {
+	return wetwire.ForEachPrefix + f.Name
+}
+
+// MarshalJSON serializes ForEach to CloudFormation syntax.
+func (f ForEach) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string][]any{
+		f.Key(): {f.Identifier, f.Collection, f.Outputs},
+	})
+}
@@ -0,0 +1,89 @@
+package wetwire_aws
+
+// Ptr returns a pointer to v. The numeric and boolean policy fields are
+// pointers because zero is a meaningful setting: MinInstancesInService:
+// Ptr(0) and WillReplace: Ptr(false) are written to the template, while a
+// nil field is omitted.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// CreationPolicy makes CloudFormation wait for a resource to be ready before
+// it is marked CREATE_COMPLETE. ResourceSignal is supported on
+// AWS::AutoScaling::AutoScalingGroup, AWS::EC2::Instance and
+// AWS::CloudFormation::WaitCondition; StartFleet on AWS::AppStream::Fleet
+// and AWS::AppStream::ImageBuilder.
+type CreationPolicy struct {
+	// AutoScalingCreationPolicy applies to Auto Scaling groups only
+	AutoScalingCreationPolicy *AutoScalingCreationPolicy `json:"AutoScalingCreationPolicy,omitempty"`
+	// ResourceSignal sets the number of signals and how long to wait for them
+	ResourceSignal *ResourceSignal `json:"ResourceSignal,omitempty"`
+	// StartFleet starts an AppStream fleet when it is created
+	StartFleet *bool `json:"StartFleet,omitempty"`
+}
+
+// AutoScalingCreationPolicy sets the share of instances that must signal
+// success for an Auto Scaling group to be created.
+type AutoScalingCreationPolicy struct {
+	// MinSuccessfulInstancesPercent is the percentage of instances that must signal success
+	MinSuccessfulInstancesPercent *int `json:"MinSuccessfulInstancesPercent,omitempty"`
+}
+
+// ResourceSignal configures the signals a CreationPolicy waits for.
+type ResourceSignal struct {
+	// Count is the number of success signals required
+	Count int `json:"Count,omitempty"`
+	// Timeout is an ISO 8601 duration, e.g. "PT15M"
+	Timeout string `json:"Timeout,omitempty"`
+}
+
+// UpdatePolicy controls how CloudFormation applies updates to a resource.
+// Each field is only valid on specific resource types:
+//
+//   - AutoScalingReplacingUpdate, AutoScalingRollingUpdate and
+//     AutoScalingScheduledAction: AWS::AutoScaling::AutoScalingGroup
+//   - CodeDeployLambdaAliasUpdate: AWS::Lambda::Alias
+//   - EnableVersionUpgrade: AWS::OpenSearchService::Domain and AWS::Elasticsearch::Domain
+//   - UseOnlineResharding: AWS::ElastiCache::ReplicationGroup
+type UpdatePolicy struct {
+	AutoScalingReplacingUpdate  *AutoScalingReplacingUpdate  `json:"AutoScalingReplacingUpdate,omitempty"`
+	AutoScalingRollingUpdate    *AutoScalingRollingUpdate    `json:"AutoScalingRollingUpdate,omitempty"`
+	AutoScalingScheduledAction  *AutoScalingScheduledAction  `json:"AutoScalingScheduledAction,omitempty"`
+	CodeDeployLambdaAliasUpdate *CodeDeployLambdaAliasUpdate `json:"CodeDeployLambdaAliasUpdate,omitempty"`
+	EnableVersionUpgrade        *bool                        `json:"EnableVersionUpgrade,omitempty"`
+	UseOnlineResharding         *bool                        `json:"UseOnlineResharding,omitempty"`
+}
+
+// AutoScalingReplacingUpdate replaces the whole Auto Scaling group on update.
+type AutoScalingReplacingUpdate struct {
+	// WillReplace keeps the old group until the new one is created successfully
+	WillReplace *bool `json:"WillReplace,omitempty"`
+}
+
+// AutoScalingRollingUpdate updates Auto Scaling group instances in batches.
+type AutoScalingRollingUpdate struct {
+	MaxBatchSize                  *int     `json:"MaxBatchSize,omitempty"`
+	MinActiveInstancesPercent     *int     `json:"MinActiveInstancesPercent,omitempty"`
+	MinInstancesInService         *int     `json:"MinInstancesInService,omitempty"`
+	MinSuccessfulInstancesPercent *int     `json:"MinSuccessfulInstancesPercent,omitempty"`
+	PauseTime                     string   `json:"PauseTime,omitempty"`
+	SuspendProcesses              []string `json:"SuspendProcesses,omitempty"`
+	WaitOnResourceSignals         *bool    `json:"WaitOnResourceSignals,omitempty"`
+}
+
+// AutoScalingScheduledAction controls group size properties when the
+// group has scheduled actions.
+type AutoScalingScheduledAction struct {
+	// IgnoreUnmodifiedGroupSizeProperties keeps scheduled sizes unless the template changes them
+	IgnoreUnmodifiedGroupSizeProperties *bool `json:"IgnoreUnmodifiedGroupSizeProperties,omitempty"`
+}
+
+// CodeDeployLambdaAliasUpdate performs a CodeDeploy deployment when the
+// version on a Lambda alias changes. ApplicationName and DeploymentGroupName
+// accept strings, resources (serialized as Ref) or intrinsics.
+type CodeDeployLambdaAliasUpdate struct {
+	AfterAllowTrafficHook  any `json:"AfterAllowTrafficHook,omitempty"`
+	ApplicationName        any `json:"ApplicationName"`
+	BeforeAllowTrafficHook any `json:"BeforeAllowTrafficHook,omitempty"`
+	DeploymentGroupName    any `json:"DeploymentGroupName"`
+}
@@ -0,0 +1,73 @@
+package wetwire_aws
+
+import "encoding/json"
+
+// Value is a property value of type T that may also be an intrinsic
+// function. Typed resource fields use it so the compiler checks literal
+// values while Ref, GetAtt, Sub and friends remain assignable:
+//
+//	var DataBucket = s3.Bucket{
+//	    BucketName:        wetwire.Literal("data"),
+//	    ObjectLockEnabled: wetwire.Literal(true),
+//	    Tags:              ...,
+//	}
+//
+//	var LogBucket = s3.Bucket{
+//	    BucketName: wetwire.Intrinsic[string](Sub{"${AWS::StackName}-logs"}),
+//	}
+//
+//	var ArchiveBucket = s3.Bucket{
+//	    BucketName: wetwire.Ref[string](LogBucket),
+//	}
+//
+// The zero Value is unset and is omitted from the template.
+type Value[T any] struct {
+	literal T
+	expr    any
+	set     bool
+}
+
+// Literal returns a Value holding the literal v.
+func Literal[T any](v T) Value[T] {
+	return Value[T]{literal: v, set: true}
+}
+
+// Expression is a value that serializes to an intrinsic function, such as
+// Ref, Sub, GetAtt or a Parameter of the intrinsics package, or an AttrRef.
+// Plain literals do not implement it, so they cannot be passed to Intrinsic.
+type Expression interface {
+	json.Marshaler
+}
+
+// Intrinsic returns a Value holding an intrinsic function.
+func Intrinsic[T any](expr Expression) Value[T] {
+	return Value[T]{expr: expr, set: true}
+}
+
+// Ref returns a Value holding a Ref to a resource, which the template
+// serializes as {"Ref": name}.
+func Ref[T any](resource Resource) Value[T] {
+	return Value[T]{expr: resource, set: true}
+}
+
+// CFValue returns the literal or intrinsic held by v, or nil if v is unset.
+// The runner uses it to serialize typed fields.
+func (v Value[T]) CFValue() any {
+	if !v.set {
+		return nil
+	}
+	if v.expr != nil {
+		return v.expr
+	}
+	return v.literal
+}
+
+// IsZero returns true if v has not been set.
+func (v Value[T]) IsZero() bool {
+	return !v.set
+}
+
+// MarshalJSON serializes the literal or intrinsic held by v.
+func (v Value[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.CFValue())
+}
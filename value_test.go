@@ -0,0 +1,53 @@
+package wetwire_aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValue_Literal(t *testing.T) {
+	v := Literal("my-bucket")
+
+	assert.False(t, v.IsZero())
+	assert.Equal(t, "my-bucket", v.CFValue())
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"my-bucket"`, string(data))
+}
+
+func TestValue_Intrinsic(t *testing.T) {
+	v := Intrinsic[string](AttrRef{Resource: "MyRole", Attribute: "Arn"})
+
+	assert.False(t, v.IsZero())
+
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Fn::GetAtt":["MyRole","Arn"]}`, string(data))
+}
+
+// testResource is a resource for Ref values.
+type testResource struct{}
+
+func (testResource) ResourceType() string { return "AWS::S3::Bucket" }
+
+func TestValue_Ref(t *testing.T) {
+	v := Ref[string](testResource{})
+
+	assert.False(t, v.IsZero())
+	assert.Equal(t, testResource{}, v.CFValue(), "the runner turns the resource into a Ref")
+}
+
+func TestValue_Zero(t *testing.T) {
+	var v Value[int]
+
+	assert.True(t, v.IsZero())
+	assert.Nil(t, v.CFValue())
+
+	// A literal zero value is still set
+	assert.False(t, Literal(0).IsZero())
+	assert.Equal(t, 0, Literal(0).CFValue())
+}